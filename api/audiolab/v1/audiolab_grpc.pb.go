@@ -0,0 +1,254 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.28.3
+// source: audiolab/v1/audiolab.proto
+
+package audiolabv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	AudioLab_ProcessAudio_FullMethodName  = "/audiolab.v1.AudioLab/ProcessAudio"
+	AudioLab_ProcessBatch_FullMethodName  = "/audiolab.v1.AudioLab/ProcessBatch"
+	AudioLab_ProbeAudio_FullMethodName    = "/audiolab.v1.AudioLab/ProbeAudio"
+	AudioLab_WatchProgress_FullMethodName = "/audiolab.v1.AudioLab/WatchProgress"
+)
+
+// AudioLabClient is the client API for AudioLab service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// AudioLab exposes the processing pipeline to non-Go services
+type AudioLabClient interface {
+	// ProcessAudio runs a single job and returns once it has finished
+	ProcessAudio(ctx context.Context, in *ProcessAudioRequest, opts ...grpc.CallOption) (*JobResult, error)
+	// ProcessBatch runs jobs concurrently, streaming each result as it finishes
+	ProcessBatch(ctx context.Context, in *ProcessBatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[JobResult], error)
+	// ProbeAudio returns metadata about a file without processing it
+	ProbeAudio(ctx context.Context, in *ProbeAudioRequest, opts ...grpc.CallOption) (*AudioMetadata, error)
+	// WatchProgress streams progress updates until the client disconnects
+	WatchProgress(ctx context.Context, in *WatchProgressRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ProgressUpdate], error)
+}
+
+type audioLabClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAudioLabClient(cc grpc.ClientConnInterface) AudioLabClient {
+	return &audioLabClient{cc}
+}
+
+func (c *audioLabClient) ProcessAudio(ctx context.Context, in *ProcessAudioRequest, opts ...grpc.CallOption) (*JobResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(JobResult)
+	err := c.cc.Invoke(ctx, AudioLab_ProcessAudio_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *audioLabClient) ProcessBatch(ctx context.Context, in *ProcessBatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[JobResult], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &AudioLab_ServiceDesc.Streams[0], AudioLab_ProcessBatch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ProcessBatchRequest, JobResult]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AudioLab_ProcessBatchClient = grpc.ServerStreamingClient[JobResult]
+
+func (c *audioLabClient) ProbeAudio(ctx context.Context, in *ProbeAudioRequest, opts ...grpc.CallOption) (*AudioMetadata, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AudioMetadata)
+	err := c.cc.Invoke(ctx, AudioLab_ProbeAudio_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *audioLabClient) WatchProgress(ctx context.Context, in *WatchProgressRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ProgressUpdate], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &AudioLab_ServiceDesc.Streams[1], AudioLab_WatchProgress_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchProgressRequest, ProgressUpdate]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AudioLab_WatchProgressClient = grpc.ServerStreamingClient[ProgressUpdate]
+
+// AudioLabServer is the server API for AudioLab service.
+// All implementations must embed UnimplementedAudioLabServer
+// for forward compatibility.
+//
+// AudioLab exposes the processing pipeline to non-Go services
+type AudioLabServer interface {
+	// ProcessAudio runs a single job and returns once it has finished
+	ProcessAudio(context.Context, *ProcessAudioRequest) (*JobResult, error)
+	// ProcessBatch runs jobs concurrently, streaming each result as it finishes
+	ProcessBatch(*ProcessBatchRequest, grpc.ServerStreamingServer[JobResult]) error
+	// ProbeAudio returns metadata about a file without processing it
+	ProbeAudio(context.Context, *ProbeAudioRequest) (*AudioMetadata, error)
+	// WatchProgress streams progress updates until the client disconnects
+	WatchProgress(*WatchProgressRequest, grpc.ServerStreamingServer[ProgressUpdate]) error
+	mustEmbedUnimplementedAudioLabServer()
+}
+
+// UnimplementedAudioLabServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAudioLabServer struct{}
+
+func (UnimplementedAudioLabServer) ProcessAudio(context.Context, *ProcessAudioRequest) (*JobResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ProcessAudio not implemented")
+}
+func (UnimplementedAudioLabServer) ProcessBatch(*ProcessBatchRequest, grpc.ServerStreamingServer[JobResult]) error {
+	return status.Errorf(codes.Unimplemented, "method ProcessBatch not implemented")
+}
+func (UnimplementedAudioLabServer) ProbeAudio(context.Context, *ProbeAudioRequest) (*AudioMetadata, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ProbeAudio not implemented")
+}
+func (UnimplementedAudioLabServer) WatchProgress(*WatchProgressRequest, grpc.ServerStreamingServer[ProgressUpdate]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchProgress not implemented")
+}
+func (UnimplementedAudioLabServer) mustEmbedUnimplementedAudioLabServer() {}
+func (UnimplementedAudioLabServer) testEmbeddedByValue()                  {}
+
+// UnsafeAudioLabServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AudioLabServer will
+// result in compilation errors.
+type UnsafeAudioLabServer interface {
+	mustEmbedUnimplementedAudioLabServer()
+}
+
+func RegisterAudioLabServer(s grpc.ServiceRegistrar, srv AudioLabServer) {
+	// If the following call pancis, it indicates UnimplementedAudioLabServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&AudioLab_ServiceDesc, srv)
+}
+
+func _AudioLab_ProcessAudio_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProcessAudioRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AudioLabServer).ProcessAudio(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AudioLab_ProcessAudio_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AudioLabServer).ProcessAudio(ctx, req.(*ProcessAudioRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AudioLab_ProcessBatch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ProcessBatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AudioLabServer).ProcessBatch(m, &grpc.GenericServerStream[ProcessBatchRequest, JobResult]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AudioLab_ProcessBatchServer = grpc.ServerStreamingServer[JobResult]
+
+func _AudioLab_ProbeAudio_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProbeAudioRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AudioLabServer).ProbeAudio(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AudioLab_ProbeAudio_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AudioLabServer).ProbeAudio(ctx, req.(*ProbeAudioRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AudioLab_WatchProgress_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchProgressRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AudioLabServer).WatchProgress(m, &grpc.GenericServerStream[WatchProgressRequest, ProgressUpdate]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AudioLab_WatchProgressServer = grpc.ServerStreamingServer[ProgressUpdate]
+
+// AudioLab_ServiceDesc is the grpc.ServiceDesc for AudioLab service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AudioLab_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "audiolab.v1.AudioLab",
+	HandlerType: (*AudioLabServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ProcessAudio",
+			Handler:    _AudioLab_ProcessAudio_Handler,
+		},
+		{
+			MethodName: "ProbeAudio",
+			Handler:    _AudioLab_ProbeAudio_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ProcessBatch",
+			Handler:       _AudioLab_ProcessBatch_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchProgress",
+			Handler:       _AudioLab_WatchProgress_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "audiolab/v1/audiolab.proto",
+}
@@ -0,0 +1,5 @@
+// Package audiolabv1 holds the gRPC service definition of audiolabd and
+// its generated Go bindings
+package audiolabv1
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative audiolab/v1/audiolab.proto
@@ -0,0 +1,331 @@
+// Package bus consumes job messages from a message bus such as NATS or
+// Kafka and publishes progress and result events back
+package bus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Skryldev/audio-lab/application/jobspec"
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/domain/ports"
+	"github.com/Skryldev/audio-lab/pkg/logger"
+	"github.com/Skryldev/audio-lab/pkg/progress"
+	"go.uber.org/zap"
+)
+
+// Message is one job message received from the bus. Data holds a
+// jobspec.Job encoded as JSON, as described by jobspec.JobJSONSchema.
+type Message struct {
+	Data []byte
+
+	// Ack marks the message as handled; Nak asks for redelivery. Either may
+	// be nil when the transport has no acknowledgements.
+	Ack func() error
+	Nak func() error
+
+	// InProgress extends the message's acknowledgement deadline. The
+	// consumer calls it every Config.HeartbeatInterval while the job runs;
+	// nil when the transport has no deadline.
+	InProgress func() error
+}
+
+// Transport connects the consumer to a message bus
+type Transport interface {
+	// Receive blocks until the next job message arrives or ctx is done
+	Receive(ctx context.Context) (*Message, error)
+
+	// Publish sends data on subject (a topic, for Kafka)
+	Publish(ctx context.Context, subject string, data []byte) error
+}
+
+// Config holds consumer configuration
+type Config struct {
+	// ResultSubject receives a jobspec.Event for every finished job
+	// (default: "audiolab.results")
+	ResultSubject string
+
+	// ProgressSubject receives jobspec.Event progress updates
+	// (default: "audiolab.progress")
+	ProgressSubject string
+
+	// DisableProgress turns progress events off
+	DisableProgress bool
+
+	// ProgressInterval is the minimum time between progress events of one
+	// job; stage changes are always sent (default: 1s)
+	ProgressInterval time.Duration
+
+	// Concurrency is the number of messages handled at once (default: 1).
+	// Encodes still share the processor's worker lane.
+	Concurrency int
+
+	// HeartbeatInterval is how often a running job's message is marked in
+	// progress, keeping it from being redelivered; keep it well below the
+	// transport's acknowledgement deadline (default: 10s)
+	HeartbeatInterval time.Duration
+
+	// Paths restricts the files and URLs jobs may name, as for the network
+	// servers. Nil trusts the publishers' paths; the input format is
+	// checked with jobspec.CheckInputFormat either way.
+	Paths *jobspec.PathPolicy
+}
+
+// Consumer processes job messages from a Transport. It is also a
+// progress.Reporter and must be subscribed to the processor's progress
+// updates for progress events to be published.
+type Consumer struct {
+	transport Transport
+	processor ports.AudioProcessor
+	cfg       Config
+	log       *logger.Logger
+
+	mu     sync.Mutex
+	active map[string]*jobProgress // by job ID
+}
+
+// jobProgress throttles the progress events of one job
+type jobProgress struct {
+	stage progress.Stage
+	sent  time.Time
+}
+
+// NewConsumer creates a consumer that runs jobs from transport on processor
+func NewConsumer(transport Transport, processor ports.AudioProcessor, cfg Config, log *logger.Logger) (*Consumer, error) {
+	if transport == nil {
+		return nil, fmt.Errorf("Transport is required")
+	}
+	if processor == nil {
+		return nil, fmt.Errorf("AudioProcessor is required")
+	}
+	if cfg.ResultSubject == "" {
+		cfg.ResultSubject = "audiolab.results"
+	}
+	if cfg.ProgressSubject == "" {
+		cfg.ProgressSubject = "audiolab.progress"
+	}
+	if cfg.ProgressInterval <= 0 {
+		cfg.ProgressInterval = time.Second
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.HeartbeatInterval <= 0 {
+		cfg.HeartbeatInterval = 10 * time.Second
+	}
+	if log == nil {
+		var err error
+		log, err = logger.New(false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create logger: %w", err)
+		}
+	}
+
+	return &Consumer{
+		transport: transport,
+		processor: processor,
+		cfg:       cfg,
+		log:       log,
+		active:    make(map[string]*jobProgress),
+	}, nil
+}
+
+// Run handles messages until ctx is canceled. Messages whose jobs are
+// interrupted are negatively acknowledged for redelivery.
+func (c *Consumer) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for i := 0; i < c.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.loop(ctx)
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (c *Consumer) loop(ctx context.Context) {
+	for {
+		msg, err := c.transport.Receive(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			c.log.Error("failed to receive job message", zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		c.handle(ctx, msg)
+	}
+}
+
+// batchJob checks spec against the path policy and converts it
+func (c *Consumer) batchJob(ctx context.Context, spec *jobspec.Job) (model.BatchJob, error) {
+	if c.cfg.Paths != nil {
+		if err := c.cfg.Paths.CheckJob(ctx, spec); err != nil {
+			return model.BatchJob{}, err
+		}
+	} else if spec.Options != nil {
+		if err := jobspec.CheckInputFormat(spec.Options.InputFormat); err != nil {
+			return model.BatchJob{}, err
+		}
+	}
+	var defaults []ports.Option
+	if d, ok := c.processor.(ports.DefaultsProvider); ok {
+		defaults = d.Defaults()
+	}
+	return spec.BatchJob(defaults...)
+}
+
+func (c *Consumer) handle(ctx context.Context, msg *Message) {
+	var spec jobspec.Job
+	if err := json.Unmarshal(msg.Data, &spec); err != nil {
+		// A malformed message will never succeed; drop it
+		c.log.Error("dropping malformed job message", zap.Error(err))
+		c.settle(msg.Ack)
+		return
+	}
+	if spec.ID == "" {
+		spec.ID = jobspec.NewJobID()
+	}
+
+	job, err := c.batchJob(ctx, &spec)
+	if err != nil {
+		c.publishResult(ctx, model.BatchResult{JobID: spec.ID, Err: err})
+		c.settle(msg.Ack)
+		return
+	}
+
+	c.track(job.ID)
+	defer c.untrack(job.ID)
+
+	if msg.InProgress != nil {
+		stop := c.heartbeat(msg.InProgress)
+		defer stop()
+	}
+
+	result := c.process(ctx, job)
+	if ctx.Err() != nil {
+		c.settle(msg.Nak)
+		return
+	}
+
+	c.publishResult(ctx, result)
+	c.settle(msg.Ack)
+}
+
+func (c *Consumer) process(ctx context.Context, job model.BatchJob) model.BatchResult {
+	results, err := c.processor.ProcessBatch(ctx, []model.BatchJob{job})
+	if err != nil {
+		return model.BatchResult{JobID: job.ID, Err: err}
+	}
+	result, ok := <-results
+	if !ok {
+		return model.BatchResult{JobID: job.ID, Err: ctx.Err()}
+	}
+	return result
+}
+
+// heartbeat calls inProgress every HeartbeatInterval until stop is called
+func (c *Consumer) heartbeat(inProgress func() error) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(c.cfg.HeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := inProgress(); err != nil {
+					c.log.Warn("failed to extend job message deadline", zap.Error(err))
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// Report publishes progress events for jobs this consumer is running
+func (c *Consumer) Report(u progress.Update) {
+	if c.cfg.DisableProgress {
+		return
+	}
+
+	c.mu.Lock()
+	p, ok := c.active[u.JobID]
+	send := ok && (u.Stage != p.stage || u.Stage == progress.StageDone || time.Since(p.sent) >= c.cfg.ProgressInterval)
+	if send {
+		p.stage, p.sent = u.Stage, time.Now()
+	}
+	c.mu.Unlock()
+	if !send {
+		return
+	}
+
+	pr := jobspec.NewProgress(u)
+	c.publish(context.Background(), c.cfg.ProgressSubject, jobspec.Event{
+		Type:      jobspec.EventProgress,
+		JobID:     u.JobID,
+		Progress:  &pr,
+		Timestamp: u.Timestamp,
+	})
+}
+
+func (c *Consumer) publishResult(ctx context.Context, r model.BatchResult) {
+	res := jobspec.NewResult(r)
+	c.publish(ctx, c.cfg.ResultSubject, jobspec.Event{
+		Type:      jobspec.EventResult,
+		JobID:     r.JobID,
+		Result:    &res,
+		Timestamp: time.Now(),
+	})
+}
+
+func (c *Consumer) publish(ctx context.Context, subject string, event jobspec.Event) {
+	data, err := json.Marshal(event)
+	if err == nil {
+		err = c.transport.Publish(ctx, subject, data)
+	}
+	if err != nil {
+		c.log.Warn("failed to publish job event",
+			zap.String("subject", subject),
+			zap.String("job_id", event.JobID),
+			zap.Error(err),
+		)
+	}
+}
+
+func (c *Consumer) track(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.active[id] = &jobProgress{}
+}
+
+func (c *Consumer) untrack(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.active, id)
+}
+
+func (c *Consumer) settle(f func() error) {
+	if f == nil {
+		return
+	}
+	if err := f(); err != nil {
+		c.log.Warn("failed to acknowledge job message", zap.Error(err))
+	}
+}
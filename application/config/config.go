@@ -0,0 +1,132 @@
+// Package config reads the YAML or JSON configuration file shared by the
+// library and the CLI: executor settings, default job options and presets
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Skryldev/audio-lab/application/jobspec"
+	"github.com/Skryldev/audio-lab/application/presets"
+	"github.com/Skryldev/audio-lab/domain/ports"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// File is the serializable configuration. YAML files use the same field
+// names as JSON.
+type File struct {
+	FFmpeg       string `json:"ffmpeg,omitempty"`
+	FFprobe      string `json:"ffprobe,omitempty"`
+	Workers      int    `json:"workers,omitempty"`
+	TempDir      string `json:"temp_dir,omitempty"`
+	Fingerprints string `json:"fingerprints,omitempty"`
+
+	// Options are the default options of every job
+	Options jobspec.Options `json:"options"`
+
+	// Presets are registered by RegisterPresets and can be selected by
+	// name with the preset option, like the curated ones
+	Presets []Preset `json:"presets,omitempty"`
+}
+
+// Preset is the serializable form of a named preset
+type Preset struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Options     jobspec.Options `json:"options"`
+}
+
+// Load reads the config file at path. Files ending in .yaml or .yml are
+// parsed as YAML, anything else as JSON. Unknown fields are rejected.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f *File
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		f, err = ParseYAML(data)
+	default:
+		f, err = ParseJSON(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// ParseJSON decodes a JSON config
+func ParseJSON(data []byte) (*File, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	f := &File{}
+	if err := dec.Decode(f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// ParseYAML decodes a YAML config. The document is converted to JSON first
+// so both formats share one schema and its json field names.
+func ParseYAML(data []byte) (*File, error) {
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return &File{}, nil
+	}
+	js, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJSON(js)
+}
+
+// RegisterPresets validates the file's presets and registers them. A
+// preset may build on one registered earlier, including those defined
+// above it in the file. Nothing is registered unless every preset is
+// valid.
+func (f *File) RegisterPresets() error {
+	defined := make(map[string]bool, len(f.Presets))
+	valid := make([]presets.Preset, 0, len(f.Presets))
+	for i, p := range f.Presets {
+		if p.Name == "" {
+			return pkgerrors.NewValidationError(fmt.Sprintf("presets[%d].name", i), "", "preset name must not be empty")
+		}
+		// A base defined above isn't registered yet; WithPreset looks it
+		// up only when the options are applied
+		o := p.Options
+		base := o.Preset
+		if defined[base] {
+			o.Preset = ""
+		}
+		opts, err := o.Apply()
+		if err != nil {
+			return fmt.Errorf("preset %s: %w", p.Name, err)
+		}
+		if defined[base] {
+			opts = append([]ports.Option{presets.WithPreset(base)}, opts...)
+		}
+		defined[p.Name] = true
+		valid = append(valid, presets.Preset{Name: p.Name, Description: p.Description, Options: opts})
+	}
+	for _, p := range valid {
+		if err := presets.Register(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// JobOptions validates the default job options and returns them as
+// functional options
+func (f *File) JobOptions() ([]ports.Option, error) {
+	return f.Options.Apply()
+}
@@ -0,0 +1,235 @@
+package fixity
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/ports"
+	"github.com/Skryldev/audio-lab/infrastructure/ffmpeg"
+	"github.com/Skryldev/audio-lab/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Status is the outcome of a single fixity check
+type Status string
+
+const (
+	StatusOK          Status = "ok"
+	StatusMissing     Status = "missing"
+	StatusBitRot      Status = "checksum_mismatch"
+	StatusDecodeError Status = "decode_error"
+	StatusCheckFailed Status = "check_failed"
+)
+
+// Record is a stored output whose integrity should be re-verified
+type Record struct {
+	Path       string
+	Checksum   string // hex-encoded SHA-256 recorded at write time
+	RecordedAt time.Time
+}
+
+// Report holds the result of re-verifying one record
+type Report struct {
+	Path      string
+	Status    Status
+	Expected  string
+	Actual    string
+	Detail    string
+	CheckedAt time.Time
+}
+
+// Store is the history store the checker verifies against
+type Store interface {
+	// List returns the records eligible for verification
+	List(ctx context.Context) ([]Record, error)
+
+	// SaveReport persists the outcome of a check
+	SaveReport(ctx context.Context, report Report) error
+}
+
+// Checksummer computes a hex-encoded SHA-256 of a stored file
+type Checksummer interface {
+	Checksum(ctx context.Context, path string) (string, error)
+}
+
+// Config holds fixity scheduler configuration
+type Config struct {
+	// Interval between spot-check rounds (default: 24h)
+	Interval time.Duration
+
+	// SampleSize is the number of records checked per round; 0 checks all
+	SampleSize int
+
+	// DecodeCheck additionally decodes each file fully with ffmpeg
+	DecodeCheck bool
+
+	// OnReport is called for every completed check (optional)
+	OnReport func(Report)
+}
+
+// Checker re-verifies stored outputs against the history store
+type Checker struct {
+	store    Store
+	storage  ports.StorageProvider
+	hasher   Checksummer
+	executor ports.FFmpegExecutor
+	cfg      Config
+	log      *logger.Logger
+}
+
+// NewChecker creates a new fixity checker
+func NewChecker(store Store, storage ports.StorageProvider, hasher Checksummer, executor ports.FFmpegExecutor, cfg Config, log *logger.Logger) (*Checker, error) {
+	if store == nil {
+		return nil, fmt.Errorf("fixity Store is required")
+	}
+	if storage == nil {
+		return nil, fmt.Errorf("StorageProvider is required")
+	}
+	if hasher == nil {
+		return nil, fmt.Errorf("Checksummer is required")
+	}
+	if cfg.DecodeCheck && executor == nil {
+		return nil, fmt.Errorf("FFmpegExecutor is required for decode checks")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 24 * time.Hour
+	}
+	if log == nil {
+		var err error
+		log, err = logger.New(false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create logger: %w", err)
+		}
+	}
+
+	return &Checker{
+		store:    store,
+		storage:  storage,
+		hasher:   hasher,
+		executor: executor,
+		cfg:      cfg,
+		log:      log,
+	}, nil
+}
+
+// Run performs spot-check rounds every Interval until ctx is canceled
+func (c *Checker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := c.CheckOnce(ctx); err != nil && ctx.Err() == nil {
+			c.log.Error("fixity round failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckOnce runs a single spot-check round and returns its reports
+func (c *Checker) CheckOnce(ctx context.Context) ([]Report, error) {
+	records, err := c.store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fixity records: %w", err)
+	}
+
+	records = sample(records, c.cfg.SampleSize)
+	reports := make([]Report, 0, len(records))
+
+	for _, rec := range records {
+		if err := ctx.Err(); err != nil {
+			return reports, err
+		}
+
+		report := c.Check(ctx, rec)
+		reports = append(reports, report)
+
+		if report.Status != StatusOK {
+			c.log.Warn("fixity check failed",
+				zap.String("path", report.Path),
+				zap.String("status", string(report.Status)),
+				zap.String("detail", report.Detail),
+			)
+		}
+		if err := c.store.SaveReport(ctx, report); err != nil {
+			c.log.Error("failed to save fixity report", zap.String("path", rec.Path), zap.Error(err))
+		}
+		if c.cfg.OnReport != nil {
+			c.cfg.OnReport(report)
+		}
+	}
+
+	return reports, nil
+}
+
+// Check verifies a single record
+func (c *Checker) Check(ctx context.Context, rec Record) Report {
+	report := Report{
+		Path:      rec.Path,
+		Expected:  rec.Checksum,
+		CheckedAt: time.Now(),
+	}
+
+	exists, err := c.storage.Exists(ctx, rec.Path)
+	if err != nil {
+		report.Status = StatusCheckFailed
+		report.Detail = err.Error()
+		return report
+	}
+	if !exists {
+		report.Status = StatusMissing
+		return report
+	}
+
+	sum, err := c.hasher.Checksum(ctx, rec.Path)
+	if err != nil {
+		report.Status = StatusCheckFailed
+		report.Detail = err.Error()
+		return report
+	}
+	report.Actual = sum
+	if !strings.EqualFold(sum, rec.Checksum) {
+		report.Status = StatusBitRot
+		return report
+	}
+
+	if c.cfg.DecodeCheck {
+		args := []string{"-v", "error", "-i", rec.Path, "-f", "null", "-"}
+		// Without output capture only the exit status is checked
+		var stderr []byte
+		if ffmpeg.SupportsPipes(c.executor) {
+			_, stderr, err = ffmpeg.Piped(c.executor).ExecuteWithOutput(ctx, args)
+		} else {
+			err = c.executor.Execute(ctx, args)
+		}
+		if err != nil || len(strings.TrimSpace(string(stderr))) > 0 {
+			report.Status = StatusDecodeError
+			report.Detail = strings.TrimSpace(string(stderr))
+			if report.Detail == "" && err != nil {
+				report.Detail = err.Error()
+			}
+			return report
+		}
+	}
+
+	report.Status = StatusOK
+	return report
+}
+
+// sample returns up to n randomly chosen records; n <= 0 returns all
+func sample(records []Record, n int) []Record {
+	if n <= 0 || n >= len(records) {
+		return records
+	}
+	out := make([]Record, len(records))
+	copy(out, records)
+	rand.Shuffle(len(out), func(i, j int) { out[i], out[j] = out[j], out[i] })
+	return out[:n]
+}
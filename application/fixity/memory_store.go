@@ -0,0 +1,53 @@
+package fixity
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryStore is an in-memory Store, useful for tests and small deployments
+type MemoryStore struct {
+	mu      sync.RWMutex
+	records map[string]Record
+	reports map[string]Report
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records: make(map[string]Record),
+		reports: make(map[string]Report),
+	}
+}
+
+// Add records a path and its checksum for later verification
+func (s *MemoryStore) Add(rec Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[rec.Path] = rec
+}
+
+func (s *MemoryStore) List(_ context.Context) ([]Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Record, 0, len(s.records))
+	for _, r := range s.records {
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) SaveReport(_ context.Context, report Report) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reports[report.Path] = report
+	return nil
+}
+
+// LastReport returns the most recent report for path
+func (s *MemoryStore) LastReport(path string) (Report, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.reports[path]
+	return r, ok
+}
@@ -0,0 +1,454 @@
+// Package jobspec defines the JSON wire format of jobs and results shared
+// by the message-bus consumer and the network servers
+package jobspec
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/Skryldev/audio-lab/application/presets"
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/domain/ports"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"github.com/Skryldev/audio-lab/pkg/progress"
+)
+
+// Job is the JSON form of a batch job
+type Job struct {
+	ID      string   `json:"id,omitempty"`
+	Input   string   `json:"input"`
+	Output  string   `json:"output"`
+	Options *Options `json:"options,omitempty"`
+
+	// Priority orders waiting jobs; higher values start first
+	Priority int `json:"priority,omitempty"`
+}
+
+// Options is the JSON form of the processing options. Omitted fields keep
+// their defaults.
+type Options struct {
+	Preset  string            `json:"preset,omitempty"` // applied first; other fields override it
+	Profile string            `json:"profile,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+
+	Codec       string `json:"codec,omitempty"`
+	Bitrate     int    `json:"bitrate,omitempty"` // bps
+	BitrateMode string `json:"bitrate_mode,omitempty"`
+	SampleRate  int    `json:"sample_rate,omitempty"`
+
+	InputFormat  string `json:"input_format,omitempty"`
+	OutputFormat string `json:"output_format,omitempty"`
+	Threads      int    `json:"threads,omitempty"`
+
+	AudioStream   *int   `json:"audio_stream,omitempty"`   // container stream index
+	AudioLanguage string `json:"audio_language,omitempty"` // ISO 639-2, e.g. "eng"
+
+	Metadata       map[string]string `json:"metadata,omitempty"`        // output tags, e.g. title
+	MetadataPolicy string            `json:"metadata_policy,omitempty"` // copy, strip or replace
+
+	CoverArt        string `json:"cover_art,omitempty"`          // image path
+	CoverArtMaxSize int    `json:"cover_art_max_size,omitempty"` // pixels
+	CoverArtFormat  string `json:"cover_art_format,omitempty"`   // jpeg or png
+
+	ID3Version int  `json:"id3_version,omitempty"` // 3 or 4, MP3 outputs only
+	ID3v1      bool `json:"id3v1,omitempty"`
+
+	Chapters []Chapter `json:"chapters,omitempty"`
+	Gapless  bool      `json:"gapless,omitempty"`
+	BWF      *BWF      `json:"bwf,omitempty"` // WAV outputs only
+
+	Lyrics         string `json:"lyrics,omitempty"`      // plain text or LRC
+	LyricsFile     string `json:"lyrics_file,omitempty"` // .txt or .lrc
+	LyricsLanguage string `json:"lyrics_language,omitempty"`
+
+	ISRC          string `json:"isrc,omitempty"`
+	Barcode       string `json:"barcode,omitempty"` // UPC or EAN
+	CatalogNumber string `json:"catalog_number,omitempty"`
+
+	Normalize      *bool    `json:"normalize,omitempty"`
+	LoudnessTarget *float64 `json:"loudness_target,omitempty"` // LUFS
+	DCRemoval      bool     `json:"dc_removal,omitempty"`
+	HighpassHz     int      `json:"highpass_hz,omitempty"`
+	LowpassHz      int      `json:"lowpass_hz,omitempty"`
+
+	LeadingPaddingSeconds  float64 `json:"leading_padding_seconds,omitempty"`
+	TrailingPaddingSeconds float64 `json:"trailing_padding_seconds,omitempty"`
+
+	AdditionalOutputs []Output `json:"additional_outputs,omitempty"`
+
+	SkipPolicy             string  `json:"skip_policy,omitempty"`
+	Verify                 bool    `json:"verify,omitempty"`
+	VerifyToleranceSeconds float64 `json:"verify_tolerance_seconds,omitempty"`
+	TimeoutSeconds         float64 `json:"timeout_seconds,omitempty"`
+	MaxRetries             *int    `json:"max_retries,omitempty"`
+	DryRun                 bool    `json:"dry_run,omitempty"`
+}
+
+// Output is an additional output encoded from the same decode pass. Zero
+// fields inherit from the job.
+type Output struct {
+	Path        string `json:"path"`
+	Codec       string `json:"codec,omitempty"`
+	Bitrate     int    `json:"bitrate,omitempty"`
+	BitrateMode string `json:"bitrate_mode,omitempty"`
+	SampleRate  int    `json:"sample_rate,omitempty"`
+}
+
+// Chapter is a chapter written to the output. A zero end runs to the next
+// chapter, or the end of the input.
+type Chapter struct {
+	StartSeconds float64 `json:"start_seconds"`
+	EndSeconds   float64 `json:"end_seconds,omitempty"`
+	Title        string  `json:"title,omitempty"`
+}
+
+// BWF is the JSON form of a Broadcast Wave bext chunk
+type BWF struct {
+	Description         string `json:"description,omitempty"`
+	Originator          string `json:"originator,omitempty"`
+	OriginatorReference string `json:"originator_reference,omitempty"`
+	OriginationDate     string `json:"origination_date,omitempty"` // yyyy-mm-dd
+	OriginationTime     string `json:"origination_time,omitempty"` // hh:mm:ss
+	TimeReference       uint64 `json:"time_reference,omitempty"`   // samples since midnight
+	CodingHistory       string `json:"coding_history,omitempty"`
+}
+
+// Result is the JSON form of a batch result
+type Result struct {
+	ID              string            `json:"id"`
+	Profile         string            `json:"profile,omitempty"`
+	Labels          map[string]string `json:"labels,omitempty"`
+	Skipped         bool              `json:"skipped,omitempty"`
+	Input           string            `json:"input,omitempty"`
+	Output          string            `json:"output,omitempty"`
+	DurationSeconds float64           `json:"duration_seconds,omitempty"`
+	Command         []string          `json:"command,omitempty"` // dry runs only
+	Error           *Error            `json:"error,omitempty"`
+}
+
+// Error is the JSON form of an error
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Event types
+const (
+	EventProgress = "progress"
+	EventResult   = "result"
+)
+
+// Event is a progress or result notification about a job
+type Event struct {
+	Type      string    `json:"type"`
+	JobID     string    `json:"job_id"`
+	Progress  *Progress `json:"progress,omitempty"`
+	Result    *Result   `json:"result,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Progress is the JSON form of a progress update
+type Progress struct {
+	Stage   string  `json:"stage"`
+	Percent float64 `json:"percent"`
+	Message string  `json:"message,omitempty"`
+	Profile string  `json:"profile,omitempty"`
+
+	ETASeconds   float64 `json:"eta_seconds,omitempty"`
+	Speed        float64 `json:"speed,omitempty"` // realtime factor
+	BytesWritten int64   `json:"bytes_written,omitempty"`
+}
+
+// BatchJob validates j and converts it into a batch job whose options are
+// the built-in defaults, then defaults, then j.Options. Pass the
+// processor's Defaults so service-wide settings such as AUDIOLAB_PRESET
+// apply to the job.
+func (j *Job) BatchJob(defaults ...ports.Option) (model.BatchJob, error) {
+	if j.Input == "" {
+		return model.BatchJob{}, pkgerrors.NewValidationError("input", "", "input must not be empty")
+	}
+	if j.Output == "" {
+		return model.BatchJob{}, pkgerrors.NewValidationError("output", "", "output must not be empty")
+	}
+
+	opts, err := j.Options.Apply()
+	if err != nil {
+		return model.BatchJob{}, err
+	}
+	options := model.DefaultProcessingOptions()
+	for _, o := range defaults {
+		o(options)
+	}
+	for _, o := range opts {
+		o(options)
+	}
+
+	return model.BatchJob{
+		ID:         j.ID,
+		InputPath:  j.Input,
+		OutputPath: j.Output,
+		Options:    options,
+		Priority:   j.Priority,
+	}, nil
+}
+
+// Apply validates o and returns the equivalent functional options. A nil
+// o yields none.
+func (o *Options) Apply() ([]ports.Option, error) {
+	if o == nil {
+		return nil, nil
+	}
+
+	var opts []ports.Option
+	if o.Preset != "" {
+		if _, ok := presets.Lookup(o.Preset); !ok {
+			return nil, pkgerrors.NewValidationError("preset", o.Preset, "unknown preset")
+		}
+		opts = append(opts, presets.WithPreset(o.Preset))
+	}
+	if o.Profile != "" {
+		opts = append(opts, ports.WithProfile(o.Profile))
+	}
+	if len(o.Labels) > 0 {
+		opts = append(opts, ports.WithLabels(o.Labels))
+	}
+
+	codecOpts, err := codecOptions("", o.Codec, o.Bitrate, o.BitrateMode, o.SampleRate)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, codecOpts...)
+
+	if o.InputFormat != "" {
+		opts = append(opts, ports.WithInputFormat(o.InputFormat))
+	}
+	if o.OutputFormat != "" {
+		opts = append(opts, ports.WithOutputFormat(o.OutputFormat))
+	}
+	if o.Threads != 0 {
+		opts = append(opts, ports.WithThreads(o.Threads))
+	}
+	if o.AudioStream != nil {
+		opts = append(opts, ports.WithAudioStream(*o.AudioStream))
+	}
+	if o.AudioLanguage != "" {
+		opts = append(opts, ports.WithAudioLanguage(o.AudioLanguage))
+	}
+	if len(o.Metadata) > 0 {
+		opts = append(opts, ports.WithMetadata(o.Metadata))
+	}
+	if o.MetadataPolicy != "" {
+		opts = append(opts, ports.WithMetadataPolicy(model.MetadataPolicy(o.MetadataPolicy)))
+	}
+	if o.CoverArt != "" {
+		opts = append(opts, ports.WithCoverArt(o.CoverArt))
+	}
+	if o.CoverArtMaxSize != 0 {
+		opts = append(opts, ports.WithCoverArtResize(o.CoverArtMaxSize))
+	}
+	if o.CoverArtFormat != "" {
+		opts = append(opts, ports.WithCoverArtFormat(model.CoverArtFormat(o.CoverArtFormat)))
+	}
+	if o.ID3Version != 0 {
+		opts = append(opts, ports.WithID3Version(o.ID3Version))
+	}
+	if o.ID3v1 {
+		opts = append(opts, ports.WithID3v1(true))
+	}
+	if len(o.Chapters) > 0 {
+		chapters := make([]model.Chapter, len(o.Chapters))
+		for i, c := range o.Chapters {
+			chapters[i] = model.Chapter{Start: seconds(c.StartSeconds), End: seconds(c.EndSeconds), Title: c.Title}
+		}
+		opts = append(opts, ports.WithChapters(chapters))
+	}
+	if o.Gapless {
+		opts = append(opts, ports.WithGapless(true))
+	}
+	if b := o.BWF; b != nil {
+		opts = append(opts, ports.WithBWF(model.BWF(*b)))
+	}
+	if o.Lyrics != "" {
+		opts = append(opts, ports.WithLyrics(o.Lyrics))
+	}
+	if o.LyricsFile != "" {
+		opts = append(opts, ports.WithLyricsFile(o.LyricsFile))
+	}
+	if o.LyricsLanguage != "" {
+		opts = append(opts, ports.WithLyricsLanguage(o.LyricsLanguage))
+	}
+	if o.ISRC != "" {
+		opts = append(opts, ports.WithISRC(o.ISRC))
+	}
+	if o.Barcode != "" {
+		opts = append(opts, ports.WithBarcode(o.Barcode))
+	}
+	if o.CatalogNumber != "" {
+		opts = append(opts, ports.WithCatalogNumber(o.CatalogNumber))
+	}
+
+	if o.Normalize != nil {
+		opts = append(opts, ports.WithNormalization(*o.Normalize))
+	}
+	if o.LoudnessTarget != nil {
+		opts = append(opts, ports.WithLoudnessTarget(*o.LoudnessTarget))
+	}
+	if o.DCRemoval {
+		opts = append(opts, ports.WithDCRemoval())
+	}
+	if o.HighpassHz < 0 || o.LowpassHz < 0 {
+		return nil, pkgerrors.NewValidationError("filters", o, "filter frequencies must not be negative")
+	}
+	if o.HighpassHz > 0 {
+		opts = append(opts, ports.WithHighpass(o.HighpassHz))
+	}
+	if o.LowpassHz > 0 {
+		opts = append(opts, ports.WithLowpass(o.LowpassHz))
+	}
+
+	if o.LeadingPaddingSeconds < 0 || o.TrailingPaddingSeconds < 0 {
+		return nil, pkgerrors.NewValidationError("padding", o, "padding must not be negative")
+	}
+	if o.LeadingPaddingSeconds > 0 || o.TrailingPaddingSeconds > 0 {
+		opts = append(opts, ports.WithPadding(seconds(o.LeadingPaddingSeconds), seconds(o.TrailingPaddingSeconds)))
+	}
+
+	for i, out := range o.AdditionalOutputs {
+		field := fmt.Sprintf("additional_outputs[%d]", i)
+		if out.Path == "" {
+			return nil, pkgerrors.NewValidationError(field+".path", "", "path must not be empty")
+		}
+		outOpts, err := codecOptions(field+".", out.Codec, out.Bitrate, out.BitrateMode, out.SampleRate)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, ports.WithAdditionalOutput(out.Path, outOpts...))
+	}
+
+	switch policy := model.SkipPolicy(o.SkipPolicy); policy {
+	case model.SkipNever:
+	case model.SkipIfNewer, model.SkipIfFingerprint:
+		opts = append(opts, ports.WithSkipPolicy(policy))
+	default:
+		return nil, pkgerrors.NewValidationError("skip_policy", o.SkipPolicy, "unknown skip policy")
+	}
+
+	if o.VerifyToleranceSeconds < 0 {
+		return nil, pkgerrors.NewValidationError("verify_tolerance_seconds", o.VerifyToleranceSeconds, "tolerance must not be negative")
+	}
+	if o.Verify {
+		opts = append(opts, ports.WithOutputVerification(seconds(o.VerifyToleranceSeconds)))
+	}
+
+	if o.TimeoutSeconds < 0 {
+		return nil, pkgerrors.NewValidationError("timeout_seconds", o.TimeoutSeconds, "timeout must not be negative")
+	}
+	if o.TimeoutSeconds > 0 {
+		opts = append(opts, ports.WithTimeout(seconds(o.TimeoutSeconds)))
+	}
+	if o.MaxRetries != nil {
+		if *o.MaxRetries < 0 {
+			return nil, pkgerrors.NewValidationError("max_retries", *o.MaxRetries, "retries must not be negative")
+		}
+		opts = append(opts, ports.WithRetryAttempts(*o.MaxRetries))
+	}
+	if o.DryRun {
+		opts = append(opts, ports.WithDryRun())
+	}
+
+	return opts, nil
+}
+
+// codecOptions validates the codec settings shared by jobs and additional
+// outputs
+func codecOptions(prefix, codec string, bitrate int, mode string, sampleRate int) ([]ports.Option, error) {
+	var opts []ports.Option
+
+	switch c := model.Codec(codec); c {
+	case "":
+	case model.CodecOpus, model.CodecAAC, model.CodecMP3, model.CodecPCM16, model.CodecPCM24:
+		opts = append(opts, ports.WithCodec(c))
+	default:
+		return nil, pkgerrors.NewValidationError(prefix+"codec", codec, "unsupported codec")
+	}
+
+	if bitrate < 0 {
+		return nil, pkgerrors.NewValidationError(prefix+"bitrate", bitrate, "bitrate must not be negative")
+	}
+	if bitrate > 0 {
+		opts = append(opts, ports.WithBitrate(bitrate))
+	}
+
+	switch m := model.BitrateMode(mode); m {
+	case "":
+	case model.BitrateModeVBR, model.BitrateCBR:
+		opts = append(opts, ports.WithBitrateMode(m))
+	default:
+		return nil, pkgerrors.NewValidationError(prefix+"bitrate_mode", mode, "bitrate mode must be vbr or cbr")
+	}
+
+	if sampleRate < 0 {
+		return nil, pkgerrors.NewValidationError(prefix+"sample_rate", sampleRate, "sample rate must not be negative")
+	}
+	if sampleRate > 0 {
+		opts = append(opts, ports.WithSampleRate(sampleRate))
+	}
+
+	return opts, nil
+}
+
+// NewResult converts a batch result into its JSON form
+func NewResult(r model.BatchResult) Result {
+	out := Result{ID: r.JobID, Profile: r.Profile, Skipped: r.Skipped}
+	if r.Result != nil {
+		out.Labels = r.Result.Labels
+		out.Input = r.Result.InputPath
+		out.Output = r.Result.OutputPath
+		out.DurationSeconds = r.Result.Duration.Seconds()
+		out.Command = r.Result.Command
+	}
+	if r.Err != nil {
+		out.Error = NewError(r.Err)
+	}
+	return out
+}
+
+// NewBWF converts a probed bext chunk into its JSON form
+func NewBWF(b *model.BWF) *BWF {
+	if b == nil {
+		return nil
+	}
+	out := BWF(*b)
+	return &out
+}
+
+// NewProgress converts a progress update into its JSON form
+func NewProgress(u progress.Update) Progress {
+	return Progress{
+		Stage:        string(u.Stage),
+		Percent:      u.Percent,
+		Message:      u.Message,
+		Profile:      u.Profile,
+		ETASeconds:   u.ETA.Seconds(),
+		Speed:        u.Speed,
+		BytesWritten: u.BytesWritten,
+	}
+}
+
+// NewError converts err into its JSON form
+func NewError(err error) *Error {
+	return &Error{Code: string(pkgerrors.CodeOf(err)), Message: err.Error()}
+}
+
+// NewJobID returns a unique ID for jobs submitted without one
+func NewJobID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("job-%d-%s", time.Now().UnixNano(), hex.EncodeToString(b))
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
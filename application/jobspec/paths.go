@@ -0,0 +1,226 @@
+package jobspec
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+)
+
+// PathPolicy restricts the server files and URLs that jobs from network
+// clients may name. The zero value accepts public http(s) inputs only.
+type PathPolicy struct {
+	// AllowPaths accepts server-side paths for inputs, outputs, cover art
+	// and lyrics files. With Roots set they must lie under one of them.
+	AllowPaths bool
+	Roots      []string
+
+	// AllowedHosts restricts URLs to these hosts; a leading dot matches
+	// subdomains (".example.com"). Empty allows any host.
+	AllowedHosts []string
+
+	// AllowPrivateNetworks accepts URLs whose host resolves to a loopback,
+	// private, link-local or otherwise internal address. They are refused
+	// by default so clients can't reach internal services through the
+	// server.
+	AllowPrivateNetworks bool
+}
+
+// CheckJob checks j's input, output and options. An empty output passes;
+// servers that require one reject it when converting the job.
+func (p PathPolicy) CheckJob(ctx context.Context, j *Job) error {
+	if err := p.CheckInput(ctx, "input", j.Input); err != nil {
+		return err
+	}
+	if j.Output != "" {
+		if err := p.checkOutput(ctx, "output", j.Output); err != nil {
+			return err
+		}
+	}
+	return p.CheckOptions(ctx, j.Options)
+}
+
+// CheckOptions checks the files and URLs named by o, and its input format
+func (p PathPolicy) CheckOptions(ctx context.Context, o *Options) error {
+	if o == nil {
+		return nil
+	}
+	if err := CheckInputFormat(o.InputFormat); err != nil {
+		return err
+	}
+	if o.CoverArt != "" {
+		if err := p.CheckInput(ctx, "cover_art", o.CoverArt); err != nil {
+			return err
+		}
+	}
+	if o.LyricsFile != "" {
+		if err := p.CheckPath("lyrics_file", o.LyricsFile); err != nil {
+			return err
+		}
+	}
+	for i, out := range o.AdditionalOutputs {
+		if err := p.checkOutput(ctx, fmt.Sprintf("additional_outputs[%d].path", i), out.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inputFormats are the demuxers a network client may force. Demuxers that
+// read other files or generate input, such as lavfi, concat and hls, would
+// let the input name bypass the path checks.
+var inputFormats = map[string]bool{
+	"aac": true, "ac3": true, "aiff": true, "amr": true, "ape": true,
+	"asf": true, "au": true, "caf": true, "dts": true, "eac3": true,
+	"flac": true, "matroska": true, "mov": true, "mp3": true, "mpegts": true,
+	"ogg": true, "tta": true, "w64": true, "wav": true, "wv": true,
+
+	// Raw PCM
+	"s16le": true, "s24le": true, "s32le": true, "f32le": true, "f64le": true, "u8": true,
+}
+
+// CheckInputFormat checks a forced input demuxer against the plain audio
+// demuxers network clients may name. An empty format passes.
+func CheckInputFormat(format string) error {
+	if format == "" || inputFormats[format] {
+		return nil
+	}
+	return pkgerrors.NewValidationError("input_format", format, "input format is not allowed")
+}
+
+// CheckInput checks a file or http(s) URL read by a job
+func (p PathPolicy) CheckInput(ctx context.Context, field, input string) error {
+	if strings.HasPrefix(input, "http://") || strings.HasPrefix(input, "https://") {
+		return p.CheckURL(ctx, field, input)
+	}
+	return p.CheckPath(field, input)
+}
+
+// checkOutput checks a file or live streaming sink written by a job
+func (p PathPolicy) checkOutput(ctx context.Context, field, output string) error {
+	for _, scheme := range []string{"icecast://", "rtmp://", "rtmps://"} {
+		if strings.HasPrefix(output, scheme) {
+			return p.CheckURL(ctx, field, output)
+		}
+	}
+	return p.CheckPath(field, output)
+}
+
+// CheckPath checks a server path. Symlinks are resolved before comparing
+// it against Roots.
+func (p PathPolicy) CheckPath(field, path string) error {
+	if !p.AllowPaths {
+		return pkgerrors.NewValidationError(field, path, "server paths are not accepted")
+	}
+	if path == "" {
+		return pkgerrors.NewValidationError(field, path, "path must not be empty")
+	}
+	// ffmpeg reads "proto:" prefixes and URLs as protocols, not files
+	if strings.Contains(path, "://") || (!filepath.IsAbs(path) && strings.Contains(path, ":")) {
+		return pkgerrors.NewValidationError(field, path, "unsupported URL scheme")
+	}
+	if len(p.Roots) == 0 {
+		return nil
+	}
+
+	resolved, err := resolvePath(path)
+	if err != nil {
+		return pkgerrors.NewValidationError(field, path, "invalid path")
+	}
+	for _, root := range p.Roots {
+		r, err := resolvePath(root)
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(r, resolved); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil
+		}
+	}
+	return pkgerrors.NewValidationError(field, path, "path is outside the allowed directories")
+}
+
+// CheckURL checks a URL against AllowedHosts and, unless
+// AllowPrivateNetworks is set, the addresses its host resolves to. The
+// check can't stop a host from resolving differently when ffmpeg connects,
+// or redirecting it, so prefer AllowedHosts for internet-facing servers.
+func (p PathPolicy) CheckURL(ctx context.Context, field, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return pkgerrors.NewValidationError(field, raw, "invalid URL")
+	}
+	host := strings.ToLower(u.Hostname())
+	if len(p.AllowedHosts) > 0 && !hostAllowed(host, p.AllowedHosts) {
+		return pkgerrors.NewValidationError(field, raw, "URL host is not allowed")
+	}
+	if p.AllowPrivateNetworks {
+		return nil
+	}
+
+	var addrs []netip.Addr
+	if a, err := netip.ParseAddr(host); err == nil {
+		addrs = []netip.Addr{a}
+	} else {
+		ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+		if err != nil {
+			return pkgerrors.NewValidationError(field, raw, "URL host cannot be resolved")
+		}
+		addrs = ips
+	}
+	for _, a := range addrs {
+		if isInternalAddr(a) {
+			return pkgerrors.NewValidationError(field, raw, "URL host resolves to a private network address")
+		}
+	}
+	return nil
+}
+
+// cgnat is the carrier-grade NAT range, internal to many cloud networks
+var cgnat = netip.MustParsePrefix("100.64.0.0/10")
+
+// isInternalAddr reports whether a isn't a public unicast address
+func isInternalAddr(a netip.Addr) bool {
+	a = a.Unmap()
+	return !a.IsGlobalUnicast() || a.IsPrivate() || cgnat.Contains(a)
+}
+
+func hostAllowed(host string, allowed []string) bool {
+	for _, h := range allowed {
+		h = strings.ToLower(h)
+		if host == h || (strings.HasPrefix(h, ".") && strings.HasSuffix(host, h)) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePath makes path absolute and resolves symlinks in its longest
+// existing prefix, so outputs that don't exist yet resolve too
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	var rest []string
+	for dir := abs; ; dir = filepath.Dir(dir) {
+		if _, err := os.Lstat(dir); err == nil {
+			resolved, err := filepath.EvalSymlinks(dir)
+			if err != nil {
+				return "", err
+			}
+			for i := len(rest) - 1; i >= 0; i-- {
+				resolved = filepath.Join(resolved, rest[i])
+			}
+			return resolved, nil
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return abs, nil
+		}
+		rest = append(rest, filepath.Base(dir))
+	}
+}
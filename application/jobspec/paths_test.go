@@ -0,0 +1,132 @@
+package jobspec
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckJobInputFormat(t *testing.T) {
+	root := t.TempDir()
+	policy := PathPolicy{AllowPaths: true, Roots: []string{root}}
+	// A lavfi graph passes the root check as a file name inside root
+	input := filepath.Join(root, "amovie=passwd")
+
+	tests := []struct {
+		format  string
+		wantErr bool
+	}{
+		{"", false},
+		{"wav", false},
+		{"s16le", false},
+		{"lavfi", true},
+		{"concat", true},
+		{"hls", true},
+		{"WAV", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			j := &Job{
+				Input:   input,
+				Output:  filepath.Join(root, "out.mp3"),
+				Options: &Options{InputFormat: tt.format},
+			}
+			err := policy.CheckJob(context.Background(), j)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckJob with input_format %q: err = %v, wantErr %v", tt.format, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPathPolicyCheckPath(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "in"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "in"), filepath.Join(outside, "back")); err != nil {
+		t.Fatal(err)
+	}
+	policy := PathPolicy{AllowPaths: true, Roots: []string{root}}
+
+	tests := []struct {
+		name    string
+		policy  PathPolicy
+		path    string
+		wantErr bool
+	}{
+		{"inside root", policy, filepath.Join(root, "in", "a.wav"), false},
+		{"new file inside root", policy, filepath.Join(root, "new", "dir", "a.mp3"), false},
+		{"root itself", policy, root, false},
+		{"dot-dot escape", policy, filepath.Join(root, "in", "..", "..", "etc", "passwd"), true},
+		{"unclean dot-dot escape", policy, root + "/in/../../etc/passwd", true},
+		{"sibling with root prefix", policy, root + "-other/a.wav", true},
+		{"symlink escape", policy, filepath.Join(root, "escape", "a.wav"), true},
+		{"symlink into root", policy, filepath.Join(outside, "back", "a.wav"), false},
+		{"file protocol", policy, "file:" + filepath.Join(root, "a.wav"), true},
+		{"concat protocol", policy, "concat:a.wav|b.wav", true},
+		{"subfile protocol", policy, "subfile,,start,0,end,0,,:a.wav", true},
+		{"url", policy, "http://example.com/a.wav", true},
+		{"empty", policy, "", true},
+		{"paths not allowed", PathPolicy{}, filepath.Join(root, "in", "a.wav"), true},
+		{"no roots", PathPolicy{AllowPaths: true}, "/srv/audio/a.wav", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.CheckPath("input", tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckPath(%q) err = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPathPolicyCheckURL(t *testing.T) {
+	hosts := PathPolicy{AllowedHosts: []string{"media.example.com", ".cdn.example.net"}, AllowPrivateNetworks: true}
+
+	tests := []struct {
+		name    string
+		policy  PathPolicy
+		url     string
+		wantErr bool
+	}{
+		{"public address", PathPolicy{}, "http://8.8.8.8/a.mp3", false},
+		{"public ipv6 address", PathPolicy{}, "https://[2001:4860:4860::8888]/a.mp3", false},
+		{"loopback", PathPolicy{}, "http://127.0.0.1/a.mp3", true},
+		{"ipv6 loopback", PathPolicy{}, "http://[::1]/a.mp3", true},
+		{"private", PathPolicy{}, "http://10.1.2.3/a.mp3", true},
+		{"private 192.168", PathPolicy{}, "http://192.168.0.10:8080/a.mp3", true},
+		{"link-local metadata", PathPolicy{}, "http://169.254.169.254/latest/meta-data", true},
+		{"cgnat", PathPolicy{}, "http://100.64.0.1/a.mp3", true},
+		{"cgnat upper bound", PathPolicy{}, "http://100.127.255.254/a.mp3", true},
+		{"just above cgnat", PathPolicy{}, "http://100.128.0.1/a.mp3", false},
+		{"ipv4-mapped private", PathPolicy{}, "http://[::ffff:10.0.0.1]/a.mp3", true},
+		{"ipv4-mapped loopback", PathPolicy{}, "http://[::ffff:127.0.0.1]/a.mp3", true},
+		{"ipv4-mapped public", PathPolicy{}, "http://[::ffff:8.8.8.8]/a.mp3", false},
+		{"unspecified", PathPolicy{}, "http://0.0.0.0/a.mp3", true},
+		{"private allowed", PathPolicy{AllowPrivateNetworks: true}, "http://10.1.2.3/a.mp3", false},
+		{"exact host", hosts, "https://media.example.com/a.mp3", false},
+		{"exact host case", hosts, "https://MEDIA.Example.com/a.mp3", false},
+		{"subdomain of exact host", hosts, "https://x.media.example.com/a.mp3", true},
+		{"suffix subdomain", hosts, "https://eu.cdn.example.net/a.mp3", false},
+		{"suffix deep subdomain", hosts, "https://a.b.cdn.example.net/a.mp3", false},
+		{"suffix apex", hosts, "https://cdn.example.net/a.mp3", true},
+		{"suffix lookalike", hosts, "https://evilcdn.example.net/a.mp3", true},
+		{"suffix as prefix", hosts, "https://cdn.example.net.evil.com/a.mp3", true},
+		{"other host", hosts, "https://example.org/a.mp3", true},
+		{"no host", PathPolicy{}, "http:///a.mp3", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.CheckURL(context.Background(), "input", tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckURL(%q) err = %v, wantErr %v", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package jobspec
+
+// JobJSONSchema is the JSON Schema (draft 2020-12) for Job, the message
+// the bus consumer and the network servers accept. Semantic checks, such
+// as preset names and path policies, are left to Job.BatchJob.
+const JobJSONSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "https://github.com/Skryldev/audio-lab/schemas/job.json",
+  "title": "Job",
+  "type": "object",
+  "required": ["input", "output"],
+  "additionalProperties": false,
+  "properties": {
+    "id": {"type": "string"},
+    "input": {"type": "string", "minLength": 1},
+    "output": {"type": "string", "minLength": 1},
+    "options": {"$ref": "#/$defs/options"},
+    "priority": {"type": "integer"}
+  },
+  "$defs": {
+    "codec": {"enum": ["opus", "aac", "mp3", "pcm_s16le", "pcm_s24le"]},
+    "bitrate_mode": {"enum": ["vbr", "cbr"]},
+    "tags": {"type": "object", "additionalProperties": {"type": "string"}},
+    "options": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "preset": {"type": "string"},
+        "profile": {"type": "string"},
+        "labels": {"$ref": "#/$defs/tags"},
+
+        "codec": {"$ref": "#/$defs/codec"},
+        "bitrate": {"type": "integer", "minimum": 0},
+        "bitrate_mode": {"$ref": "#/$defs/bitrate_mode"},
+        "sample_rate": {"type": "integer", "minimum": 0},
+
+        "input_format": {"enum": ["aac", "ac3", "aiff", "amr", "ape", "asf", "au", "caf", "dts", "eac3", "flac", "matroska", "mov", "mp3", "mpegts", "ogg", "tta", "w64", "wav", "wv", "s16le", "s24le", "s32le", "f32le", "f64le", "u8"]},
+        "output_format": {"type": "string"},
+        "threads": {"type": "integer", "minimum": 0},
+
+        "audio_stream": {"type": "integer", "minimum": 0},
+        "audio_language": {"type": "string"},
+
+        "metadata": {"$ref": "#/$defs/tags"},
+        "metadata_policy": {"enum": ["copy", "strip", "replace"]},
+
+        "cover_art": {"type": "string"},
+        "cover_art_max_size": {"type": "integer", "minimum": 0},
+        "cover_art_format": {"enum": ["jpeg", "png"]},
+
+        "id3_version": {"enum": [3, 4]},
+        "id3v1": {"type": "boolean"},
+
+        "chapters": {
+          "type": "array",
+          "items": {
+            "type": "object",
+            "required": ["start_seconds"],
+            "additionalProperties": false,
+            "properties": {
+              "start_seconds": {"type": "number", "minimum": 0},
+              "end_seconds": {"type": "number", "minimum": 0},
+              "title": {"type": "string"}
+            }
+          }
+        },
+        "gapless": {"type": "boolean"},
+        "bwf": {
+          "type": "object",
+          "additionalProperties": false,
+          "properties": {
+            "description": {"type": "string"},
+            "originator": {"type": "string"},
+            "originator_reference": {"type": "string"},
+            "origination_date": {"type": "string"},
+            "origination_time": {"type": "string"},
+            "time_reference": {"type": "integer", "minimum": 0},
+            "coding_history": {"type": "string"}
+          }
+        },
+
+        "lyrics": {"type": "string"},
+        "lyrics_file": {"type": "string"},
+        "lyrics_language": {"type": "string"},
+
+        "isrc": {"type": "string"},
+        "barcode": {"type": "string"},
+        "catalog_number": {"type": "string"},
+
+        "normalize": {"type": "boolean"},
+        "loudness_target": {"type": "number"},
+        "dc_removal": {"type": "boolean"},
+        "highpass_hz": {"type": "integer", "minimum": 0},
+        "lowpass_hz": {"type": "integer", "minimum": 0},
+
+        "leading_padding_seconds": {"type": "number", "minimum": 0},
+        "trailing_padding_seconds": {"type": "number", "minimum": 0},
+
+        "additional_outputs": {
+          "type": "array",
+          "items": {
+            "type": "object",
+            "required": ["path"],
+            "additionalProperties": false,
+            "properties": {
+              "path": {"type": "string", "minLength": 1},
+              "codec": {"$ref": "#/$defs/codec"},
+              "bitrate": {"type": "integer", "minimum": 0},
+              "bitrate_mode": {"$ref": "#/$defs/bitrate_mode"},
+              "sample_rate": {"type": "integer", "minimum": 0}
+            }
+          }
+        },
+
+        "skip_policy": {"enum": ["", "newer", "fingerprint"]},
+        "verify": {"type": "boolean"},
+        "verify_tolerance_seconds": {"type": "number", "minimum": 0},
+        "timeout_seconds": {"type": "number", "minimum": 0},
+        "max_retries": {"type": "integer", "minimum": 0},
+        "dry_run": {"type": "boolean"}
+      }
+    }
+  }
+}`
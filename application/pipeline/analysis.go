@@ -0,0 +1,237 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/internal/argfmt"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"github.com/Skryldev/audio-lab/pkg/progress"
+)
+
+// silenceDB is the level reported for digital silence, below 24-bit
+// resolution, since JSON can't encode -Inf
+const silenceDB = -144.0
+
+// AnalyzeAudio decodes job.InputPath to 32-bit float PCM at its own rate
+// and channel count and measures its peaks, DC offset and clipping sample
+// by sample without buffering the decoded audio. Float keeps the overs of
+// float sources, so clipping is found before normalization or a lossy
+// encode can hide it.
+func (p *Pipeline) AnalyzeAudio(ctx context.Context, job *Job, opts model.AnalysisOptions) (*model.AudioAnalysis, error) {
+	if job.InputPath == "" {
+		return nil, pkgerrors.NewValidationError("inputPath", "", "input path must not be empty")
+	}
+	if err := p.checkInputExists(ctx, job.InputPath); err != nil {
+		return nil, err
+	}
+
+	threshold := opts.ClipThreshold
+	if threshold == 0 {
+		threshold = -0.01
+	}
+	minRun := opts.ClipMinRun
+	if minRun <= 0 {
+		minRun = 3
+	}
+	mergeGap := opts.ClipMergeGap
+	if mergeGap <= 0 {
+		mergeGap = 50 * time.Millisecond
+	}
+
+	meta, err := p.probeFile(ctx, job.InputPath)
+	if err != nil {
+		return nil, pkgerrors.NewProcessingError("probe", "failed to probe input file", err)
+	}
+	job.report(progress.StageProbe, 5, "input probed")
+	if job, err = selectAudioStream(job, meta); err != nil {
+		return nil, err
+	}
+
+	sampleRate := meta.SampleRate
+	if sampleRate <= 0 {
+		sampleRate = 44100
+	}
+	channels := meta.Channels
+	if channels <= 0 {
+		channels = 2
+	}
+
+	args := append(decodeArgs(job),
+		"-ac", argfmt.Int(channels),
+		"-ar", argfmt.Hz(sampleRate),
+		"-f", "f32le",
+		"-acodec", "pcm_f32le",
+		"pipe:1",
+	)
+
+	scan := newClipScanner(channels, float32(dbToLinear(threshold)), minRun, int64(mergeGap.Seconds()*float64(sampleRate)))
+
+	job.report(progress.StageEncode, 20, "analyzing samples")
+
+	if _, err := p.executor.ExecutePiped(ctx, args, nil, scan); err != nil {
+		return nil, err
+	}
+	scan.flush()
+
+	job.report(progress.StageDone, 100, "done")
+
+	at := func(frame int64) time.Duration {
+		return time.Duration(float64(frame) / float64(sampleRate) * float64(time.Second))
+	}
+	a := &model.AudioAnalysis{
+		SampleRate: sampleRate,
+		Channels:   channels,
+		Duration:   at(scan.frames),
+		Peak:       make([]float64, channels),
+		DCOffset:   make([]float64, channels),
+		Clipping:   model.ClippingReport{Samples: scan.clipped},
+	}
+	for c, peak := range scan.peak {
+		a.Peak[c] = linearToDB(peak)
+		if scan.frames > 0 {
+			a.DCOffset[c] = scan.sum[c] / float64(scan.frames)
+		}
+	}
+	for _, r := range scan.regions {
+		a.Clipping.Regions = append(a.Clipping.Regions, model.ClippingRegion{
+			Start:   at(r.start),
+			End:     at(r.end),
+			Samples: r.samples,
+			Peak:    linearToDB(r.peak),
+		})
+	}
+	return a, nil
+}
+
+// clipScanner consumes interleaved f32le frames, tracking per-channel
+// sample peaks and runs of samples at or above a clip threshold
+type clipScanner struct {
+	channels  int
+	threshold float32
+	minRun    int
+	gap       int64 // frames between clips that still merge into a region
+
+	carry   []byte // partial frame left over from the previous Write
+	frames  int64
+	peak    []float32
+	sum     []float64 // of the samples per channel, for the DC offset
+	runs    []clipRun // the current run per channel
+	clipped int64
+	regions []clipRegion
+}
+
+// clipRun is a run of consecutive samples at or above the threshold
+type clipRun struct {
+	start int64
+	n     int
+	peak  float32
+}
+
+// clipRegion spans frames [start, end) holding clips
+type clipRegion struct {
+	start   int64
+	end     int64
+	samples int64
+	peak    float32
+}
+
+func newClipScanner(channels int, threshold float32, minRun int, gap int64) *clipScanner {
+	return &clipScanner{
+		channels:  channels,
+		threshold: threshold,
+		minRun:    minRun,
+		gap:       gap,
+		peak:      make([]float32, channels),
+		sum:       make([]float64, channels),
+		runs:      make([]clipRun, channels),
+	}
+}
+
+func (s *clipScanner) Write(b []byte) (int, error) {
+	n := len(b)
+	frameSize := 4 * s.channels
+
+	if len(s.carry) > 0 {
+		b = append(s.carry, b...)
+		s.carry = nil
+	}
+
+	for len(b) >= frameSize {
+		for c := 0; c < s.channels; c++ {
+			v := math.Float32frombits(binary.LittleEndian.Uint32(b[4*c:]))
+			s.sum[c] += float64(v)
+			if v < 0 {
+				v = -v
+			}
+			if v > s.peak[c] {
+				s.peak[c] = v
+			}
+			r := &s.runs[c]
+			if v >= s.threshold {
+				if r.n == 0 {
+					r.start = s.frames
+				}
+				r.n++
+				r.peak = max(r.peak, v)
+			} else if r.n > 0 {
+				s.endRun(r)
+			}
+		}
+		b = b[frameSize:]
+		s.frames++
+	}
+
+	if len(b) > 0 {
+		s.carry = append([]byte(nil), b...)
+	}
+	return n, nil
+}
+
+// flush ends the runs still open at the end of the audio
+func (s *clipScanner) flush() {
+	for c := range s.runs {
+		if s.runs[c].n > 0 {
+			s.endRun(&s.runs[c])
+		}
+	}
+}
+
+// endRun records r as a clip if it is long enough, merging it with the
+// regions it overlaps or nearly touches, and resets it. Runs end in frame
+// order, so regions stay sorted and only trailing ones can merge.
+func (s *clipScanner) endRun(r *clipRun) {
+	if r.n >= s.minRun {
+		s.clipped += int64(r.n)
+		region := clipRegion{start: r.start, end: r.start + int64(r.n), samples: int64(r.n), peak: r.peak}
+		for len(s.regions) > 0 {
+			last := s.regions[len(s.regions)-1]
+			if region.start > last.end+s.gap {
+				break
+			}
+			region.start = min(region.start, last.start)
+			region.end = max(region.end, last.end)
+			region.samples += last.samples
+			region.peak = max(region.peak, last.peak)
+			s.regions = s.regions[:len(s.regions)-1]
+		}
+		s.regions = append(s.regions, region)
+	}
+	*r = clipRun{}
+}
+
+// dbToLinear converts a level in dB to a linear amplitude
+func dbToLinear(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+// linearToDB converts a linear amplitude to dB, flooring at silenceDB
+func linearToDB(v float32) float64 {
+	if v <= 0 {
+		return silenceDB
+	}
+	return max(20*math.Log10(float64(v)), silenceDB)
+}
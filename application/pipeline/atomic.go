@@ -0,0 +1,179 @@
+package pipeline
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// muxerByExt maps output extensions onto ffmpeg muxers. Temp outputs lose
+// their extension, so the muxer has to be given explicitly.
+var muxerByExt = map[string]string{
+	".mp3":  "mp3",
+	".opus": "opus",
+	".ogg":  "ogg",
+	".oga":  "ogg",
+	".flac": "flac",
+	".wav":  "wav",
+	".aif":  "aiff",
+	".aiff": "aiff",
+	".m4a":  "ipod",
+	".m4b":  "ipod",
+	".mp4":  "mp4",
+	".aac":  "adts",
+	".ac3":  "ac3",
+	".mka":  "matroska",
+	".webm": "webm",
+	".caf":  "caf",
+	".wv":   "wv",
+}
+
+// atomicWrite tracks outputs encoded into "<output>.tmp.<rand>" siblings
+// that are renamed into place once they are verified, so watchers of the
+// output directory never see partial files
+type atomicWrite struct {
+	p         *Pipeline
+	finals    []string
+	temps     []string
+	committed bool
+}
+
+func (p *Pipeline) newAtomicWrite() *atomicWrite {
+	return &atomicWrite{p: p}
+}
+
+// add returns the path to encode final into and the muxer to force. Live
+// sinks, pipes and outputs whose muxer can't be determined are written in
+// place.
+func (a *atomicWrite) add(final, format string) (string, string) {
+	if final == pipeOutput || isStreamingOutput(final) {
+		return final, format
+	}
+	if format == "" {
+		format = muxerByExt[strings.ToLower(filepath.Ext(final))]
+		if format == "" {
+			return final, format
+		}
+	}
+
+	var b [4]byte
+	_, _ = rand.Read(b[:])
+	temp := final + ".tmp." + hex.EncodeToString(b[:])
+
+	a.finals = append(a.finals, final)
+	a.temps = append(a.temps, temp)
+	return temp, format
+}
+
+// path returns where final is currently being written
+func (a *atomicWrite) path(final string) string {
+	for i, f := range a.finals {
+		if f == final {
+			return a.temps[i]
+		}
+	}
+	return final
+}
+
+// commit renames every temp output to its final path
+func (a *atomicWrite) commit(ctx context.Context) error {
+	for i, temp := range a.temps {
+		if err := a.p.storage.Rename(ctx, temp, a.finals[i]); err != nil {
+			return pkgerrors.NewProcessingError("commit", "failed to move output into place", err)
+		}
+	}
+	a.committed = true
+	return nil
+}
+
+// discard removes temp outputs unless they were committed; deferred by
+// callers so partial files never outlive a failed or canceled run
+func (a *atomicWrite) discard(ctx context.Context) {
+	if a.committed {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	for _, temp := range a.temps {
+		exists, err := a.p.storage.Exists(ctx, temp)
+		if err != nil || !exists {
+			continue
+		}
+		if err := a.p.storage.Remove(ctx, temp); err != nil {
+			a.p.log.Warn("failed to remove partial output", zap.String("path", temp), zap.Error(err))
+		}
+	}
+}
+
+// atomicJob returns a copy of job whose file outputs point at temp
+// siblings, along with the atomicWrite that moves them into place
+func (p *Pipeline) atomicJob(job *Job) (*Job, *atomicWrite) {
+	a := p.newAtomicWrite()
+	if job.Options.DirectOutputWrites {
+		return job, a
+	}
+
+	enc := *job
+	opts := *job.Options
+	enc.Options = &opts
+	enc.OutputPath, opts.OutputFormat = a.add(job.OutputPath, job.Options.OutputFormat)
+
+	opts.AdditionalOutputs = make([]model.AdditionalOutput, len(job.Options.AdditionalOutputs))
+	for i, out := range job.Options.AdditionalOutputs {
+		// The output's own muxer is only known once Configure has run
+		configure := out.Configure
+		resolved := *job.Options
+		resolved.OutputFormat = ""
+		if configure != nil {
+			configure(&resolved)
+		}
+
+		var format string
+		out.Path, format = a.add(out.Path, resolved.OutputFormat)
+		out.Configure = func(o *model.ProcessingOptions) {
+			if configure != nil {
+				configure(o)
+			}
+			if o.OutputFormat == "" {
+				o.OutputFormat = format
+			}
+		}
+		opts.AdditionalOutputs[i] = out
+	}
+	return &enc, a
+}
+
+// atomicOutput starts an atomic write of a single output, returning the
+// path to encode into and the muxer to force (empty lets ffmpeg infer it)
+func (p *Pipeline) atomicOutput(opts *model.ProcessingOptions, final string) (*atomicWrite, string, string) {
+	a := p.newAtomicWrite()
+	if opts.DirectOutputWrites {
+		return a, final, opts.OutputFormat
+	}
+	path, format := a.add(final, opts.OutputFormat)
+	return a, path, format
+}
+
+// verify probes a written output. For atomic writes a failed probe is an
+// error, so the temp file is never moved into place.
+func (a *atomicWrite) verify(ctx context.Context, path string) (*model.AudioMetadata, error) {
+	meta, err := a.p.probeFile(ctx, path)
+	if err == nil {
+		return meta, nil
+	}
+	if a.active() {
+		return nil, pkgerrors.NewProcessingError("verify", "output failed verification", err)
+	}
+	a.p.log.Warn("failed to probe output file", zap.String("output", path), zap.Error(err))
+	return &model.AudioMetadata{}, nil
+}
+
+// active reports whether any output is being written atomically
+func (a *atomicWrite) active() bool {
+	return len(a.temps) > 0
+}
@@ -0,0 +1,197 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/infrastructure/ffmpeg"
+	"github.com/Skryldev/audio-lab/internal/argfmt"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"github.com/Skryldev/audio-lab/pkg/progress"
+	"go.uber.org/zap"
+)
+
+// audiobookMediaType is the iTunes media kind (stik atom) of audiobooks
+const audiobookMediaType = "2"
+
+// BuildAudiobook concatenates inputs into one AAC file at job.OutputPath,
+// an .m4b (or .m4a/.mp4), with a chapter per input. The inputs' tags are
+// not copied; book tags come from job.Options.Metadata. The result's
+// InputMeta holds the total duration and the chapters written.
+func (p *Pipeline) BuildAudiobook(ctx context.Context, job *Job, inputs []model.ChapterInput) (*model.ProcessingResult, error) {
+	start := time.Now()
+
+	if len(inputs) == 0 {
+		return nil, pkgerrors.NewValidationError("inputs", 0, "at least one chapter input is required")
+	}
+	switch outputMuxer(job.Options, job.OutputPath) {
+	case "ipod", "mp4":
+	default:
+		return nil, pkgerrors.NewValidationError("outputPath", job.OutputPath, "audiobooks need an .m4b, .m4a or .mp4 output")
+	}
+	if job.Options.Codec != model.CodecAAC {
+		return nil, pkgerrors.NewValidationError("codec", job.Options.Codec, "audiobooks are encoded to AAC")
+	}
+	if len(job.Options.Chapters) > 0 {
+		return nil, pkgerrors.NewValidationError("chapters", len(job.Options.Chapters), "audiobook chapters are taken from the inputs")
+	}
+
+	// Every input becomes a chapter as long as the file
+	chapters := make([]model.Chapter, len(inputs))
+	var total time.Duration
+	for i, in := range inputs {
+		if err := p.checkInputExists(ctx, in.Path); err != nil {
+			return nil, err
+		}
+		meta, err := p.probeFile(ctx, in.Path)
+		if err != nil {
+			return nil, pkgerrors.NewProcessingError("probe", "failed to probe "+in.Path, err)
+		}
+		if meta.Duration <= 0 {
+			return nil, pkgerrors.NewValidationError(fmt.Sprintf("inputs[%d]", i), in.Path, "input has no duration")
+		}
+		chapters[i] = model.Chapter{Start: total, End: total + meta.Duration, Title: chapterTitle(in, meta)}
+		total += meta.Duration
+	}
+	job.report(progress.StageProbe, 5, "inputs probed")
+
+	opts := *job.Options
+	opts.Chapters = chapters
+	opts.MetadataPolicy = model.MetadataReplace
+	opts.Metadata = make(map[string]string, len(job.Options.Metadata)+1)
+	opts.Metadata["media_type"] = audiobookMediaType
+	for k, v := range job.Options.Metadata {
+		opts.Metadata[k] = v
+	}
+	book := *job
+	book.InputPath = inputs[0].Path
+	book.Options = &opts
+
+	if err := p.validateInput(ctx, &book); err != nil {
+		return nil, err
+	}
+	if err := p.prepareOutputs(ctx, &opts, book.OutputPath); err != nil {
+		return nil, err
+	}
+
+	atomic, output, format := p.atomicOutput(&opts, book.OutputPath)
+	defer atomic.discard(ctx)
+	enc := opts
+	enc.OutputFormat = format
+
+	plan, err := buildAudiobookPlan(&book, &enc, inputs, output)
+	if err != nil {
+		return nil, err
+	}
+
+	job.report(progress.StageEncode, encodeStartPercent, "encoding audiobook")
+
+	var stderr []byte
+	if p.pipes {
+		plan.GlobalArgs = append(plan.GlobalArgs, ffmpeg.ProgressArgs...)
+		stderr, err = p.executor.ExecutePiped(ctx, plan.Args(), nil, job.encodeProgress(total))
+	} else {
+		err = p.executor.Execute(ctx, plan.Args())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := p.finalizeOutputs(ctx, &opts, output); err != nil {
+		return nil, err
+	}
+
+	var loudness *model.LoudnessReport
+	if opts.NormalizationEnabled {
+		loudness, err = buildLoudnessReport(stderr, opts.LoudnessTarget)
+		if err != nil {
+			p.log.Warn("failed to parse loudness report", zap.Error(err))
+		}
+	}
+
+	job.report(progress.StageEncode, encodeEndPercent, "encoding complete")
+
+	outputMeta, err := atomic.verify(ctx, output)
+	if err != nil {
+		return nil, err
+	}
+	if err := atomic.commit(ctx); err != nil {
+		return nil, err
+	}
+
+	job.report(progress.StageDone, 100, "done")
+
+	return &model.ProcessingResult{
+		InputPath:   book.InputPath,
+		OutputPath:  book.OutputPath,
+		InputMeta:   &model.AudioMetadata{Duration: total, Chapters: chapters},
+		OutputMeta:  outputMeta,
+		Duration:    time.Since(start),
+		ProcessedAt: time.Now(),
+		Profile:     job.profile(),
+		Labels:      job.labels(),
+		Loudness:    loudness,
+	}, nil
+}
+
+// buildAudiobookPlan concatenates the first audio stream of every input,
+// runs the result through the job's filter chain and encodes it to output
+// together with the cover art and chapters
+func buildAudiobookPlan(job *Job, opts *model.ProcessingOptions, inputs []model.ChapterInput, output string) (*model.CommandPlan, error) {
+	codecArgs, err := buildCodecArgs(opts)
+	if err != nil {
+		return nil, pkgerrors.NewProcessingError("encode", "failed to build codec args", err)
+	}
+
+	plan := &model.CommandPlan{
+		Version:    model.CommandPlanVersion,
+		JobID:      job.ID,
+		GlobalArgs: []string{"-y"},
+	}
+	graph := ffmpeg.NewFiltergraphBuilder()
+	pads := make([]ffmpeg.Pad, len(inputs))
+	for i, in := range inputs {
+		inArgs := append(remoteInputArgs(in.Path), threadArgs(opts)...)
+		plan.Inputs = append(plan.Inputs, model.PlanInput{Path: ffmpegPath(in.Path), Args: inArgs})
+		pads[i] = ffmpeg.InputPad(i)
+	}
+	out := graph.Filter(graph.Concat(pads...), buildFilterChain(opts))
+	plan.Filtergraph = graph.Build()
+
+	maps := []string{out.Map()}
+	args := append([]string{"-ar", argfmt.Hz(opts.SampleRate)}, codecArgs...)
+	args = append(args, threadArgs(opts)...)
+	args = append(args, metadataArgs(opts)...)
+	args = append(args, gaplessArgs(opts, output)...)
+	if embedsCoverArt(opts, output) {
+		artMap, artArgs := coverArtArgs(opts, len(plan.Inputs))
+		plan.Inputs = append(plan.Inputs, coverArtInput(opts))
+		maps = append(maps, artMap)
+		args = append(args, artArgs...)
+	}
+	args = append(args, chapterArgs(len(plan.Inputs))...)
+	plan.Inputs = append(plan.Inputs, chapterInput(opts))
+	if opts.OutputFormat != "" {
+		args = append(args, "-f", opts.OutputFormat)
+	}
+	args = append(args, opts.ExtraOutputArgs...)
+
+	plan.Outputs = []model.PlanOutput{{Path: ffmpegPath(output), Maps: maps, Args: args}}
+	return plan, nil
+}
+
+// chapterTitle names an audiobook chapter: the given title, the file's
+// title tag, or its name without the extension
+func chapterTitle(in model.ChapterInput, meta *model.AudioMetadata) string {
+	if in.Title != "" {
+		return in.Title
+	}
+	if meta.Tags.Title != "" {
+		return meta.Tags.Title
+	}
+	name := filepath.Base(in.Path)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
@@ -0,0 +1,138 @@
+package pipeline
+
+import (
+	"runtime"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// AutoTuneConfig configures a worker pool that sizes itself from the CPU
+// count and adjusts its concurrency to the observed encode throughput and
+// system load
+type AutoTuneConfig struct {
+	// Min and Max bound the number of workers (default: 1 and
+	// runtime.NumCPU())
+	Min int
+	Max int
+
+	// Interval is how often the pool is resized (default: 30s)
+	Interval time.Duration
+
+	// MaxLoad is the load average per CPU above which workers are removed
+	// (default: 1.0). Workers are only added below 80% of it.
+	MaxLoad float64
+
+	// Load returns the system load average. Nil tunes on throughput alone.
+	Load func() (float64, error)
+}
+
+// autoTuneHold is how many intervals growth pauses after adding a worker
+// didn't raise throughput
+const autoTuneHold = 5
+
+// autoTuneGain is the throughput increase that justifies an added worker
+const autoTuneGain = 1.05
+
+func (c *AutoTuneConfig) setDefaults() {
+	if c.Max <= 0 {
+		c.Max = runtime.NumCPU()
+	}
+	if c.Min <= 0 {
+		c.Min = 1
+	}
+	if c.Min > c.Max {
+		c.Min = c.Max
+	}
+	if c.Interval <= 0 {
+		c.Interval = 30 * time.Second
+	}
+	if c.MaxLoad <= 0 {
+		c.MaxLoad = 1
+	}
+}
+
+// AutoTune starts resizing the pool's encode lane every cfg.Interval until
+// Shutdown. It starts at runtime.NumCPU() workers within the bounds. Each
+// interval a worker is removed while the load is above cfg.MaxLoad, and
+// one is added while jobs are waiting and the load leaves headroom. An
+// added worker that doesn't raise the encoded audio per second is removed
+// again.
+func (wp *WorkerPool) AutoTune(cfg AutoTuneConfig) {
+	cfg.setDefaults()
+	size := min(max(runtime.NumCPU(), cfg.Min), cfg.Max)
+	wp.lane.Resize(size)
+	wp.log.Info("auto-tuning workers",
+		zap.Int("workers", size),
+		zap.Int("min", cfg.Min),
+		zap.Int("max", cfg.Max),
+	)
+
+	wp.mu.Lock()
+	if wp.stopTuning == nil {
+		wp.stopTuning = make(chan struct{})
+	}
+	stop := wp.stopTuning
+	wp.mu.Unlock()
+
+	go wp.autoTune(cfg, stop)
+}
+
+func (wp *WorkerPool) autoTune(cfg AutoTuneConfig, stop <-chan struct{}) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	cpus := float64(runtime.NumCPU())
+	lastAudio := wp.stats.encodedAudio()
+	var lastThroughput float64
+	grew := false
+	hold := 0
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		audio := wp.stats.encodedAudio()
+		throughput := (audio - lastAudio).Seconds() / cfg.Interval.Seconds()
+		lastAudio = audio
+
+		load := -1.0
+		if cfg.Load != nil {
+			if l, err := cfg.Load(); err != nil {
+				wp.log.Debug("failed to read system load", zap.Error(err))
+			} else {
+				load = l / cpus
+			}
+		}
+
+		size := wp.lane.Size()
+		next := size
+		waiting := wp.lane.Waiting() > 0 || wp.stats.Snapshot().Queued > 0
+		switch {
+		case load > cfg.MaxLoad && size > cfg.Min:
+			next = size - 1
+		case grew && throughput < lastThroughput*autoTuneGain && size > cfg.Min:
+			// The last worker added didn't pay off
+			next = size - 1
+			hold = autoTuneHold
+		case hold > 0:
+			hold--
+		case waiting && load < cfg.MaxLoad*0.8 && size < cfg.Max:
+			next = size + 1
+		}
+		grew = next > size
+		lastThroughput = throughput
+
+		if next != size {
+			wp.log.Info("resizing worker pool",
+				zap.Int("workers", next),
+				zap.Float64("load_per_cpu", load),
+				zap.Float64("throughput", throughput),
+			)
+			wp.lane.Resize(next)
+		}
+	}
+}
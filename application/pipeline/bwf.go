@@ -0,0 +1,113 @@
+package pipeline
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+)
+
+// bextKeys are the tags ffmpeg maps the bext chunk onto; "description" is
+// left out as other WAV chunks set it too
+var bextKeys = []string{
+	"originator", "originator_reference", "origination_date",
+	"origination_time", "time_reference", "coding_history",
+}
+
+// parseBWF reads the bext chunk from the lower-cased tags of a WAV file,
+// returning nil when it has none
+func parseBWF(tags map[string]string) *model.BWF {
+	found := false
+	for _, k := range bextKeys {
+		if _, ok := tags[k]; ok {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil
+	}
+	ref, _ := strconv.ParseUint(tags["time_reference"], 10, 64)
+	return &model.BWF{
+		Description:         tags["description"],
+		Originator:          tags["originator"],
+		OriginatorReference: tags["originator_reference"],
+		OriginationDate:     tags["origination_date"],
+		OriginationTime:     tags["origination_time"],
+		TimeReference:       ref,
+		CodingHistory:       tags["coding_history"],
+	}
+}
+
+// validateBWF checks the bext fields against the chunk's fixed sizes, which
+// ffmpeg would silently truncate to
+func validateBWF(job *Job) error {
+	bwf := job.Options.BWF
+	if bwf == nil {
+		return nil
+	}
+	if job.Options.MetadataPolicy == model.MetadataStrip {
+		return pkgerrors.NewValidationError("metadataPolicy", job.Options.MetadataPolicy, "BWF metadata can't be set when stripping tags")
+	}
+	if outputMuxer(job.Options, job.OutputPath) != "wav" {
+		return pkgerrors.NewValidationError("bwf", job.OutputPath, "BWF metadata needs a WAV output")
+	}
+	// Broadcast WAV carries PCM; ffmpeg would put compressed audio in RIFF
+	if !job.Options.Codec.IsPCM() {
+		return pkgerrors.NewValidationError("codec", job.Options.Codec, "BWF metadata needs a PCM codec (pcm_s16le or pcm_s24le)")
+	}
+	for _, f := range []struct {
+		field, value string
+		max          int
+	}{
+		{"bwf.description", bwf.Description, 256},
+		{"bwf.originator", bwf.Originator, 32},
+		{"bwf.originatorReference", bwf.OriginatorReference, 32},
+	} {
+		if len(f.value) > f.max {
+			return pkgerrors.NewValidationError(f.field, f.value, "must not be longer than "+strconv.Itoa(f.max)+" bytes")
+		}
+	}
+	if d := bwf.OriginationDate; d != "" {
+		if _, err := time.Parse(time.DateOnly, d); err != nil {
+			return pkgerrors.NewValidationError("bwf.originationDate", d, "origination date must be yyyy-mm-dd")
+		}
+	}
+	if t := bwf.OriginationTime; t != "" {
+		if _, err := time.Parse(time.TimeOnly, t); err != nil {
+			return pkgerrors.NewValidationError("bwf.originationTime", t, "origination time must be hh:mm:ss")
+		}
+	}
+	if strings.ContainsRune(bwf.Description+bwf.Originator+bwf.OriginatorReference+bwf.CodingHistory, 0) {
+		return pkgerrors.NewValidationError("bwf", *bwf, "BWF fields must not contain NUL")
+	}
+	return nil
+}
+
+// bwfArgs returns the options writing opts.BWF to the output at path; only
+// WAV outputs have a bext chunk. ffmpeg reads its fields from the tags.
+func bwfArgs(opts *model.ProcessingOptions, path string) []string {
+	bwf := opts.BWF
+	if bwf == nil || outputMuxer(opts, path) != "wav" {
+		return nil
+	}
+	args := []string{"-write_bext", "1"}
+	for _, tag := range []struct{ key, value string }{
+		{"description", bwf.Description},
+		{"originator", bwf.Originator},
+		{"originator_reference", bwf.OriginatorReference},
+		{"origination_date", bwf.OriginationDate},
+		{"origination_time", bwf.OriginationTime},
+		{"coding_history", bwf.CodingHistory},
+	} {
+		if tag.value != "" {
+			args = append(args, "-metadata", tag.key+"="+tag.value)
+		}
+	}
+	if bwf.TimeReference != 0 {
+		args = append(args, "-metadata", "time_reference="+strconv.FormatUint(bwf.TimeReference, 10))
+	}
+	return args
+}
@@ -0,0 +1,122 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+)
+
+// poolJob is a batch job queued or running in a worker pool
+type poolJob struct {
+	job     model.BatchJob
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	started bool // guarded by WorkerPool.mu
+}
+
+// canceled replaces err with the cause when the job was stopped by Cancel,
+// its batch policy or Shutdown, since a killed ffmpeg reports its exit
+// rather than the cause
+func (pj *poolJob) canceled(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch cause := context.Cause(pj.ctx); cause {
+	case pkgerrors.ErrJobCanceled, pkgerrors.ErrBatchAborted, pkgerrors.ErrShuttingDown:
+		return fmt.Errorf("job %s: %w", pj.job.ID, cause)
+	}
+	return err
+}
+
+// track registers jobs so that Cancel and Shutdown can find them. It
+// fails with ErrShuttingDown once Shutdown was called.
+func (wp *WorkerPool) track(ctx context.Context, jobs ...model.BatchJob) ([]*poolJob, error) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	if wp.closing {
+		return nil, pkgerrors.ErrShuttingDown
+	}
+
+	tracked := make([]*poolJob, len(jobs))
+	for i, job := range jobs {
+		jobCtx, cancel := context.WithCancelCause(ctx)
+		pj := &poolJob{job: job, ctx: jobCtx, cancel: cancel}
+		if wp.running[job.ID] == nil {
+			wp.running[job.ID] = make(map[*poolJob]struct{})
+		}
+		wp.running[job.ID][pj] = struct{}{}
+		tracked[i] = pj
+	}
+	wp.active.Add(len(jobs))
+	return tracked, nil
+}
+
+// start marks a queued job as running. It reports false once Shutdown was
+// called, as queued jobs are not started any more.
+func (wp *WorkerPool) start(pj *poolJob) bool {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	pj.started = !wp.closing
+	return pj.started
+}
+
+// untrack forgets a finished job and releases its context
+func (wp *WorkerPool) untrack(pj *poolJob) {
+	pj.cancel(nil)
+
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	delete(wp.running[pj.job.ID], pj)
+	if len(wp.running[pj.job.ID]) == 0 {
+		delete(wp.running, pj.job.ID)
+	}
+	wp.active.Done()
+}
+
+// Cancel stops the queued or running batch jobs with the given ID, killing
+// their ffmpeg processes; other jobs are unaffected. Their results carry
+// pkgerrors.ErrJobCanceled. It reports whether any job matched.
+func (wp *WorkerPool) Cancel(jobID string) bool {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	for pj := range wp.running[jobID] {
+		pj.cancel(pkgerrors.ErrJobCanceled)
+	}
+	return len(wp.running[jobID]) > 0
+}
+
+// failureCounter applies a batch policy to the outcomes of its jobs
+type failureCounter struct {
+	policy model.BatchPolicy
+
+	mu       sync.Mutex
+	finished int
+	failed   int
+}
+
+func newFailureCounter(policy model.BatchPolicy) *failureCounter {
+	return &failureCounter{policy: policy}
+}
+
+// record counts a finished job and reports whether the policy tripped.
+// Jobs canceled by Cancel or by the policy itself are not failures.
+func (c *failureCounter) record(err error) bool {
+	if !c.policy.Enabled() {
+		return false
+	}
+	if err != nil && (errors.Is(err, context.Canceled) || pkgerrors.CodeOf(err) == pkgerrors.ErrCodeCanceled) {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.finished++
+	if err != nil {
+		c.failed++
+	}
+	return c.policy.Tripped(c.finished, c.failed)
+}
@@ -0,0 +1,52 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+)
+
+// SetCapabilities installs the detected ffmpeg capabilities. Jobs needing
+// an encoder or filter the build lacks then fail validation.
+func (p *Pipeline) SetCapabilities(c *model.Capabilities) {
+	p.capabilities = c
+}
+
+// Capabilities returns the detected ffmpeg capabilities, or nil
+func (p *Pipeline) Capabilities() *model.Capabilities {
+	return p.capabilities
+}
+
+// checkCapabilities picks the job's encoders from those the ffmpeg build
+// has and verifies it has the filters the job needs. It passes when
+// capabilities weren't detected.
+func (p *Pipeline) checkCapabilities(opts *model.ProcessingOptions) error {
+	if !p.capabilities.Detected() {
+		return nil
+	}
+
+	p.resolveEncoders(opts)
+	if err := p.checkEncoder("codec", opts); err != nil {
+		return err
+	}
+	for i, out := range opts.AdditionalOutputs {
+		o := *opts
+		if out.Configure != nil {
+			out.Configure(&o)
+		}
+		if err := p.checkEncoder(fmt.Sprintf("additionalOutputs[%d].codec", i), &o); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range buildFilterChain(opts).Filters() {
+		name, _, _ := strings.Cut(f, "=")
+		if !p.capabilities.HasFilter(name) {
+			return pkgerrors.NewValidationError("filters", name,
+				fmt.Sprintf("ffmpeg %s was built without the %s filter", p.capabilities.Version, name))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/infrastructure/ogg"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+)
+
+// scanChains lists the chains of a local Ogg file
+func (p *Pipeline) scanChains(ctx context.Context, path string) ([]ogg.Chain, error) {
+	f, err := p.storage.Open(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ogg.ScanChains(f)
+}
+
+// addChains fills meta.Chains for chained Ogg files. ffprobe only reports
+// the first chain, so the duration is replaced with the total.
+func (p *Pipeline) addChains(ctx context.Context, path string, meta *model.AudioMetadata) {
+	if IsRemoteInput(path) || !strings.Contains(meta.Format, "ogg") {
+		return
+	}
+	chains, err := p.scanChains(ctx, path)
+	if err != nil || len(chains) < 2 {
+		return
+	}
+	meta.Duration = 0
+	for _, c := range chains {
+		meta.Chains = append(meta.Chains, model.ChainInfo{
+			Index:      c.Index,
+			Offset:     c.Offset,
+			Size:       c.Size,
+			Codec:      c.Codec,
+			SampleRate: c.SampleRate,
+			Channels:   c.Channels,
+			Duration:   c.Duration,
+		})
+		meta.Duration += c.Duration
+	}
+}
+
+// ProcessChains splits a chained Ogg input into its logical streams and
+// runs each through the pipeline, writing one output per chain.
+// job.OutputPath must contain model.ChainPlaceholder and
+// job.Options.Timeout applies to each chain. An unchained Ogg
+// input, or one in another format, yields a single output. Chains are streamed to ffmpeg's stdin rather than copied
+// to intermediate files, so like RunReader their encodes aren't probed up
+// front; InputMeta holds what the Ogg headers describe.
+func (p *Pipeline) ProcessChains(ctx context.Context, job *Job) ([]*model.ProcessingResult, error) {
+	if !strings.Contains(job.OutputPath, model.ChainPlaceholder) {
+		return nil, pkgerrors.NewValidationError("outputPath", job.OutputPath, "output path must contain "+model.ChainPlaceholder)
+	}
+	if IsRemoteInput(job.InputPath) {
+		return nil, pkgerrors.NewValidationError("inputPath", job.InputPath, "chain splitting needs a local input")
+	}
+	if err := p.checkInputExists(ctx, job.InputPath); err != nil {
+		return nil, err
+	}
+
+	// Inputs that aren't Ogg have a single stream and are encoded whole
+	chains, err := p.scanChains(ctx, job.InputPath)
+	if err != nil && !errors.Is(err, ogg.ErrNotOgg) {
+		return nil, pkgerrors.NewProcessingError("probe", "failed to scan ogg chains", err)
+	}
+
+	chainJob := func(i int) *Job {
+		j := *job
+		j.ID = fmt.Sprintf("%s-chain-%02d", job.ID, i+1)
+		j.OutputPath = strings.ReplaceAll(job.OutputPath, model.ChainPlaceholder, fmt.Sprintf("%02d", i+1))
+		return &j
+	}
+
+	if len(chains) < 2 {
+		result, err := p.runTimed(ctx, chainJob(0))
+		if err != nil {
+			return nil, fmt.Errorf("chain 1: %w", err)
+		}
+		return []*model.ProcessingResult{result}, nil
+	}
+	if job.Options.DryRun {
+		return p.dryRunChains(ctx, job, chains, chainJob)
+	}
+
+	src, err := p.storage.Open(ctx, job.InputPath)
+	if err != nil {
+		return nil, pkgerrors.NewProcessingError("split", "failed to open input", err)
+	}
+	defer src.Close()
+
+	results := make([]*model.ProcessingResult, 0, len(chains))
+	var failed error
+	err = ogg.Split(src, chains, func(c ogg.Chain) (io.WriteCloser, error) {
+		i := len(results)
+		pr, pw := io.Pipe()
+		w := &chainWriter{PipeWriter: pw, done: make(chan struct{})}
+		go func() {
+			defer close(w.done)
+			ctx, cancel := withJobTimeout(ctx, job)
+			defer cancel()
+			result, err := p.RunReader(ctx, chainJob(i), pr)
+			// Stop the copy if the encode ended without reading it all
+			pr.CloseWithError(errChainUnread)
+			if err != nil {
+				failed = fmt.Errorf("chain %d: %w", i+1, err)
+				w.err = failed
+				return
+			}
+			result.InputPath = job.InputPath
+			result.InputMeta = chainMeta(c)
+			results = append(results, result)
+		}()
+		return w, nil
+	})
+	if failed != nil {
+		return results, failed
+	}
+	if err != nil {
+		return results, pkgerrors.NewProcessingError("split", "failed to split ogg chains", err)
+	}
+	return results, nil
+}
+
+// runTimed runs job with its own Timeout, for operations that encode
+// several outputs one after another
+func (p *Pipeline) runTimed(ctx context.Context, job *Job) (*model.ProcessingResult, error) {
+	ctx, cancel := withJobTimeout(ctx, job)
+	defer cancel()
+	return p.Run(ctx, job)
+}
+
+// withJobTimeout bounds ctx by job's Timeout; zero means no timeout
+func withJobTimeout(ctx context.Context, job *Job) (context.Context, context.CancelFunc) {
+	if job.Options.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, job.Options.Timeout)
+}
+
+// errChainUnread ends the copy of a chain whose encode has finished
+var errChainUnread = errors.New("chain encode stopped reading")
+
+// chainWriter feeds one chain to its encode. Close waits for the encode
+// to finish, so chains are encoded one at a time, and returns its error.
+type chainWriter struct {
+	*io.PipeWriter
+	done chan struct{}
+	err  error
+}
+
+func (w *chainWriter) Close() error {
+	w.PipeWriter.Close()
+	<-w.done
+	return w.err
+}
+
+// chainMeta describes a chain as scanned from its Ogg headers
+func chainMeta(c ogg.Chain) *model.AudioMetadata {
+	return &model.AudioMetadata{
+		Format:     "ogg",
+		Codec:      c.Codec,
+		SampleRate: c.SampleRate,
+		Channels:   c.Channels,
+		Duration:   c.Duration,
+		Size:       c.Size,
+	}
+}
+
+// dryRunChains returns the command each chain's encode would run, writing
+// to temp files like dryRun
+func (p *Pipeline) dryRunChains(ctx context.Context, job *Job, chains []ogg.Chain, chainJob func(int) *Job) ([]*model.ProcessingResult, error) {
+	if err := p.validateInput(ctx, job); err != nil {
+		return nil, err
+	}
+	results := make([]*model.ProcessingResult, 0, len(chains))
+	for i, c := range chains {
+		start := time.Now()
+		j := chainJob(i)
+		j.InputPath = pipeInput
+		enc, _ := p.atomicJob(j)
+		plan, err := buildPlan(enc)
+		if err != nil {
+			return results, fmt.Errorf("chain %d: %w", i+1, err)
+		}
+		results = append(results, &model.ProcessingResult{
+			InputPath:   job.InputPath,
+			OutputPath:  j.OutputPath,
+			InputMeta:   chainMeta(c),
+			Duration:    time.Since(start),
+			ProcessedAt: time.Now(),
+			Profile:     j.profile(),
+			Labels:      j.labels(),
+			Command:     plan.Args(),
+		})
+	}
+	return results, nil
+}
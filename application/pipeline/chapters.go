@@ -0,0 +1,124 @@
+package pipeline
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/internal/argfmt"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+)
+
+// chapterMuxers are the muxers that write chapters: QuickTime chapters in
+// MP4, M4A and M4B, ID3 CHAP frames in MP3
+var chapterMuxers = map[string]bool{
+	"mp3":  true,
+	"ipod": true,
+	"mp4":  true,
+}
+
+// maxChapterArg bounds the data: URI carrying the ffmetadata document. It
+// is passed as a single argument, which Linux caps at 128 KiB including
+// the terminating NUL; the rest is headroom for the end fillChapterEnds
+// adds to the last chapter.
+const maxChapterArg = 127 << 10
+
+// ffmetadataEscaper escapes the characters ffmetadata gives a meaning
+var ffmetadataEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"=", `\=`,
+	";", `\;`,
+	"#", `\#`,
+	"\n", "\\\n",
+)
+
+// validateChapters checks the chapters against the main output
+func validateChapters(job *Job) error {
+	opts := job.Options
+	if len(opts.Chapters) == 0 {
+		return nil
+	}
+	if opts.MetadataPolicy == model.MetadataStrip {
+		return pkgerrors.NewValidationError("metadataPolicy", opts.MetadataPolicy, "chapters can't be set when stripping tags")
+	}
+	for i, c := range opts.Chapters {
+		field := fmt.Sprintf("chapters[%d]", i)
+		if c.Start < 0 {
+			return pkgerrors.NewValidationError(field+".start", c.Start, "chapter start must not be negative")
+		}
+		if c.End != 0 && c.End <= c.Start {
+			return pkgerrors.NewValidationError(field+".end", c.End, "chapter must end after it starts")
+		}
+		if i > 0 && c.Start <= opts.Chapters[i-1].Start {
+			return pkgerrors.NewValidationError(field+".start", c.Start, "chapters must be in order of their start")
+		}
+	}
+	if len(chapterInput(opts).Path) > maxChapterArg {
+		return pkgerrors.NewValidationError("chapters", len(opts.Chapters), "chapter titles are too long to pass to ffmpeg")
+	}
+	if !chapterMuxers[outputMuxer(opts, job.OutputPath)] {
+		return pkgerrors.NewValidationError("chapters", job.OutputPath, "chapters need an MP3 or M4A/M4B/MP4 output")
+	}
+	return nil
+}
+
+// writesChapters reports whether the output at path gets opts.Chapters
+func writesChapters(opts *model.ProcessingOptions, path string) bool {
+	return len(opts.Chapters) > 0 && chapterMuxers[outputMuxer(opts, path)]
+}
+
+// chapterInput returns the plan input reading the chapters. The ffmetadata
+// document is inlined as a data: URI rather than written to a temp file so
+// stored plans stay self-contained and fingerprints track the chapters.
+func chapterInput(opts *model.ProcessingOptions) model.PlanInput {
+	doc := base64.StdEncoding.EncodeToString([]byte(chapterMetadata(opts.Chapters)))
+	return model.PlanInput{
+		Path: "data:text/plain;base64," + doc,
+		Args: []string{"-f", "ffmetadata"},
+	}
+}
+
+// chapterArgs copies the chapters of input index input into an output
+func chapterArgs(input int) []string {
+	return []string{"-map_chapters", argfmt.Int(input)}
+}
+
+// chapterMetadata renders chapters as an ffmetadata document. A chapter
+// whose end is unknown runs until the next one starts; the last one is
+// left open until fillChapterEnds has seen the input's duration.
+func chapterMetadata(chapters []model.Chapter) string {
+	var b strings.Builder
+	b.WriteString(";FFMETADATA1\n")
+	for i, c := range chapters {
+		end := c.End
+		if end == 0 && i+1 < len(chapters) {
+			end = chapters[i+1].Start
+		}
+		b.WriteString("[CHAPTER]\nTIMEBASE=1/1000\n")
+		fmt.Fprintf(&b, "START=%d\n", c.Start.Milliseconds())
+		if end > 0 {
+			fmt.Fprintf(&b, "END=%d\n", end.Milliseconds())
+		}
+		if c.Title != "" {
+			b.WriteString("title=" + ffmetadataEscaper.Replace(c.Title) + "\n")
+		}
+	}
+	return b.String()
+}
+
+// fillChapterEnds returns a copy of job whose last chapter ends with the
+// input when it has no end of its own
+func fillChapterEnds(job *Job, duration time.Duration) *Job {
+	chapters := job.Options.Chapters
+	if len(chapters) == 0 || chapters[len(chapters)-1].End != 0 || duration <= chapters[len(chapters)-1].Start {
+		return job
+	}
+	opts := *job.Options
+	opts.Chapters = append([]model.Chapter(nil), chapters...)
+	opts.Chapters[len(chapters)-1].End = duration
+	j := *job
+	j.Options = &opts
+	return &j
+}
@@ -0,0 +1,89 @@
+package pipeline
+
+import (
+	"fmt"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+)
+
+// codecLimits are the encoder constraints checked before ffmpeg runs
+type codecLimits struct {
+	sampleRates []int // Hz
+	minBitrate  int   // bps; both 0 for PCM, which has no bitrate setting
+	maxBitrate  int   // bps
+}
+
+// pcmSampleRates are the rates accepted for PCM outputs
+var pcmSampleRates = []int{8000, 11025, 16000, 22050, 32000, 44100, 48000, 88200, 96000, 176400, 192000}
+
+var codecLimitsByCodec = map[model.Codec]codecLimits{
+	// libopus only runs at its internal rates; resample 44.1 kHz sources
+	model.CodecOpus: {
+		sampleRates: []int{8000, 12000, 16000, 24000, 48000},
+		minBitrate:  6000,
+		maxBitrate:  510000,
+	},
+	model.CodecAAC: {
+		sampleRates: []int{8000, 11025, 12000, 16000, 22050, 24000, 32000, 44100, 48000, 64000, 88200, 96000},
+		minBitrate:  8000,
+		maxBitrate:  512000,
+	},
+	model.CodecMP3: {
+		sampleRates: []int{8000, 11025, 12000, 16000, 22050, 24000, 32000, 44100, 48000},
+		minBitrate:  8000,
+		maxBitrate:  320000,
+	},
+	model.CodecPCM16: {sampleRates: pcmSampleRates},
+	model.CodecPCM24: {sampleRates: pcmSampleRates},
+}
+
+// mp3LowRateMaxBitrate caps MPEG-2/2.5 layer III, used below 32 kHz
+const mp3LowRateMaxBitrate = 160000
+
+// validateCodecOptions rejects codec, sample rate and bitrate combinations
+// the encoder can't produce. prefix qualifies the field names, e.g.
+// "additionalOutputs[0].".
+func validateCodecOptions(prefix string, opts *model.ProcessingOptions) error {
+	limits, ok := codecLimitsByCodec[opts.Codec]
+	if !ok {
+		return pkgerrors.NewValidationError(prefix+"codec", opts.Codec, "unsupported codec")
+	}
+
+	if !containsInt(limits.sampleRates, opts.SampleRate) {
+		return pkgerrors.NewValidationError(prefix+"sampleRate", opts.SampleRate,
+			fmt.Sprintf("%s does not support a sample rate of %d Hz (supported: %v)", opts.Codec, opts.SampleRate, limits.sampleRates))
+	}
+
+	// AAC and MP3 VBR encode to a quality level and ignore the bitrate
+	if opts.BitrateMode == model.BitrateModeVBR && opts.Codec != model.CodecOpus {
+		return nil
+	}
+	if opts.Codec.IsPCM() {
+		return nil
+	}
+
+	maxBitrate := limits.maxBitrate
+	if opts.Codec == model.CodecMP3 && opts.SampleRate < 32000 {
+		maxBitrate = mp3LowRateMaxBitrate
+	}
+	if opts.Bitrate < limits.minBitrate {
+		return pkgerrors.NewValidationError(prefix+"bitrate", opts.Bitrate,
+			fmt.Sprintf("%s bitrate must be at least %d bps", opts.Codec, limits.minBitrate))
+	}
+	if opts.Bitrate > maxBitrate {
+		return pkgerrors.NewValidationError(prefix+"bitrate", opts.Bitrate,
+			fmt.Sprintf("%s bitrate must be at most %d bps at %d Hz", opts.Codec, maxBitrate, opts.SampleRate))
+	}
+
+	return nil
+}
+
+func containsInt(values []int, v int) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package pipeline
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/internal/argfmt"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"github.com/Skryldev/audio-lab/pkg/progress"
+)
+
+// coverArtEncoders maps image extensions onto the encoder writing them.
+// Temp outputs lose their extension, so the encoder has to be given
+// explicitly.
+var coverArtEncoders = map[string]string{
+	".jpg":  "mjpeg",
+	".jpeg": "mjpeg",
+	".png":  "png",
+	".bmp":  "bmp",
+}
+
+// ExtractCoverArt writes the input's first attached picture to
+// job.OutputPath. The picture is copied as is when the output extension
+// matches its codec, and converted otherwise.
+func (p *Pipeline) ExtractCoverArt(ctx context.Context, job *Job) (*model.ProcessingResult, error) {
+	start := time.Now()
+
+	if job.InputPath == "" {
+		return nil, pkgerrors.NewValidationError("inputPath", "", "input path must not be empty")
+	}
+	if job.OutputPath == "" {
+		return nil, pkgerrors.NewValidationError("outputPath", "", "output path must not be empty")
+	}
+	encoder, ok := coverArtEncoders[strings.ToLower(filepath.Ext(job.OutputPath))]
+	if !ok {
+		return nil, pkgerrors.NewValidationError("outputPath", job.OutputPath, "cover art output must be .jpg, .jpeg, .png or .bmp")
+	}
+	if err := p.checkInputExists(ctx, job.InputPath); err != nil {
+		return nil, err
+	}
+	if err := p.prepareOutputs(ctx, job.Options, job.OutputPath); err != nil {
+		return nil, err
+	}
+
+	inputMeta, err := p.probeFile(ctx, job.InputPath)
+	if err != nil {
+		return nil, pkgerrors.NewProcessingError("probe", "failed to probe input file", err)
+	}
+	job.report(progress.StageProbe, 5, "input probed")
+
+	picture, ok := inputMeta.CoverArt()
+	if !ok {
+		return nil, pkgerrors.NewValidationError("inputPath", job.InputPath, "input has no cover art")
+	}
+	if picture.Codec == encoder {
+		encoder = "copy"
+	}
+
+	args := []string{"-y"}
+	args = append(args, remoteInputArgs(job.InputPath)...)
+	args = append(args,
+		"-i", ffmpegPath(job.InputPath),
+		"-map", "0:"+argfmt.Int(picture.Index),
+		"-frames:v", "1",
+		"-c:v", encoder,
+		"-update", "1",
+	)
+
+	atomic := p.newAtomicWrite()
+	defer atomic.discard(ctx)
+	output := job.OutputPath
+	if !job.Options.DirectOutputWrites {
+		output, _ = atomic.add(job.OutputPath, "image2")
+	}
+	args = append(args, "-f", "image2", ffmpegPath(output))
+
+	job.report(progress.StageEncode, 20, "extracting cover art")
+
+	if err := p.executor.Execute(ctx, args); err != nil {
+		return nil, err
+	}
+	if err := p.finalizeOutputs(ctx, job.Options, output); err != nil {
+		return nil, err
+	}
+
+	outputMeta, err := atomic.verify(ctx, output)
+	if err != nil {
+		return nil, err
+	}
+	if err := atomic.commit(ctx); err != nil {
+		return nil, err
+	}
+
+	job.report(progress.StageDone, 100, "done")
+
+	return &model.ProcessingResult{
+		InputPath:   job.InputPath,
+		OutputPath:  job.OutputPath,
+		InputMeta:   inputMeta,
+		OutputMeta:  outputMeta,
+		Duration:    time.Since(start),
+		ProcessedAt: time.Now(),
+		Profile:     job.profile(),
+		Labels:      job.labels(),
+	}, nil
+}
+
+// pictureMuxers are the muxers that can hold an attached picture
+var pictureMuxers = map[string]bool{
+	"mp3":  true,
+	"ipod": true,
+	"mp4":  true,
+	"flac": true,
+}
+
+// coverArtFormatEncoders maps cover art formats onto their encoder
+var coverArtFormatEncoders = map[model.CoverArtFormat]string{
+	model.CoverArtJPEG: "mjpeg",
+	model.CoverArtPNG:  "png",
+}
+
+// outputMuxer returns the muxer of the output at path: the forced one, or
+// the one its extension implies
+func outputMuxer(opts *model.ProcessingOptions, path string) string {
+	if opts.OutputFormat != "" {
+		return opts.OutputFormat
+	}
+	return muxerByExt[strings.ToLower(filepath.Ext(path))]
+}
+
+// validateCoverArt checks the cover art options against the main output
+func validateCoverArt(job *Job) error {
+	art := job.Options.CoverArt
+	if art.Path == "" {
+		return nil
+	}
+	if art.MaxSize < 0 {
+		return pkgerrors.NewValidationError("coverArt.maxSize", art.MaxSize, "cover art size must not be negative")
+	}
+	if _, ok := coverArtFormatEncoders[art.Format]; art.Format != "" && !ok {
+		return pkgerrors.NewValidationError("coverArt.format", art.Format, "cover art format must be jpeg or png")
+	}
+	if !pictureMuxers[outputMuxer(job.Options, job.OutputPath)] {
+		return pkgerrors.NewValidationError("coverArt", job.OutputPath, "cover art needs an MP3, M4A/MP4 or FLAC output")
+	}
+	return nil
+}
+
+// embedsCoverArt reports whether the output at path gets opts.CoverArt
+func embedsCoverArt(opts *model.ProcessingOptions, path string) bool {
+	return opts.CoverArt.Path != "" && pictureMuxers[outputMuxer(opts, path)]
+}
+
+// coverArtInput returns the plan input reading the cover art image
+func coverArtInput(opts *model.ProcessingOptions) model.PlanInput {
+	return model.PlanInput{Path: ffmpegPath(opts.CoverArt.Path), Args: remoteInputArgs(opts.CoverArt.Path)}
+}
+
+// coverArtArgs maps the image of input index input onto an output and
+// flags it as the front cover, returning the -map specifier and the
+// output options
+func coverArtArgs(opts *model.ProcessingOptions, input int) (string, []string) {
+	art := opts.CoverArt
+	encoder := "copy"
+	if art.Format != "" {
+		encoder = coverArtFormatEncoders[art.Format]
+	} else if art.MaxSize > 0 {
+		encoder = coverArtFormatEncoders[model.CoverArtJPEG]
+	}
+
+	args := []string{"-c:v", encoder}
+	if art.MaxSize > 0 {
+		size := argfmt.Int(art.MaxSize)
+		args = append(args, "-vf", "scale='min("+size+",iw)':'min("+size+",ih)':force_original_aspect_ratio=decrease")
+	}
+	// ID3 readers pick the picture by its APIC type, "Cover (front)"
+	args = append(args,
+		"-disposition:v", "attached_pic",
+		"-metadata:s:v", "title=Album cover",
+		"-metadata:s:v", "comment=Cover (front)",
+	)
+	return argfmt.Int(input) + ":v:0", args
+}
@@ -0,0 +1,181 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/infrastructure/cue"
+	"github.com/Skryldev/audio-lab/internal/argfmt"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"github.com/Skryldev/audio-lab/pkg/pathutil"
+)
+
+// SplitByCue cuts job.InputPath into the audio tracks of the CUE sheet at
+// cuePath and runs each through the pipeline, writing "NN - Title.ext"
+// files to dir. Tracks start at their INDEX 01, so pregaps stay at the end
+// of the previous track as on the disc. Sheet tags are written to every
+// track; job.Options.Metadata overrides them. job.Options.Timeout applies
+// to each track.
+func (p *Pipeline) SplitByCue(ctx context.Context, job *Job, cuePath, dir string) ([]*model.ProcessingResult, error) {
+	if dir == "" {
+		return nil, pkgerrors.NewValidationError("outputDir", "", "output directory must not be empty")
+	}
+	if IsRemoteInput(cuePath) {
+		return nil, pkgerrors.NewValidationError("cuePath", cuePath, "cue sheet must be a local file")
+	}
+	ext := job.Options.Codec.Extension()
+	if ext == "" {
+		return nil, pkgerrors.NewValidationError("codec", job.Options.Codec, "no default extension for codec "+string(job.Options.Codec))
+	}
+	if len(job.Options.Chapters) > 0 {
+		return nil, pkgerrors.NewValidationError("chapters", len(job.Options.Chapters), "chapters can't be set when splitting by cue sheet")
+	}
+	if err := p.checkInputExists(ctx, cuePath); err != nil {
+		return nil, err
+	}
+	if err := p.checkInputExists(ctx, job.InputPath); err != nil {
+		return nil, err
+	}
+
+	sheet, err := p.readCueSheet(ctx, cuePath)
+	if err != nil {
+		return nil, err
+	}
+	if files := sheet.Files(); len(files) > 1 {
+		return nil, pkgerrors.NewValidationError("cuePath", cuePath, "cue sheets referencing several files are not supported")
+	}
+	var tracks []cue.Track
+	numbers := make(map[int]bool, len(sheet.Tracks))
+	for _, t := range sheet.Tracks {
+		// Tracks sharing a number would write the same file
+		if numbers[t.Number] {
+			return nil, pkgerrors.NewValidationError("cuePath", t.Number, "cue sheet has duplicate track numbers")
+		}
+		numbers[t.Number] = true
+		if t.IsAudio() {
+			tracks = append(tracks, t)
+		}
+	}
+	if len(tracks) == 0 {
+		return nil, pkgerrors.NewValidationError("cuePath", cuePath, "cue sheet has no audio tracks")
+	}
+	for i := 1; i < len(tracks); i++ {
+		if tracks[i].Start <= tracks[i-1].Start {
+			return nil, pkgerrors.NewValidationError("cuePath", tracks[i].Number, "cue tracks must be in order of their start")
+		}
+	}
+
+	inputMeta, err := p.probeFile(ctx, job.InputPath)
+	if err != nil {
+		return nil, pkgerrors.NewProcessingError("probe", "failed to probe input file", err)
+	}
+	if last := tracks[len(tracks)-1]; inputMeta.Duration > 0 && last.Start >= inputMeta.Duration {
+		return nil, pkgerrors.NewValidationError("cuePath", last.Number, "cue track starts beyond the end of the input")
+	}
+
+	results := make([]*model.ProcessingResult, 0, len(tracks))
+	for i, t := range tracks {
+		var length time.Duration
+		if i+1 < len(tracks) {
+			length = tracks[i+1].Start - t.Start
+		}
+
+		trackJob := *job
+		trackJob.ID = fmt.Sprintf("%s-track-%02d", job.ID, t.Number)
+		trackJob.OutputPath = filepath.Join(dir, cueTrackName(t, ext))
+		trackJob.Options = cueTrackOptions(job.Options, sheet, t, len(tracks), length, trackJob.OutputPath)
+
+		result, err := p.runTimed(ctx, &trackJob)
+		if err != nil {
+			return results, fmt.Errorf("track %d: %w", t.Number, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// readCueSheet parses the local CUE sheet at path
+func (p *Pipeline) readCueSheet(ctx context.Context, path string) (*cue.Sheet, error) {
+	f, err := p.storage.Open(ctx, path)
+	if err != nil {
+		return nil, pkgerrors.NewProcessingError("probe", "failed to open cue sheet", err)
+	}
+	defer f.Close()
+	sheet, err := cue.Parse(f)
+	if err != nil {
+		return nil, pkgerrors.NewValidationError("cuePath", path, err.Error())
+	}
+	return sheet, nil
+}
+
+// cueTrackOptions returns a copy of opts that cuts track t out of the input
+// and, unless tags are stripped, tags it from the sheet. A zero length runs
+// to the end of the input. CD frames are 1/75 s, so the cut points are
+// given to the microsecond. The sheet's ISRC and catalog are only used
+// when valid and the output at path can hold them.
+func cueTrackOptions(opts *model.ProcessingOptions, sheet *cue.Sheet, t cue.Track, total int, length time.Duration, path string) *model.ProcessingOptions {
+	o := *opts
+	seek := []string{"-ss", argfmt.Float(t.Start.Seconds(), 6)}
+	if length > 0 {
+		seek = append(seek, "-t", argfmt.Float(length.Seconds(), 6))
+	}
+	o.ExtraInputArgs = append(seek, opts.ExtraInputArgs...)
+	if opts.MetadataPolicy == model.MetadataStrip {
+		return &o
+	}
+
+	tags := map[string]string{
+		"track":        strconv.Itoa(t.Number) + "/" + strconv.Itoa(total),
+		"title":        t.Title,
+		"artist":       firstNonEmpty(t.Performer, sheet.Performer),
+		"album":        sheet.Title,
+		"album_artist": sheet.Performer,
+		"composer":     firstNonEmpty(t.Songwriter, sheet.Songwriter),
+		"genre":        sheet.Genre,
+		"date":         sheet.Date,
+	}
+	o.Metadata = make(map[string]string, len(tags)+len(opts.Metadata))
+	for k, v := range tags {
+		if v != "" {
+			o.Metadata[k] = v
+		}
+	}
+	for k, v := range opts.Metadata {
+		o.Metadata[k] = v
+	}
+
+	if _, ok := identifierTags[outputMuxer(&o, path)]; ok {
+		if isrc := normalizeISRC(t.ISRC); o.Identifiers.ISRC == "" && validISRC(isrc) {
+			o.Identifiers.ISRC = isrc
+		}
+		// Rippers write all zeros when the disc has no catalog number
+		if c := sheet.Catalog; o.Identifiers.Barcode == "" && validBarcode(c) && strings.Trim(c, "0") != "" {
+			o.Identifiers.Barcode = c
+		}
+	}
+	return &o
+}
+
+// cueTrackName returns the file name of track t
+func cueTrackName(t cue.Track, ext string) string {
+	name := fmt.Sprintf("%02d", t.Number)
+	if t.Title != "" {
+		name += " - " + t.Title
+	}
+	return pathutil.SanitizeName(name+ext, 0)
+}
+
+// firstNonEmpty returns the first of values that isn't empty
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,197 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/domain/ports"
+	"github.com/Skryldev/audio-lab/infrastructure/storage"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// sizeMargin covers VBR overshoot and container overhead
+	sizeMargin = 1.2
+
+	// sizeHeadroom is added per output for headers, seek tables and cover art
+	sizeHeadroom = 1 << 20
+)
+
+// losslessEncoders compress without loss. Their output is estimated at
+// the PCM size of 24-bit audio, which they stay below for any source.
+var losslessEncoders = map[string]bool{
+	"flac":    true,
+	"alac":    true,
+	"wavpack": true,
+	"tta":     true,
+	"truehd":  true,
+	"mlp":     true,
+}
+
+// estimateOutputSize returns the expected size in bytes of a duration-long
+// encode of input with opts, including a safety margin. Lossy encodes are
+// sized by their bitrate, PCM and lossless ones by their sample rate,
+// channels and sample size.
+func estimateOutputSize(duration time.Duration, opts *model.ProcessingOptions, input *model.AudioMetadata) uint64 {
+	if duration <= 0 {
+		return sizeHeadroom
+	}
+	bitrate := float64(opts.Bitrate)
+	if bits := sampleBits(outputEncoder(opts)); bits > 0 {
+		rate := opts.SampleRate
+		if rate <= 0 {
+			rate = input.SampleRate
+		}
+		channels := outputChannels(opts, input)
+		bitrate = float64(rate * channels * bits)
+	}
+	if bitrate <= 0 {
+		return sizeHeadroom
+	}
+	return uint64(duration.Seconds()*bitrate/8*sizeMargin) + sizeHeadroom
+}
+
+// outputEncoder returns the audio encoder of an output with opts. A codec
+// set through ExtraOutputArgs comes later on the command line and wins.
+func outputEncoder(opts *model.ProcessingOptions) string {
+	encoder := encoderFor(opts)
+	if v := lastArg(opts.ExtraOutputArgs, "-c:a", "-acodec", "-codec:a", "-c", "-codec"); v != "" {
+		encoder = v
+	}
+	return encoder
+}
+
+// outputChannels returns the channel count of an output with opts, which
+// keeps the input's unless ExtraOutputArgs set -ac
+func outputChannels(opts *model.ProcessingOptions, input *model.AudioMetadata) int {
+	if v := lastArg(opts.ExtraOutputArgs, "-ac"); v != "" {
+		var n int
+		if _, err := fmt.Sscan(v, &n); err == nil && n > 0 {
+			return n
+		}
+	}
+	if input.Channels > 0 {
+		return input.Channels
+	}
+	return 2
+}
+
+// sampleBits returns the bits per sample that size the output of a PCM or
+// lossless encoder, or 0 for lossy ones
+func sampleBits(encoder string) int {
+	if losslessEncoders[encoder] {
+		return 24
+	}
+	format, ok := strings.CutPrefix(encoder, "pcm_")
+	if !ok {
+		return 0
+	}
+	switch format {
+	case "alaw", "mulaw":
+		return 8
+	}
+	var bits int
+	for _, c := range format {
+		if c >= '0' && c <= '9' {
+			bits = bits*10 + int(c-'0')
+		}
+	}
+	if bits == 0 {
+		return 16
+	}
+	return bits
+}
+
+// lastArg returns the value following the last of names in args
+func lastArg(args []string, names ...string) string {
+	var v string
+	for i := 0; i+1 < len(args); i++ {
+		for _, n := range names {
+			if args[i] == n {
+				v = args[i+1]
+			}
+		}
+	}
+	return v
+}
+
+// checkDiskSpace fails with an ErrCodeIO error wrapping
+// ErrInsufficientSpace when any output directory lacks room for the
+// estimated encode. Staged outputs of remote storage are in the temp
+// directory, so its volume is checked for them. Outputs on storage that
+// can't report free space, and inputs of unknown duration, are not checked.
+func (p *Pipeline) checkDiskSpace(ctx context.Context, job *Job, input *model.AudioMetadata) error {
+	reporter, ok := p.provider.(ports.SpaceReporter)
+	if !ok || input.Duration <= 0 {
+		return nil
+	}
+	duration := input.Duration + job.Options.LeadingPadding + job.Options.TrailingPadding
+
+	// Outputs sharing a directory are summed; distinct directories on the
+	// same volume are checked independently
+	required := make(map[string]uint64)
+	var dirs []string
+	add := func(path string, opts *model.ProcessingOptions) {
+		dir := filepath.Dir(path)
+		if _, seen := required[dir]; !seen {
+			dirs = append(dirs, dir)
+		}
+		required[dir] += estimateOutputSize(duration, opts, input)
+	}
+
+	if job.OutputPath != pipeOutput && !isStreamingOutput(job.OutputPath) {
+		add(job.OutputPath, job.Options)
+	}
+	for _, out := range job.Options.AdditionalOutputs {
+		if isStreamingOutput(out.Path) {
+			continue
+		}
+		opts := *job.Options
+		opts.AdditionalOutputs = nil
+		if out.Configure != nil {
+			out.Configure(&opts)
+		}
+		add(out.Path, &opts)
+	}
+
+	for _, dir := range dirs {
+		if err := p.checkFreeSpace(ctx, reporter, dir, required[dir]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkTempSpace fails like checkDiskSpace when the temp directory lacks
+// room for size bytes, e.g. of a remote input about to be downloaded
+func (p *Pipeline) checkTempSpace(ctx context.Context, size int64) error {
+	reporter, ok := p.provider.(ports.SpaceReporter)
+	if !ok || size <= 0 {
+		return nil
+	}
+	return p.checkFreeSpace(ctx, reporter, p.temps.Dir(), uint64(size)+sizeHeadroom)
+}
+
+// checkFreeSpace fails when dir has less than required bytes free. Volumes
+// whose free space can't be read pass.
+func (p *Pipeline) checkFreeSpace(ctx context.Context, reporter ports.SpaceReporter, dir string, required uint64) error {
+	free, err := reporter.FreeSpace(ctx, dir)
+	if errors.Is(err, storage.ErrFreeSpaceUnsupported) {
+		return nil
+	}
+	if err != nil {
+		p.log.Warn("failed to check free space", zap.String("dir", dir), zap.Error(err))
+		return nil
+	}
+	if free < required {
+		return pkgerrors.NewIOError(dir, fmt.Sprintf(
+			"need about %d bytes, %d available", required, free), pkgerrors.ErrInsufficientSpace)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package pipeline
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+)
+
+// AudioHash decodes the audio stream of job.InputPath to 16-bit PCM and
+// returns the hex-encoded SHA-256 of the samples. Tags, cover art and the
+// container don't affect it, so a WAV and a FLAC of the same recording, or
+// two copies of an MP3 with different tags, hash the same.
+func (p *Pipeline) AudioHash(ctx context.Context, job *Job) (string, error) {
+	if job.InputPath == "" {
+		return "", pkgerrors.NewValidationError("inputPath", "", "input path must not be empty")
+	}
+	if err := p.checkInputExists(ctx, job.InputPath); err != nil {
+		return "", err
+	}
+	meta, err := p.probeFile(ctx, job.InputPath)
+	if err != nil {
+		return "", pkgerrors.NewProcessingError("probe", "failed to probe input file", err)
+	}
+	if job, err = selectAudioStream(job, meta); err != nil {
+		return "", err
+	}
+
+	args := append(decodeArgs(job), "-f", "s16le", "-acodec", "pcm_s16le", "pipe:1")
+
+	h := sha256.New()
+	if _, err := p.executor.ExecutePiped(ctx, args, nil, h); err != nil {
+		return "", pkgerrors.NewProcessingError("hash", "failed to decode input", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// InputMetadataKey hashes the tags and chapters that job's metadata policy
+// copies from its input, returning "" when it copies none. Input cover art
+// isn't part of it: outputs drop it and only embed opts.CoverArt, which
+// EncodingKey covers.
+func (p *Pipeline) InputMetadataKey(ctx context.Context, job *Job) (string, error) {
+	policy := job.Options.MetadataPolicy
+	if policy == model.MetadataStrip {
+		return "", nil
+	}
+	meta, err := p.probeFile(ctx, job.InputPath)
+	if err != nil {
+		return "", pkgerrors.NewProcessingError("probe", "failed to probe input file", err)
+	}
+
+	h := sha256.New()
+	if policy != model.MetadataReplace {
+		keys := make([]string, 0, len(meta.Tags.Raw))
+		for k := range meta.Tags.Raw {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(h, "tag\x00%s\x00%s\x00", k, meta.Tags.Raw[k])
+		}
+	}
+	for _, c := range meta.Chapters {
+		fmt.Fprintf(h, "chapter\x00%d\x00%d\x00%s\x00", c.Start, c.End, c.Title)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// EncodingKey hashes the ffmpeg command job would run with its input and
+// main output paths left out. Jobs with the same key and the same input
+// audio produce the same output. Only file outputs have a key.
+func EncodingKey(job *Job) (string, error) {
+	if job.OutputPath == pipeOutput || isStreamingOutput(job.OutputPath) {
+		return "", pkgerrors.NewValidationError("outputPath", job.OutputPath, "only file outputs can be compared")
+	}
+	masked := *job
+	masked.InputPath = "input"
+	masked.OutputPath = "output" + strings.ToLower(filepath.Ext(job.OutputPath))
+	plan, err := buildPlan(&masked)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(strings.Join(plan.Args(), "\x00")))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// LinkOutput makes dst a copy of the finished output src, creating its
+// directory per opts. Storage providers with a Link method get a hard link
+// instead, unless linking fails, e.g. across devices.
+func (p *Pipeline) LinkOutput(ctx context.Context, opts *model.ProcessingOptions, src, dst string) error {
+	if err := p.prepareOutputs(ctx, opts, dst); err != nil {
+		return err
+	}
+	if l, ok := p.provider.(interface {
+		Link(ctx context.Context, oldPath, newPath string) error
+	}); ok {
+		if err := l.Link(ctx, src, dst); err == nil {
+			return nil
+		}
+	}
+
+	in, err := p.storage.Open(ctx, src)
+	if err != nil {
+		return pkgerrors.NewProcessingError("finalize", "failed to open "+src, err)
+	}
+	defer in.Close()
+	out, err := p.storage.Create(ctx, dst)
+	if err != nil {
+		return pkgerrors.NewProcessingError("finalize", "failed to create "+dst, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return pkgerrors.NewProcessingError("finalize", "failed to copy "+src+" to "+dst, err)
+	}
+	if err := out.Close(); err != nil {
+		return pkgerrors.NewProcessingError("finalize", "failed to copy "+src+" to "+dst, err)
+	}
+	if opts.FileMode != 0 || opts.Owner != nil {
+		if err := p.storage.SetPermissions(ctx, dst, opts.FileMode, opts.Owner); err != nil {
+			return pkgerrors.NewProcessingError("finalize", "failed to set permissions of "+dst, err)
+		}
+	}
+	return nil
+}
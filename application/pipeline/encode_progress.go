@@ -0,0 +1,58 @@
+package pipeline
+
+import (
+	"io"
+	"time"
+
+	"github.com/Skryldev/audio-lab/infrastructure/ffmpeg"
+	"github.com/Skryldev/audio-lab/pkg/progress"
+)
+
+// The encode reports progress within this range of the job's overall percent
+const (
+	encodeStartPercent = 20
+	encodeEndPercent   = 90
+)
+
+// encodeProgress returns a writer for ffmpeg's -progress output that
+// reports encode progress as the share of total, the expected output
+// duration, that has been written, along with speed and ETA. With an
+// unknown duration only the start and end milestones are reported.
+func (j *Job) encodeProgress(total time.Duration) io.Writer {
+	return j.encodeProgressFrom(total, 0, nil)
+}
+
+// encodeProgressFrom is encodeProgress for an encode that starts offset
+// into the output, passing seen each position it reaches in its own output
+func (j *Job) encodeProgressFrom(total, offset time.Duration, seen func(time.Duration)) io.Writer {
+	return ffmpeg.NewProgressWriter(func(r ffmpeg.ProgressReport) {
+		if seen != nil && r.OutTime > 0 {
+			seen(r.OutTime)
+		}
+		if total <= 0 || r.Done {
+			return
+		}
+		r.OutTime += offset
+		done := float64(r.OutTime) / float64(total)
+		if done > 1 {
+			done = 1
+		}
+
+		var eta time.Duration
+		if r.Speed > 0 && r.OutTime < total {
+			eta = time.Duration(float64(total-r.OutTime) / r.Speed)
+		}
+
+		j.reportUpdate(progress.Update{
+			JobID:        j.ID,
+			Profile:      j.profile(),
+			Stage:        progress.StageEncode,
+			Percent:      encodeStartPercent + done*(encodeEndPercent-encodeStartPercent),
+			Message:      "encoding",
+			Timestamp:    time.Now(),
+			ETA:          eta,
+			Speed:        r.Speed,
+			BytesWritten: r.TotalSize,
+		})
+	})
+}
@@ -0,0 +1,108 @@
+package pipeline
+
+import (
+	"fmt"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+)
+
+// defaultEncoders lists the ffmpeg encoders of each codec in order of
+// preference, used for codecs without a model.ProcessingOptions
+// EncoderPreference entry
+var defaultEncoders = map[model.Codec][]string{
+	model.CodecOpus:  {"libopus", "opus"},
+	model.CodecAAC:   {"libfdk_aac", "aac"},
+	model.CodecMP3:   {"libmp3lame", "libshine"},
+	model.CodecPCM16: {"pcm_s16le"},
+	model.CodecPCM24: {"pcm_s24le"},
+}
+
+// commonEncoders are used when capabilities weren't detected and the job
+// states no preference: the encoders most ffmpeg builds include
+var commonEncoders = map[model.Codec]string{
+	model.CodecOpus:  "libopus",
+	model.CodecAAC:   "aac",
+	model.CodecMP3:   "libmp3lame",
+	model.CodecPCM16: "pcm_s16le",
+	model.CodecPCM24: "pcm_s24le",
+}
+
+// encoderPreference returns the encoders to try for codec
+func encoderPreference(opts *model.ProcessingOptions, codec model.Codec) []string {
+	if prefs := opts.EncoderPreference[codec]; len(prefs) > 0 {
+		return prefs
+	}
+	return defaultEncoders[codec]
+}
+
+// encoderFor returns the encoder used for the options' codec: the first in
+// its preference list, which resolveEncoders narrows to an available one
+func encoderFor(opts *model.ProcessingOptions) string {
+	if prefs := opts.EncoderPreference[opts.Codec]; len(prefs) > 0 {
+		return prefs[0]
+	}
+	return commonEncoders[opts.Codec]
+}
+
+// resolveEncoders narrows every codec's preference list in opts to the
+// first encoder the ffmpeg build has, so plans built from opts (and from
+// copies for additional outputs and renditions) pick it. Codecs with no
+// available encoder keep their list and fail checkEncoder if used.
+func (p *Pipeline) resolveEncoders(opts *model.ProcessingOptions) {
+	if !p.capabilities.Detected() {
+		return
+	}
+
+	resolved := make(map[model.Codec][]string, len(defaultEncoders))
+	for codec := range defaultEncoders {
+		resolved[codec] = nil
+	}
+	for codec := range opts.EncoderPreference {
+		resolved[codec] = nil
+	}
+	for codec := range resolved {
+		prefs := encoderPreference(opts, codec)
+		resolved[codec] = prefs
+		for _, name := range prefs {
+			if p.capabilities.HasEncoder(name) {
+				resolved[codec] = []string{name}
+				break
+			}
+		}
+	}
+	// A new map, so preferences shared with the caller aren't modified
+	opts.EncoderPreference = resolved
+}
+
+// UnavailableCodecs returns the codecs for which the ffmpeg build has none
+// of the default encoders, or nil when capabilities weren't detected
+func (p *Pipeline) UnavailableCodecs() []model.Codec {
+	if !p.capabilities.Detected() {
+		return nil
+	}
+	var missing []model.Codec
+	for _, codec := range []model.Codec{model.CodecOpus, model.CodecAAC, model.CodecMP3, model.CodecPCM16, model.CodecPCM24} {
+		found := false
+		for _, name := range defaultEncoders[codec] {
+			found = found || p.capabilities.HasEncoder(name)
+		}
+		if !found {
+			missing = append(missing, codec)
+		}
+	}
+	return missing
+}
+
+// checkEncoder fails when the ffmpeg build has none of codec's preferred
+// encoders
+func (p *Pipeline) checkEncoder(field string, opts *model.ProcessingOptions) error {
+	prefs := encoderPreference(opts, opts.Codec)
+	for _, name := range prefs {
+		if p.capabilities.HasEncoder(name) {
+			return nil
+		}
+	}
+	return pkgerrors.NewValidationError(field, opts.Codec,
+		fmt.Sprintf("ffmpeg %s was built without any %s encoder (tried %v)", p.capabilities.Version, opts.Codec, prefs))
+}
@@ -0,0 +1,20 @@
+package pipeline
+
+import (
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/domain/ports"
+	"github.com/Skryldev/audio-lab/infrastructure/ffmpeg"
+)
+
+// WithFilterChainHook registers fn to edit a job's audio filter chain after
+// the generated and custom filters are added, e.g. to insert, reorder or
+// drop filters. Hooks run in the order they are given.
+func WithFilterChainHook(fn func(*ffmpeg.FilterChainBuilder)) ports.Option {
+	return func(o *model.ProcessingOptions) {
+		o.FilterHooks = append(o.FilterHooks, func(filters []string) []string {
+			fb := ffmpeg.NewFilterChainBuilder().SetFilters(filters)
+			fn(fb)
+			return fb.Filters()
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package pipeline
+
+import (
+	"github.com/Skryldev/audio-lab/domain/model"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+)
+
+// validateGapless checks that the main output can carry gapless metadata.
+// Both the LAME header and the MP4 edit list are written once the encode
+// has finished, which needs a seekable output.
+func validateGapless(job *Job) error {
+	if !job.Options.Gapless {
+		return nil
+	}
+	if job.OutputPath == pipeOutput || isStreamingOutput(job.OutputPath) {
+		return pkgerrors.NewValidationError("gapless", job.OutputPath, "gapless metadata needs a file output")
+	}
+	if outputMuxer(job.Options, job.OutputPath) == "adts" {
+		return pkgerrors.NewValidationError("gapless", job.OutputPath, "raw AAC can't carry gapless metadata; use an .m4a output")
+	}
+	return nil
+}
+
+// gaplessArgs returns the muxer options recording the encoder delay and
+// padding of the output at path. MP3 gets them in the LAME header; MP4
+// outputs get an edit list trimming them and the iTunes gapless flag
+// (pgap). ffmpeg can't write iTunSMPB, but players that read it also honor
+// the edit list. Ogg and lossless outputs carry their delay without help.
+func gaplessArgs(opts *model.ProcessingOptions, path string) []string {
+	if !opts.Gapless {
+		return nil
+	}
+	switch outputMuxer(opts, path) {
+	case "mp3":
+		return []string{"-write_xing", "1"}
+	case "ipod", "mp4", "mov":
+		return []string{"-use_editlist", "1", "-metadata", "gapless_playback=1"}
+	}
+	return nil
+}
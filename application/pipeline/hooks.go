@@ -0,0 +1,50 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+)
+
+// SetHooks installs the lifecycle hooks called for jobs and stages
+func (p *Pipeline) SetHooks(h model.Hooks) {
+	p.hooks = h
+}
+
+// JobStarted calls OnJobStart for a job run outside the worker pool
+func (p *Pipeline) JobStarted(ctx context.Context, job *Job) {
+	if p.hooks.OnJobStart != nil {
+		p.hooks.OnJobStart(ctx, job.info())
+	}
+}
+
+// JobCompleted calls OnError for a failed job, then OnJobComplete
+func (p *Pipeline) JobCompleted(ctx context.Context, job *Job, result *model.ProcessingResult, err error) {
+	if err != nil && p.hooks.OnError != nil {
+		p.hooks.OnError(ctx, job.info(), err)
+	}
+	if p.hooks.OnJobComplete != nil {
+		p.hooks.OnJobComplete(ctx, job.info(), result, err)
+	}
+}
+
+// Retrying calls OnError and OnRetry for a failed attempt that is retried
+func (p *Pipeline) Retrying(ctx context.Context, job *Job, attempt int, err error) {
+	if p.hooks.OnError != nil {
+		p.hooks.OnError(ctx, job.info(), err)
+	}
+	if p.hooks.OnRetry != nil {
+		p.hooks.OnRetry(ctx, job.info(), attempt, err)
+	}
+}
+
+// info describes the job to hooks
+func (j *Job) info() model.JobInfo {
+	return model.JobInfo{
+		ID:         j.ID,
+		InputPath:  j.InputPath,
+		OutputPath: j.OutputPath,
+		Profile:    j.profile(),
+		Labels:     j.labels(),
+	}
+}
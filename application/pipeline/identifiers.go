@@ -0,0 +1,110 @@
+package pipeline
+
+import (
+	"strings"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+)
+
+// identifierTags maps muxers onto the tags holding the ISRC, barcode and
+// catalog number. ffmpeg writes "TSRC" as the ID3 frame and other unknown
+// keys as TXXX frames with the key as description, which is what tagging
+// tools read. MP4 would need iTunes freeform atoms and WAV has no such
+// tags, neither of which ffmpeg can write.
+var identifierTags = map[string][3]string{
+	"mp3":      {"TSRC", "BARCODE", "CATALOGNUMBER"},
+	"flac":     {"ISRC", "BARCODE", "CATALOGNUMBER"},
+	"ogg":      {"ISRC", "BARCODE", "CATALOGNUMBER"},
+	"opus":     {"ISRC", "BARCODE", "CATALOGNUMBER"},
+	"matroska": {"ISRC", "BARCODE", "CATALOG_NUMBER"},
+	"webm":     {"ISRC", "BARCODE", "CATALOG_NUMBER"},
+}
+
+// validateIdentifiers checks the identifiers' formats and that the main
+// output can hold them
+func validateIdentifiers(job *Job) error {
+	ids := job.Options.Identifiers
+	if ids == (model.Identifiers{}) {
+		return nil
+	}
+	if job.Options.MetadataPolicy == model.MetadataStrip {
+		return pkgerrors.NewValidationError("metadataPolicy", job.Options.MetadataPolicy, "identifiers can't be set when stripping tags")
+	}
+	if ids.ISRC != "" && !validISRC(normalizeISRC(ids.ISRC)) {
+		return pkgerrors.NewValidationError("identifiers.isrc", ids.ISRC, "ISRC must be CC-XXX-YY-NNNNN, e.g. USRC17607839")
+	}
+	if ids.Barcode != "" && !validBarcode(ids.Barcode) {
+		return pkgerrors.NewValidationError("identifiers.barcode", ids.Barcode, "barcode must be a 12-digit UPC or 13-digit EAN with a valid check digit")
+	}
+	if strings.ContainsAny(ids.CatalogNumber, "\x00\n") {
+		return pkgerrors.NewValidationError("identifiers.catalogNumber", ids.CatalogNumber, "catalog number must be a single line")
+	}
+	if _, ok := identifierTags[outputMuxer(job.Options, job.OutputPath)]; !ok {
+		return pkgerrors.NewValidationError("identifiers", job.OutputPath, "identifiers need an MP3, FLAC, Ogg, Opus or Matroska output")
+	}
+	return nil
+}
+
+// identifierArgs returns the options tagging the output at path with
+// opts.Identifiers
+func identifierArgs(opts *model.ProcessingOptions, path string) []string {
+	keys, ok := identifierTags[outputMuxer(opts, path)]
+	if !ok {
+		return nil
+	}
+	ids := opts.Identifiers
+	var args []string
+	for i, v := range []string{normalizeISRC(ids.ISRC), ids.Barcode, ids.CatalogNumber} {
+		if v != "" {
+			args = append(args, "-metadata", keys[i]+"="+v)
+		}
+	}
+	return args
+}
+
+// normalizeISRC strips the hyphens ISRCs are often printed with and
+// upper-cases the code
+func normalizeISRC(isrc string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(isrc), "-", ""))
+}
+
+// validISRC reports whether a normalized ISRC has the ISO 3901 layout: a
+// country code, a registrant code, a two-digit year and a designation code
+func validISRC(isrc string) bool {
+	if len(isrc) != 12 {
+		return false
+	}
+	for i, c := range isrc {
+		switch {
+		case i < 2 && 'A' <= c && c <= 'Z':
+		case i >= 2 && i < 5 && ('A' <= c && c <= 'Z' || '0' <= c && c <= '9'):
+		case i >= 5 && '0' <= c && c <= '9':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// validBarcode reports whether code is a UPC-A or EAN-13 with a correct
+// GTIN check digit
+func validBarcode(code string) bool {
+	if len(code) != 12 && len(code) != 13 {
+		return false
+	}
+	sum := 0
+	for i := len(code) - 1; i >= 0; i-- {
+		c := code[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		d := int(c - '0')
+		// Weights alternate 1, 3 from the check digit leftwards
+		if (len(code)-1-i)%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
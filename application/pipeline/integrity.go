@@ -0,0 +1,90 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/infrastructure/ffmpeg"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+)
+
+// maxValidationMessages bounds the distinct errors and warnings kept per
+// file; a damaged file can log one per frame
+const maxValidationMessages = 20
+
+// ValidateFile decodes job.InputPath in full, discarding the audio, and
+// reports the decode errors and container warnings ffmpeg logs, with CRC
+// and bitstream checks on, and whether the audio ends short of the length
+// the container declares. Files that can't be read as audio are reported
+// invalid rather than failing.
+func (p *Pipeline) ValidateFile(ctx context.Context, job *Job) (*model.FileValidation, error) {
+	if job.InputPath == "" {
+		return nil, pkgerrors.NewValidationError("inputPath", "", "input path must not be empty")
+	}
+	if err := p.checkInputExists(ctx, job.InputPath); err != nil {
+		return nil, err
+	}
+
+	v := &model.FileValidation{Path: job.InputPath}
+	seen := make(map[string]bool)
+	record := func(l ffmpeg.LogLine) {
+		msg := l.Message
+		if l.Component != "" {
+			msg = l.Component + ": " + msg
+		}
+		list, count := &v.Warnings, &v.WarningCount
+		if l.IsError() {
+			list, count = &v.Errors, &v.ErrorCount
+		}
+		*count++
+		if !seen[msg] && len(*list) < maxValidationMessages {
+			seen[msg] = true
+			*list = append(*list, msg)
+		}
+	}
+
+	meta, err := p.probeFile(ctx, job.InputPath)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		record(ffmpeg.LogLine{Level: "error", Message: "not a readable media file"})
+		return v, nil
+	}
+	v.Expected = meta.Duration
+	if job, err = selectAudioStream(job, meta); err != nil {
+		var verr *pkgerrors.ValidationError
+		if !errors.As(err, &verr) {
+			return nil, err
+		}
+		record(ffmpeg.LogLine{Level: "error", Message: verr.Message})
+		return v, nil
+	}
+
+	args := append([]string(nil), ffmpeg.LevelLogArgs...)
+	args = append(args, remoteInputArgs(job.InputPath)...)
+	args = append(args, "-err_detect", "crccheck+bitstream", "-i", ffmpegPath(job.InputPath))
+	if spec := audioStreamSpec(job.Options); spec != "" {
+		args = append(args, "-map", spec)
+	}
+	args = append(args, "-vn")
+	decoded, stderr, err := p.decodeToNull(ctx, args)
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	v.Duration = decoded
+	for _, l := range ffmpeg.ParseLog(stderr) {
+		record(l)
+	}
+	if err != nil && v.ErrorCount == 0 {
+		record(ffmpeg.LogLine{Level: "error", Message: "decoding failed"})
+	}
+
+	// Containers round their declared length; allow 1% or the output
+	// verification tolerance, whichever is more
+	tolerance := max(defaultVerifyTolerance, v.Expected/100)
+	v.Truncated = v.Expected > 0 && decoded < v.Expected-tolerance
+	v.Valid = v.ErrorCount == 0 && !v.Truncated
+	return v, nil
+}
@@ -0,0 +1,48 @@
+package pipeline
+
+import (
+	"regexp"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+)
+
+// labelKeyRe matches names that are valid Prometheus label names
+var labelKeyRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// reservedLabels are label names the library sets itself on metrics
+var reservedLabels = map[string]bool{
+	"job_id":  true,
+	"profile": true,
+	"codec":   true,
+	"stage":   true,
+	"status":  true,
+}
+
+// validateLabels keeps job labels usable as metric labels: a bounded set
+// of well-formed keys with short values
+func validateLabels(labels map[string]string) error {
+	if len(labels) > model.MaxJobLabels {
+		return pkgerrors.NewValidationError("labels", len(labels), "too many job labels")
+	}
+	for k, v := range labels {
+		if !labelKeyRe.MatchString(k) || len(k) >= 2 && k[:2] == "__" {
+			return pkgerrors.NewValidationError("labels", k, "label name must match [a-zA-Z_][a-zA-Z0-9_]* and not start with __")
+		}
+		if reservedLabels[k] {
+			return pkgerrors.NewValidationError("labels", k, "label name is reserved")
+		}
+		if len(v) > model.MaxLabelValueLength {
+			return pkgerrors.NewValidationError("labels."+k, len(v), "label value is too long")
+		}
+	}
+	return nil
+}
+
+// labels returns the job's labels, tolerating nil options
+func (j *Job) labels() map[string]string {
+	if j.Options == nil {
+		return nil
+	}
+	return j.Options.Labels
+}
@@ -0,0 +1,260 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/infrastructure/ffmpeg"
+	"github.com/Skryldev/audio-lab/internal/argfmt"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"github.com/Skryldev/audio-lab/pkg/progress"
+	"go.uber.org/zap"
+)
+
+// ProcessLadder decodes and filters job.InputPath once and encodes every
+// rendition from the same decoded signal in a single ffmpeg invocation
+func (p *Pipeline) ProcessLadder(ctx context.Context, job *Job, specs []model.RenditionSpec) ([]*model.ProcessingResult, error) {
+	start := time.Now()
+
+	if len(specs) == 0 {
+		return nil, pkgerrors.NewValidationError("renditions", 0, "at least one rendition is required")
+	}
+
+	// Renditions copy the job options, so pick the encoders first
+	p.resolveEncoders(job.Options)
+	renditionOpts := make([]*model.ProcessingOptions, len(specs))
+	for i, spec := range specs {
+		if spec.OutputPath == "" {
+			return nil, pkgerrors.NewValidationError(fmt.Sprintf("renditions[%d].outputPath", i), "", "output path must not be empty")
+		}
+		renditionOpts[i] = renditionOptions(job.Options, spec)
+		if renditionOpts[i].Bitrate <= 0 {
+			return nil, pkgerrors.NewValidationError(fmt.Sprintf("renditions[%d].bitrate", i), spec.Bitrate, "bitrate must be positive")
+		}
+		if err := validateCodecOptions(fmt.Sprintf("renditions[%d].", i), renditionOpts[i]); err != nil {
+			return nil, err
+		}
+		if p.capabilities.Detected() {
+			if err := p.checkEncoder(fmt.Sprintf("renditions[%d].codec", i), renditionOpts[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// validateInput needs an output path; the first rendition stands in
+	job.OutputPath = specs[0].OutputPath
+	if err := p.validateInput(ctx, job); err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, len(specs))
+	for i, spec := range specs {
+		paths[i] = spec.OutputPath
+	}
+	if err := p.prepareOutputs(ctx, job.Options, paths...); err != nil {
+		return nil, err
+	}
+
+	inputMeta, err := p.probeFile(ctx, job.InputPath)
+	if err != nil {
+		return nil, pkgerrors.NewProcessingError("probe", "failed to probe input file", err)
+	}
+	job.report(progress.StageProbe, 5, "input probed")
+	if job, err = resolveJob(job, inputMeta); err != nil {
+		return nil, err
+	}
+	if job, err = p.loadLyrics(ctx, job); err != nil {
+		return nil, err
+	}
+	for _, opts := range renditionOpts {
+		opts.Lyrics = job.Options.Lyrics
+	}
+
+	// Encode every rendition into a temp sibling moved into place once verified
+	atomic := p.newAtomicWrite()
+	defer atomic.discard(ctx)
+	encSpecs := append([]model.RenditionSpec(nil), specs...)
+	if !job.Options.DirectOutputWrites {
+		for i := range encSpecs {
+			encSpecs[i].OutputPath, renditionOpts[i].OutputFormat = atomic.add(specs[i].OutputPath, renditionOpts[i].OutputFormat)
+		}
+	}
+
+	plan, err := buildLadderPlan(job, encSpecs, renditionOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	job.report(progress.StageEncode, 20, "encoding renditions")
+
+	_, stderr, err := p.executor.ExecuteWithOutput(ctx, plan.Args())
+	if err != nil {
+		return nil, err
+	}
+	for i := range encSpecs {
+		paths[i] = encSpecs[i].OutputPath
+		if err := p.writeID3Lyrics(ctx, job, renditionOpts[i], paths[i]); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.finalizeOutputs(ctx, job.Options, paths...); err != nil {
+		return nil, err
+	}
+
+	var loudness *model.LoudnessReport
+	if job.Options.NormalizationEnabled {
+		loudness, err = buildLoudnessReport(stderr, job.Options.LoudnessTarget)
+		if err != nil {
+			p.log.Warn("failed to parse loudness report", zap.Error(err))
+		}
+	}
+
+	job.report(progress.StageEncode, 90, "encoding complete")
+
+	results := make([]*model.ProcessingResult, len(specs))
+	for i, spec := range specs {
+		outputMeta, err := atomic.verify(ctx, encSpecs[i].OutputPath)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = &model.ProcessingResult{
+			InputPath:   job.InputPath,
+			OutputPath:  spec.OutputPath,
+			InputMeta:   inputMeta,
+			OutputMeta:  outputMeta,
+			Duration:    time.Since(start),
+			ProcessedAt: time.Now(),
+			Profile:     job.profile(),
+			Labels:      job.labels(),
+			Loudness:    loudness,
+		}
+	}
+
+	if err := atomic.commit(ctx); err != nil {
+		return nil, err
+	}
+
+	job.report(progress.StageDone, 100, "done")
+
+	return results, nil
+}
+
+// buildLadderPlan splits the filtered input into one labeled pad per
+// rendition and maps each pad to its own output
+func buildLadderPlan(job *Job, specs []model.RenditionSpec, renditionOpts []*model.ProcessingOptions) (*model.CommandPlan, error) {
+	graph := ffmpeg.NewFiltergraphBuilder()
+	pads := make([]ffmpeg.Pad, len(specs))
+	for i := range pads {
+		pads[i] = graph.Pad("r" + argfmt.Int(i))
+	}
+	input := ffmpeg.InputPad(0)
+	if spec := audioStreamSpec(job.Options); spec != "" {
+		input = ffmpeg.SpecifierPad(spec)
+	}
+	chain := buildFilterChain(job.Options).Add("asplit=" + argfmt.Int(len(specs)))
+	graph.Chain([]ffmpeg.Pad{input}, chain.Build(), pads...)
+
+	inArgs := append(remoteInputArgs(job.InputPath), threadArgs(job.Options)...)
+	inArgs = append(inArgs, job.Options.ExtraInputArgs...)
+	for _, spec := range specs {
+		if isStreamingOutput(spec.OutputPath) {
+			// Read the input at its native rate so the stream plays in real time
+			inArgs = append(inArgs, "-re")
+			break
+		}
+	}
+
+	plan := &model.CommandPlan{
+		Version:    model.CommandPlanVersion,
+		JobID:      job.ID,
+		GlobalArgs: []string{"-y"},
+		Inputs: []model.PlanInput{{
+			Path: ffmpegPath(job.InputPath),
+			Args: inArgs,
+		}},
+		Filtergraph: graph.Build(),
+	}
+
+	// The cover art image is read once and mapped to every output that
+	// can hold it
+	art := -1
+	for i, spec := range specs {
+		if embedsCoverArt(renditionOpts[i], spec.OutputPath) {
+			art = len(plan.Inputs)
+			plan.Inputs = append(plan.Inputs, coverArtInput(job.Options))
+			break
+		}
+	}
+	// Likewise the chapters
+	chapters := -1
+	for i, spec := range specs {
+		if writesChapters(renditionOpts[i], spec.OutputPath) {
+			chapters = len(plan.Inputs)
+			plan.Inputs = append(plan.Inputs, chapterInput(job.Options))
+			break
+		}
+	}
+
+	for i, spec := range specs {
+		opts := renditionOpts[i]
+		codecArgs, err := buildCodecArgs(opts)
+		if err != nil {
+			return nil, pkgerrors.NewProcessingError("encode", "failed to build codec args", err)
+		}
+		args := append([]string{"-ar", argfmt.Hz(opts.SampleRate)}, codecArgs...)
+		args = append(args, threadArgs(opts)...)
+		args = append(args, metadataArgs(opts)...)
+		args = append(args, id3Args(opts, spec.OutputPath)...)
+		args = append(args, gaplessArgs(opts, spec.OutputPath)...)
+		args = append(args, bwfArgs(opts, spec.OutputPath)...)
+		args = append(args, identifierArgs(opts, spec.OutputPath)...)
+		args = append(args, lyricsArgs(opts, spec.OutputPath)...)
+		if opts.OutputFormat != "" {
+			args = append(args, "-f", opts.OutputFormat)
+		}
+		if isStreamingOutput(spec.OutputPath) {
+			muxArgs, err := streamMuxerArgs(spec.OutputPath, opts.Codec)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, muxArgs...)
+		}
+		maps := []string{pads[i].Map()}
+		if art >= 0 && embedsCoverArt(opts, spec.OutputPath) {
+			artMap, artArgs := coverArtArgs(opts, art)
+			maps = append(maps, artMap)
+			args = append(args, artArgs...)
+		}
+		if chapters >= 0 && writesChapters(opts, spec.OutputPath) {
+			args = append(args, chapterArgs(chapters)...)
+		}
+		args = append(args, opts.ExtraOutputArgs...)
+		plan.Outputs = append(plan.Outputs, model.PlanOutput{
+			Path: ffmpegPath(spec.OutputPath),
+			Maps: maps,
+			Args: args,
+		})
+	}
+
+	return plan, nil
+}
+
+// renditionOptions overlays a rendition's codec settings onto the job options
+func renditionOptions(base *model.ProcessingOptions, spec model.RenditionSpec) *model.ProcessingOptions {
+	opts := *base
+	if spec.Codec != "" {
+		opts.Codec = spec.Codec
+	}
+	if spec.Bitrate != 0 {
+		opts.Bitrate = spec.Bitrate
+	}
+	if spec.BitrateMode != "" {
+		opts.BitrateMode = spec.BitrateMode
+	}
+	if spec.SampleRate != 0 {
+		opts.SampleRate = spec.SampleRate
+	}
+	return &opts
+}
@@ -0,0 +1,149 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+)
+
+// Lane is a bounded execution lane. Separate lanes keep cheap work (probes,
+// analysis) from queuing behind long-running encodes. When the lane is
+// full, freed slots go to the waiter with the highest priority, and to the
+// longest-waiting one among equals.
+type Lane struct {
+	name string
+	size int
+
+	mu       sync.Mutex
+	inFlight int
+	waiters  []*laneWaiter
+	seq      uint64
+}
+
+// laneWaiter is a blocked Acquire; ready is closed when it gets a slot
+type laneWaiter struct {
+	priority int
+	seq      uint64
+	ready    chan struct{}
+}
+
+// NewLane creates a lane admitting at most size concurrent tasks
+func NewLane(name string, size int) *Lane {
+	if size <= 0 {
+		size = 1
+	}
+	return &Lane{name: name, size: size}
+}
+
+// Acquire blocks until a slot is free or ctx is done
+func (l *Lane) Acquire(ctx context.Context) error {
+	return l.AcquirePriority(ctx, 0)
+}
+
+// AcquirePriority is Acquire for a task of the given priority; higher
+// priorities are admitted first
+func (l *Lane) AcquirePriority(ctx context.Context, priority int) error {
+	l.mu.Lock()
+	if l.inFlight < l.size && len(l.waiters) == 0 {
+		l.inFlight++
+		l.mu.Unlock()
+		return nil
+	}
+	w := &laneWaiter{priority: priority, seq: l.seq, ready: make(chan struct{})}
+	l.seq++
+	l.waiters = append(l.waiters, w)
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	select {
+	case <-w.ready:
+		// Granted while giving up; pass the slot on
+		l.mu.Unlock()
+		l.Release()
+	default:
+		for i, other := range l.waiters {
+			if other == w {
+				l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+				break
+			}
+		}
+		l.mu.Unlock()
+	}
+	return ctx.Err()
+}
+
+// Release frees a slot previously obtained with Acquire
+func (l *Lane) Release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// A lane shrunk by Resize drops slots as they free up
+	if len(l.waiters) == 0 || l.inFlight > l.size {
+		l.inFlight--
+		return
+	}
+
+	// The slot passes straight to the next waiter
+	l.grantNext()
+}
+
+// Resize changes the lane capacity. Growing admits waiters at once;
+// shrinking lets running tasks finish and admits no new ones until fewer
+// than size are in flight.
+func (l *Lane) Resize(size int) {
+	if size <= 0 {
+		size = 1
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.size = size
+	for l.inFlight < l.size && len(l.waiters) > 0 {
+		l.inFlight++
+		l.grantNext()
+	}
+}
+
+// grantNext hands a slot to the highest-priority waiter. The caller holds
+// l.mu and has counted the slot in inFlight.
+func (l *Lane) grantNext() {
+	next := 0
+	for i, w := range l.waiters[1:] {
+		best := l.waiters[next]
+		if w.priority > best.priority || (w.priority == best.priority && w.seq < best.seq) {
+			next = i + 1
+		}
+	}
+	w := l.waiters[next]
+	l.waiters = append(l.waiters[:next], l.waiters[next+1:]...)
+	close(w.ready)
+}
+
+// Name returns the lane name
+func (l *Lane) Name() string { return l.name }
+
+// Size returns the lane capacity
+func (l *Lane) Size() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.size
+}
+
+// Waiting returns the number of tasks blocked in Acquire
+func (l *Lane) Waiting() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.waiters)
+}
+
+// InFlight returns the number of occupied slots
+func (l *Lane) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight
+}
@@ -0,0 +1,97 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+)
+
+// VerifyLossless decodes job.InputPath and job.OutputPath and compares the
+// MD5 of their samples, along with their sample rate and channel count.
+// Samples are hashed as 64-bit floats, which hold every integer and float
+// sample format exactly, so a 16-bit source padded to 24 bits still
+// matches while truncation or any lossy step doesn't. A mismatch is
+// reported in the result, not as an error.
+func (p *Pipeline) VerifyLossless(ctx context.Context, job *Job) (*model.LosslessVerification, error) {
+	if job.InputPath == "" || job.OutputPath == "" {
+		return nil, pkgerrors.NewValidationError("path", "", "input and output paths must not be empty")
+	}
+	if err := p.checkInputExists(ctx, job.InputPath); err != nil {
+		return nil, err
+	}
+	if !IsRemoteInput(job.OutputPath) {
+		exists, err := p.storage.Exists(ctx, job.OutputPath)
+		if err != nil {
+			return nil, pkgerrors.NewProcessingError("validate", "failed to check output file", err)
+		}
+		if !exists {
+			return nil, pkgerrors.NewValidationError("outputPath", job.OutputPath, "output file does not exist")
+		}
+	}
+
+	// The output's stream is picked by default, the input's per the options
+	outOpts := *job.Options
+	outOpts.AudioStream = nil
+	outOpts.AudioLanguage = ""
+	output := &Job{ID: job.ID, InputPath: job.OutputPath, Options: &outOpts, Log: job.Log}
+
+	inMeta, inSum, err := p.decodedMD5(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	outMeta, outSum, err := p.decodedMD5(ctx, output)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &model.LosslessVerification{InputMD5: inSum, OutputMD5: outSum}
+	switch {
+	case inMeta.SampleRate != outMeta.SampleRate:
+		v.Mismatch = fmt.Sprintf("sample rate changed from %d Hz to %d Hz", inMeta.SampleRate, outMeta.SampleRate)
+	case inMeta.Channels != outMeta.Channels:
+		v.Mismatch = fmt.Sprintf("channel count changed from %d to %d", inMeta.Channels, outMeta.Channels)
+	case inSum != outSum:
+		v.Mismatch = "decoded samples differ"
+	}
+	v.Identical = v.Mismatch == ""
+	return v, nil
+}
+
+// decodedMD5 probes job.InputPath and returns the MD5 of its selected audio
+// stream decoded to 64-bit float PCM
+func (p *Pipeline) decodedMD5(ctx context.Context, job *Job) (*model.AudioMetadata, string, error) {
+	meta, err := p.probeFile(ctx, job.InputPath)
+	if err != nil {
+		return nil, "", pkgerrors.NewProcessingError("probe", "failed to probe "+job.InputPath, err)
+	}
+	if job, err = selectAudioStream(job, meta); err != nil {
+		return nil, "", err
+	}
+
+	args := append(decodeArgs(job), "-acodec", "pcm_f64le", "-f", "md5", "pipe:1")
+	stdout, _, err := p.executor.ExecuteWithOutput(ctx, args)
+	if err != nil {
+		return nil, "", pkgerrors.NewProcessingError("verify", "failed to decode "+job.InputPath, err)
+	}
+	sum, ok := strings.CutPrefix(string(bytes.TrimSpace(stdout)), "MD5=")
+	if !ok || sum == "" {
+		return nil, "", pkgerrors.NewProcessingError("verify", "unexpected md5 output for "+job.InputPath, nil)
+	}
+	return meta, sum, nil
+}
+
+// decodeArgs returns the arguments that open job.InputPath and select its
+// audio stream for decoding, up to the output options
+func decodeArgs(job *Job) []string {
+	args := []string{"-v", "error"}
+	args = append(args, remoteInputArgs(job.InputPath)...)
+	args = append(args, "-i", ffmpegPath(job.InputPath))
+	if spec := audioStreamSpec(job.Options); spec != "" {
+		args = append(args, "-map", spec)
+	}
+	return append(args, "-vn")
+}
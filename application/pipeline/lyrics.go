@@ -0,0 +1,216 @@
+package pipeline
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/infrastructure/id3"
+	"github.com/Skryldev/audio-lab/infrastructure/lrc"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// lyricsMuxers are the muxers that hold lyrics: ID3 USLT/SYLT frames in
+// MP3, the ©lyr atom in MP4 and the LYRICS comment in Ogg and FLAC
+var lyricsMuxers = map[string]bool{
+	"mp3":  true,
+	"ipod": true,
+	"mp4":  true,
+	"flac": true,
+	"ogg":  true,
+	"opus": true,
+}
+
+// maxLyrics bounds the lyrics. Outside MP3 they are passed as a single
+// -metadata argument, which Linux caps at 128 KiB.
+const maxLyrics = 96 << 10
+
+// defaultLyricsLanguage is the ISO 639-2 code for an undetermined language
+const defaultLyricsLanguage = "und"
+
+// validateLyrics checks the lyrics against the main output. MP3 lyrics are
+// added to the file once it is written, so they need a file output.
+func validateLyrics(job *Job) error {
+	l := job.Options.Lyrics
+	if l.Text == "" && l.Path == "" {
+		return nil
+	}
+	if job.Options.MetadataPolicy == model.MetadataStrip {
+		return pkgerrors.NewValidationError("metadataPolicy", job.Options.MetadataPolicy, "lyrics can't be set when stripping tags")
+	}
+	if l.Text != "" && l.Path != "" {
+		return pkgerrors.NewValidationError("lyrics", l.Path, "lyrics text and file are mutually exclusive")
+	}
+	if IsRemoteInput(l.Path) {
+		return pkgerrors.NewValidationError("lyrics.path", l.Path, "lyrics file must be local")
+	}
+	if len(l.Text) > maxLyrics {
+		return pkgerrors.NewValidationError("lyrics.text", len(l.Text), "lyrics are too long to pass to ffmpeg")
+	}
+	if lang := l.Language; lang != "" && (len(lang) != 3 || strings.ToLower(lang) != lang || strings.Trim(lang, "abcdefghijklmnopqrstuvwxyz") != "") {
+		return pkgerrors.NewValidationError("lyrics.language", lang, "language must be a lower-case ISO 639-2 code, e.g. eng")
+	}
+	muxer := outputMuxer(job.Options, job.OutputPath)
+	if !lyricsMuxers[muxer] {
+		return pkgerrors.NewValidationError("lyrics", job.OutputPath, "lyrics need an MP3, M4A/MP4, FLAC, Ogg or Opus output")
+	}
+	if muxer == "mp3" && (job.OutputPath == pipeOutput || isStreamingOutput(job.OutputPath)) {
+		return pkgerrors.NewValidationError("lyrics", job.OutputPath, "MP3 lyrics need a file output")
+	}
+	return nil
+}
+
+// loadLyrics returns a copy of job with its lyrics file read into the
+// options' text
+func (p *Pipeline) loadLyrics(ctx context.Context, job *Job) (*Job, error) {
+	path := job.Options.Lyrics.Path
+	if path == "" {
+		return job, nil
+	}
+	f, err := p.storage.Open(ctx, path)
+	if err != nil {
+		return nil, pkgerrors.NewProcessingError("prepare", "failed to open lyrics file", err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxLyrics+1))
+	if err != nil {
+		return nil, pkgerrors.NewProcessingError("prepare", "failed to read lyrics file", err)
+	}
+	if len(data) > maxLyrics {
+		return nil, pkgerrors.NewValidationError("lyrics.path", path, "lyrics file is too large")
+	}
+
+	opts := *job.Options
+	opts.Lyrics.Text = strings.TrimPrefix(string(data), "\ufeff")
+	opts.Lyrics.Path = ""
+	j := *job
+	j.Options = &opts
+	return &j, nil
+}
+
+// lyricsArgs returns the options writing opts.Lyrics to the output at
+// path. MP4 has no synchronized lyrics, so LRC is reduced to its text
+// there; Ogg and FLAC players read LRC from the LYRICS comment as is. MP3
+// outputs get theirs from writeLyrics.
+func lyricsArgs(opts *model.ProcessingOptions, path string) []string {
+	text := opts.Lyrics.Text
+	if text == "" {
+		return nil
+	}
+	switch outputMuxer(opts, path) {
+	case "ipod", "mp4":
+		if l, ok := lrc.Parse(text); ok {
+			text = l.Text()
+		}
+		return []string{"-metadata", "lyrics=" + text}
+	case "flac", "ogg", "opus":
+		return []string{"-metadata", "LYRICS=" + text}
+	}
+	return nil
+}
+
+// writeLyrics adds the lyrics to the job's MP3 outputs, which ffmpeg can't
+// do itself
+func (p *Pipeline) writeLyrics(ctx context.Context, job *Job) error {
+	if job.Options.Lyrics.Text == "" {
+		return nil
+	}
+	if job.OutputPath != pipeOutput && !isStreamingOutput(job.OutputPath) {
+		if err := p.writeID3Lyrics(ctx, job, job.Options, job.OutputPath); err != nil {
+			return err
+		}
+	}
+	for _, out := range job.Options.AdditionalOutputs {
+		if isStreamingOutput(out.Path) {
+			continue
+		}
+		opts := *job.Options
+		opts.OutputFormat = ""
+		if out.Configure != nil {
+			out.Configure(&opts)
+		}
+		if err := p.writeID3Lyrics(ctx, job, &opts, out.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeID3Lyrics rewrites the ID3v2 tag of the MP3 at path with a USLT
+// frame, and a SYLT frame for LRC lyrics. Other outputs are left alone.
+func (p *Pipeline) writeID3Lyrics(ctx context.Context, job *Job, opts *model.ProcessingOptions, path string) error {
+	if opts.Lyrics.Text == "" || outputMuxer(opts, path) != "mp3" {
+		return nil
+	}
+	lang := opts.Lyrics.Language
+	if lang == "" {
+		lang = defaultLyricsLanguage
+	}
+	frames := []id3.Frame{id3.USLT(lang, opts.Lyrics.Text)}
+	if l, ok := lrc.Parse(opts.Lyrics.Text); ok {
+		synced := make([]id3.SyncedText, len(l.Lines))
+		for i, line := range l.Lines {
+			synced[i] = id3.SyncedText{Time: line.Time, Text: line.Text}
+		}
+		frames = []id3.Frame{id3.USLT(lang, l.Text()), id3.SYLT(lang, synced)}
+	}
+
+	// The tagged file is written to a managed temp file and copied back
+	// over path, which keeps the file mode ffmpeg created it with. The
+	// temp directory may be on another volume, so it isn't renamed.
+	temps := p.temps.Scope(job.ID)
+	defer func() {
+		if err := temps.Release(); err != nil {
+			p.log.Warn("failed to remove temp files", zap.String("job_id", job.ID), zap.Error(err))
+		}
+	}()
+	tmp, err := temps.Allocate(".mp3")
+	if err != nil {
+		return pkgerrors.NewProcessingError("finalize", "failed to create lyrics temp file", err)
+	}
+	if err := p.rewriteID3(ctx, path, tmp, frames); err != nil {
+		return pkgerrors.NewProcessingError("finalize", "failed to write lyrics to "+path, err)
+	}
+	if err := p.copyFile(ctx, tmp, path); err != nil {
+		return pkgerrors.NewProcessingError("finalize", "failed to write lyrics to "+path, err)
+	}
+	return nil
+}
+
+// copyFile overwrites dst with the contents of src
+func (p *Pipeline) copyFile(ctx context.Context, src, dst string) error {
+	in, err := p.storage.Open(ctx, src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := p.storage.Create(ctx, dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// rewriteID3 copies the MP3 at src to dst with frames added to its tag
+func (p *Pipeline) rewriteID3(ctx context.Context, src, dst string, frames []id3.Frame) error {
+	in, err := p.storage.Open(ctx, src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := p.storage.Create(ctx, dst)
+	if err != nil {
+		return err
+	}
+	if err := id3.Rewrite(out, in, frames...); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
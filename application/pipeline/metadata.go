@@ -0,0 +1,83 @@
+package pipeline
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/internal/argfmt"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+)
+
+// validateMetadata checks the metadata policy and tags that can't be
+// passed to -metadata. ffmpeg splits the argument at the first '=', so keys
+// must not contain one; values are taken verbatim, including '=' and line
+// breaks.
+func validateMetadata(opts *model.ProcessingOptions) error {
+	switch opts.MetadataPolicy {
+	case "", model.MetadataCopy, model.MetadataReplace:
+	case model.MetadataStrip:
+		if len(opts.Metadata) > 0 {
+			return pkgerrors.NewValidationError("metadataPolicy", opts.MetadataPolicy, "metadata can't be set when stripping tags")
+		}
+	default:
+		return pkgerrors.NewValidationError("metadataPolicy", opts.MetadataPolicy, "metadata policy must be copy, strip or replace")
+	}
+	switch opts.ID3.Version {
+	case 0, 3, 4:
+	default:
+		return pkgerrors.NewValidationError("id3.version", opts.ID3.Version, "ID3v2 version must be 3 or 4")
+	}
+	for k, v := range opts.Metadata {
+		if k == "" {
+			return pkgerrors.NewValidationError("metadata", k, "metadata key must not be empty")
+		}
+		if strings.ContainsAny(k, "=\x00") {
+			return pkgerrors.NewValidationError("metadata", k, "metadata key must not contain '=' or NUL")
+		}
+		if strings.ContainsRune(v, 0) {
+			return pkgerrors.NewValidationError("metadata."+k, v, "metadata value must not contain NUL")
+		}
+	}
+	return nil
+}
+
+// metadataArgs returns the output options applying opts.MetadataPolicy and
+// writing opts.Metadata, sorted by key so the command (and its
+// fingerprint) is stable. Each tag is a single argv entry, so no shell
+// quoting is involved.
+func metadataArgs(opts *model.ProcessingOptions) []string {
+	var args []string
+	switch opts.MetadataPolicy {
+	case model.MetadataStrip:
+		args = append(args, "-map_metadata", "-1", "-map_metadata:s:a", "-1", "-map_chapters", "-1")
+	case model.MetadataReplace:
+		args = append(args, "-map_metadata", "-1", "-map_metadata:s:a", "-1")
+	}
+
+	keys := make([]string, 0, len(opts.Metadata))
+	for k := range opts.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "-metadata", k+"="+opts.Metadata[k])
+	}
+	return args
+}
+
+// id3Args returns the muxer options controlling the ID3 tags of the
+// output at path; only MP3 outputs have any
+func id3Args(opts *model.ProcessingOptions, path string) []string {
+	if outputMuxer(opts, path) != "mp3" {
+		return nil
+	}
+	var args []string
+	if opts.ID3.Version != 0 {
+		args = append(args, "-id3v2_version", argfmt.Int(opts.ID3.Version))
+	}
+	if opts.ID3.WriteV1 {
+		args = append(args, "-write_id3v1", "1")
+	}
+	return args
+}
@@ -0,0 +1,73 @@
+package pipeline
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+)
+
+// outputPaths returns the job's file outputs, skipping live sinks and pipes
+func outputPaths(job *Job) []string {
+	var paths []string
+	if job.OutputPath != pipeOutput && !isStreamingOutput(job.OutputPath) {
+		paths = append(paths, job.OutputPath)
+	}
+	for _, out := range job.Options.AdditionalOutputs {
+		if !isStreamingOutput(out.Path) {
+			paths = append(paths, out.Path)
+		}
+	}
+	return paths
+}
+
+// prepareOutputs creates the parent directory of every output path
+func (p *Pipeline) prepareOutputs(ctx context.Context, opts *model.ProcessingOptions, paths ...string) error {
+	dirs := make([]string, 0, len(paths))
+	for _, path := range paths {
+		dirs = append(dirs, filepath.Dir(path))
+	}
+	return p.prepareDirs(ctx, opts, dirs...)
+}
+
+// prepareDirs creates dirs with opts.DirMode unless SkipCreateOutputDirs is set.
+// Ownership is applied to the leaf directory only so pre-existing parents
+// are never modified.
+func (p *Pipeline) prepareDirs(ctx context.Context, opts *model.ProcessingOptions, dirs ...string) error {
+	if opts.SkipCreateOutputDirs {
+		return nil
+	}
+	mode := opts.DirMode
+	if mode == 0 {
+		mode = 0o755
+	}
+	for _, dir := range dirs {
+		if dir == "" || dir == "." {
+			continue
+		}
+		if err := p.storage.MkdirAll(ctx, dir, mode); err != nil {
+			return pkgerrors.NewProcessingError("prepare", "failed to create output directory "+dir, err)
+		}
+		if opts.Owner != nil {
+			if err := p.storage.SetPermissions(ctx, dir, 0, opts.Owner); err != nil {
+				return pkgerrors.NewProcessingError("prepare", "failed to set output directory owner", err)
+			}
+		}
+	}
+	return nil
+}
+
+// finalizeOutputs applies the configured file mode and ownership to the
+// written outputs
+func (p *Pipeline) finalizeOutputs(ctx context.Context, opts *model.ProcessingOptions, paths ...string) error {
+	if opts.FileMode == 0 && opts.Owner == nil {
+		return nil
+	}
+	for _, path := range paths {
+		if err := p.storage.SetPermissions(ctx, path, opts.FileMode, opts.Owner); err != nil {
+			return pkgerrors.NewProcessingError("finalize", "failed to set permissions on "+path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/internal/argfmt"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"github.com/Skryldev/audio-lab/pkg/progress"
+)
+
+// DefaultLadder is the bitrate ladder used when none is given
+var DefaultLadder = []int{64000, 128000, 192000}
+
+// ladder is the shared description of a multi-bitrate encode used by the
+// HLS and DASH packagers
+type ladder struct {
+	codec           model.Codec
+	bitrates        []int
+	segmentDuration time.Duration
+}
+
+func newLadder(codec model.Codec, bitrates []int, segment time.Duration) (*ladder, error) {
+	if codec == "" {
+		codec = model.CodecAAC
+	}
+	if _, err := encoderName(codec); err != nil {
+		return nil, pkgerrors.NewValidationError("codec", codec, err.Error())
+	}
+	if len(bitrates) == 0 {
+		bitrates = DefaultLadder
+	}
+	for _, b := range bitrates {
+		if b <= 0 {
+			return nil, pkgerrors.NewValidationError("bitrates", b, "bitrate must be positive")
+		}
+	}
+	if segment <= 0 {
+		segment = 6 * time.Second
+	}
+	return &ladder{codec: codec, bitrates: bitrates, segmentDuration: segment}, nil
+}
+
+// encodeArgs decodes the input once and maps its audio into one output
+// stream per rendition, each with its own bitrate
+func (l *ladder) encodeArgs(job *Job) []string {
+	args := []string{"-y"}
+	args = append(args, remoteInputArgs(job.InputPath)...)
+	args = append(args, "-i", ffmpegPath(job.InputPath))
+
+	spec := audioStreamSpec(job.Options)
+	if spec == "" {
+		spec = "0:a:0"
+	}
+	for range l.bitrates {
+		args = append(args, "-map", spec)
+	}
+
+	if filterStr := buildFilterChain(job.Options).Build(); filterStr != "" {
+		args = append(args, "-af", filterStr)
+	}
+	args = append(args, "-ar", argfmt.Hz(job.Options.SampleRate))
+
+	encoder, _ := encoderName(l.codec)
+	args = append(args, "-c:a", encoder)
+	for i, b := range l.bitrates {
+		args = append(args, "-b:a:"+argfmt.Int(i), argfmt.Kbps(b))
+	}
+	return args
+}
+
+// PackageHLS encodes job.InputPath into an HLS bitrate ladder under dir,
+// writing variant playlists and a master playlist in a single ffmpeg run
+func (p *Pipeline) PackageHLS(ctx context.Context, job *Job, dir string, opts model.HLSOptions) (*model.PackageResult, error) {
+	l, err := newLadder(opts.Codec, opts.Bitrates, opts.SegmentDuration)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.runPackaging(ctx, job, dir, l, func(args []string) ([]string, string, []model.Rendition) {
+		varMap := ""
+		renditions := make([]model.Rendition, len(l.bitrates))
+		for i, b := range l.bitrates {
+			if i > 0 {
+				varMap += " "
+			}
+			varMap += fmt.Sprintf("a:%d", i)
+			renditions[i] = model.Rendition{
+				Codec:        l.codec,
+				Bitrate:      b,
+				PlaylistPath: filepath.Join(dir, fmt.Sprintf("stream_%d", i), "playlist.m3u8"),
+			}
+		}
+
+		// Opus is only valid in fMP4 segments; everything else uses MPEG-TS
+		segmentType, segmentExt := "mpegts", "ts"
+		if l.codec == model.CodecOpus {
+			segmentType, segmentExt = "fmp4", "m4s"
+		}
+
+		args = append(args,
+			"-f", "hls",
+			"-hls_time", argfmt.Seconds(l.segmentDuration),
+			"-hls_playlist_type", "vod",
+			"-hls_segment_type", segmentType,
+			"-hls_segment_filename", filepath.Join(dir, "stream_%v", "seg_%05d."+segmentExt),
+			"-master_pl_name", "master.m3u8",
+			"-var_stream_map", varMap,
+			filepath.Join(dir, "stream_%v", "playlist.m3u8"),
+		)
+		return args, filepath.Join(dir, "master.m3u8"), renditions
+	})
+}
+
+// PackageDASH encodes job.InputPath into an MPEG-DASH bitrate ladder under
+// dir, writing fMP4 segments and a manifest.mpd in a single ffmpeg run
+func (p *Pipeline) PackageDASH(ctx context.Context, job *Job, dir string, opts model.DASHOptions) (*model.PackageResult, error) {
+	l, err := newLadder(opts.Codec, opts.Bitrates, opts.SegmentDuration)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.runPackaging(ctx, job, dir, l, func(args []string) ([]string, string, []model.Rendition) {
+		renditions := make([]model.Rendition, len(l.bitrates))
+		for i, b := range l.bitrates {
+			renditions[i] = model.Rendition{Codec: l.codec, Bitrate: b}
+		}
+
+		manifest := filepath.Join(dir, "manifest.mpd")
+		args = append(args,
+			"-f", "dash",
+			"-seg_duration", argfmt.Seconds(l.segmentDuration),
+			"-use_template", "1",
+			"-use_timeline", "1",
+			"-adaptation_sets", "id=0,streams=a",
+			"-init_seg_name", "init-$RepresentationID$.m4s",
+			"-media_seg_name", "chunk-$RepresentationID$-$Number%05d$.m4s",
+			manifest,
+		)
+		return args, manifest, renditions
+	})
+}
+
+// runPackaging validates, probes and executes a packaging command built by
+// the format-specific build callback
+func (p *Pipeline) runPackaging(
+	ctx context.Context,
+	job *Job,
+	dir string,
+	l *ladder,
+	build func(args []string) ([]string, string, []model.Rendition),
+) (*model.PackageResult, error) {
+	start := time.Now()
+
+	if dir == "" {
+		return nil, pkgerrors.NewValidationError("dir", "", "output directory must not be empty")
+	}
+	// validateInput requires an output path; packaging writes into dir instead
+	job.OutputPath = dir
+	if err := p.validateInput(ctx, job); err != nil {
+		return nil, err
+	}
+	if err := p.prepareDirs(ctx, job.Options, dir); err != nil {
+		return nil, err
+	}
+
+	inputMeta, err := p.probeFile(ctx, job.InputPath)
+	if err != nil {
+		return nil, pkgerrors.NewProcessingError("probe", "failed to probe input file", err)
+	}
+	job.report(progress.StageProbe, 5, "input probed")
+	if job, err = selectAudioStream(job, inputMeta); err != nil {
+		return nil, err
+	}
+
+	args, manifest, renditions := build(l.encodeArgs(job))
+
+	job.report(progress.StageEncode, 20, "packaging started")
+
+	if err := p.executor.Execute(ctx, args); err != nil {
+		return nil, err
+	}
+
+	job.report(progress.StageDone, 100, "done")
+
+	return &model.PackageResult{
+		InputPath:    job.InputPath,
+		ManifestPath: manifest,
+		Renditions:   renditions,
+		InputMeta:    inputMeta,
+		Duration:     time.Since(start),
+		ProcessedAt:  time.Now(),
+	}, nil
+}
+
+// encoderName maps a codec onto its ffmpeg encoder
+func encoderName(codec model.Codec) (string, error) {
+	switch codec {
+	case model.CodecOpus:
+		return "libopus", nil
+	case model.CodecAAC:
+		return "aac", nil
+	case model.CodecMP3:
+		return "libmp3lame", nil
+	default:
+		return "", fmt.Errorf("unsupported codec: %s", codec)
+	}
+}
@@ -0,0 +1,14 @@
+package pipeline
+
+import (
+	"github.com/Skryldev/audio-lab/pkg/pathutil"
+)
+
+// ffmpegPath converts a local input or output path into an ffmpeg argument.
+// Pipes, remote inputs and live sinks are passed through unchanged.
+func ffmpegPath(path string) string {
+	if path == pipeInput || path == pipeOutput || IsRemoteInput(path) || isStreamingOutput(path) {
+		return path
+	}
+	return pathutil.FFmpegArg(path)
+}
@@ -2,35 +2,25 @@ package pipeline
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"io"
 	"time"
 
+	"github.com/Skryldev/audio-lab/application/presets"
 	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/internal/argfmt"
 	"github.com/Skryldev/audio-lab/domain/ports"
 	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
 	"github.com/Skryldev/audio-lab/pkg/logger"
 	"github.com/Skryldev/audio-lab/pkg/progress"
 	"github.com/Skryldev/audio-lab/infrastructure/ffmpeg"
+	"github.com/Skryldev/audio-lab/infrastructure/storage"
+	"github.com/Skryldev/audio-lab/infrastructure/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
-// ffprobeOutput maps key fields from ffprobe JSON
-type ffprobeOutput struct {
-	Format struct {
-		Duration string `json:"duration"`
-		BitRate  string `json:"bit_rate"`
-		Size     string `json:"size"`
-		FormatName string `json:"format_name"`
-	} `json:"format"`
-	Streams []struct {
-		CodecName   string `json:"codec_name"`
-		SampleRate  string `json:"sample_rate"`
-		Channels    int    `json:"channels"`
-		BitRate     string `json:"bit_rate"`
-	} `json:"streams"`
-}
-
 // Stage represents a single pipeline stage function
 type Stage func(ctx context.Context, job *Job) error
 
@@ -43,13 +33,24 @@ type Job struct {
 	Options    *model.ProcessingOptions
 	Reporter   progress.Reporter
 	Log        *logger.Logger
+
+	temps *storage.TempScope // intermediate files of the current run
 }
 
 // Pipeline orchestrates audio processing stages
 type Pipeline struct {
-	executor ports.FFmpegExecutor
-	storage  ports.StorageProvider
-	stages   []namedStage
+	executor ports.PipedExecutor
+	pipes    bool // the configured executor implements ports.PipedExecutor
+	storage  ports.FileStorage
+	provider ports.StorageProvider // as configured, for optional interfaces
+	stages   []namedStage // custom stages run before probing
+	afterStages []namedStage // custom stages run before committing outputs
+	middleware  []StageMiddleware
+	temps    *storage.TempManager
+	fingerprints ports.FingerprintStore
+	capabilities *model.Capabilities // nil until detected
+	tracer   trace.Tracer
+	hooks    model.Hooks
 	log      *logger.Logger
 }
 
@@ -58,203 +59,709 @@ type namedStage struct {
 	stage Stage
 }
 
-// NewPipeline creates a new audio processing pipeline
-func NewPipeline(executor ports.FFmpegExecutor, storage ports.StorageProvider, log *logger.Logger) *Pipeline {
+// NewPipeline creates a new audio processing pipeline. Executors and
+// providers implementing only the base interfaces are completed with
+// ffmpeg.Piped and storage.Files.
+func NewPipeline(executor ports.FFmpegExecutor, store ports.StorageProvider, log *logger.Logger) *Pipeline {
 	p := &Pipeline{
-		executor: executor,
-		storage:  storage,
+		executor: ffmpeg.Piped(executor),
+		pipes:    ffmpeg.SupportsPipes(executor),
+		storage:  storage.Files(store),
+		provider: store,
+		temps:    storage.NewTempManager(""),
+		tracer:   tracing.Tracer(nil),
 		log:      log,
 	}
 	return p
 }
 
-// Run executes the full pipeline for a job
-func (p *Pipeline) Run(ctx context.Context, job *Job) (*model.ProcessingResult, error) {
+// Run executes the full pipeline for a job. With remote storage the input
+// is staged to a local temp file and outputs are uploaded afterwards.
+func (p *Pipeline) Run(ctx context.Context, job *Job) (result *model.ProcessingResult, err error) {
+	ctx, span := p.tracer.Start(ctx, "audiolab.Run", trace.WithAttributes(jobAttributes(job)...))
+	defer func() {
+		span.SetAttributes(attribute.Bool("audiolab.skipped", result != nil && result.Skipped))
+		tracing.End(span, err)
+	}()
+	defer p.releaseTemps(job)
+
+	if job.Options.DryRun {
+		return p.dryRun(ctx, job)
+	}
+
+	fingerprint, skip, err := p.checkUpToDate(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	if skip {
+		return p.skippedResult(ctx, job), nil
+	}
+
+	if p.needsStaging(job) {
+		result, err = p.runStaged(ctx, job)
+	} else {
+		result, err = p.run(ctx, job)
+	}
+	if err == nil && fingerprint != "" {
+		p.recordFingerprint(ctx, job, fingerprint)
+	}
+	return result, err
+}
+
+// run executes the pipeline against local paths
+func (p *Pipeline) run(ctx context.Context, job *Job) (*model.ProcessingResult, error) {
 	start := time.Now()
 
 	// Validate input
-	if err := p.validateInput(ctx, job); err != nil {
+	err := p.runStage(ctx, job, "validate", func(ctx context.Context, job *Job) error {
+		if err := p.validateInput(ctx, job); err != nil {
+			return err
+		}
+		return p.prepareOutputs(ctx, job.Options, outputPaths(job)...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.runStages(ctx, job, p.stages); err != nil {
 		return nil, err
 	}
 
 	// Probe input metadata
-	inputMeta, err := p.probeFile(ctx, job.InputPath)
+	var inputMeta *model.AudioMetadata
+	err = p.runStage(ctx, job, "probe", func(ctx context.Context, job *Job) error {
+		var err error
+		inputMeta, err = p.probeFile(ctx, job.InputPath)
+		return err
+	})
 	if err != nil {
 		return nil, pkgerrors.NewProcessingError("probe", "failed to probe input file", err)
 	}
 
 	job.report(progress.StageProbe, 5, "input probed")
+	if job, err = resolveJob(job, inputMeta); err != nil {
+		return nil, err
+	}
+	if job, err = p.loadLyrics(ctx, job); err != nil {
+		return nil, err
+	}
+
+	// Encode into temp siblings that are moved into place once verified
+	enc, atomic := p.atomicJob(job)
+	defer atomic.discard(ctx)
+
+	// Fail before encoding rather than on a full disk mid-encode
+	if err := p.checkDiskSpace(ctx, enc, inputMeta); err != nil {
+		return nil, err
+	}
 
 	// Build and execute FFmpeg command
-	if err := p.runFFmpeg(ctx, job); err != nil {
+	streaming := isStreamingOutput(job.OutputPath)
+	var stderr []byte
+	total := inputMeta.Duration + job.Options.LeadingPadding + job.Options.TrailingPadding
+	err = p.runStage(ctx, job, "encode", func(ctx context.Context, _ *Job) error {
+		var err error
+		if StreamsLive(job.OutputPath, job.Options) {
+			stderr, err = p.runStreaming(ctx, enc, total)
+		} else {
+			stderr, err = p.runFFmpeg(ctx, enc, enc.encodeProgress(total))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var loudness *model.LoudnessReport
+	if job.Options.NormalizationEnabled {
+		loudness, err = buildLoudnessReport(stderr, job.Options.LoudnessTarget)
+		if err != nil {
+			// non-fatal: the encode itself succeeded
+			p.log.Warn("failed to parse loudness report", zap.Error(err))
+		}
+	}
+
+	err = p.runStage(ctx, job, "finalize", func(ctx context.Context, _ *Job) error {
+		if err := p.writeLyrics(ctx, enc); err != nil {
+			return err
+		}
+		return p.finalizeOutputs(ctx, job.Options, outputPaths(enc)...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	job.report(progress.StageEncode, encodeEndPercent, "encoding complete")
+
+	// Probe output; live sinks can't be probed. A temp output that fails to
+	// probe, or to decode when verification is on, is never moved into place.
+	outputMeta := &model.AudioMetadata{}
+	var additional []*model.ProcessingResult
+	verify := job.Options.VerifyOutput
+	expected := expectedDuration(job.Options, inputMeta.Duration)
+	err = p.runStage(ctx, job, "verify", func(ctx context.Context, _ *Job) error {
+		if !streaming {
+			meta, err := p.probeFile(ctx, enc.OutputPath)
+			if err != nil {
+				if atomic.active() || verify {
+					return pkgerrors.NewProcessingError("verify", "output failed verification", err)
+				}
+				// non-fatal: output probe failure shouldn't fail the whole operation
+				p.log.Warn("failed to probe output file", zap.Error(err))
+				meta = &model.AudioMetadata{}
+			}
+			if verify {
+				if err := p.verifyOutput(ctx, job.Options, enc.OutputPath, meta, expected); err != nil {
+					return err
+				}
+			}
+			outputMeta = meta
+		}
+
+		for i, out := range job.Options.AdditionalOutputs {
+			meta := &model.AudioMetadata{}
+			if path := enc.Options.AdditionalOutputs[i].Path; !isStreamingOutput(path) {
+				var err error
+				if meta, err = p.probeFile(ctx, path); err != nil {
+					if atomic.active() || verify {
+						return pkgerrors.NewProcessingError("verify", "additional output failed verification", err)
+					}
+					p.log.Warn("failed to probe additional output", zap.String("output", out.Path), zap.Error(err))
+					meta = &model.AudioMetadata{}
+				}
+				if verify {
+					if err := p.verifyOutput(ctx, job.Options, path, meta, expected); err != nil {
+						return fmt.Errorf("%s: %w", out.Path, err)
+					}
+				}
+			}
+			additional = append(additional, &model.ProcessingResult{
+				InputPath:   job.InputPath,
+				OutputPath:  out.Path,
+				InputMeta:   inputMeta,
+				OutputMeta:  meta,
+				Duration:    time.Since(start),
+				ProcessedAt: time.Now(),
+				Profile:     job.profile(),
+				Labels:      job.labels(),
+				Loudness:    loudness,
+			})
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	job.report(progress.StageEncode, 90, "encoding complete")
+	if err := p.runStages(ctx, enc, p.afterStages); err != nil {
+		return nil, err
+	}
 
-	// Probe output
-	outputMeta, err := p.probeFile(ctx, job.OutputPath)
+	err = p.runStage(ctx, job, "commit", func(ctx context.Context, _ *Job) error {
+		return atomic.commit(ctx)
+	})
 	if err != nil {
-		// non-fatal: output probe failure shouldn't fail the whole operation
-		p.log.Warn("failed to probe output file", zap.Error(err))
-		outputMeta = &model.AudioMetadata{}
+		return nil, err
 	}
 
 	job.report(progress.StageDone, 100, "done")
 
 	return &model.ProcessingResult{
-		InputPath:   job.InputPath,
-		OutputPath:  job.OutputPath,
-		InputMeta:   inputMeta,
-		OutputMeta:  outputMeta,
-		Duration:    time.Since(start),
-		ProcessedAt: time.Now(),
+		InputPath:         job.InputPath,
+		OutputPath:        job.OutputPath,
+		InputMeta:         inputMeta,
+		OutputMeta:        outputMeta,
+		Duration:          time.Since(start),
+		ProcessedAt:       time.Now(),
+		Profile:           job.profile(),
+		Labels:            job.labels(),
+		Loudness:          loudness,
+		AdditionalOutputs: additional,
 	}, nil
 }
 
 func (p *Pipeline) validateInput(ctx context.Context, job *Job) error {
+	if err := ValidateJob(job); err != nil {
+		return err
+	}
+	if err := p.checkCapabilities(job.Options); err != nil {
+		return err
+	}
+	if art := job.Options.CoverArt.Path; art != "" {
+		if err := p.checkInputExists(ctx, art); err != nil {
+			return err
+		}
+	}
+	if lyrics := job.Options.Lyrics.Path; lyrics != "" {
+		if err := p.checkInputExists(ctx, lyrics); err != nil {
+			return err
+		}
+	}
+	return p.checkInputExists(ctx, job.InputPath)
+}
+
+// ValidateJob checks the job's paths and options without touching storage
+func ValidateJob(job *Job) error {
 	if job.InputPath == "" {
 		return pkgerrors.NewValidationError("inputPath", "", "input path must not be empty")
 	}
 	if job.OutputPath == "" {
 		return pkgerrors.NewValidationError("outputPath", "", "output path must not be empty")
 	}
+	return validateOptions(job)
+}
 
-	exists, err := p.storage.Exists(ctx, job.InputPath)
-	if err != nil {
-		return pkgerrors.NewProcessingError("validate", "failed to check input file", err)
+// validateOptions checks the job options independently of the input source
+func validateOptions(job *Job) error {
+	opts := job.Options
+	if err := validateLabels(opts.Labels); err != nil {
+		return err
 	}
-	if !exists {
-		return pkgerrors.NewValidationError("inputPath", job.InputPath, "input file does not exist")
+	if err := validateMetadata(opts); err != nil {
+		return err
+	}
+	if err := validateCoverArt(job); err != nil {
+		return err
+	}
+	if err := validateChapters(job); err != nil {
+		return err
+	}
+	if err := validateGapless(job); err != nil {
+		return err
+	}
+	if err := validateBWF(job); err != nil {
+		return err
+	}
+	if err := validateIdentifiers(job); err != nil {
+		return err
+	}
+	if err := validateLyrics(job); err != nil {
+		return err
+	}
+	if opts.Preset != "" {
+		if _, ok := presets.Lookup(opts.Preset); !ok {
+			return pkgerrors.NewValidationError("preset", opts.Preset, "unknown preset")
+		}
 	}
-
-	opts := job.Options
 	if opts.Bitrate <= 0 {
 		return pkgerrors.NewValidationError("bitrate", opts.Bitrate, "bitrate must be positive")
 	}
 	if opts.SampleRate <= 0 {
 		return pkgerrors.NewValidationError("sampleRate", opts.SampleRate, "sample rate must be positive")
 	}
+	if err := validateCodecOptions("", opts); err != nil {
+		return err
+	}
+	if opts.AudioStream != nil && *opts.AudioStream < 0 {
+		return pkgerrors.NewValidationError("audioStream", *opts.AudioStream, "audio stream index must not be negative")
+	}
+	if opts.Threads < 0 {
+		return pkgerrors.NewValidationError("threads", opts.Threads, "threads must not be negative")
+	}
+	if opts.LeadingPadding < 0 {
+		return pkgerrors.NewValidationError("leadingPadding", opts.LeadingPadding, "padding must not be negative")
+	}
+	if opts.TrailingPadding < 0 {
+		return pkgerrors.NewValidationError("trailingPadding", opts.TrailingPadding, "padding must not be negative")
+	}
+	for i, out := range opts.AdditionalOutputs {
+		field := fmt.Sprintf("additionalOutputs[%d]", i)
+		if out.Path == "" {
+			return pkgerrors.NewValidationError(field, "", "output path must not be empty")
+		}
+		if out.Path == job.OutputPath {
+			return pkgerrors.NewValidationError(field, out.Path, "additional output must differ from the main output")
+		}
+	}
 
 	return nil
 }
 
-func (p *Pipeline) runFFmpeg(ctx context.Context, job *Job) error {
+// encodePlan builds the command runFFmpeg executes for job
+func encodePlan(job *Job) (*model.CommandPlan, error) {
+	plan, err := buildPlan(job)
+	if err != nil {
+		return nil, err
+	}
+	plan.GlobalArgs = append(plan.GlobalArgs, ffmpeg.ProgressArgs...)
+	return plan, nil
+}
+
+// runFFmpeg runs the job's encode, writing ffmpeg's progress output to w.
+// Executors without pipes encode without progress and return no stderr.
+func (p *Pipeline) runFFmpeg(ctx context.Context, job *Job, w io.Writer) ([]byte, error) {
+	if !p.pipes {
+		plan, err := buildPlan(job)
+		if err != nil {
+			return nil, err
+		}
+		job.report(progress.StageEncode, encodeStartPercent, "encoding started")
+		return nil, p.executor.Execute(ctx, plan.Args())
+	}
+
+	plan, err := encodePlan(job)
+	if err != nil {
+		return nil, err
+	}
+
+	job.report(progress.StageEncode, encodeStartPercent, "encoding started")
+
+	return p.executor.ExecutePiped(ctx, plan.Args(), nil, w)
+}
+
+// BuildPlan validates the job and returns the ffmpeg command plan that Run
+// would execute, without executing it
+func (p *Pipeline) BuildPlan(ctx context.Context, job *Job) (*model.CommandPlan, error) {
+	if err := p.validateInput(ctx, job); err != nil {
+		return nil, err
+	}
+	return buildPlan(job)
+}
+
+// dryRun validates and probes the job and returns the command Run would
+// execute, progress flags included. Outputs are named by the temp files
+// they would be encoded into; those names are random, so a real run uses
+// different ones. Nothing is written.
+func (p *Pipeline) dryRun(ctx context.Context, job *Job) (*model.ProcessingResult, error) {
+	start := time.Now()
+	if err := p.validateInput(ctx, job); err != nil {
+		return nil, err
+	}
+	inputMeta, err := p.probeFile(ctx, job.InputPath)
+	if err != nil {
+		return nil, pkgerrors.NewProcessingError("probe", "failed to probe input file", err)
+	}
+	if job, err = resolveJob(job, inputMeta); err != nil {
+		return nil, err
+	}
+	if job, err = p.loadLyrics(ctx, job); err != nil {
+		return nil, err
+	}
+	enc, _ := p.atomicJob(job)
+	plan, err := encodePlan(enc)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ProcessingResult{
+		InputPath:   job.InputPath,
+		OutputPath:  job.OutputPath,
+		InputMeta:   inputMeta,
+		Duration:    time.Since(start),
+		ProcessedAt: time.Now(),
+		Profile:     job.profile(),
+		Labels:      job.labels(),
+		Command:     plan.Args(),
+	}, nil
+}
+
+// PlanJob validates the job and builds its command plan without storage or
+// an executor; the input's existence is not checked
+func PlanJob(job *Job) (*model.CommandPlan, error) {
+	if err := ValidateJob(job); err != nil {
+		return nil, err
+	}
+	return buildPlan(job)
+}
+
+// ExecutePlan runs a previously built (and possibly stored) command plan
+func (p *Pipeline) ExecutePlan(ctx context.Context, plan *model.CommandPlan) error {
+	if plan == nil {
+		return pkgerrors.NewValidationError("plan", nil, "plan must not be nil")
+	}
+	if err := plan.Validate(); err != nil {
+		return pkgerrors.NewValidationError("plan", plan.JobID, err.Error())
+	}
+	return p.executor.Execute(ctx, plan.Args())
+}
+
+// ExecutePlans runs a multi-stage command without intermediate files:
+// every plan but the last writes its single output to model.PlanPipeOutput
+// and the next plan reads it from model.PlanPipeInput, e.g. decoded PCM
+// muxed with ffmpeg.NUTArgs. All stages run at once and the first failure
+// stops the others.
+func (p *Pipeline) ExecutePlans(ctx context.Context, plans ...*model.CommandPlan) error {
+	if len(plans) == 0 {
+		return pkgerrors.NewValidationError("plans", 0, "at least one plan is required")
+	}
+	commands := make([][]string, len(plans))
+	for i, plan := range plans {
+		if plan == nil {
+			return pkgerrors.NewValidationError(fmt.Sprintf("plans[%d]", i), nil, "plan must not be nil")
+		}
+		if err := plan.Validate(); err != nil {
+			return pkgerrors.NewValidationError("plan", plan.JobID, err.Error())
+		}
+		if i < len(plans)-1 && (len(plan.Outputs) != 1 || plan.Outputs[0].Path != model.PlanPipeOutput) {
+			return pkgerrors.NewValidationError(fmt.Sprintf("plans[%d]", i), plan.JobID, "intermediate stage must have a single output to "+model.PlanPipeOutput)
+		}
+		if i > 0 && !readsPipe(plan) {
+			return pkgerrors.NewValidationError(fmt.Sprintf("plans[%d]", i), plan.JobID, "stage must read its input from "+model.PlanPipeInput)
+		}
+		commands[i] = plan.Args()
+	}
+	_, err := ffmpeg.RunPiped(ctx, p.executor, nil, nil, commands...)
+	return err
+}
+
+// readsPipe reports whether one of the plan's inputs is stdin
+func readsPipe(plan *model.CommandPlan) bool {
+	for _, in := range plan.Inputs {
+		if in.Path == model.PlanPipeInput {
+			return true
+		}
+	}
+	return false
+}
+
+func buildPlan(job *Job) (*model.CommandPlan, error) {
 	opts := job.Options
-	args := []string{"-y", "-i", job.InputPath}
 
-	// Build audio filter chain
+	if len(opts.AdditionalOutputs) > 0 {
+		return buildMultiOutputPlan(job)
+	}
+
+	// Codec-specific encoding arguments
+	codecArgs, err := buildCodecArgs(opts)
+	if err != nil {
+		return nil, pkgerrors.NewProcessingError("encode", "failed to build codec args", err)
+	}
+
+	// Drop video and cover art streams; only audio is encoded, along with
+	// the cover art image when one is embedded
+	embedArt := embedsCoverArt(opts, job.OutputPath)
+	var outArgs []string
+	if !embedArt {
+		outArgs = append(outArgs, "-vn")
+	}
+	outArgs = append(outArgs, "-ar", argfmt.Hz(opts.SampleRate))
+	outArgs = append(outArgs, codecArgs...)
+	outArgs = append(outArgs, threadArgs(opts)...)
+	outArgs = append(outArgs, metadataArgs(opts)...)
+	outArgs = append(outArgs, id3Args(opts, job.OutputPath)...)
+	outArgs = append(outArgs, gaplessArgs(opts, job.OutputPath)...)
+	outArgs = append(outArgs, bwfArgs(opts, job.OutputPath)...)
+	outArgs = append(outArgs, identifierArgs(opts, job.OutputPath)...)
+	outArgs = append(outArgs, lyricsArgs(opts, job.OutputPath)...)
+
+	if opts.OutputFormat != "" {
+		outArgs = append(outArgs, "-f", opts.OutputFormat)
+	}
+
+	var maps []string
+	if spec := audioStreamSpec(opts); spec != "" {
+		maps = append(maps, spec)
+	} else if embedArt {
+		maps = append(maps, "0:a:0")
+	}
+	if embedArt {
+		artMap, artArgs := coverArtArgs(opts, 1)
+		maps = append(maps, artMap)
+		outArgs = append(outArgs, artArgs...)
+	}
+
+	input := model.PlanInput{Path: ffmpegPath(job.InputPath), Args: remoteInputArgs(job.InputPath)}
+	if opts.InputFormat != "" {
+		input.Args = append(input.Args, "-f", opts.InputFormat)
+	}
+	input.Args = append(input.Args, threadArgs(opts)...)
+	input.Args = append(input.Args, opts.ExtraInputArgs...)
+	if isStreamingOutput(job.OutputPath) {
+		muxArgs, err := streamMuxerArgs(job.OutputPath, opts.Codec)
+		if err != nil {
+			return nil, err
+		}
+		outArgs = append(outArgs, muxArgs...)
+		// Read the input at its native rate so the stream plays in real time
+		input.Args = append(input.Args, "-re")
+	}
+
+	inputs := []model.PlanInput{input}
+	if embedArt {
+		inputs = append(inputs, coverArtInput(opts))
+	}
+	if writesChapters(opts, job.OutputPath) {
+		outArgs = append(outArgs, chapterArgs(len(inputs))...)
+		inputs = append(inputs, chapterInput(opts))
+	}
+	outArgs = append(outArgs, opts.ExtraOutputArgs...)
+
+	return &model.CommandPlan{
+		Version:    model.CommandPlanVersion,
+		JobID:      job.ID,
+		GlobalArgs: []string{"-y"},
+		Inputs:     inputs,
+		Outputs: []model.PlanOutput{{
+			Path:   ffmpegPath(job.OutputPath),
+			Maps:   maps,
+			Filter: buildFilterChain(opts).Build(),
+			Args:   outArgs,
+		}},
+	}, nil
+}
+
+// threadArgs caps the decoder or encoder threads ffmpeg starts
+func threadArgs(opts *model.ProcessingOptions) []string {
+	if opts.Threads <= 0 {
+		return nil
+	}
+	return []string{"-threads", argfmt.Int(opts.Threads)}
+}
+
+// buildMultiOutputPlan encodes the main output and all additional outputs
+// from a single decode and filter pass
+func buildMultiOutputPlan(job *Job) (*model.CommandPlan, error) {
+	outputs := job.Options.AdditionalOutputs
+	specs := make([]model.RenditionSpec, 0, len(outputs)+1)
+	renditionOpts := make([]*model.ProcessingOptions, 0, len(outputs)+1)
+
+	specs = append(specs, model.RenditionSpec{OutputPath: job.OutputPath})
+	renditionOpts = append(renditionOpts, job.Options)
+
+	// Every output is encoded from the one filter pass
+	filters := buildFilterChain(job.Options).Build()
+	for i, out := range outputs {
+		opts := *job.Options
+		opts.AdditionalOutputs = nil
+		opts.OutputFormat = ""
+		if out.Configure != nil {
+			out.Configure(&opts)
+		}
+		if opts.Bitrate <= 0 {
+			return nil, pkgerrors.NewValidationError(fmt.Sprintf("additionalOutputs[%d].bitrate", i), opts.Bitrate, "bitrate must be positive")
+		}
+		if err := validateCodecOptions(fmt.Sprintf("additionalOutputs[%d].", i), &opts); err != nil {
+			return nil, err
+		}
+		if buildFilterChain(&opts).Build() != filters {
+			return nil, pkgerrors.NewValidationError(fmt.Sprintf("additionalOutputs[%d]", i), out.Path, "additional outputs share the job's filters and can't change them")
+		}
+		specs = append(specs, model.RenditionSpec{OutputPath: out.Path})
+		renditionOpts = append(renditionOpts, &opts)
+	}
+
+	return buildLadderPlan(job, specs, renditionOpts)
+}
+
+// buildFilterChain builds the audio filter chain shared by all encode paths
+func buildFilterChain(opts *model.ProcessingOptions) *ffmpeg.FilterChainBuilder {
 	fb := ffmpeg.NewFilterChainBuilder()
 
+	if opts.DCRemoval {
+		fb.AddDCRemoval()
+	}
 	if opts.HighpassEnabled {
 		fb.AddHighpass(opts.HighpassFreq)
 	}
 	if opts.LowpassEnabled {
 		fb.AddLowpass(opts.LowpassFreq)
 	}
+	if opts.Podcast != nil {
+		addPodcastCleanup(fb, opts.Podcast)
+	}
 	if opts.NormalizationEnabled {
-		fb.AddLoudnorm(opts.LoudnessTarget, opts.TruePeakLimit, opts.LoudnessRange)
+		fb.AddLoudnormWithReport(opts.LoudnessTarget, opts.TruePeakLimit, opts.LoudnessRange)
 	}
-
-	filterStr := fb.Build()
-	if filterStr != "" {
-		args = append(args, "-af", filterStr)
+	if opts.Podcast != nil {
+		addPodcastLimiter(fb, opts.Podcast, opts.TruePeakLimit)
+	}
+	// Padding goes last so loudness measurement isn't skewed by added silence
+	if opts.LeadingPadding > 0 {
+		fb.AddDelay(opts.LeadingPadding)
+	}
+	if opts.TrailingPadding > 0 {
+		fb.AddPad(opts.TrailingPadding)
+	}
+	for _, expr := range opts.CustomFilters {
+		fb.Add(expr)
+	}
+	for _, hook := range opts.FilterHooks {
+		fb.SetFilters(hook(fb.Filters()))
 	}
 
-	// Sample rate
-	args = append(args, "-ar", fmt.Sprintf("%d", opts.SampleRate))
+	return fb
+}
 
-	// Codec-specific encoding arguments
-	codecArgs, err := buildCodecArgs(opts)
+// buildLoudnessReport converts loudnorm's stderr JSON into a LoudnessReport
+func buildLoudnessReport(stderr []byte, target float64) (*model.LoudnessReport, error) {
+	stats, err := ffmpeg.ParseLoudnormOutput(stderr)
 	if err != nil {
-		return pkgerrors.NewProcessingError("encode", "failed to build codec args", err)
+		return nil, err
 	}
-	args = append(args, codecArgs...)
-
-	// Output path
-	args = append(args, job.OutputPath)
-
-	job.report(progress.StageEncode, 20, "encoding started")
-
-	return p.executor.Execute(ctx, args)
+	return &model.LoudnessReport{
+		Target: target,
+		Input: model.LoudnessMeasurement{
+			Integrated: stats.InputI,
+			TruePeak:   stats.InputTP,
+			LRA:        stats.InputLRA,
+			Threshold:  stats.InputThresh,
+		},
+		Output: model.LoudnessMeasurement{
+			Integrated: stats.OutputI,
+			TruePeak:   stats.OutputTP,
+			LRA:        stats.OutputLRA,
+			Threshold:  stats.OutputThresh,
+		},
+		AppliedGain:       stats.OutputI - stats.InputI,
+		NormalizationType: stats.NormalizationType,
+	}, nil
 }
 
+// buildCodecArgs returns the encoder options for opts, using the encoder
+// selected from the codec's preference list
 func buildCodecArgs(opts *model.ProcessingOptions) ([]string, error) {
-	bitrate := fmt.Sprintf("%dk", opts.Bitrate/1000)
+	bitrate := argfmt.Kbps(opts.Bitrate)
+	vbr := opts.BitrateMode == model.BitrateModeVBR
+
+	encoder := encoderFor(opts)
+	if encoder == "" {
+		return nil, fmt.Errorf("unsupported codec: %s", opts.Codec)
+	}
+	args := []string{"-c:a", encoder}
 
-	switch opts.Codec {
-	case model.CodecOpus:
-		args := []string{"-c:a", "libopus"}
-		if opts.BitrateMode == model.BitrateModeVBR {
+	switch encoder {
+	case "libopus":
+		if vbr {
 			args = append(args, "-vbr", "on", "-b:a", bitrate)
 		} else {
 			args = append(args, "-vbr", "off", "-b:a", bitrate)
 		}
-		return args, nil
 
-	case model.CodecAAC:
-		args := []string{"-c:a", "aac"}
-		if opts.BitrateMode == model.BitrateModeVBR {
-			// AAC VBR uses quality scale 1-5
-			args = append(args, "-q:a", "2")
+	case "opus":
+		// The native Opus encoder is still marked experimental and has no
+		// VBR switch
+		args = append(args, "-strict", "experimental", "-b:a", bitrate)
+
+	case "libfdk_aac":
+		if vbr {
+			// libfdk_aac VBR uses modes 1-5
+			args = append(args, "-vbr", "4")
 		} else {
 			args = append(args, "-b:a", bitrate)
 		}
-		return args, nil
 
-	case model.CodecMP3:
-		args := []string{"-c:a", "libmp3lame"}
-		if opts.BitrateMode == model.BitrateModeVBR {
+	case "aac", "libmp3lame":
+		if vbr {
+			// Quality scale; lower is better
 			args = append(args, "-q:a", "2")
 		} else {
 			args = append(args, "-b:a", bitrate)
 		}
-		return args, nil
+
+	case "pcm_s16le", "pcm_s24le":
+		// Uncompressed; the bitrate follows from rate and channels
 
 	default:
-		return nil, fmt.Errorf("unsupported codec: %s", opts.Codec)
+		// libshine and user-preferred encoders: a plain target bitrate
+		args = append(args, "-b:a", bitrate)
 	}
+	return args, nil
 }
 
-func (p *Pipeline) probeFile(ctx context.Context, path string) (*model.AudioMetadata, error) {
-	data, err := p.executor.Probe(ctx, path)
-	if err != nil {
-		return nil, err
-	}
-
-	var probe ffprobeOutput
-	if err := json.Unmarshal(data, &probe); err != nil {
-		return nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
-	}
-
-	meta := &model.AudioMetadata{
-		Format: probe.Format.FormatName,
-	}
-
-	// Parse duration
-	var durationSec float64
-	if _, err := fmt.Sscanf(probe.Format.Duration, "%f", &durationSec); err == nil {
-		meta.Duration = time.Duration(durationSec * float64(time.Second))
-	}
-
-	// Parse size
-	fmt.Sscanf(probe.Format.Size, "%d", &meta.Size)
-
-	// Parse stream info
-	for _, s := range probe.Streams {
-		meta.Codec = s.CodecName
-		meta.Channels = s.Channels
-		fmt.Sscanf(s.SampleRate, "%d", &meta.SampleRate)
-		fmt.Sscanf(s.BitRate, "%d", &meta.Bitrate)
-		break // take first audio stream
+// reportUpdate forwards a prepared update to the job's reporter
+func (j *Job) reportUpdate(u progress.Update) {
+	if j.Reporter != nil {
+		j.Reporter.Report(u)
 	}
-
-	return meta, nil
-}
-
-// ProbeFile probes audio metadata for a path.
-func (p *Pipeline) ProbeFile(ctx context.Context, path string) (*model.AudioMetadata, error) {
-	return p.probeFile(ctx, path)
 }
 
 // report is a helper to emit progress updates
@@ -264,8 +771,17 @@ func (j *Job) report(stage progress.Stage, percent float64, msg string) {
 	}
 	j.Reporter.Report(progress.Update{
 		JobID:   j.ID,
+		Profile: j.profile(),
 		Stage:   stage,
 		Percent: percent,
 		Message: msg,
 	})
 }
+
+// profile returns the job's profile name, tolerating nil options
+func (j *Job) profile() string {
+	if j.Options == nil {
+		return ""
+	}
+	return j.Options.Profile
+}
@@ -0,0 +1,63 @@
+package pipeline
+
+import (
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/domain/ports"
+	"github.com/Skryldev/audio-lab/infrastructure/ffmpeg"
+	"github.com/Skryldev/audio-lab/internal/argfmt"
+)
+
+// Podcast mastering settings, tuned for a voice recorded close to the mic
+const (
+	podcastNoiseFloor    = -25 // dB
+	podcastGateThreshold = -45 // dBFS
+	podcastGateRatio     = 2   // a gentle expander rather than a hard gate
+	podcastCompThreshold = -20 // dBFS
+	podcastCompRatio     = 3
+	podcastCompMakeup    = 3    // dB
+	podcastDeEsser       = 0.4  // intensity
+	podcastCeilingMargin = -0.1 // dB below the true-peak limit the limiter sits at
+)
+
+// WithPodcastMastering adds the podcast cleanup chain: denoise, gate,
+// compressor and de-esser before loudness normalization, and a limiter at
+// the true-peak limit after it. It mixes the output down to mono unless
+// podcast.Stereo is set. The chain is kept in the options rather than as a
+// filter hook so queued jobs keep it.
+func WithPodcastMastering(podcast model.PodcastOptions) ports.Option {
+	return func(o *model.ProcessingOptions) {
+		o.Podcast = &podcast
+
+		channels := 1
+		if podcast.Stereo {
+			channels = 2
+		}
+		// Prepended so that a -ac in the caller's own args still wins
+		o.ExtraOutputArgs = append([]string{"-ac", argfmt.Int(channels)}, o.ExtraOutputArgs...)
+	}
+}
+
+// addPodcastCleanup adds the stages of podcast that run ahead of loudness
+// normalization
+func addPodcastCleanup(fb *ffmpeg.FilterChainBuilder, podcast *model.PodcastOptions) {
+	if !podcast.SkipDenoise {
+		fb.AddDenoise(podcastNoiseFloor)
+	}
+	if !podcast.SkipGate {
+		fb.AddGate(podcastGateThreshold, podcastGateRatio)
+	}
+	if !podcast.SkipCompressor {
+		fb.AddCompressor(podcastCompThreshold, podcastCompRatio, podcastCompMakeup)
+	}
+	if !podcast.SkipDeEsser {
+		fb.AddDeEsser(podcastDeEsser)
+	}
+}
+
+// addPodcastLimiter adds the limiter of podcast, which follows loudness
+// normalization, just under truePeak
+func addPodcastLimiter(fb *ffmpeg.FilterChainBuilder, podcast *model.PodcastOptions, truePeak float64) {
+	if !podcast.SkipLimiter {
+		fb.AddLimiter(truePeak + podcastCeilingMargin)
+	}
+}
@@ -0,0 +1,147 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/internal/argfmt"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"github.com/Skryldev/audio-lab/pkg/progress"
+)
+
+// DefaultPreviewLength is used when PreviewOptions.Length is zero
+const DefaultPreviewLength = 30 * time.Second
+
+// GeneratePreview cuts a faded snippet out of job.InputPath, applying the
+// job's regular filter chain and codec settings
+func (p *Pipeline) GeneratePreview(ctx context.Context, job *Job, preview model.PreviewOptions) (*model.ProcessingResult, error) {
+	start := time.Now()
+
+	if err := p.validateInput(ctx, job); err != nil {
+		return nil, err
+	}
+	if err := p.prepareOutputs(ctx, job.Options, job.OutputPath); err != nil {
+		return nil, err
+	}
+
+	inputMeta, err := p.probeFile(ctx, job.InputPath)
+	if err != nil {
+		return nil, pkgerrors.NewProcessingError("probe", "failed to probe input file", err)
+	}
+	job.report(progress.StageProbe, 5, "input probed")
+	if job, err = selectAudioStream(job, inputMeta); err != nil {
+		return nil, err
+	}
+
+	length, err := resolvePreviewLength(preview, inputMeta.Duration)
+	if err != nil {
+		return nil, err
+	}
+
+	// The fades run after padding, so they are shifted past the leading
+	// silence to land on the clip itself
+	fb := buildFilterChain(job.Options)
+	lead := job.Options.LeadingPadding
+	if preview.FadeIn > 0 {
+		fb.AddFadeInAt(lead, preview.FadeIn)
+	}
+	if preview.FadeOut > 0 {
+		fb.AddFadeOut(lead+length-preview.FadeOut, preview.FadeOut)
+	}
+
+	// Input seeking (-ss before -i) is fast and sample-accurate for audio
+	args := []string{
+		"-y",
+		"-ss", argfmt.Seconds(preview.Start),
+		"-t", argfmt.Seconds(length),
+	}
+	args = append(args, remoteInputArgs(job.InputPath)...)
+	args = append(args, "-i", ffmpegPath(job.InputPath))
+	if spec := audioStreamSpec(job.Options); spec != "" {
+		args = append(args, "-map", spec)
+	}
+	args = append(args, "-vn")
+	if filterStr := fb.Build(); filterStr != "" {
+		args = append(args, "-af", filterStr)
+	}
+	args = append(args, "-ar", argfmt.Hz(job.Options.SampleRate))
+
+	codecArgs, err := buildCodecArgs(job.Options)
+	if err != nil {
+		return nil, pkgerrors.NewProcessingError("encode", "failed to build codec args", err)
+	}
+	args = append(args, codecArgs...)
+	args = append(args, metadataArgs(job.Options)...)
+	args = append(args, id3Args(job.Options, job.OutputPath)...)
+	args = append(args, bwfArgs(job.Options, job.OutputPath)...)
+	args = append(args, identifierArgs(job.Options, job.OutputPath)...)
+
+	atomic, output, format := p.atomicOutput(job.Options, job.OutputPath)
+	defer atomic.discard(ctx)
+	if format != "" {
+		args = append(args, "-f", format)
+	}
+	args = append(args, ffmpegPath(output))
+
+	job.report(progress.StageEncode, 20, "encoding preview")
+
+	if err := p.executor.Execute(ctx, args); err != nil {
+		return nil, err
+	}
+	if err := p.finalizeOutputs(ctx, job.Options, output); err != nil {
+		return nil, err
+	}
+
+	outputMeta, err := atomic.verify(ctx, output)
+	if err != nil {
+		return nil, err
+	}
+	if err := atomic.commit(ctx); err != nil {
+		return nil, err
+	}
+
+	job.report(progress.StageDone, 100, "done")
+
+	return &model.ProcessingResult{
+		InputPath:   job.InputPath,
+		OutputPath:  job.OutputPath,
+		InputMeta:   inputMeta,
+		OutputMeta:  outputMeta,
+		Duration:    time.Since(start),
+		ProcessedAt: time.Now(),
+		Profile:     job.profile(),
+		Labels:      job.labels(),
+	}, nil
+}
+
+// resolvePreviewLength validates the preview window against the source
+// duration and clamps the clip so it doesn't run past the end
+func resolvePreviewLength(preview model.PreviewOptions, sourceDuration time.Duration) (time.Duration, error) {
+	if preview.Start < 0 {
+		return 0, pkgerrors.NewValidationError("start", preview.Start, "start must not be negative")
+	}
+	if preview.FadeIn < 0 || preview.FadeOut < 0 {
+		return 0, pkgerrors.NewValidationError("fade", preview, "fade durations must not be negative")
+	}
+
+	length := preview.Length
+	if length <= 0 {
+		length = DefaultPreviewLength
+	}
+
+	if sourceDuration > 0 {
+		if preview.Start >= sourceDuration {
+			return 0, pkgerrors.NewValidationError("start", preview.Start, "start is beyond the end of the input")
+		}
+		if remaining := sourceDuration - preview.Start; length > remaining {
+			length = remaining
+		}
+	}
+
+	if preview.FadeIn+preview.FadeOut > length {
+		return 0, pkgerrors.NewValidationError("fade", preview, "fades are longer than the preview")
+	}
+
+	return length, nil
+}
@@ -0,0 +1,44 @@
+package pipeline
+
+import "container/heap"
+
+// jobQueue orders the jobs of a batch by descending priority, keeping
+// submission order among equal priorities
+type jobQueue struct {
+	jobs []*poolJob
+	seq  map[*poolJob]int
+}
+
+func newJobQueue(jobs []*poolJob) *jobQueue {
+	q := &jobQueue{jobs: jobs, seq: make(map[*poolJob]int, len(jobs))}
+	for i, pj := range jobs {
+		q.seq[pj] = i
+	}
+	heap.Init(q)
+	return q
+}
+
+// next removes and returns the highest-priority job, or nil when empty
+func (q *jobQueue) next() *poolJob {
+	if q.Len() == 0 {
+		return nil
+	}
+	return heap.Pop(q).(*poolJob)
+}
+
+// heap.Interface
+func (q *jobQueue) Len() int { return len(q.jobs) }
+func (q *jobQueue) Less(i, j int) bool {
+	a, b := q.jobs[i], q.jobs[j]
+	if a.job.Priority != b.job.Priority {
+		return a.job.Priority > b.job.Priority
+	}
+	return q.seq[a] < q.seq[b]
+}
+func (q *jobQueue) Swap(i, j int)      { q.jobs[i], q.jobs[j] = q.jobs[j], q.jobs[i] }
+func (q *jobQueue) Push(x interface{}) { q.jobs = append(q.jobs, x.(*poolJob)) }
+func (q *jobQueue) Pop() interface{} {
+	last := q.jobs[len(q.jobs)-1]
+	q.jobs = q.jobs[:len(q.jobs)-1]
+	return last
+}
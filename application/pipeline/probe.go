@@ -0,0 +1,241 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+)
+
+// ffprobeOutput maps key fields from ffprobe JSON
+type ffprobeOutput struct {
+	Format struct {
+		Duration   string            `json:"duration"`
+		BitRate    string            `json:"bit_rate"`
+		Size       string            `json:"size"`
+		FormatName string            `json:"format_name"`
+		Tags       map[string]string `json:"tags"`
+	} `json:"format"`
+	Streams  []ffprobeStream  `json:"streams"`
+	Chapters []ffprobeChapter `json:"chapters"`
+}
+
+type ffprobeChapter struct {
+	StartTime string            `json:"start_time"`
+	EndTime   string            `json:"end_time"`
+	Tags      map[string]string `json:"tags"`
+}
+
+type ffprobeStream struct {
+	Index       int    `json:"index"`
+	CodecName   string `json:"codec_name"`
+	CodecType   string `json:"codec_type"`
+	SampleRate  string `json:"sample_rate"`
+	Channels    int    `json:"channels"`
+	BitRate     string `json:"bit_rate"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	Disposition struct {
+		Default         int `json:"default"`
+		Dub             int `json:"dub"`
+		Original        int `json:"original"`
+		Comment         int `json:"comment"`
+		Forced          int `json:"forced"`
+		HearingImpaired int `json:"hearing_impaired"`
+		VisualImpaired  int `json:"visual_impaired"`
+		AttachedPic     int `json:"attached_pic"`
+	} `json:"disposition"`
+	Tags map[string]string `json:"tags"`
+}
+
+// kind classifies a stream, separating cover art from real video
+func (s ffprobeStream) kind() model.StreamKind {
+	switch s.CodecType {
+	case "audio":
+		return model.StreamKindAudio
+	case "video":
+		if s.Disposition.AttachedPic == 1 {
+			return model.StreamKindAttachedPic
+		}
+		return model.StreamKindVideo
+	case "subtitle":
+		return model.StreamKindSubtitle
+	case "data":
+		return model.StreamKindData
+	default:
+		return model.StreamKindUnknown
+	}
+}
+
+// info converts the stream into its model form
+func (s ffprobeStream) info() model.StreamInfo {
+	d := s.Disposition
+	tags := lowerKeys(s.Tags)
+	info := model.StreamInfo{
+		Index:    s.Index,
+		Kind:     s.kind(),
+		Codec:    s.CodecName,
+		Language: tags["language"],
+		Title:    tags["title"],
+		Disposition: model.StreamDisposition{
+			Default:         d.Default == 1,
+			Original:        d.Original == 1,
+			Dub:             d.Dub == 1,
+			Comment:         d.Comment == 1,
+			Forced:          d.Forced == 1,
+			HearingImpaired: d.HearingImpaired == 1,
+			VisualImpaired:  d.VisualImpaired == 1,
+			AttachedPic:     d.AttachedPic == 1,
+		},
+	}
+	if info.Kind == model.StreamKindAudio {
+		info.Channels = s.Channels
+		fmt.Sscanf(s.SampleRate, "%d", &info.SampleRate)
+		fmt.Sscanf(s.BitRate, "%d", &info.Bitrate)
+	} else {
+		info.Width = s.Width
+		info.Height = s.Height
+	}
+	// "und" is ffmpeg's placeholder for an untagged language
+	if info.Language == "und" {
+		info.Language = ""
+	}
+	return info
+}
+
+func (p *Pipeline) probeFile(ctx context.Context, path string) (*model.AudioMetadata, error) {
+	data, err := p.executor.Probe(ctx, ffmpegPath(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var probe ffprobeOutput
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+
+	meta := &model.AudioMetadata{
+		Format: probe.Format.FormatName,
+	}
+
+	meta.Duration = parseSeconds(probe.Format.Duration)
+
+	// Parse size
+	fmt.Sscanf(probe.Format.Size, "%d", &meta.Size)
+
+	// Classify every stream; cover art shows up as a "video" stream
+	for _, s := range probe.Streams {
+		meta.Streams = append(meta.Streams, s.info())
+	}
+	_, meta.HasCoverArt = meta.CoverArt()
+	tags := lowerKeys(probe.Format.Tags)
+	if audio, ok := meta.DefaultAudioStream(); ok {
+		meta.Codec = audio.Codec
+		meta.Channels = audio.Channels
+		meta.SampleRate = audio.SampleRate
+		meta.Bitrate = audio.Bitrate
+
+		// Ogg and some Matroska files tag the stream rather than the
+		// container; container tags win
+		for _, s := range probe.Streams {
+			if s.Index != audio.Index {
+				continue
+			}
+			for k, v := range lowerKeys(s.Tags) {
+				if _, ok := tags[k]; !ok && k != "language" && k != "handler_name" && k != "vendor_id" {
+					tags[k] = v
+				}
+			}
+		}
+	}
+	meta.Tags = parseTags(tags)
+	if strings.Contains(meta.Format, "wav") {
+		meta.BWF = parseBWF(tags)
+	}
+
+	for _, c := range probe.Chapters {
+		meta.Chapters = append(meta.Chapters, model.Chapter{
+			Start: parseSeconds(c.StartTime),
+			End:   parseSeconds(c.EndTime),
+			Title: lowerKeys(c.Tags)["title"],
+		})
+	}
+
+	p.addChains(ctx, path, meta)
+
+	return meta, nil
+}
+
+// parseSeconds reads an ffprobe time in seconds, e.g. "12.345000"; invalid
+// or missing values are zero
+func parseSeconds(s string) time.Duration {
+	var sec float64
+	if _, err := fmt.Sscanf(s, "%f", &sec); err != nil {
+		return 0
+	}
+	return time.Duration(sec * float64(time.Second))
+}
+
+// lowerKeys copies tags with lower-case keys; formats disagree on case,
+// e.g. ID3 "title" and Vorbis "TITLE"
+func lowerKeys(tags map[string]string) map[string]string {
+	out := make(map[string]string, len(tags))
+	for k, v := range tags {
+		out[strings.ToLower(k)] = v
+	}
+	return out
+}
+
+// parseTags maps lower-cased container tags onto model.Tags
+func parseTags(raw map[string]string) model.Tags {
+	first := func(keys ...string) string {
+		for _, k := range keys {
+			if v := strings.TrimSpace(raw[k]); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	t := model.Tags{
+		Title:       first("title"),
+		Artist:      first("artist"),
+		Album:       first("album"),
+		AlbumArtist: first("album_artist", "albumartist"),
+		Date:        first("date", "year"),
+		Genre:       first("genre"),
+		Composer:    first("composer"),
+		Comment:     first("comment", "description"),
+		Encoder:     first("encoder", "encoded_by"),
+
+		ISRC:          first("isrc", "tsrc"),
+		Barcode:       first("barcode", "upc", "ean"),
+		CatalogNumber: first("catalognumber", "catalog_number", "catalog"),
+	}
+	t.Track, t.TrackTotal = parsePosition(first("track", "tracknumber"), first("tracktotal", "totaltracks"))
+	t.Disc, t.DiscTotal = parsePosition(first("disc", "discnumber"), first("disctotal", "totaldiscs"))
+	if len(raw) > 0 {
+		t.Raw = raw
+	}
+	return t
+}
+
+// parsePosition reads "3" or "3/12" style track and disc numbers; total
+// is the separate total tag, used when the number has none
+func parsePosition(number, total string) (int, int) {
+	n, of, _ := strings.Cut(number, "/")
+	pos, _ := strconv.Atoi(strings.TrimSpace(n))
+	count, _ := strconv.Atoi(strings.TrimSpace(of))
+	if count == 0 {
+		count, _ = strconv.Atoi(total)
+	}
+	return pos, count
+}
+
+// ProbeFile probes audio metadata for a path.
+func (p *Pipeline) ProbeFile(ctx context.Context, path string) (*model.AudioMetadata, error) {
+	return p.probeFile(ctx, path)
+}
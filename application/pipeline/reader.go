@@ -0,0 +1,214 @@
+package pipeline
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"github.com/Skryldev/audio-lab/pkg/progress"
+	"go.uber.org/zap"
+)
+
+const (
+	// pipeInput is the ffmpeg URL for reading the input from stdin
+	pipeInput = model.PlanPipeInput
+
+	// pipeOutput is the ffmpeg URL for writing the output to stdout
+	pipeOutput = model.PlanPipeOutput
+)
+
+// streamableFormats are muxers that never need to seek back in their output
+var streamableFormats = map[string]bool{
+	"ogg":      true,
+	"opus":     true,
+	"mp3":      true,
+	"adts":     true,
+	"flac":     true,
+	"webm":     true,
+	"matroska": true,
+}
+
+// streamFormatForCodec picks a streamable container for a codec
+func streamFormatForCodec(codec model.Codec) (string, error) {
+	switch codec {
+	case model.CodecOpus:
+		return "ogg", nil
+	case model.CodecMP3:
+		return "mp3", nil
+	case model.CodecAAC:
+		return "adts", nil
+	case model.CodecPCM16, model.CodecPCM24:
+		// The header's sizes are left unset when the output isn't seekable
+		return "wav", nil
+	default:
+		return "", pkgerrors.NewValidationError("codec", codec, "no streamable container for codec")
+	}
+}
+
+// RunReader executes the pipeline reading the input from r via ffmpeg's
+// stdin. The input cannot be probed up front, so InputMeta stays empty.
+func (p *Pipeline) RunReader(ctx context.Context, job *Job, r io.Reader) (*model.ProcessingResult, error) {
+	start := time.Now()
+
+	if r == nil {
+		return nil, pkgerrors.NewValidationError("reader", nil, "reader must not be nil")
+	}
+	if job.OutputPath == "" {
+		return nil, pkgerrors.NewValidationError("outputPath", "", "output path must not be empty")
+	}
+	if isStreamingOutput(job.OutputPath) {
+		return nil, pkgerrors.NewValidationError("outputPath", job.OutputPath, "streaming outputs are not supported with reader input")
+	}
+	job.InputPath = pipeInput
+	if err := validateOptions(job); err != nil {
+		return nil, err
+	}
+	job, err := p.loadLyrics(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.prepareOutputs(ctx, job.Options, outputPaths(job)...); err != nil {
+		return nil, err
+	}
+
+	enc, atomic := p.atomicJob(job)
+	defer atomic.discard(ctx)
+
+	plan, err := buildPlan(enc)
+	if err != nil {
+		return nil, err
+	}
+
+	job.report(progress.StageEncode, 20, "encoding started")
+
+	stderr, err := p.executor.ExecutePiped(ctx, plan.Args(), r, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.writeLyrics(ctx, enc); err != nil {
+		return nil, err
+	}
+	if err := p.finalizeOutputs(ctx, job.Options, outputPaths(enc)...); err != nil {
+		return nil, err
+	}
+
+	var loudness *model.LoudnessReport
+	if job.Options.NormalizationEnabled {
+		loudness, err = buildLoudnessReport(stderr, job.Options.LoudnessTarget)
+		if err != nil {
+			p.log.Warn("failed to parse loudness report", zap.Error(err))
+		}
+	}
+
+	job.report(progress.StageEncode, 90, "encoding complete")
+
+	outputMeta, err := atomic.verify(ctx, enc.OutputPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, out := range enc.Options.AdditionalOutputs {
+		if isStreamingOutput(out.Path) {
+			continue
+		}
+		if _, err := atomic.verify(ctx, out.Path); err != nil {
+			return nil, err
+		}
+	}
+	if err := atomic.commit(ctx); err != nil {
+		return nil, err
+	}
+
+	job.report(progress.StageDone, 100, "done")
+
+	return &model.ProcessingResult{
+		InputPath:   pipeInput,
+		OutputPath:  job.OutputPath,
+		InputMeta:   &model.AudioMetadata{},
+		OutputMeta:  outputMeta,
+		Duration:    time.Since(start),
+		ProcessedAt: time.Now(),
+		Profile:     job.profile(),
+		Labels:      job.labels(),
+		Loudness:    loudness,
+	}, nil
+}
+
+// RunWriter executes the pipeline writing the encoded output to w via
+// ffmpeg's stdout. Only streamable containers are accepted.
+func (p *Pipeline) RunWriter(ctx context.Context, job *Job, w io.Writer) (*model.ProcessingResult, error) {
+	start := time.Now()
+
+	if w == nil {
+		return nil, pkgerrors.NewValidationError("writer", nil, "writer must not be nil")
+	}
+	if len(job.Options.AdditionalOutputs) > 0 {
+		return nil, pkgerrors.NewValidationError("additionalOutputs", len(job.Options.AdditionalOutputs), "additional outputs are not supported with writer output")
+	}
+
+	format := job.Options.OutputFormat
+	if format == "" {
+		var err error
+		if format, err = streamFormatForCodec(job.Options.Codec); err != nil {
+			return nil, err
+		}
+	}
+	if !streamableFormats[format] {
+		return nil, pkgerrors.NewValidationError("outputFormat", format, "container is not streamable")
+	}
+	opts := *job.Options
+	opts.OutputFormat = format
+	job.Options = &opts
+
+	job.OutputPath = pipeOutput
+	if err := p.validateInput(ctx, job); err != nil {
+		return nil, err
+	}
+
+	inputMeta, err := p.probeFile(ctx, job.InputPath)
+	if err != nil {
+		return nil, pkgerrors.NewProcessingError("probe", "failed to probe input file", err)
+	}
+	job.report(progress.StageProbe, 5, "input probed")
+	if job, err = resolveJob(job, inputMeta); err != nil {
+		return nil, err
+	}
+	if job, err = p.loadLyrics(ctx, job); err != nil {
+		return nil, err
+	}
+
+	plan, err := buildPlan(job)
+	if err != nil {
+		return nil, err
+	}
+
+	job.report(progress.StageEncode, 20, "encoding started")
+
+	stderr, err := p.executor.ExecutePiped(ctx, plan.Args(), nil, w)
+	if err != nil {
+		return nil, err
+	}
+
+	var loudness *model.LoudnessReport
+	if job.Options.NormalizationEnabled {
+		loudness, err = buildLoudnessReport(stderr, job.Options.LoudnessTarget)
+		if err != nil {
+			p.log.Warn("failed to parse loudness report", zap.Error(err))
+		}
+	}
+
+	job.report(progress.StageDone, 100, "done")
+
+	return &model.ProcessingResult{
+		InputPath:   job.InputPath,
+		OutputPath:  pipeOutput,
+		InputMeta:   inputMeta,
+		OutputMeta:  &model.AudioMetadata{Format: format},
+		Duration:    time.Since(start),
+		ProcessedAt: time.Now(),
+		Profile:     job.profile(),
+		Labels:      job.labels(),
+		Loudness:    loudness,
+	}, nil
+}
@@ -0,0 +1,44 @@
+package pipeline
+
+import (
+	"context"
+	"strings"
+
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+)
+
+// IsRemoteInput reports whether path is an http(s) URL that ffmpeg reads
+// directly, e.g. a presigned object-store URL
+func IsRemoteInput(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// remoteInputArgs returns input options that let ffmpeg resume an HTTP
+// download after a dropped connection
+func remoteInputArgs(path string) []string {
+	if !IsRemoteInput(path) {
+		return nil
+	}
+	return []string{
+		"-reconnect", "1",
+		"-reconnect_streamed", "1",
+		"-reconnect_on_network_error", "1",
+		"-reconnect_delay_max", "10",
+	}
+}
+
+// checkInputExists verifies a local input exists. Remote inputs are
+// checked by ffmpeg/ffprobe when they are opened.
+func (p *Pipeline) checkInputExists(ctx context.Context, path string) error {
+	if IsRemoteInput(path) {
+		return nil
+	}
+	exists, err := p.storage.Exists(ctx, path)
+	if err != nil {
+		return pkgerrors.NewProcessingError("validate", "failed to check input file", err)
+	}
+	if !exists {
+		return pkgerrors.NewValidationError("inputPath", path, "input file does not exist")
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"github.com/Skryldev/audio-lab/pkg/retry"
+	"go.uber.org/zap"
+)
+
+// RetryConfig returns the retry configuration set by opts, filling in
+// defaults for unset values
+func RetryConfig(opts *model.ProcessingOptions) retry.Config {
+	cfg := retry.Config{
+		MaxAttempts: opts.MaxRetries,
+		Delay:       opts.RetryDelay,
+		Multiplier:  opts.RetryMultiplier,
+		MaxDelay:    opts.RetryMaxDelay,
+		Jitter:      opts.RetryJitter,
+	}
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.Multiplier < 1 {
+		cfg.Multiplier = 2.0
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = 30 * time.Second
+	}
+	return cfg
+}
+
+// runWithRetry runs job, retrying failed attempts as configured by its
+// options, and returns the history of the failed attempts
+func (wp *WorkerPool) runWithRetry(ctx context.Context, job *Job) (*model.ProcessingResult, []model.JobAttempt, error) {
+	var (
+		result    *model.ProcessingResult
+		history   []model.JobAttempt
+		permanent error
+	)
+	cfg := RetryConfig(job.Options)
+	if StreamsLive(job.OutputPath, job.Options) {
+		// runStreaming reconnects instead
+		cfg.MaxAttempts = 1
+	}
+	cfg.OnRetry = func(attempt int, err error) {
+		wp.pipeline.Retrying(ctx, job, attempt, err)
+	}
+	err := retry.Do(ctx, cfg, func() error {
+		start := time.Now()
+		var err error
+		result, err = wp.pipeline.Run(ctx, job)
+		if err == nil {
+			return nil
+		}
+		history = append(history, model.JobAttempt{
+			Attempt:   len(history) + 1,
+			StartedAt: start,
+			Elapsed:   time.Since(start),
+			Error:     err.Error(),
+		})
+		// A shutdown drains running attempts but starts no new ones
+		if !retryable(ctx, err) || wp.shuttingDown() {
+			permanent = err
+			return nil // stop retrying
+		}
+		return err
+	})
+	if permanent != nil {
+		err = permanent
+	}
+	return result, history, err
+}
+
+// retryable reports whether a failed attempt is worth repeating
+func retryable(ctx context.Context, err error) bool {
+	return ctx.Err() == nil && pkgerrors.IsRetryable(err)
+}
+
+// deadLetter records a job that failed for good in the dead-letter store
+func (wp *WorkerPool) deadLetter(ctx context.Context, job model.BatchJob, history []model.JobAttempt, err error) {
+	// Canceled, aborted and interrupted jobs didn't fail on their own
+	if wp.deadLetters == nil || ctx.Err() != nil || pkgerrors.CodeOf(err) == pkgerrors.ErrCodeCanceled {
+		return
+	}
+	letter := model.DeadLetter{
+		Job:       job,
+		Error:     err.Error(),
+		ErrorCode: string(pkgerrors.CodeOf(err)),
+		Attempts:  history,
+		FailedAt:  time.Now(),
+	}
+	if err := wp.deadLetters.Add(ctx, letter); err != nil {
+		wp.log.Warn("failed to record dead letter",
+			zap.String("job_id", job.ID),
+			zap.Error(err),
+		)
+	}
+}
@@ -0,0 +1,94 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+)
+
+// Begin registers a single-file job running outside Run, so that Cancel
+// can stop it and Shutdown waits for it. The job must run on the returned
+// context and pass its error to end when it finishes; end returns the
+// error to report, with the cancellation cause if the job was stopped. It
+// fails with ErrShuttingDown once Shutdown was called.
+func (wp *WorkerPool) Begin(ctx context.Context, jobID string) (jobCtx context.Context, end func(error) error, err error) {
+	tracked, err := wp.track(ctx, model.BatchJob{ID: jobID})
+	if err != nil {
+		return ctx, nil, err
+	}
+	pj := tracked[0]
+	wp.start(pj)
+	return pj.ctx, func(err error) error {
+		err = pj.canceled(err)
+		wp.untrack(pj)
+		return err
+	}, nil
+}
+
+// shuttingDown reports whether Shutdown was called
+func (wp *WorkerPool) shuttingDown() bool {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	return wp.closing
+}
+
+// Ping checks that the pool accepts work. It fails while shutting down,
+// or when no encode slot frees up before ctx is done, as happens when the
+// pool is saturated or its jobs are stuck. The probe takes precedence over
+// queued jobs, so it only waits for a running job to finish.
+func (wp *WorkerPool) Ping(ctx context.Context) error {
+	if wp.shuttingDown() {
+		return pkgerrors.ErrShuttingDown
+	}
+	if err := wp.lane.AcquirePriority(ctx, math.MaxInt); err != nil {
+		return fmt.Errorf("worker pool unresponsive: no encode slot freed up: %w", err)
+	}
+	wp.lane.Release()
+	return nil
+}
+
+// Shutdown stops the pool accepting jobs and fails the queued ones with
+// ErrShuttingDown, then waits for running jobs to finish. When ctx is done
+// first, the remaining jobs are canceled and Shutdown returns ctx's error
+// once they have exited.
+func (wp *WorkerPool) Shutdown(ctx context.Context) error {
+	wp.mu.Lock()
+	if wp.stopTuning != nil && !wp.closing {
+		close(wp.stopTuning)
+	}
+	wp.closing = true
+	for _, jobs := range wp.running {
+		for pj := range jobs {
+			if !pj.started {
+				pj.cancel(pkgerrors.ErrShuttingDown)
+			}
+		}
+	}
+	wp.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		wp.active.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+	}
+
+	wp.log.Warn("shutdown deadline reached, canceling running jobs")
+	wp.mu.Lock()
+	for _, jobs := range wp.running {
+		for pj := range jobs {
+			pj.cancel(pkgerrors.ErrShuttingDown)
+		}
+	}
+	wp.mu.Unlock()
+	<-drained
+	return ctx.Err()
+}
@@ -0,0 +1,151 @@
+package pipeline
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/domain/ports"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"github.com/Skryldev/audio-lab/pkg/progress"
+	"go.uber.org/zap"
+)
+
+// fingerprintVersion changes whenever the fingerprint layout does, so old
+// records never match
+const fingerprintVersion = "v1"
+
+// SetFingerprintStore installs the store used by model.SkipIfFingerprint
+func (p *Pipeline) SetFingerprintStore(s ports.FingerprintStore) {
+	p.fingerprints = s
+}
+
+// checkUpToDate applies the job's SkipPolicy. For SkipIfFingerprint it
+// also returns the job's current fingerprint so it can be recorded after
+// a successful run.
+func (p *Pipeline) checkUpToDate(ctx context.Context, job *Job) (fingerprint string, skip bool, err error) {
+	policy := job.Options.SkipPolicy
+	if policy == model.SkipNever || IsRemoteInput(job.InputPath) || StreamsLive(job.OutputPath, job.Options) {
+		return "", false, nil
+	}
+	if err := ValidateJob(job); err != nil {
+		return "", false, err
+	}
+
+	outputs := outputPaths(job)
+	for _, out := range outputs {
+		exists, err := p.storage.Exists(ctx, out)
+		if err != nil {
+			return "", false, pkgerrors.NewProcessingError("skip", "failed to check output", err)
+		}
+		if !exists {
+			skip = false
+			break
+		}
+		skip = true
+	}
+
+	switch policy {
+	case model.SkipIfNewer:
+		if !skip {
+			return "", false, nil
+		}
+		inputTime, err := p.storage.ModTime(ctx, job.InputPath)
+		if err != nil {
+			return "", false, pkgerrors.NewProcessingError("skip", "failed to stat input", err)
+		}
+		for _, out := range outputs {
+			outTime, err := p.storage.ModTime(ctx, out)
+			if err != nil {
+				return "", false, pkgerrors.NewProcessingError("skip", "failed to stat output", err)
+			}
+			if !outTime.After(inputTime) {
+				return "", false, nil
+			}
+		}
+		return "", true, nil
+
+	case model.SkipIfFingerprint:
+		if p.fingerprints == nil {
+			return "", false, pkgerrors.NewValidationError("skipPolicy", policy, "no fingerprint store configured")
+		}
+		fingerprint, err = p.fingerprint(ctx, job)
+		if err != nil {
+			return "", false, err
+		}
+		if !skip {
+			return fingerprint, false, nil
+		}
+		for _, out := range outputs {
+			recorded, ok, err := p.fingerprints.Get(ctx, out)
+			if err != nil {
+				return "", false, pkgerrors.NewProcessingError("skip", "failed to read fingerprint", err)
+			}
+			if !ok || recorded != fingerprint {
+				return fingerprint, false, nil
+			}
+		}
+		return fingerprint, true, nil
+
+	default:
+		return "", false, pkgerrors.NewValidationError("skipPolicy", policy, "unknown skip policy")
+	}
+}
+
+// fingerprint hashes the input content together with the ffmpeg command
+// that would produce the outputs, so any option change invalidates it
+func (p *Pipeline) fingerprint(ctx context.Context, job *Job) (string, error) {
+	hasher, ok := p.provider.(interface {
+		Checksum(ctx context.Context, path string) (string, error)
+	})
+	if !ok {
+		return "", pkgerrors.NewValidationError("skipPolicy", model.SkipIfFingerprint, "storage provider does not support checksums")
+	}
+	sum, err := hasher.Checksum(ctx, job.InputPath)
+	if err != nil {
+		return "", pkgerrors.NewProcessingError("skip", "failed to hash input", err)
+	}
+	plan, err := buildPlan(job)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(fingerprintVersion + "\n" + sum + "\n"))
+	h.Write([]byte(strings.Join(plan.Args(), "\x00")))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// recordFingerprint stores fingerprint for every output of job
+func (p *Pipeline) recordFingerprint(ctx context.Context, job *Job, fingerprint string) {
+	for _, out := range outputPaths(job) {
+		if err := p.fingerprints.Put(ctx, out, fingerprint); err != nil {
+			p.log.Warn("failed to record fingerprint", zap.String("output", out), zap.Error(err))
+		}
+	}
+}
+
+// skippedResult describes a job whose outputs were already up to date
+func (p *Pipeline) skippedResult(ctx context.Context, job *Job) *model.ProcessingResult {
+	outputMeta, err := p.probeFile(ctx, job.OutputPath)
+	if err != nil {
+		p.log.Warn("failed to probe output file", zap.Error(err))
+		outputMeta = &model.AudioMetadata{}
+	}
+
+	job.report(progress.StageDone, 100, "outputs up to date, skipped")
+
+	return &model.ProcessingResult{
+		InputPath:   job.InputPath,
+		OutputPath:  job.OutputPath,
+		InputMeta:   &model.AudioMetadata{},
+		OutputMeta:  outputMeta,
+		ProcessedAt: time.Now(),
+		Skipped:     true,
+		Profile:     job.profile(),
+		Labels:      job.labels(),
+	}
+}
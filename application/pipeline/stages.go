@@ -0,0 +1,65 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+)
+
+// Use registers a custom stage run after validation and before the input
+// is probed, e.g. a virus scan or an external API call. Stages run in
+// registration order, traced and reported to hooks under name, and a
+// failing stage fails the job. Register stages before processing starts.
+func (p *Pipeline) Use(name string, stage Stage) {
+	p.stages = append(p.stages, namedStage{name: name, stage: stage})
+}
+
+// UseAfter registers a custom stage run once the outputs are encoded and
+// verified but before they are moved into place, e.g. watermarking. The
+// job it receives points at the files being written.
+func (p *Pipeline) UseAfter(name string, stage Stage) {
+	p.afterStages = append(p.afterStages, namedStage{name: name, stage: stage})
+}
+
+// runStages runs custom stages in order, stopping at the first failure
+func (p *Pipeline) runStages(ctx context.Context, job *Job, stages []namedStage) error {
+	for _, s := range stages {
+		if err := p.runStage(ctx, job, s.name, s.stage); err != nil {
+			return fmt.Errorf("stage %s: %w", s.name, err)
+		}
+	}
+	return nil
+}
+
+// StageMiddleware wraps every pipeline stage, built-in and custom, e.g. to
+// add timing, logging or tracing once for all of them. StageName returns
+// the name of the wrapped stage from its context.
+type StageMiddleware func(next Stage) Stage
+
+// stageNameKey is the context key of the running stage's name
+type stageNameKey struct{}
+
+// StageName returns the name of the stage running with ctx, or "" outside
+// a stage
+func StageName(ctx context.Context) string {
+	name, _ := ctx.Value(stageNameKey{}).(string)
+	return name
+}
+
+// UseMiddleware adds stage middleware. The first middleware added is the
+// outermost. Add middleware before processing starts.
+func (p *Pipeline) UseMiddleware(mw ...StageMiddleware) {
+	p.middleware = append(p.middleware, mw...)
+}
+
+// runStage runs one stage through the middleware chain, traced and
+// reported to hooks
+func (p *Pipeline) runStage(ctx context.Context, job *Job, name string, stage Stage) error {
+	ctx, st := p.startStage(ctx, job, name)
+	ctx = context.WithValue(ctx, stageNameKey{}, name)
+	for i := len(p.middleware) - 1; i >= 0; i-- {
+		stage = p.middleware[i](stage)
+	}
+	err := stage(ctx, job)
+	st.end(err)
+	return err
+}
@@ -0,0 +1,171 @@
+package pipeline
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/domain/ports"
+	"github.com/Skryldev/audio-lab/infrastructure/storage"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"github.com/Skryldev/audio-lab/pkg/progress"
+	"go.uber.org/zap"
+)
+
+// remoteFor returns the remote provider responsible for path, resolving
+// routers, and the path to pass it. ok is false for paths ffmpeg can open,
+// in which case routed is the path to give ffmpeg.
+func (p *Pipeline) remoteFor(path string) (remote ports.RemoteStorage, routed string, ok bool) {
+	if IsRemoteInput(path) || isStreamingOutput(path) {
+		return nil, path, false
+	}
+	provider, routed := p.provider, path
+	if r, isRouter := provider.(ports.StorageRouter); isRouter {
+		var err error
+		if provider, routed, err = r.Route(path); err != nil {
+			return nil, path, false
+		}
+	}
+	remote, ok = provider.(ports.RemoteStorage)
+	return remote, routed, ok
+}
+
+// needsStaging reports whether the job's input or any output lives on
+// remote storage
+func (p *Pipeline) needsStaging(job *Job) bool {
+	if _, _, ok := p.remoteFor(job.InputPath); ok {
+		return true
+	}
+	for _, path := range outputPaths(job) {
+		if _, _, ok := p.remoteFor(path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// runStaged downloads remote inputs into local temp files, runs the job
+// locally and uploads remote outputs. Uploaded outputs are removed again if
+// a later upload fails.
+func (p *Pipeline) runStaged(ctx context.Context, job *Job) (*model.ProcessingResult, error) {
+	if err := p.validateInput(ctx, job); err != nil {
+		return nil, err
+	}
+
+	// Staging files are released by Run
+	tempFor := func(path string) (string, error) {
+		return p.AllocateTemp(job, filepath.Ext(path))
+	}
+
+	staged := *job
+	opts := *job.Options
+	staged.Options = &opts
+	// permissions and ownership are applied to the uploaded outputs instead
+	opts.FileMode = 0
+	opts.Owner = nil
+	// staging files are private, so they are written in place
+	opts.DirectOutputWrites = true
+
+	remote, path, ok := p.remoteFor(job.InputPath)
+	staged.InputPath = path
+	if ok {
+		if size, err := remote.Size(ctx, path); err == nil {
+			if err := p.checkTempSpace(ctx, size); err != nil {
+				return nil, err
+			}
+		}
+		job.report(progress.StageDownload, 0, "downloading input")
+		local, err := tempFor(job.InputPath)
+		if err != nil {
+			return nil, pkgerrors.NewProcessingError("download", "failed to create staging file", err)
+		}
+		err = p.runStage(ctx, job, "download", func(ctx context.Context, _ *Job) error {
+			return remote.Download(ctx, path, local)
+		})
+		if err != nil {
+			return nil, pkgerrors.NewProcessingError("download", "failed to download input", err)
+		}
+		staged.InputPath = local
+	}
+
+	// uploads pairs each remote output with its local staging file
+	type upload struct {
+		local  string
+		path   string // as given by the caller
+		remote ports.RemoteStorage
+		routed string // as passed to remote
+	}
+	var uploads []upload
+	stage := func(path string) (string, error) {
+		remote, routed, ok := p.remoteFor(path)
+		if !ok {
+			return routed, nil
+		}
+		local, err := tempFor(path)
+		if err != nil {
+			return "", pkgerrors.NewProcessingError("upload", "failed to create staging file", err)
+		}
+		uploads = append(uploads, upload{local, path, remote, routed})
+		return local, nil
+	}
+
+	var err error
+	if staged.OutputPath, err = stage(job.OutputPath); err != nil {
+		return nil, err
+	}
+	opts.AdditionalOutputs = make([]model.AdditionalOutput, len(job.Options.AdditionalOutputs))
+	for i, out := range job.Options.AdditionalOutputs {
+		if out.Path, err = stage(out.Path); err != nil {
+			return nil, err
+		}
+		opts.AdditionalOutputs[i] = out
+	}
+
+	// The local run's own "done" update is held back until uploads finish
+	staged.Reporter = progress.ReporterFunc(func(u progress.Update) {
+		if u.Stage != progress.StageDone {
+			job.reportUpdate(u)
+		}
+	})
+
+	localPipeline := *p
+	local := storage.NewLocalStorage()
+	localPipeline.storage = local
+	localPipeline.provider = local
+	result, err := localPipeline.run(ctx, &staged)
+	if err != nil {
+		return nil, err
+	}
+
+	job.report(progress.StageUpload, 95, "uploading outputs")
+	err = p.runStage(ctx, job, "upload", func(sctx context.Context, _ *Job) error {
+		for i, u := range uploads {
+			if err := u.remote.Upload(sctx, u.local, u.routed); err != nil {
+				for _, done := range uploads[:i] {
+					if rmErr := done.remote.Remove(ctx, done.routed); rmErr != nil {
+						p.log.Warn("failed to remove uploaded output", zap.String("output", done.path), zap.Error(rmErr))
+					}
+				}
+				return pkgerrors.NewProcessingError("upload", "failed to upload "+u.path, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.finalizeOutputs(ctx, job.Options, outputPaths(job)...); err != nil {
+		return nil, err
+	}
+
+	result.InputPath = job.InputPath
+	result.OutputPath = job.OutputPath
+	for i, r := range result.AdditionalOutputs {
+		r.InputPath = job.InputPath
+		r.OutputPath = job.Options.AdditionalOutputs[i].Path
+	}
+
+	job.report(progress.StageDone, 100, "done")
+	return result, nil
+}
@@ -0,0 +1,120 @@
+package pipeline
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+)
+
+// StatsTracker accumulates runtime statistics of the jobs run by a worker
+// pool and by single-file calls sharing it. It is safe for concurrent use.
+type StatsTracker struct {
+	mu        sync.Mutex
+	inFlight  int
+	queued    int
+	completed int64
+	failed    int64
+	skipped   int64
+	bytesIn   int64
+	bytesOut  int64
+	audio     time.Duration // input duration of encoded jobs
+	wall      time.Duration // time spent encoding them
+	byCodec   map[model.Codec]int64
+}
+
+func newStatsTracker() *StatsTracker {
+	return &StatsTracker{byCodec: make(map[model.Codec]int64)}
+}
+
+// JobQueued counts a batch job waiting for a worker
+func (t *StatsTracker) JobQueued() {
+	t.mu.Lock()
+	t.queued++
+	t.mu.Unlock()
+}
+
+// JobDequeued counts a batch job leaving the queue, started or not
+func (t *StatsTracker) JobDequeued() {
+	t.mu.Lock()
+	t.queued--
+	t.mu.Unlock()
+}
+
+// JobStarted counts a job that starts processing
+func (t *StatsTracker) JobStarted() {
+	t.mu.Lock()
+	t.inFlight++
+	t.mu.Unlock()
+}
+
+// JobFinished records the outcome of a job started with JobStarted
+func (t *StatsTracker) JobFinished(opts *model.ProcessingOptions, result *model.ProcessingResult, err error, elapsed time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.inFlight--
+	switch {
+	case err != nil:
+		t.failed++
+	case result != nil && result.Skipped:
+		t.skipped++
+	default:
+		t.completed++
+		if opts != nil {
+			t.byCodec[opts.Codec]++
+		}
+		if result == nil {
+			return
+		}
+		if result.InputMeta != nil {
+			t.bytesIn += result.InputMeta.Size
+			t.audio += result.InputMeta.Duration
+			t.wall += elapsed
+		}
+		t.bytesOut += resultBytes(result)
+	}
+}
+
+// Snapshot returns the current statistics
+func (t *StatsTracker) Snapshot() model.Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := model.Stats{
+		InFlight:  t.inFlight,
+		Queued:    t.queued,
+		Completed: t.completed,
+		Failed:    t.failed,
+		Skipped:   t.skipped,
+		BytesIn:   t.bytesIn,
+		BytesOut:  t.bytesOut,
+		ByCodec:   make(map[model.Codec]int64, len(t.byCodec)),
+	}
+	if t.wall > 0 {
+		s.AverageSpeed = t.audio.Seconds() / t.wall.Seconds()
+	}
+	for c, n := range t.byCodec {
+		s.ByCodec[c] = n
+	}
+	return s
+}
+
+// encodedAudio returns the input duration of all jobs encoded so far
+func (t *StatsTracker) encodedAudio() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.audio
+}
+
+// resultBytes sums the output sizes of result and its additional outputs
+func resultBytes(result *model.ProcessingResult) int64 {
+	var n int64
+	if result.OutputMeta != nil {
+		n += result.OutputMeta.Size
+	}
+	for _, extra := range result.AdditionalOutputs {
+		n += resultBytes(extra)
+	}
+	return n
+}
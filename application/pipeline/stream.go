@@ -0,0 +1,137 @@
+package pipeline
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/internal/argfmt"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// isStreamingOutput reports whether path is a live streaming sink rather
+// than a file
+func isStreamingOutput(path string) bool {
+	return strings.HasPrefix(path, "icecast://") ||
+		strings.HasPrefix(path, "rtmp://") ||
+		strings.HasPrefix(path, "rtmps://")
+}
+
+// StreamsLive reports whether outputPath or one of the additional outputs
+// in opts is a live streaming sink
+func StreamsLive(outputPath string, opts *model.ProcessingOptions) bool {
+	if isStreamingOutput(outputPath) {
+		return true
+	}
+	for _, out := range opts.AdditionalOutputs {
+		if isStreamingOutput(out.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// streamMuxerArgs returns the muxer flags required by a streaming sink
+func streamMuxerArgs(path string, codec model.Codec) ([]string, error) {
+	if strings.HasPrefix(path, "icecast://") {
+		switch codec {
+		case model.CodecOpus:
+			return []string{"-f", "ogg", "-content_type", "application/ogg"}, nil
+		case model.CodecMP3:
+			return []string{"-f", "mp3", "-content_type", "audio/mpeg"}, nil
+		case model.CodecAAC:
+			return []string{"-f", "adts", "-content_type", "audio/aac"}, nil
+		}
+		return nil, pkgerrors.NewValidationError("codec", codec, "codec is not supported for icecast output")
+	}
+
+	// RTMP carries FLV, which only supports AAC and MP3 audio
+	switch codec {
+	case model.CodecAAC, model.CodecMP3:
+		return []string{"-f", "flv"}, nil
+	}
+	return nil, pkgerrors.NewValidationError("codec", codec, "RTMP output requires AAC or MP3")
+}
+
+// runStreaming runs the encode against a live sink, reconnecting after
+// failures until the attempts are exhausted or ctx is canceled. Each
+// reconnect resumes the input at the position streamed so far rather than
+// replaying it from the start, unless the job also writes files, which
+// have to hold the whole input.
+func (p *Pipeline) runStreaming(ctx context.Context, job *Job, total time.Duration) ([]byte, error) {
+	attempts := job.Options.StreamReconnectAttempts
+	delay := job.Options.StreamReconnectDelay
+	if delay <= 0 {
+		delay = 2 * time.Second
+	}
+
+	var (
+		lastErr error
+		pos     time.Duration // of the output streamed so far
+	)
+	for attempt := 0; attempt <= attempts; attempt++ {
+		if attempt > 0 {
+			p.log.Warn("stream disconnected, reconnecting",
+				zap.String("job_id", job.ID),
+				zap.Int("attempt", attempt),
+				zap.Error(lastErr),
+			)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		run, base := job, pos
+		if !onlyLive(job) {
+			base = 0
+		} else if pos > 0 {
+			run = resumeJob(job, pos)
+		}
+		stderr, err := p.runFFmpeg(ctx, run, run.encodeProgressFrom(total, base, func(t time.Duration) {
+			pos = base + t
+		}))
+		if err == nil {
+			return stderr, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		if _, ok := pkgerrors.As[*pkgerrors.ValidationError](err); ok {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// onlyLive reports whether every output of job is a live streaming sink
+func onlyLive(job *Job) bool {
+	if !isStreamingOutput(job.OutputPath) {
+		return false
+	}
+	for _, out := range job.Options.AdditionalOutputs {
+		if !isStreamingOutput(out.Path) {
+			return false
+		}
+	}
+	return true
+}
+
+// resumeJob returns a copy of job that starts at pos into its output,
+// seeking the input past what was already streamed
+func resumeJob(job *Job, pos time.Duration) *Job {
+	opts := *job.Options
+	seek := pos - opts.LeadingPadding
+	opts.LeadingPadding = max(opts.LeadingPadding-pos, 0)
+	if seek > 0 {
+		opts.ExtraInputArgs = append(slices.Clip(opts.ExtraInputArgs), "-ss", argfmt.Seconds(seek))
+	}
+	j := *job
+	j.Options = &opts
+	return &j
+}
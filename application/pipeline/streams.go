@@ -0,0 +1,89 @@
+package pipeline
+
+import (
+	"strings"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/internal/argfmt"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+)
+
+// audioStreamSpec returns the ffmpeg stream specifier of the audio stream
+// selected by the options, or "" to let ffmpeg choose. Jobs that probed
+// their input have it pinned by index; the language specifier only
+// remains for unprobed ones like dry runs.
+func audioStreamSpec(opts *model.ProcessingOptions) string {
+	switch {
+	case opts.AudioStream != nil:
+		return "0:" + argfmt.Int(*opts.AudioStream)
+	case opts.AudioLanguage != "":
+		return "0:a:m:language:" + opts.AudioLanguage
+	}
+	return ""
+}
+
+// resolveJob returns the job with the options that depend on the probed
+// input settled: the audio stream to encode and the end of the chapters
+func resolveJob(job *Job, meta *model.AudioMetadata) (*Job, error) {
+	job, err := selectAudioStream(job, meta)
+	if err != nil {
+		return nil, err
+	}
+	return fillChapterEnds(job, meta.Duration), nil
+}
+
+// selectAudioStream resolves the job's audio stream against the probed
+// input and returns a copy of job with it pinned by index. meta is updated
+// to describe the selected stream. Inputs probed without stream details
+// are left to ffmpeg.
+func selectAudioStream(job *Job, meta *model.AudioMetadata) (*Job, error) {
+	if len(meta.Streams) == 0 {
+		return job, nil
+	}
+	opts := job.Options
+
+	var (
+		stream model.StreamInfo
+		found  bool
+	)
+	switch {
+	case opts.AudioStream != nil:
+		for _, s := range meta.Streams {
+			if s.Index == *opts.AudioStream {
+				stream, found = s, true
+				break
+			}
+		}
+		if !found || stream.Kind != model.StreamKindAudio {
+			return nil, pkgerrors.NewValidationError("audioStream", *opts.AudioStream, "input has no audio stream at this index")
+		}
+	case opts.AudioLanguage != "":
+		for _, s := range meta.AudioStreams() {
+			if !strings.EqualFold(s.Language, opts.AudioLanguage) {
+				continue
+			}
+			if !found || (s.Disposition.Default && !stream.Disposition.Default) {
+				stream, found = s, true
+			}
+		}
+		if !found {
+			return nil, pkgerrors.NewValidationError("audioLanguage", opts.AudioLanguage, "input has no audio stream in this language")
+		}
+	default:
+		if stream, found = meta.DefaultAudioStream(); !found {
+			return nil, pkgerrors.NewValidationError("inputPath", job.InputPath, "input has no audio stream")
+		}
+	}
+
+	meta.Codec = stream.Codec
+	meta.SampleRate = stream.SampleRate
+	meta.Channels = stream.Channels
+	meta.Bitrate = stream.Bitrate
+
+	pinned := *opts
+	pinned.AudioStream = &stream.Index
+	pinned.AudioLanguage = ""
+	j := *job
+	j.Options = &pinned
+	return &j, nil
+}
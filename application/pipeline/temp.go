@@ -0,0 +1,52 @@
+package pipeline
+
+import (
+	"time"
+
+	"github.com/Skryldev/audio-lab/infrastructure/storage"
+	"go.uber.org/zap"
+)
+
+// SetTempManager replaces the manager that allocates intermediate files
+func (p *Pipeline) SetTempManager(m *storage.TempManager) {
+	p.temps = m
+}
+
+// TempManager returns the manager that allocates intermediate files
+func (p *Pipeline) TempManager() *storage.TempManager {
+	return p.temps
+}
+
+// SweepTemps removes intermediate files left behind by crashed processes
+// and files older than maxAge
+func (p *Pipeline) SweepTemps(maxAge time.Duration) (int, error) {
+	return p.temps.Sweep(maxAge)
+}
+
+// AllocateTemp creates an intermediate file for job that is removed when
+// the job's run ends, however it ends. The first file becomes job.TempPath.
+func (p *Pipeline) AllocateTemp(job *Job, ext string) (string, error) {
+	if job.temps == nil {
+		job.temps = p.temps.Scope(job.ID)
+	}
+	path, err := job.temps.Allocate(ext)
+	if err != nil {
+		return "", err
+	}
+	if job.TempPath == "" {
+		job.TempPath = path
+	}
+	return path, nil
+}
+
+// releaseTemps removes the job's intermediate files
+func (p *Pipeline) releaseTemps(job *Job) {
+	if job.temps == nil {
+		return
+	}
+	if err := job.temps.Release(); err != nil {
+		p.log.Warn("failed to remove temp files", zap.String("job_id", job.ID), zap.Error(err))
+	}
+	job.temps = nil
+	job.TempPath = ""
+}
@@ -0,0 +1,161 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/internal/argfmt"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"github.com/Skryldev/audio-lab/pkg/progress"
+)
+
+// GenerateTone renders a synthetic signal described by spec into job.OutputPath
+// using ffmpeg's lavfi sources. job.InputPath is ignored.
+func (p *Pipeline) GenerateTone(ctx context.Context, job *Job, spec model.ToneSpec) (*model.ProcessingResult, error) {
+	start := time.Now()
+
+	if job.OutputPath == "" {
+		return nil, pkgerrors.NewValidationError("outputPath", "", "output path must not be empty")
+	}
+
+	source, err := buildToneSource(spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.prepareOutputs(ctx, job.Options, job.OutputPath); err != nil {
+		return nil, err
+	}
+
+	args := []string{"-y", "-f", "lavfi", "-i", source}
+	args = append(args, "-ar", argfmt.Hz(job.Options.SampleRate))
+
+	codecArgs, err := buildCodecArgs(job.Options)
+	if err != nil {
+		return nil, pkgerrors.NewProcessingError("encode", "failed to build codec args", err)
+	}
+	args = append(args, codecArgs...)
+
+	atomic, output, format := p.atomicOutput(job.Options, job.OutputPath)
+	defer atomic.discard(ctx)
+	if format != "" {
+		args = append(args, "-f", format)
+	}
+	args = append(args, ffmpegPath(output))
+
+	job.report(progress.StageEncode, 20, "generating tone")
+
+	if err := p.executor.Execute(ctx, args); err != nil {
+		return nil, err
+	}
+	if err := p.finalizeOutputs(ctx, job.Options, output); err != nil {
+		return nil, err
+	}
+
+	outputMeta, err := atomic.verify(ctx, output)
+	if err != nil {
+		return nil, err
+	}
+	if err := atomic.commit(ctx); err != nil {
+		return nil, err
+	}
+
+	job.report(progress.StageDone, 100, "done")
+
+	return &model.ProcessingResult{
+		OutputPath:  job.OutputPath,
+		OutputMeta:  outputMeta,
+		Duration:    time.Since(start),
+		ProcessedAt: time.Now(),
+		Profile:     job.profile(),
+		Labels:      job.labels(),
+	}, nil
+}
+
+// buildToneSource converts a ToneSpec into a lavfi source description
+func buildToneSource(spec model.ToneSpec) (string, error) {
+	if spec.Duration <= 0 {
+		return "", pkgerrors.NewValidationError("duration", spec.Duration, "duration must be positive")
+	}
+
+	sampleRate := spec.SampleRate
+	if sampleRate <= 0 {
+		sampleRate = 48000
+	}
+	channels := spec.Channels
+	if channels <= 0 {
+		channels = 1
+	}
+	amplitude := spec.Amplitude
+	if amplitude == 0 {
+		amplitude = 0.5
+	}
+	if amplitude < 0 || amplitude > 1 {
+		return "", pkgerrors.NewValidationError("amplitude", spec.Amplitude, "amplitude must be between 0 and 1")
+	}
+	freq := spec.Frequency
+	if freq <= 0 {
+		freq = 440
+	}
+	seconds := spec.Duration.Seconds()
+
+	switch spec.Kind {
+	case model.ToneSine, "":
+		// sine has no amplitude control, so express it through aevalsrc
+		expr := argfmt.Float(amplitude, 6) + "*sin(2*PI*" + argfmt.Float(freq, 6) + "*t)"
+		return "aevalsrc=" + channelExprs(expr, channels) +
+			":c=" + channelLayout(channels) +
+			":s=" + argfmt.Hz(sampleRate) +
+			":d=" + argfmt.Float(seconds, 6), nil
+
+	case model.ToneSweep:
+		endFreq := spec.EndFrequency
+		if endFreq <= 0 {
+			endFreq = 20000
+		}
+		// linear chirp: phase = 2π(f0·t + (f1-f0)/(2D)·t²)
+		expr := argfmt.Float(amplitude, 6) + "*sin(2*PI*(" + argfmt.Float(freq, 6) +
+			"*t+" + argfmt.Float((endFreq-freq)/(2*seconds), 6) + "*t*t))"
+		return "aevalsrc=" + channelExprs(expr, channels) +
+			":c=" + channelLayout(channels) +
+			":s=" + argfmt.Hz(sampleRate) +
+			":d=" + argfmt.Float(seconds, 6), nil
+
+	case model.ToneNoise:
+		color := spec.NoiseColor
+		if color == "" {
+			color = "white"
+		}
+		src := "anoisesrc=c=" + color +
+			":r=" + argfmt.Hz(sampleRate) +
+			":a=" + argfmt.Float(amplitude, 6) +
+			":d=" + argfmt.Float(seconds, 6) +
+			":s=" + argfmt.Int64(spec.Seed)
+		if channels > 1 {
+			src += ",aformat=channel_layouts=" + channelLayout(channels)
+		}
+		return src, nil
+
+	default:
+		return "", pkgerrors.NewValidationError("kind", spec.Kind, "unsupported tone kind")
+	}
+}
+
+func channelExprs(expr string, channels int) string {
+	out := expr
+	for i := 1; i < channels; i++ {
+		out += "|" + expr
+	}
+	return out
+}
+
+func channelLayout(channels int) string {
+	switch channels {
+	case 1:
+		return "mono"
+	case 2:
+		return "stereo"
+	default:
+		return argfmt.Int(channels) + "c"
+	}
+}
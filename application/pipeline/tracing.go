@@ -0,0 +1,66 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+
+	"github.com/Skryldev/audio-lab/infrastructure/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SetTracerProvider sets the provider of job and stage spans. Without one
+// the global provider is used.
+func (p *Pipeline) SetTracerProvider(tp trace.TracerProvider) {
+	p.tracer = tracing.Tracer(tp)
+}
+
+// Tracer returns the tracer of job and stage spans
+func (p *Pipeline) Tracer() trace.Tracer {
+	return p.tracer
+}
+
+// stageRun is a running pipeline stage, traced and reported to hooks
+type stageRun struct {
+	p     *Pipeline
+	ctx   context.Context
+	job   *Job
+	name  string
+	start time.Time
+	span  trace.Span
+}
+
+// startStage starts a pipeline stage; the returned context carries its span
+func (p *Pipeline) startStage(ctx context.Context, job *Job, name string) (context.Context, *stageRun) {
+	ctx, span := p.tracer.Start(ctx, "audiolab."+name, trace.WithAttributes(
+		attribute.String("audiolab.job_id", job.ID),
+	))
+	if p.hooks.OnStageStart != nil {
+		p.hooks.OnStageStart(ctx, job.info(), name)
+	}
+	return ctx, &stageRun{p: p, ctx: ctx, job: job, name: name, start: time.Now(), span: span}
+}
+
+// end finishes the stage with its outcome
+func (s *stageRun) end(err error) {
+	if s.p.hooks.OnStageEnd != nil {
+		s.p.hooks.OnStageEnd(s.ctx, s.job.info(), s.name, time.Since(s.start), err)
+	}
+	tracing.End(s.span, err)
+}
+
+// jobAttributes describes a job on its span
+func jobAttributes(job *Job) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("audiolab.job_id", job.ID),
+		attribute.String("audiolab.input", job.InputPath),
+		attribute.String("audiolab.output", job.OutputPath),
+	}
+	if job.Options != nil {
+		attrs = append(attrs,
+			attribute.String("audiolab.codec", string(job.Options.Codec)),
+			attribute.String("audiolab.profile", job.Options.Profile),
+		)
+	}
+	return attrs
+}
@@ -0,0 +1,90 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/infrastructure/ffmpeg"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+)
+
+// defaultVerifyTolerance is how far an output's decoded duration may be off
+// the expected one. Encoder delay and padding account for tens of
+// milliseconds.
+const defaultVerifyTolerance = 500 * time.Millisecond
+
+// cutArgs are raw ffmpeg options that shorten the output, leaving its
+// expected duration unknown
+var cutArgs = map[string]bool{"-ss": true, "-t": true, "-to": true, "-sseof": true}
+
+// expectedDuration returns how long the outputs of a job with opts should
+// be given the input's duration, or 0 when raw options cut the input
+func expectedDuration(opts *model.ProcessingOptions, input time.Duration) time.Duration {
+	for _, args := range [][]string{opts.ExtraInputArgs, opts.ExtraOutputArgs} {
+		for _, a := range args {
+			if cutArgs[a] {
+				return 0
+			}
+		}
+	}
+	return input + opts.LeadingPadding + opts.TrailingPadding
+}
+
+// verifyOutput decodes the output at path in full, discarding the audio,
+// and fails when ffmpeg reports decode errors, meta lacks a sample rate or
+// bitrate, or the decoded duration is more than opts.VerifyTolerance off
+// expected. A zero expected duration skips the duration check.
+func (p *Pipeline) verifyOutput(ctx context.Context, opts *model.ProcessingOptions, path string, meta *model.AudioMetadata, expected time.Duration) error {
+	fail := func(reason string) error {
+		return pkgerrors.NewProcessingError("verify", "output failed verification: "+reason, nil)
+	}
+	if meta.SampleRate <= 0 {
+		return fail("no sample rate")
+	}
+	// Ogg and FLAC streams carry no bitrate; the file's average stands in
+	bitrate := float64(meta.Bitrate)
+	if bitrate <= 0 && meta.Duration > 0 {
+		bitrate = float64(meta.Size*8) / meta.Duration.Seconds()
+	}
+	if bitrate <= 0 {
+		return fail("no bitrate")
+	}
+
+	decoded, stderr, err := p.decodeToNull(ctx, []string{"-v", "error", "-i", ffmpegPath(path), "-map", "0:a:0"})
+	if err != nil {
+		return pkgerrors.NewProcessingError("verify", "output failed verification: decoding failed", err)
+	}
+	if msg := strings.TrimSpace(string(stderr)); msg != "" {
+		line, _, _ := strings.Cut(msg, "\n")
+		return fail("decode error: " + line)
+	}
+
+	if expected <= 0 {
+		return nil
+	}
+	tolerance := opts.VerifyTolerance
+	if tolerance <= 0 {
+		tolerance = defaultVerifyTolerance
+	}
+	if diff := decoded - expected; diff > tolerance || -diff > tolerance {
+		return fail(fmt.Sprintf("decoded %s of audio, expected %s", decoded.Round(time.Millisecond), expected.Round(time.Millisecond)))
+	}
+	return nil
+}
+
+// decodeToNull runs ffmpeg with inputArgs, which open an input and select
+// its audio, decoding to the null muxer. It returns the length of the
+// decoded audio and ffmpeg's log.
+func (p *Pipeline) decodeToNull(ctx context.Context, inputArgs []string) (time.Duration, []byte, error) {
+	var decoded time.Duration
+	progress := ffmpeg.NewProgressWriter(func(r ffmpeg.ProgressReport) {
+		decoded = r.OutTime
+	})
+	args := append(append([]string(nil), ffmpeg.ProgressArgs...), inputArgs...)
+	args = append(args, "-f", "null", "-")
+	stderr, err := p.executor.ExecutePiped(ctx, args, nil, progress)
+	return decoded, stderr, err
+}
@@ -0,0 +1,161 @@
+package pipeline
+
+import (
+	"context"
+	"math"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/internal/argfmt"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"github.com/Skryldev/audio-lab/pkg/progress"
+)
+
+// GenerateWaveform decodes job.InputPath to 16-bit PCM and reduces it to
+// min/max peaks without buffering the decoded audio
+func (p *Pipeline) GenerateWaveform(ctx context.Context, job *Job, opts model.WaveformOptions) (*model.Waveform, error) {
+	if job.InputPath == "" {
+		return nil, pkgerrors.NewValidationError("inputPath", "", "input path must not be empty")
+	}
+	if err := p.checkInputExists(ctx, job.InputPath); err != nil {
+		return nil, err
+	}
+
+	spp := opts.SamplesPerPixel
+	if spp <= 0 {
+		spp = 256
+	}
+	channels := opts.Channels
+	if channels <= 0 {
+		channels = 1
+	}
+	if channels > 2 {
+		return nil, pkgerrors.NewValidationError("channels", channels, "waveform supports 1 or 2 channels")
+	}
+
+	meta, err := p.probeFile(ctx, job.InputPath)
+	if err != nil {
+		return nil, pkgerrors.NewProcessingError("probe", "failed to probe input file", err)
+	}
+	job.report(progress.StageProbe, 5, "input probed")
+	if job, err = selectAudioStream(job, meta); err != nil {
+		return nil, err
+	}
+
+	sampleRate := meta.SampleRate
+	if sampleRate <= 0 {
+		sampleRate = 44100
+	}
+
+	args := []string{"-v", "error"}
+	args = append(args, remoteInputArgs(job.InputPath)...)
+	args = append(args, "-i", ffmpegPath(job.InputPath))
+	if spec := audioStreamSpec(job.Options); spec != "" {
+		args = append(args, "-map", spec)
+	}
+	args = append(args,
+		"-vn",
+		"-ac", argfmt.Int(channels),
+		"-ar", argfmt.Hz(sampleRate),
+		"-f", "s16le",
+		"-acodec", "pcm_s16le",
+		"pipe:1",
+	)
+
+	peaks := newPeakWriter(channels, spp)
+
+	job.report(progress.StageEncode, 20, "computing peaks")
+
+	if _, err := p.executor.ExecutePiped(ctx, args, nil, peaks); err != nil {
+		return nil, err
+	}
+	peaks.flush()
+
+	job.report(progress.StageDone, 100, "done")
+
+	return &model.Waveform{
+		Version:         2,
+		Channels:        channels,
+		SampleRate:      sampleRate,
+		SamplesPerPixel: spp,
+		Bits:            16,
+		Length:          peaks.pixels,
+		Data:            peaks.data,
+	}, nil
+}
+
+// peakWriter consumes interleaved s16le frames and accumulates min/max pairs
+type peakWriter struct {
+	channels int
+	spp      int
+
+	carry  []byte // partial frame left over from the previous Write
+	count  int    // frames accumulated into the current pixel
+	min    []int16
+	max    []int16
+	data   []int16
+	pixels int
+}
+
+func newPeakWriter(channels, spp int) *peakWriter {
+	w := &peakWriter{
+		channels: channels,
+		spp:      spp,
+		min:      make([]int16, channels),
+		max:      make([]int16, channels),
+	}
+	w.reset()
+	return w
+}
+
+func (w *peakWriter) Write(b []byte) (int, error) {
+	n := len(b)
+	frameSize := 2 * w.channels
+
+	if len(w.carry) > 0 {
+		b = append(w.carry, b...)
+		w.carry = nil
+	}
+
+	for len(b) >= frameSize {
+		for c := 0; c < w.channels; c++ {
+			s := int16(uint16(b[2*c]) | uint16(b[2*c+1])<<8)
+			if s < w.min[c] {
+				w.min[c] = s
+			}
+			if s > w.max[c] {
+				w.max[c] = s
+			}
+		}
+		b = b[frameSize:]
+
+		w.count++
+		if w.count == w.spp {
+			w.flush()
+		}
+	}
+
+	if len(b) > 0 {
+		w.carry = append([]byte(nil), b...)
+	}
+	return n, nil
+}
+
+// flush emits the current pixel if any frames were accumulated
+func (w *peakWriter) flush() {
+	if w.count == 0 {
+		return
+	}
+	for c := 0; c < w.channels; c++ {
+		w.data = append(w.data, w.min[c], w.max[c])
+	}
+	w.pixels++
+	w.reset()
+}
+
+func (w *peakWriter) reset() {
+	w.count = 0
+	for c := 0; c < w.channels; c++ {
+		w.min[c] = math.MaxInt16
+		w.max[c] = math.MinInt16
+	}
+}
@@ -4,10 +4,15 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/domain/ports"
+	"github.com/Skryldev/audio-lab/infrastructure/metrics"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
 	"github.com/Skryldev/audio-lab/pkg/logger"
 	"github.com/Skryldev/audio-lab/pkg/progress"
+	"github.com/Skryldev/audio-lab/pkg/schedule"
 	"go.uber.org/zap"
 )
 
@@ -15,7 +20,21 @@ import (
 type WorkerPool struct {
 	pipeline *Pipeline
 	workers  int
+	lane     *Lane
+	windows  schedule.Windows
+	governor ports.AdmissionGovernor
+	metrics  *metrics.Metrics
+	stats    *StatsTracker
 	log      *logger.Logger
+
+	deadLetters ports.DeadLetterStore
+
+	mu      sync.Mutex
+	running map[string]map[*poolJob]struct{} // queued and running jobs by ID
+	closing bool                             // set by Shutdown
+	active  sync.WaitGroup                   // tracked jobs
+
+	stopTuning chan struct{} // closed by Shutdown to stop AutoTune
 }
 
 // NewWorkerPool creates a new worker pool
@@ -26,50 +45,110 @@ func NewWorkerPool(p *Pipeline, workers int, log *logger.Logger) *WorkerPool {
 	return &WorkerPool{
 		pipeline: p,
 		workers:  workers,
+		lane:     NewLane("encode", workers),
+		stats:    newStatsTracker(),
 		log:      log,
+		running:  make(map[string]map[*poolJob]struct{}),
 	}
 }
 
 // Run processes batch jobs concurrently and sends results to returned channel
 // The channel is closed when all jobs are complete or context is canceled
 func (wp *WorkerPool) Run(ctx context.Context, jobs []model.BatchJob, reporter progress.Reporter) (<-chan model.BatchResult, error) {
+	return wp.RunWithPolicy(ctx, jobs, reporter, model.BatchPolicy{})
+}
+
+// RunWithPolicy is Run, aborting the batch when policy trips
+func (wp *WorkerPool) RunWithPolicy(ctx context.Context, jobs []model.BatchJob, reporter progress.Reporter, policy model.BatchPolicy) (<-chan model.BatchResult, error) {
 	results := make(chan model.BatchResult, len(jobs))
 
+	// Aborting the batch cancels every job derived from its context
+	batchCtx, abort := context.WithCancelCause(ctx)
+	failures := newFailureCounter(policy)
+
+	// Each job gets its own context so that Cancel can stop it alone
+	tracked, err := wp.track(batchCtx, jobs...)
+	if err != nil {
+		abort(nil)
+		return nil, err
+	}
+	for range tracked {
+		wp.metrics.JobQueued()
+		wp.stats.JobQueued()
+	}
+	queue := newJobQueue(tracked)
+
 	go func() {
 		defer close(results)
+		defer abort(nil)
 
-		jobCh := make(chan model.BatchJob, len(jobs))
-		for _, j := range jobs {
-			jobCh <- j
+		var wg sync.WaitGroup
+
+		send := func(pj *poolJob, result model.BatchResult) {
+			if pj.job.OnComplete != nil {
+				pj.job.OnComplete(result)
+			}
+			results <- result
 		}
-		close(jobCh)
 
-		var wg sync.WaitGroup
-		semaphore := make(chan struct{}, wp.workers)
-
-		for job := range jobCh {
-			select {
-			case <-ctx.Done():
-				results <- model.BatchResult{
-					JobID: job.ID,
-					Err:   ctx.Err(),
+		fail := func(pj *poolJob, err error) {
+			wp.metrics.JobDequeued()
+			wp.stats.JobDequeued()
+			send(pj, model.BatchResult{
+				JobID:   pj.job.ID,
+				Profile: batchProfile(pj.job),
+				Err:     pj.canceled(err),
+			})
+			wp.untrack(pj)
+		}
+
+		for pj := queue.next(); pj != nil; pj = queue.next() {
+			if err := pj.ctx.Err(); err != nil {
+				fail(pj, err)
+				continue
+			}
+
+			// Dry runs only build a command, so they skip the processing
+			// window, admission and the encode lane
+			release := func() {}
+			if !isDryRun(pj.job) {
+				if err := wp.acquire(pj); err != nil {
+					fail(pj, err)
+					continue
 				}
+				release = wp.lane.Release
+			}
+			if !wp.start(pj) {
+				release()
+				fail(pj, pkgerrors.ErrShuttingDown)
 				continue
-			case semaphore <- struct{}{}:
 			}
+			wp.metrics.JobDequeued()
+			wp.stats.JobDequeued()
 
 			wg.Add(1)
-			go func(j model.BatchJob) {
+			go func(pj *poolJob) {
 				defer wg.Done()
-				defer func() { <-semaphore }()
+				defer release()
+				defer wp.untrack(pj)
 
-				result, err := wp.processJob(ctx, j, reporter)
-				results <- model.BatchResult{
-					JobID:  j.ID,
-					Result: result,
-					Err:    err,
+				result, err := wp.processJob(pj.ctx, pj.job, reporter)
+				err = pj.canceled(err)
+				if failures.record(err) {
+					wp.log.Warn("batch failure policy tripped, aborting remaining jobs",
+						zap.String("job_id", pj.job.ID),
+						zap.Error(err),
+					)
+					abort(pkgerrors.ErrBatchAborted)
 				}
-			}(job)
+				send(pj, model.BatchResult{
+					JobID:   pj.job.ID,
+					Profile: batchProfile(pj.job),
+					Skipped: result != nil && result.Skipped,
+					Result:  result,
+					Err:     err,
+				})
+			}(pj)
 		}
 
 		wg.Wait()
@@ -78,6 +157,76 @@ func (wp *WorkerPool) Run(ctx context.Context, jobs []model.BatchJob, reporter p
 	return results, nil
 }
 
+// acquire waits for a processing window, admission and a slot in the
+// encode lane for pj
+func (wp *WorkerPool) acquire(pj *poolJob) error {
+	if !wp.windows.Open(time.Now()) {
+		wp.log.Info("outside processing window, holding batch jobs",
+			zap.Time("next_open", wp.windows.NextOpen(time.Now())),
+		)
+	}
+	if err := wp.windows.Wait(pj.ctx); err != nil {
+		return err
+	}
+
+	if wp.governor != nil {
+		if err := wp.governor.Admit(pj.ctx, pj.job.ID); err != nil {
+			return err
+		}
+	}
+
+	// The encode lane is shared by all batches and single-file calls;
+	// higher-priority jobs of other batches get the next free slot
+	return wp.lane.AcquirePriority(pj.ctx, pj.job.Priority)
+}
+
+// SetWindows restricts batch jobs to the given time-of-day windows. Jobs
+// queued outside a window are held until one opens.
+func (wp *WorkerPool) SetWindows(ws schedule.Windows) {
+	wp.windows = ws
+}
+
+// SetGovernor installs an admission governor consulted before each job
+func (wp *WorkerPool) SetGovernor(g ports.AdmissionGovernor) {
+	wp.governor = g
+}
+
+// Governor returns the installed admission governor, if any
+func (wp *WorkerPool) Governor() ports.AdmissionGovernor {
+	return wp.governor
+}
+
+// SetMetrics installs the metrics recorded for each job
+func (wp *WorkerPool) SetMetrics(m *metrics.Metrics) {
+	wp.metrics = m
+}
+
+// Metrics returns the installed metrics, if any
+func (wp *WorkerPool) Metrics() *metrics.Metrics {
+	return wp.metrics
+}
+
+// SetDeadLetters installs the store recording jobs that failed after
+// exhausting their retries
+func (wp *WorkerPool) SetDeadLetters(s ports.DeadLetterStore) {
+	wp.deadLetters = s
+}
+
+// DeadLetters returns the installed dead-letter store, if any
+func (wp *WorkerPool) DeadLetters() ports.DeadLetterStore {
+	return wp.deadLetters
+}
+
+// Stats returns the statistics tracker shared by this pool
+func (wp *WorkerPool) Stats() *StatsTracker {
+	return wp.stats
+}
+
+// Lane returns the encode lane shared by this pool
+func (wp *WorkerPool) Lane() *Lane {
+	return wp.lane
+}
+
 func (wp *WorkerPool) processJob(ctx context.Context, job model.BatchJob, reporter progress.Reporter) (*model.ProcessingResult, error) {
 	opts := job.Options
 	if opts == nil {
@@ -95,17 +244,47 @@ func (wp *WorkerPool) processJob(ctx context.Context, job model.BatchJob, report
 
 	wp.log.Info("processing batch job",
 		zap.String("job_id", job.ID),
+		zap.String("profile", opts.Profile),
+		zap.Any("labels", opts.Labels),
 		zap.String("input", job.InputPath),
 	)
 
-	result, err := wp.pipeline.Run(ctx, pipelineJob)
+	if opts.DryRun {
+		return wp.pipeline.Run(ctx, pipelineJob)
+	}
+
+	start := time.Now()
+	wp.metrics.JobStarted(opts)
+	wp.stats.JobStarted()
+	wp.pipeline.JobStarted(ctx, pipelineJob)
+	result, history, err := wp.runWithRetry(ctx, pipelineJob)
+	wp.metrics.JobFinished(opts, result, err, time.Since(start))
+	wp.stats.JobFinished(opts, result, err, time.Since(start))
+	wp.pipeline.JobCompleted(ctx, pipelineJob, result, err)
 	if err != nil {
 		wp.log.Error("batch job failed",
 			zap.String("job_id", job.ID),
+			zap.Any("labels", opts.Labels),
+			zap.Int("attempts", len(history)),
 			zap.Error(err),
 		)
-		return nil, fmt.Errorf("job %s failed: %w", job.ID, err)
+		err = fmt.Errorf("job %s failed: %w", job.ID, err)
+		wp.deadLetter(ctx, job, history, err)
+		return nil, err
 	}
 
 	return result, nil
-}
\ No newline at end of file
+}
+
+// isDryRun reports whether job only builds its command
+func isDryRun(job model.BatchJob) bool {
+	return job.Options != nil && job.Options.DryRun
+}
+
+// batchProfile returns the profile name of a batch job, if any
+func batchProfile(job model.BatchJob) string {
+	if job.Options == nil {
+		return ""
+	}
+	return job.Options.Profile
+}
@@ -0,0 +1,58 @@
+// Package planner exposes the option resolution, validation and ffmpeg
+// argument construction used by the Processor without an executor or
+// storage, so UIs, linters and config validators can reuse the exact same
+// logic
+package planner
+
+import (
+	"github.com/Skryldev/audio-lab/application/pipeline"
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/domain/ports"
+)
+
+// Resolve applies opts on top of the default processing options, exactly
+// as the Processor does
+func Resolve(opts ...ports.Option) *model.ProcessingOptions {
+	options := model.DefaultProcessingOptions()
+	for _, o := range opts {
+		o(options)
+	}
+	return options
+}
+
+// Validate checks paths and options. It never touches the filesystem, so a
+// missing input is not reported.
+func Validate(inputPath, outputPath string, options *model.ProcessingOptions) error {
+	return pipeline.ValidateJob(newJob(inputPath, outputPath, options))
+}
+
+// Plan resolves opts, validates them and builds the command plan the
+// Processor would execute for inputPath and outputPath
+func Plan(inputPath, outputPath string, opts ...ports.Option) (*model.CommandPlan, error) {
+	return PlanOptions(inputPath, outputPath, Resolve(opts...))
+}
+
+// PlanOptions is Plan for already resolved options
+func PlanOptions(inputPath, outputPath string, options *model.ProcessingOptions) (*model.CommandPlan, error) {
+	return pipeline.PlanJob(newJob(inputPath, outputPath, options))
+}
+
+// Args returns the ffmpeg argv (without the binary name) for a job
+func Args(inputPath, outputPath string, opts ...ports.Option) ([]string, error) {
+	plan, err := Plan(inputPath, outputPath, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return plan.Args(), nil
+}
+
+func newJob(inputPath, outputPath string, options *model.ProcessingOptions) *pipeline.Job {
+	if options == nil {
+		options = model.DefaultProcessingOptions()
+	}
+	return &pipeline.Job{
+		InputPath:  inputPath,
+		OutputPath: outputPath,
+		Options:    options,
+	}
+}
@@ -0,0 +1,164 @@
+// Package presets holds named bundles of processing options, applied with
+// WithPreset. It ships curated presets and accepts user-registered ones.
+package presets
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/domain/ports"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+)
+
+// Preset is a named set of options
+type Preset struct {
+	Name        string
+	Description string
+	Options     []ports.Option
+}
+
+// Curated preset names
+const (
+	Podcast        = "podcast"
+	MusicStreaming = "music-streaming"
+	VoiceMemo      = "voice-memo"
+	Archival       = "archival"
+	Audiobook      = "audiobook"
+)
+
+var registry = struct {
+	mu      sync.RWMutex
+	presets map[string]Preset
+}{presets: make(map[string]Preset)}
+
+func init() {
+	for _, p := range curated {
+		registry.presets[p.Name] = p
+	}
+}
+
+var curated = []Preset{
+	{
+		Name:        Podcast,
+		Description: "speech for podcast apps: MP3 128k CBR at 44.1 kHz, -16 LUFS, rumble filtered",
+		Options: []ports.Option{
+			ports.WithCodec(model.CodecMP3),
+			ports.WithBitrate(128000),
+			ports.WithBitrateMode(model.BitrateCBR),
+			ports.WithSampleRate(44100),
+			ports.WithNormalization(true),
+			ports.WithLoudnessTarget(-16),
+			ports.WithHighpass(80),
+		},
+	},
+	{
+		Name:        MusicStreaming,
+		Description: "music for streaming services: Opus 160k VBR at 48 kHz, -14 LUFS",
+		Options: []ports.Option{
+			ports.WithCodec(model.CodecOpus),
+			ports.WithBitrate(160000),
+			ports.WithBitrateMode(model.BitrateModeVBR),
+			ports.WithSampleRate(48000),
+			ports.WithNormalization(true),
+			ports.WithLoudnessTarget(-14),
+		},
+	},
+	{
+		Name:        VoiceMemo,
+		Description: "compact speech: Opus 32k VBR at 16 kHz, -18 LUFS, band-limited to voice",
+		Options: []ports.Option{
+			ports.WithCodec(model.CodecOpus),
+			ports.WithBitrate(32000),
+			ports.WithBitrateMode(model.BitrateModeVBR),
+			ports.WithSampleRate(16000),
+			ports.WithNormalization(true),
+			ports.WithLoudnessTarget(-18),
+			ports.WithHighpass(100),
+			ports.WithLowpass(7000),
+		},
+	},
+	{
+		Name:        Archival,
+		Description: "transparent copy: AAC 320k CBR at 48 kHz, no loudness or filter changes",
+		Options: []ports.Option{
+			ports.WithCodec(model.CodecAAC),
+			ports.WithBitrate(320000),
+			ports.WithBitrateMode(model.BitrateCBR),
+			ports.WithSampleRate(48000),
+			ports.WithNormalization(false),
+		},
+	},
+	{
+		Name:        Audiobook,
+		Description: "audiobook retail (ACX style): MP3 192k CBR at 44.1 kHz, -20 LUFS, -3 dBTP",
+		Options: []ports.Option{
+			ports.WithCodec(model.CodecMP3),
+			ports.WithBitrate(192000),
+			ports.WithBitrateMode(model.BitrateCBR),
+			ports.WithSampleRate(44100),
+			ports.WithNormalization(true),
+			ports.WithLoudnessTarget(-20),
+			truePeak(-3),
+			ports.WithHighpass(80),
+		},
+	},
+}
+
+// Register adds or replaces a preset
+func Register(p Preset) error {
+	if p.Name == "" {
+		return pkgerrors.NewValidationError("name", "", "preset name must not be empty")
+	}
+	p.Options = append([]ports.Option(nil), p.Options...)
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.presets[p.Name] = p
+	return nil
+}
+
+// Lookup returns the named preset
+func Lookup(name string) (Preset, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	p, ok := registry.presets[name]
+	return p, ok
+}
+
+// List returns all presets sorted by name
+func List() []Preset {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	out := make([]Preset, 0, len(registry.presets))
+	for _, p := range registry.presets {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// WithPreset applies the named preset's options and, unless one was set
+// before it, uses its name as the profile. Options given after it override
+// the preset's. An unknown name fails validation when the job runs.
+func WithPreset(name string) ports.Option {
+	return func(o *model.ProcessingOptions) {
+		profile := o.Profile
+		if p, ok := Lookup(name); ok {
+			for _, opt := range p.Options {
+				opt(o)
+			}
+		}
+		// Set after the options so a preset built on another keeps its own name
+		o.Preset = name
+		if profile == "" {
+			o.Profile = name
+		}
+	}
+}
+
+func truePeak(dbtp float64) ports.Option {
+	return func(o *model.ProcessingOptions) {
+		o.TruePeakLimit = dbtp
+	}
+}
@@ -0,0 +1,196 @@
+// Package report aggregates batch results into a summary for operations
+// review, exportable as JSON or CSV.
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+)
+
+// JobStatus is the outcome of one job in a BatchReport
+type JobStatus string
+
+const (
+	StatusSucceeded JobStatus = "succeeded"
+	StatusFailed    JobStatus = "failed"
+	StatusSkipped   JobStatus = "skipped"
+)
+
+// JobReport summarizes one job of a batch
+type JobReport struct {
+	JobID   string    `json:"job_id"`
+	Profile string    `json:"profile,omitempty"`
+	Status  JobStatus `json:"status"`
+	Input   string    `json:"input,omitempty"`
+	Output  string    `json:"output,omitempty"`
+
+	// Elapsed is the processing time; AudioDuration the input's length
+	Elapsed       time.Duration `json:"elapsed_ns"`
+	AudioDuration time.Duration `json:"audio_duration_ns"`
+
+	// InputBytes and OutputBytes count the main and additional outputs
+	InputBytes  int64 `json:"input_bytes"`
+	OutputBytes int64 `json:"output_bytes"`
+
+	// CompressionRatio is InputBytes / OutputBytes, or 0 when unknown
+	CompressionRatio float64 `json:"compression_ratio"`
+
+	ErrorCode pkgerrors.ErrorCode `json:"error_code,omitempty"`
+	Error     string              `json:"error,omitempty"`
+}
+
+// BatchReport is the summary of a batch, built by adding its results
+type BatchReport struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"`
+
+	InputBytes       int64   `json:"input_bytes"`
+	OutputBytes      int64   `json:"output_bytes"`
+	CompressionRatio float64 `json:"compression_ratio"`
+
+	// Errors counts failed jobs by error code
+	Errors map[pkgerrors.ErrorCode]int `json:"errors,omitempty"`
+
+	Jobs []JobReport `json:"jobs"`
+}
+
+// New returns an empty report started now
+func New() *BatchReport {
+	now := time.Now()
+	return &BatchReport{
+		StartedAt:  now,
+		FinishedAt: now,
+		Errors:     make(map[pkgerrors.ErrorCode]int),
+	}
+}
+
+// Collect drains results into a new report
+func Collect(results <-chan model.BatchResult) *BatchReport {
+	r := New()
+	for result := range results {
+		r.Add(result)
+	}
+	return r
+}
+
+// Add records a job result
+func (r *BatchReport) Add(result model.BatchResult) {
+	job := JobReport{
+		JobID:   result.JobID,
+		Profile: result.Profile,
+		Status:  StatusSucceeded,
+	}
+	if res := result.Result; res != nil {
+		job.Input = res.InputPath
+		job.Output = res.OutputPath
+		job.Elapsed = res.Duration
+		if res.InputMeta != nil {
+			job.InputBytes = res.InputMeta.Size
+			job.AudioDuration = res.InputMeta.Duration
+		}
+		job.OutputBytes = outputBytes(res)
+		job.CompressionRatio = ratio(job.InputBytes, job.OutputBytes)
+	}
+
+	switch {
+	case result.Err != nil:
+		job.Status = StatusFailed
+		job.ErrorCode = pkgerrors.CodeOf(result.Err)
+		job.Error = result.Err.Error()
+		r.Failed++
+		if r.Errors == nil {
+			r.Errors = make(map[pkgerrors.ErrorCode]int)
+		}
+		r.Errors[job.ErrorCode]++
+	case result.Skipped:
+		job.Status = StatusSkipped
+		r.Skipped++
+	default:
+		r.Succeeded++
+		r.InputBytes += job.InputBytes
+		r.OutputBytes += job.OutputBytes
+		r.CompressionRatio = ratio(r.InputBytes, r.OutputBytes)
+	}
+
+	r.Total++
+	r.Jobs = append(r.Jobs, job)
+	r.FinishedAt = time.Now()
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *BatchReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// csvHeader names the columns written by WriteCSV
+var csvHeader = []string{
+	"job_id", "profile", "status", "input", "output",
+	"elapsed_seconds", "audio_duration_seconds",
+	"input_bytes", "output_bytes", "compression_ratio",
+	"error_code", "error",
+}
+
+// WriteCSV writes one row per job, preceded by a header row
+func (r *BatchReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, job := range r.Jobs {
+		row := []string{
+			job.JobID,
+			job.Profile,
+			string(job.Status),
+			job.Input,
+			job.Output,
+			formatFloat(job.Elapsed.Seconds()),
+			formatFloat(job.AudioDuration.Seconds()),
+			strconv.FormatInt(job.InputBytes, 10),
+			strconv.FormatInt(job.OutputBytes, 10),
+			formatFloat(job.CompressionRatio),
+			string(job.ErrorCode),
+			job.Error,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// outputBytes sums the sizes of a result's main and additional outputs
+func outputBytes(res *model.ProcessingResult) int64 {
+	var n int64
+	if res.OutputMeta != nil {
+		n += res.OutputMeta.Size
+	}
+	for _, extra := range res.AdditionalOutputs {
+		n += outputBytes(extra)
+	}
+	return n
+}
+
+func ratio(in, out int64) float64 {
+	if in <= 0 || out <= 0 {
+		return 0
+	}
+	return float64(in) / float64(out)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', 3, 64)
+}
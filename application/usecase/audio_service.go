@@ -2,17 +2,26 @@ package usecase
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/Skryldev/audio-lab/application/pipeline"
+	"github.com/Skryldev/audio-lab/application/presets"
 	"github.com/Skryldev/audio-lab/domain/model"
 	"github.com/Skryldev/audio-lab/domain/ports"
+	"github.com/Skryldev/audio-lab/infrastructure/ffmpeg"
+	"github.com/Skryldev/audio-lab/infrastructure/governor"
+	"github.com/Skryldev/audio-lab/infrastructure/metrics"
+	"github.com/Skryldev/audio-lab/infrastructure/storage"
+	"github.com/Skryldev/audio-lab/infrastructure/tracing"
 	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
 	"github.com/Skryldev/audio-lab/pkg/logger"
 	"github.com/Skryldev/audio-lab/pkg/progress"
 	"github.com/Skryldev/audio-lab/pkg/retry"
+	"github.com/Skryldev/audio-lab/pkg/schedule"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -20,22 +29,52 @@ import (
 type AudioService struct {
 	pipeline   *pipeline.Pipeline
 	workerPool *pipeline.WorkerPool
-	storage    ports.StorageProvider
-	reporter   progress.Reporter
+	probeLane  *pipeline.Lane
+	storage    ports.FileStorage
+	reporter   *progress.MultiReporter
 	log        *logger.Logger
 	retryCfg   retry.Config
+	templates  *templateRegistry
+	defaults   []ports.Option
+	executor   ports.FFmpegExecutor
 }
 
 // Config holds AudioService configuration
 type Config struct {
-	Executor    ports.FFmpegExecutor
-	Storage     ports.StorageProvider
-	Reporter    progress.Reporter
-	Logger      *logger.Logger
-	Workers     int
-	RetryConfig retry.Config
+	Executor     ports.FFmpegExecutor
+	Storage      ports.StorageProvider
+	Reporter     progress.Reporter
+	Logger       *logger.Logger
+	Workers      int
+	ProbeWorkers int                      // size of the probe/analysis lane (default: 2)
+	AutoTune     *pipeline.AutoTuneConfig // resize the encode lane at runtime; overrides Workers
+	RetryConfig  retry.Config
+	Templates    []model.JobTemplate
+	Windows      schedule.Windows // batch processing windows; empty means always
+	Governor     ports.AdmissionGovernor
+	TempDir      string // intermediate files; default: per-user dir under os.TempDir()
+
+	FingerprintStore ports.FingerprintStore // required by model.SkipIfFingerprint
+	Metrics          *metrics.Metrics       // nil disables metrics
+	TracerProvider   trace.TracerProvider   // nil uses the global provider
+	Hooks            model.Hooks
+	DeadLetters      ports.DeadLetterStore // default: in memory
+
+	// Defaults are applied to every job's options before its own
+	Defaults []ports.Option
+
+	// SkipCapabilityDetection skips listing ffmpeg's encoders and filters
+	// at startup, and with it the check that jobs only use available ones
+	SkipCapabilityDetection bool
 }
 
+// capabilityDetectionTimeout bounds the ffmpeg runs listing capabilities
+const capabilityDetectionTimeout = 10 * time.Second
+
+// staleTempAge is the age after which intermediate files of live processes
+// are swept at startup as well
+const staleTempAge = 24 * time.Hour
+
 // NewAudioService creates a new AudioService
 func NewAudioService(cfg Config) (*AudioService, error) {
 	if cfg.Executor == nil {
@@ -69,39 +108,89 @@ func NewAudioService(cfg Config) (*AudioService, error) {
 		workers = 4
 	}
 
+	probeWorkers := cfg.ProbeWorkers
+	if probeWorkers <= 0 {
+		probeWorkers = 2
+	}
+
 	p := pipeline.NewPipeline(cfg.Executor, cfg.Storage, log)
+	p.SetTempManager(storage.NewTempManager(cfg.TempDir))
+	p.SetTracerProvider(cfg.TracerProvider)
+	p.SetHooks(cfg.Hooks)
+	if !cfg.SkipCapabilityDetection {
+		detectCtx, cancel := context.WithTimeout(context.Background(), capabilityDetectionTimeout)
+		caps, err := ffmpeg.DetectCapabilities(detectCtx, cfg.Executor)
+		cancel()
+		if err != nil {
+			// Jobs still run; a missing encoder then surfaces as an ffmpeg error
+			log.Warn("failed to detect ffmpeg capabilities", zap.Error(err))
+		} else {
+			p.SetCapabilities(caps)
+		}
+	}
+	if cfg.FingerprintStore != nil {
+		p.SetFingerprintStore(cfg.FingerprintStore)
+	}
+	if n, err := p.SweepTemps(staleTempAge); err != nil {
+		log.Warn("failed to sweep stale temp files", zap.Error(err))
+	} else if n > 0 {
+		log.Info("removed stale temp files", zap.Int("count", n))
+	}
 	wp := pipeline.NewWorkerPool(p, workers, log)
+	wp.SetWindows(cfg.Windows)
+	wp.SetGovernor(cfg.Governor)
+	wp.SetMetrics(cfg.Metrics)
+	if cfg.AutoTune != nil {
+		tune := *cfg.AutoTune
+		if tune.Load == nil {
+			tune.Load = governor.ReadLoadAverage
+		}
+		wp.AutoTune(tune)
+	}
+	if cfg.DeadLetters != nil {
+		wp.SetDeadLetters(cfg.DeadLetters)
+	} else {
+		wp.SetDeadLetters(storage.NewMemoryDeadLetters())
+	}
 
-	return &AudioService{
+	svc := &AudioService{
 		pipeline:   p,
 		workerPool: wp,
-		storage:    cfg.Storage,
-		reporter:   reporter,
+		probeLane:  pipeline.NewLane("probe", probeWorkers),
+		storage:    storage.Files(cfg.Storage),
+		reporter:   progress.NewMultiReporter(reporter),
 		log:        log,
 		retryCfg:   retryCfg,
-	}, nil
+		templates:  newTemplateRegistry(),
+		defaults:   cfg.Defaults,
+		executor:   cfg.Executor,
+	}
+	for _, t := range cfg.Templates {
+		if err := svc.RegisterTemplate(t); err != nil {
+			return nil, err
+		}
+	}
+
+	return svc, nil
 }
 
 // ProcessAudio processes a single audio file with optional configuration
-func (s *AudioService) ProcessAudio(ctx context.Context, inputPath, outputPath string, opts ...ports.Option) (*model.ProcessingResult, error) {
-	// Apply options on top of defaults
-	options := model.DefaultProcessingOptions()
-	for _, o := range opts {
-		o(options)
-	}
+func (s *AudioService) ProcessAudio(ctx context.Context, inputPath, outputPath string, opts ...ports.Option) (result *model.ProcessingResult, err error) {
+	ctx, span := s.pipeline.Tracer().Start(ctx, "audiolab.ProcessAudio", trace.WithAttributes(
+		attribute.String("audiolab.input", inputPath),
+		attribute.String("audiolab.output", outputPath),
+	))
+	defer func() { tracing.End(span, err) }()
 
-	// Apply timeout
-	if options.Timeout > 0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
-		defer cancel()
-	}
+	// Apply options on top of defaults
+	options := s.options(opts)
 
 	s.log.Info("starting audio processing",
 		zap.String("input", inputPath),
 		zap.String("output", outputPath),
 		zap.String("codec", string(options.Codec)),
 		zap.Int("bitrate", options.Bitrate),
+		zap.Any("labels", options.Labels),
 	)
 
 	job := &pipeline.Job{
@@ -113,29 +202,57 @@ func (s *AudioService) ProcessAudio(ctx context.Context, inputPath, outputPath s
 		Log:        s.log,
 	}
 
-	var result *model.ProcessingResult
+	if options.DryRun {
+		return s.dryRun(ctx, job)
+	}
 
-	err := retry.Do(ctx, retry.Config{
-		MaxAttempts: options.MaxRetries,
-		Delay:       options.RetryDelay,
-		Multiplier:  2.0,
-		MaxDelay:    30 * time.Second,
-	}, func() error {
+	// Live streams run for as long as the input plays, so they have no
+	// timeout
+	timeout := options.Timeout
+	if pipeline.StreamsLive(outputPath, options) {
+		timeout = 0
+	}
+	ctx, end, err := s.begin(ctx, job.ID, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = end(err) }()
+
+	m := s.workerPool.Metrics()
+	stats := s.workerPool.Stats()
+	start := time.Now()
+	m.JobStarted(options)
+	stats.JobStarted()
+	s.pipeline.JobStarted(ctx, job)
+	var permanent error
+	retryCfg := pipeline.RetryConfig(options)
+	if pipeline.StreamsLive(outputPath, options) {
+		// The pipeline reconnects dropped streams instead
+		retryCfg.MaxAttempts = 1
+	}
+	retryCfg.OnRetry = func(attempt int, err error) {
+		s.pipeline.Retrying(ctx, job, attempt, err)
+	}
+	err = retry.Do(ctx, retryCfg, func() error {
 		var runErr error
 		result, runErr = s.pipeline.Run(ctx, job)
-		if runErr != nil {
-			// Don't retry validation errors
-			var valErr *pkgerrors.ValidationError
-			if isValidationError(runErr, &valErr) {
-				return nil // non-retryable: clear error to stop retries
-			}
+		if runErr != nil && !pkgerrors.IsRetryable(runErr) {
+			permanent = runErr
+			return nil // non-retryable: clear error to stop retries
 		}
 		return runErr
 	})
+	if permanent != nil {
+		err = permanent
+	}
+	m.JobFinished(options, result, err, time.Since(start))
+	stats.JobFinished(options, result, err, time.Since(start))
+	s.pipeline.JobCompleted(ctx, job, result, err)
 
 	if err != nil {
 		s.log.Error("audio processing failed",
 			zap.String("input", inputPath),
+			zap.Any("labels", options.Labels),
 			zap.Error(err),
 		)
 		return nil, err
@@ -144,13 +261,650 @@ func (s *AudioService) ProcessAudio(ctx context.Context, inputPath, outputPath s
 	s.log.Info("audio processing completed",
 		zap.String("output", outputPath),
 		zap.Duration("duration", result.Duration),
+		zap.Any("labels", options.Labels),
 	)
 
 	return result, nil
 }
 
+// ProcessAudioReader processes audio read from r (fed to ffmpeg via stdin).
+// A reader can only be consumed once, so failures are not retried.
+func (s *AudioService) ProcessAudioReader(ctx context.Context, r io.Reader, outputPath string, opts ...ports.Option) (_ *model.ProcessingResult, err error) {
+	options := s.options(opts)
+
+	job := &pipeline.Job{
+		ID:         generateJobID(outputPath),
+		OutputPath: outputPath,
+		Options:    options,
+		Reporter:   s.reporter,
+		Log:        s.log,
+	}
+
+	ctx, end, err := s.begin(ctx, job.ID, options.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = end(err) }()
+
+	result, err := s.pipeline.RunReader(ctx, job, r)
+	if err != nil {
+		s.log.Error("reader processing failed",
+			zap.String("output", outputPath),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+	return result, nil
+}
+
+// ProcessAudioWriter processes inputPath and streams the encoded output
+// into w. Partially written output cannot be taken back, so failures are
+// not retried.
+func (s *AudioService) ProcessAudioWriter(ctx context.Context, inputPath string, w io.Writer, opts ...ports.Option) (_ *model.ProcessingResult, err error) {
+	options := s.options(opts)
+
+	job := &pipeline.Job{
+		ID:        generateJobID(inputPath),
+		InputPath: inputPath,
+		Options:   options,
+		Reporter:  s.reporter,
+		Log:       s.log,
+	}
+
+	ctx, end, err := s.begin(ctx, job.ID, options.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = end(err) }()
+
+	result, err := s.pipeline.RunWriter(ctx, job, w)
+	if err != nil {
+		s.log.Error("writer processing failed",
+			zap.String("input", inputPath),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+	return result, nil
+}
+
+// PlanAudio resolves options, validates the job and returns the ffmpeg
+// command plan ProcessAudio would run, without executing it
+func (s *AudioService) PlanAudio(ctx context.Context, inputPath, outputPath string, opts ...ports.Option) (*model.CommandPlan, error) {
+	options := s.options(opts)
+
+	job := &pipeline.Job{
+		ID:         generateJobID(inputPath),
+		InputPath:  inputPath,
+		OutputPath: outputPath,
+		Options:    options,
+		Log:        s.log,
+	}
+	return s.pipeline.BuildPlan(ctx, job)
+}
+
+// ExecutePlan runs a previously produced command plan
+func (s *AudioService) ExecutePlan(ctx context.Context, plan *model.CommandPlan) (err error) {
+	if plan == nil {
+		return pkgerrors.NewValidationError("plan", nil, "plan must not be nil")
+	}
+	ctx, end, err := s.begin(ctx, plan.JobID, 0)
+	if err != nil {
+		return err
+	}
+	defer func() { err = end(err) }()
+
+	if err := s.pipeline.ExecutePlan(ctx, plan); err != nil {
+		s.log.Error("plan execution failed",
+			zap.String("job_id", plan.JobID),
+			zap.Error(err),
+		)
+		return err
+	}
+	return nil
+}
+
+// ExecutePlans runs command plans as one piped multi-stage command, each
+// stage reading the previous one's output without intermediate files
+func (s *AudioService) ExecutePlans(ctx context.Context, plans ...*model.CommandPlan) (err error) {
+	ctx, end, err := s.begin(ctx, planJobID(plans), 0)
+	if err != nil {
+		return err
+	}
+	defer func() { err = end(err) }()
+
+	if err := s.pipeline.ExecutePlans(ctx, plans...); err != nil {
+		s.log.Error("piped plan execution failed",
+			zap.Int("stages", len(plans)),
+			zap.Error(err),
+		)
+		return err
+	}
+	return nil
+}
+
+// GenerateTone renders a deterministic synthetic signal (sine, noise, sweep)
+// to outputPath, encoded according to opts
+func (s *AudioService) GenerateTone(ctx context.Context, spec model.ToneSpec, outputPath string, opts ...ports.Option) (_ *model.ProcessingResult, err error) {
+	options := s.options(opts)
+	if spec.SampleRate > 0 {
+		options.SampleRate = spec.SampleRate
+	}
+
+	job := &pipeline.Job{
+		ID:         generateJobID(outputPath),
+		OutputPath: outputPath,
+		Options:    options,
+		Reporter:   s.reporter,
+		Log:        s.log,
+	}
+
+	ctx, end, err := s.begin(ctx, job.ID, options.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = end(err) }()
+
+	result, err := s.pipeline.GenerateTone(ctx, job, spec)
+	if err != nil {
+		s.log.Error("tone generation failed",
+			zap.String("output", outputPath),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+	return result, nil
+}
+
+// GeneratePreview produces a short faded snippet of inputPath
+func (s *AudioService) GeneratePreview(ctx context.Context, inputPath, outputPath string, preview model.PreviewOptions, opts ...ports.Option) (_ *model.ProcessingResult, err error) {
+	options := s.options(opts)
+
+	job := &pipeline.Job{
+		ID:         generateJobID(inputPath),
+		InputPath:  inputPath,
+		OutputPath: outputPath,
+		Options:    options,
+		Reporter:   s.reporter,
+		Log:        s.log,
+	}
+
+	ctx, end, err := s.begin(ctx, job.ID, options.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = end(err) }()
+
+	result, err := s.pipeline.GeneratePreview(ctx, job, preview)
+	if err != nil {
+		s.log.Error("preview generation failed",
+			zap.String("input", inputPath),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+	return result, nil
+}
+
+// GenerateWaveform computes min/max peaks of inputPath for web players
+func (s *AudioService) GenerateWaveform(ctx context.Context, inputPath string, opts model.WaveformOptions) (_ *model.Waveform, err error) {
+	job := &pipeline.Job{
+		ID:        generateJobID(inputPath),
+		InputPath: inputPath,
+		Options:   model.DefaultProcessingOptions(),
+		Reporter:  s.reporter,
+		Log:       s.log,
+	}
+
+	ctx, end, err := s.begin(ctx, job.ID, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = end(err) }()
+
+	waveform, err := s.pipeline.GenerateWaveform(ctx, job, opts)
+	if err != nil {
+		s.log.Error("waveform generation failed",
+			zap.String("input", inputPath),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+	return waveform, nil
+}
+
+// AnalyzeAudio measures the sample peaks, DC offset and clipping of inputPath
+func (s *AudioService) AnalyzeAudio(ctx context.Context, inputPath string, opts model.AnalysisOptions) (_ *model.AudioAnalysis, err error) {
+	job := &pipeline.Job{
+		ID:        generateJobID(inputPath),
+		InputPath: inputPath,
+		Options:   model.DefaultProcessingOptions(),
+		Reporter:  s.reporter,
+		Log:       s.log,
+	}
+
+	// Analysis decodes the whole input, so it shares the encode lane
+	ctx, end, err := s.begin(ctx, job.ID, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = end(err) }()
+
+	analysis, err := s.pipeline.AnalyzeAudio(ctx, job, opts)
+	if err != nil {
+		s.log.Error("audio analysis failed",
+			zap.String("input", inputPath),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+	return analysis, nil
+}
+
+// BuildAudiobook concatenates inputs into an AAC audiobook at outputPath
+// with a chapter per input
+func (s *AudioService) BuildAudiobook(ctx context.Context, inputs []model.ChapterInput, outputPath string, opts ...ports.Option) (_ *model.ProcessingResult, err error) {
+	options := s.options(append([]ports.Option{ports.WithCodec(model.CodecAAC)}, opts...))
+
+	job := &pipeline.Job{
+		ID:         generateJobID(outputPath),
+		OutputPath: outputPath,
+		Options:    options,
+		Reporter:   s.reporter,
+		Log:        s.log,
+	}
+
+	ctx, end, err := s.begin(ctx, job.ID, options.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = end(err) }()
+
+	result, err := s.pipeline.BuildAudiobook(ctx, job, inputs)
+	if err != nil {
+		s.log.Error("audiobook build failed",
+			zap.String("output", outputPath),
+			zap.Int("chapters", len(inputs)),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+	return result, nil
+}
+
+// ProcessPodcastEpisode processes inputPath with the podcast preset and
+// mastering chain; opts are applied on top of the preset
+func (s *AudioService) ProcessPodcastEpisode(ctx context.Context, inputPath, outputPath string, podcast model.PodcastOptions, opts ...ports.Option) (*model.ProcessingResult, error) {
+	all := make([]ports.Option, 0, len(opts)+2)
+	all = append(all, presets.WithPreset(presets.Podcast))
+	all = append(all, opts...)
+	// Last, so the limiter follows the caller's true-peak limit
+	all = append(all, pipeline.WithPodcastMastering(podcast))
+	return s.ProcessAudio(ctx, inputPath, outputPath, all...)
+}
+
+// ExtractCoverArt writes the attached picture of inputPath to outputPath
+func (s *AudioService) ExtractCoverArt(ctx context.Context, inputPath, outputPath string) (_ *model.ProcessingResult, err error) {
+	job := &pipeline.Job{
+		ID:         generateJobID(inputPath),
+		InputPath:  inputPath,
+		OutputPath: outputPath,
+		Options:    s.options(nil),
+		Reporter:   s.reporter,
+		Log:        s.log,
+	}
+
+	ctx, end, err := s.begin(ctx, job.ID, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = end(err) }()
+
+	result, err := s.pipeline.ExtractCoverArt(ctx, job)
+	if err != nil {
+		s.log.Error("cover art extraction failed",
+			zap.String("input", inputPath),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+	return result, nil
+}
+
+// VerifyLossless compares the decoded samples of inputPath and outputPath
+func (s *AudioService) VerifyLossless(ctx context.Context, inputPath, outputPath string) (_ *model.LosslessVerification, err error) {
+	job := &pipeline.Job{
+		ID:         generateJobID(inputPath),
+		InputPath:  inputPath,
+		OutputPath: outputPath,
+		Options:    s.options(nil),
+		Reporter:   s.reporter,
+		Log:        s.log,
+	}
+
+	// Both files are decoded in full, so this shares the encode lane
+	ctx, end, err := s.begin(ctx, job.ID, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = end(err) }()
+
+	v, err := s.pipeline.VerifyLossless(ctx, job)
+	if err != nil {
+		s.log.Error("lossless verification failed",
+			zap.String("input", inputPath),
+			zap.String("output", outputPath),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+	if !v.Identical {
+		s.log.Warn("transcode is not lossless",
+			zap.String("input", inputPath),
+			zap.String("output", outputPath),
+			zap.String("mismatch", v.Mismatch),
+		)
+	}
+	return v, nil
+}
+
+// ValidateFile decodes path in full and reports its integrity
+func (s *AudioService) ValidateFile(ctx context.Context, path string) (_ *model.FileValidation, err error) {
+	job := &pipeline.Job{
+		ID:        generateJobID(path),
+		InputPath: path,
+		Options:   s.options(nil),
+		Reporter:  s.reporter,
+		Log:       s.log,
+	}
+
+	// A full decode costs about as much as an encode, so it shares their lane
+	ctx, end, err := s.begin(ctx, job.ID, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = end(err) }()
+
+	v, err := s.pipeline.ValidateFile(ctx, job)
+	if err != nil {
+		s.log.Error("file validation failed",
+			zap.String("input", path),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+	if !v.Valid {
+		s.log.Warn("file is damaged",
+			zap.String("input", path),
+			zap.Int("errors", v.ErrorCount),
+			zap.Bool("truncated", v.Truncated),
+		)
+	}
+	return v, nil
+}
+
+// ProcessLadder encodes several renditions of inputPath from a single decode
+func (s *AudioService) ProcessLadder(ctx context.Context, inputPath string, specs []model.RenditionSpec, opts ...ports.Option) (_ []*model.ProcessingResult, err error) {
+	options := s.options(opts)
+
+	job := &pipeline.Job{
+		ID:        generateJobID(inputPath),
+		InputPath: inputPath,
+		Options:   options,
+		Reporter:  s.reporter,
+		Log:       s.log,
+	}
+
+	ctx, end, err := s.begin(ctx, job.ID, options.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = end(err) }()
+
+	results, err := s.pipeline.ProcessLadder(ctx, job, specs)
+	if err != nil {
+		s.log.Error("ladder processing failed",
+			zap.String("input", inputPath),
+			zap.Int("renditions", len(specs)),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+	return results, nil
+}
+
+// ProcessChains processes each logical stream of a chained Ogg input into
+// its own output; outputPattern must contain model.ChainPlaceholder
+func (s *AudioService) ProcessChains(ctx context.Context, inputPath, outputPattern string, opts ...ports.Option) (_ []*model.ProcessingResult, err error) {
+	options := s.options(opts)
+
+	job := &pipeline.Job{
+		ID:         generateJobID(inputPath),
+		InputPath:  inputPath,
+		OutputPath: outputPattern,
+		Options:    options,
+		Reporter:   s.reporter,
+		Log:        s.log,
+	}
+
+	// The timeout applies to each chain; see Pipeline.ProcessChains
+	ctx, end, err := s.begin(ctx, job.ID, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = end(err) }()
+
+	results, err := s.pipeline.ProcessChains(ctx, job)
+	if err != nil {
+		s.log.Error("chain processing failed",
+			zap.String("input", inputPath),
+			zap.Int("completed", len(results)),
+			zap.Error(err),
+		)
+		return results, err
+	}
+	return results, nil
+}
+
+// SplitByCue cuts inputPath into the tracks of the CUE sheet at cuePath,
+// writing one output per track to outDir
+func (s *AudioService) SplitByCue(ctx context.Context, inputPath, cuePath, outDir string, opts ...ports.Option) (_ []*model.ProcessingResult, err error) {
+	options := s.options(opts)
+
+	job := &pipeline.Job{
+		ID:        generateJobID(inputPath),
+		InputPath: inputPath,
+		Options:   options,
+		Reporter:  s.reporter,
+		Log:       s.log,
+	}
+
+	// The timeout applies to each track; see Pipeline.SplitByCue
+	ctx, end, err := s.begin(ctx, job.ID, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = end(err) }()
+
+	results, err := s.pipeline.SplitByCue(ctx, job, cuePath, outDir)
+	if err != nil {
+		s.log.Error("cue splitting failed",
+			zap.String("input", inputPath),
+			zap.String("cue", cuePath),
+			zap.Int("completed", len(results)),
+			zap.Error(err),
+		)
+		return results, err
+	}
+	return results, nil
+}
+
+// PackageHLS encodes inputPath into an HLS bitrate ladder under dir
+func (s *AudioService) PackageHLS(ctx context.Context, inputPath, dir string, hls model.HLSOptions, opts ...ports.Option) (*model.PackageResult, error) {
+	return s.runPackaging(ctx, "HLS", inputPath, opts, func(ctx context.Context, job *pipeline.Job) (*model.PackageResult, error) {
+		return s.pipeline.PackageHLS(ctx, job, dir, hls)
+	})
+}
+
+// PackageDASH encodes inputPath into an MPEG-DASH bitrate ladder under dir
+func (s *AudioService) PackageDASH(ctx context.Context, inputPath, dir string, dash model.DASHOptions, opts ...ports.Option) (*model.PackageResult, error) {
+	return s.runPackaging(ctx, "DASH", inputPath, opts, func(ctx context.Context, job *pipeline.Job) (*model.PackageResult, error) {
+		return s.pipeline.PackageDASH(ctx, job, dir, dash)
+	})
+}
+
+func (s *AudioService) runPackaging(
+	ctx context.Context,
+	format, inputPath string,
+	opts []ports.Option,
+	run func(ctx context.Context, job *pipeline.Job) (*model.PackageResult, error),
+) (_ *model.PackageResult, err error) {
+	options := s.options(opts)
+
+	job := &pipeline.Job{
+		ID:        generateJobID(inputPath),
+		InputPath: inputPath,
+		Options:   options,
+		Reporter:  s.reporter,
+		Log:       s.log,
+	}
+
+	ctx, end, err := s.begin(ctx, job.ID, options.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = end(err) }()
+
+	result, err := run(ctx, job)
+	if err != nil {
+		s.log.Error("packaging failed",
+			zap.String("format", format),
+			zap.String("input", inputPath),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+	return result, nil
+}
+
+// CancelJob stops the queued or running batch jobs with the given ID and
+// reports whether any matched
+func (s *AudioService) CancelJob(jobID string) bool {
+	return s.workerPool.Cancel(jobID)
+}
+
+// Capabilities returns the ffmpeg capabilities detected at startup, or nil
+// when detection was skipped or failed
+func (s *AudioService) Capabilities() *model.Capabilities {
+	return s.pipeline.Capabilities()
+}
+
+// Stats returns a snapshot of runtime statistics
+func (s *AudioService) Stats() model.Stats {
+	return s.workerPool.Stats().Snapshot()
+}
+
+// Use registers a custom pipeline stage run before the input is probed
+func (s *AudioService) Use(name string, stage pipeline.Stage) {
+	s.pipeline.Use(name, stage)
+}
+
+// UseAfter registers a custom pipeline stage run before outputs are
+// moved into place
+func (s *AudioService) UseAfter(name string, stage pipeline.Stage) {
+	s.pipeline.UseAfter(name, stage)
+}
+
+// UseMiddleware wraps every pipeline stage with mw
+func (s *AudioService) UseMiddleware(mw ...pipeline.StageMiddleware) {
+	s.pipeline.UseMiddleware(mw...)
+}
+
+// Shutdown stops accepting jobs and waits for running ones to finish,
+// canceling them once ctx is done
+func (s *AudioService) Shutdown(ctx context.Context) error {
+	s.log.Info("shutting down, draining running jobs")
+	return s.workerPool.Shutdown(ctx)
+}
+
+// DeadLetters returns the batch jobs that failed after exhausting their
+// retries, oldest failure first
+func (s *AudioService) DeadLetters(ctx context.Context) ([]model.DeadLetter, error) {
+	return s.workerPool.DeadLetters().List(ctx)
+}
+
+// RetryFailed removes the dead-lettered jobs from the store and processes
+// them again as a batch. Jobs that fail again are dead-lettered anew; if
+// the batch can't be started, the removed jobs are put back.
+func (s *AudioService) RetryFailed(ctx context.Context) (<-chan model.BatchResult, error) {
+	store := s.workerPool.DeadLetters()
+	letters, err := store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]model.BatchJob, 0, len(letters))
+	for i, l := range letters {
+		if err := store.Remove(ctx, l.Job.ID); err != nil {
+			s.restoreDeadLetters(letters[:i])
+			return nil, err
+		}
+		jobs = append(jobs, l.Job)
+	}
+
+	s.log.Info("retrying dead-lettered jobs", zap.Int("job_count", len(jobs)))
+	results, err := s.ProcessBatch(ctx, jobs)
+	if err != nil {
+		s.restoreDeadLetters(letters)
+		return nil, err
+	}
+	return results, nil
+}
+
+// restoreDeadLetters puts back letters removed for a retry that didn't
+// start. ctx may be the reason it didn't, so they are added without it.
+func (s *AudioService) restoreDeadLetters(letters []model.DeadLetter) {
+	store := s.workerPool.DeadLetters()
+	for _, l := range letters {
+		if err := store.Add(context.Background(), l); err != nil {
+			s.log.Error("failed to restore dead-lettered job", zap.String("job_id", l.Job.ID), zap.Error(err))
+		}
+	}
+}
+
+// AddReporter subscribes r to the progress updates of all jobs, in
+// addition to the configured Reporter
+func (s *AudioService) AddReporter(r progress.Reporter) {
+	s.reporter.Add(r)
+}
+
+// Defaults returns the service defaults, applied to every job ahead of its
+// own options
+func (s *AudioService) Defaults() []ports.Option {
+	return append([]ports.Option(nil), s.defaults...)
+}
+
+// options applies the service defaults and then opts on top of the
+// built-in default options
+func (s *AudioService) options(opts []ports.Option) *model.ProcessingOptions {
+	options := model.DefaultProcessingOptions()
+	for _, o := range s.defaults {
+		o(options)
+	}
+	for _, o := range opts {
+		o(options)
+	}
+	return options
+}
+
 // ProcessBatch processes multiple jobs concurrently
 func (s *AudioService) ProcessBatch(ctx context.Context, jobs []model.BatchJob) (<-chan model.BatchResult, error) {
+	return s.ProcessBatchWithPolicy(ctx, jobs, model.BatchPolicy{})
+}
+
+// ProcessBatchWithPolicy is ProcessBatch, aborting the remaining jobs when
+// policy trips
+func (s *AudioService) ProcessBatchWithPolicy(ctx context.Context, jobs []model.BatchJob, policy model.BatchPolicy) (<-chan model.BatchResult, error) {
 	if len(jobs) == 0 {
 		ch := make(chan model.BatchResult)
 		close(ch)
@@ -159,29 +913,116 @@ func (s *AudioService) ProcessBatch(ctx context.Context, jobs []model.BatchJob)
 
 	s.log.Info("starting batch processing",
 		zap.Int("job_count", len(jobs)),
+		zap.Bool("fail_fast", policy.FailFast),
+		zap.Float64("max_failure_percent", policy.MaxFailurePercent),
 	)
 
-	return s.workerPool.Run(ctx, jobs, s.reporter)
+	if len(s.defaults) > 0 {
+		// Jobs without options get the service defaults, not the built-in ones
+		jobs = append([]model.BatchJob(nil), jobs...)
+		for i := range jobs {
+			if jobs[i].Options == nil {
+				jobs[i].Options = s.options(nil)
+			}
+		}
+	}
+
+	return s.workerPool.RunWithPolicy(ctx, jobs, s.reporter, policy)
+}
+
+// planJobID names piped plans after their last stage, which writes the
+// final output
+func planJobID(plans []*model.CommandPlan) string {
+	if n := len(plans); n > 0 && plans[n-1] != nil {
+		return plans[n-1].JobID
+	}
+	return generateJobID("plans")
+}
+
+// admit waits for the admission governor, if one is installed, to let
+// the job jobID start
+func (s *AudioService) admit(ctx context.Context, jobID string) error {
+	if g := s.workerPool.Governor(); g != nil {
+		return g.Admit(ctx, jobID)
+	}
+	return nil
+}
+
+// begin waits for admission, registers a single-file operation with the
+// pool, so that CancelJob and Shutdown reach it, and takes a slot in the
+// encode lane, which it shares with batch jobs. The operation must run on
+// the returned context and pass its error to end when it finishes; see
+// WorkerPool.Begin and encodeSlot.
+func (s *AudioService) begin(ctx context.Context, jobID string, timeout time.Duration) (_ context.Context, end func(error) error, err error) {
+	if err := s.admit(ctx, jobID); err != nil {
+		return nil, nil, err
+	}
+	ctx, done, err := s.workerPool.Begin(ctx, jobID)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, release, err := s.encodeSlot(ctx, timeout)
+	if err != nil {
+		return nil, nil, done(err)
+	}
+	return ctx, func(err error) error {
+		release()
+		return done(err)
+	}, nil
+}
+
+// dryRun builds job's command without an encode slot, admission or
+// metrics; nothing is encoded
+func (s *AudioService) dryRun(ctx context.Context, job *pipeline.Job) (_ *model.ProcessingResult, err error) {
+	ctx, done, err := s.workerPool.Begin(ctx, job.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = done(err) }()
+	return s.pipeline.Run(ctx, job)
+}
+
+// encodeSlot waits for a slot in the encode lane, which single-file
+// operations share with batch jobs, and then starts timeout, so time spent
+// queued doesn't count against it. Zero means no timeout. release frees
+// the slot.
+func (s *AudioService) encodeSlot(ctx context.Context, timeout time.Duration) (_ context.Context, release func(), err error) {
+	lane := s.workerPool.Lane()
+	if err := lane.Acquire(ctx); err != nil {
+		return nil, nil, err
+	}
+	if timeout <= 0 {
+		return ctx, lane.Release, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		lane.Release()
+	}, nil
 }
 
 // ProbeAudio returns metadata about an audio file without processing it
 func (s *AudioService) ProbeAudio(ctx context.Context, inputPath string) (*model.AudioMetadata, error) {
-	exists, err := s.storage.Exists(ctx, inputPath)
-	if err != nil {
-		return nil, pkgerrors.NewProcessingError("probe", "failed to check file", err)
+	if !pipeline.IsRemoteInput(inputPath) {
+		exists, err := s.storage.Exists(ctx, inputPath)
+		if err != nil {
+			return nil, pkgerrors.NewProcessingError("probe", "failed to check file", err)
+		}
+		if !exists {
+			return nil, pkgerrors.NewValidationError("inputPath", inputPath, "file does not exist")
+		}
 	}
-	if !exists {
-		return nil, pkgerrors.NewValidationError("inputPath", inputPath, "file does not exist")
+
+	// Probes run on their own lane so they never wait behind encodes
+	if err := s.probeLane.Acquire(ctx); err != nil {
+		return nil, err
 	}
+	defer s.probeLane.Release()
 
 	// Probe via pipeline public API.
 	return s.pipeline.ProbeFile(ctx, inputPath)
 }
 
-func isValidationError(err error, target **pkgerrors.ValidationError) bool {
-	return errors.As(err, target)
-}
-
 func generateJobID(input string) string {
 	return fmt.Sprintf("job-%d-%s", time.Now().UnixNano(), sanitize(input))
 }
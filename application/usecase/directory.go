@@ -0,0 +1,190 @@
+package usecase
+
+import (
+	"context"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/domain/ports"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"github.com/Skryldev/audio-lab/pkg/pathutil"
+	"go.uber.org/zap"
+)
+
+// ProcessDirectory discovers audio files under inputDir and processes
+// them as a batch, mirroring the directory structure under outputDir
+func (s *AudioService) ProcessDirectory(ctx context.Context, inputDir, outputDir string, dir model.DirOptions, opts ...ports.Option) (<-chan model.BatchResult, error) {
+	jobs, err := s.DirectoryJobs(ctx, inputDir, outputDir, dir, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Info("discovered directory inputs",
+		zap.String("input_dir", inputDir),
+		zap.String("output_dir", outputDir),
+		zap.Int("files", len(jobs)),
+	)
+
+	return s.ProcessBatch(ctx, jobs)
+}
+
+// DirectoryJobs builds the batch ProcessDirectory would run, in lexical
+// path order, without running it. Inputs mapping to the same output, such
+// as a.wav and a.flac, are rejected rather than overwriting each other.
+func (s *AudioService) DirectoryJobs(ctx context.Context, inputDir, outputDir string, dir model.DirOptions, opts ...ports.Option) ([]model.BatchJob, error) {
+	if inputDir == "" {
+		return nil, pkgerrors.NewValidationError("inputDir", "", "input directory must not be empty")
+	}
+	if outputDir == "" {
+		return nil, pkgerrors.NewValidationError("outputDir", "", "output directory must not be empty")
+	}
+
+	options := s.options(opts)
+
+	ext, include, err := dirSelection(dir, options)
+	if err != nil {
+		return nil, err
+	}
+
+	inputs, err := s.discover(ctx, inputDir, outputDir, dir.Recursive, include, dir.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]model.BatchJob, 0, len(inputs))
+	outputs := make(map[string]string, len(inputs)) // output key -> input
+	for _, in := range inputs {
+		out, err := mirrorPath(inputDir, outputDir, in, ext)
+		if err != nil {
+			return nil, err
+		}
+		if prev, ok := outputs[pathutil.Key(out)]; ok {
+			return nil, pkgerrors.NewValidationError("outputDir", out, "inputs "+prev+" and "+in+" map to the same output")
+		}
+		outputs[pathutil.Key(out)] = in
+
+		jobOpts := *options
+		jobs = append(jobs, model.BatchJob{
+			ID:         generateJobID(in),
+			InputPath:  in,
+			OutputPath: out,
+			Options:    &jobOpts,
+		})
+	}
+	return jobs, nil
+}
+
+// dirSelection validates dir and returns the output extension and the
+// effective include patterns
+func dirSelection(dir model.DirOptions, options *model.ProcessingOptions) (string, []string, error) {
+	ext := dir.OutputExt
+	if ext == "" {
+		ext = options.Codec.Extension()
+		if ext == "" {
+			return "", nil, pkgerrors.NewValidationError("outputExt", "", "no default extension for codec "+string(options.Codec))
+		}
+	}
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+
+	include := dir.Include
+	if len(include) == 0 {
+		include = model.DefaultAudioPatterns
+	}
+	for _, pattern := range append(append([]string{}, include...), dir.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return "", nil, pkgerrors.NewValidationError("pattern", pattern, "malformed glob pattern")
+		}
+	}
+	return ext, include, nil
+}
+
+// mirrorPath maps input, found under inputDir, to the same relative path
+// under outputDir with its extension replaced by ext
+func mirrorPath(inputDir, outputDir, input, ext string) (string, error) {
+	rel, err := filepath.Rel(inputDir, input)
+	if err != nil {
+		return "", pkgerrors.NewProcessingError("discover", "failed to relativize input path", err)
+	}
+	out := filepath.Join(outputDir, strings.TrimSuffix(rel, filepath.Ext(rel))+ext)
+	if pathutil.Key(out) == pathutil.Key(input) {
+		return "", pkgerrors.NewValidationError("outputDir", outputDir, "output would overwrite input "+input)
+	}
+	return out, nil
+}
+
+// discover walks root breadth-first and returns the matching files in
+// lexical order. outputDir is never descended into, so re-running into a
+// subdirectory of the input doesn't pick up earlier outputs.
+func (s *AudioService) discover(ctx context.Context, root, outputDir string, recursive bool, include, exclude []string) ([]string, error) {
+	outputKey := pathutil.Key(filepath.Clean(outputDir))
+
+	var found []string
+	queue := []string{root}
+	for len(queue) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		dir := queue[0]
+		queue = queue[1:]
+
+		files, err := s.storage.List(ctx, dir)
+		if err != nil {
+			return nil, pkgerrors.NewProcessingError("discover", "failed to list "+dir, err)
+		}
+		for _, f := range files {
+			rel := relSlash(root, f)
+			if matchAny(include, rel) && !matchAny(exclude, rel) {
+				found = append(found, f)
+			}
+		}
+
+		if !recursive {
+			continue
+		}
+		subdirs, err := s.storage.ListDirs(ctx, dir)
+		if err != nil {
+			return nil, pkgerrors.NewProcessingError("discover", "failed to list "+dir, err)
+		}
+		for _, d := range subdirs {
+			if pathutil.Key(filepath.Clean(d)) == outputKey || matchAny(exclude, relSlash(root, d)) {
+				continue
+			}
+			queue = append(queue, d)
+		}
+	}
+
+	sort.Strings(found)
+	return found, nil
+}
+
+// relSlash returns p relative to root with forward slashes
+func relSlash(root, p string) string {
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		rel = filepath.Base(p)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// matchAny reports whether rel matches any pattern, case-insensitively.
+// Patterns without a "/" match the last path element only.
+func matchAny(patterns []string, rel string) bool {
+	rel = strings.ToLower(rel)
+	base := path.Base(rel)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		target := base
+		if strings.Contains(pattern, "/") {
+			target = rel
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
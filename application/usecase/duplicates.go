@@ -0,0 +1,209 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/Skryldev/audio-lab/application/pipeline"
+	"github.com/Skryldev/audio-lab/domain/model"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"github.com/Skryldev/audio-lab/pkg/pathutil"
+	"go.uber.org/zap"
+)
+
+// FindDuplicates is a batch preflight: it hashes the decoded audio of every
+// job's input and groups the jobs that would encode the same audio with the
+// same settings, such as a WAV and a FLAC of one recording. Jobs whose
+// metadata policy copies the input's tags or chapters only match inputs
+// carrying the same ones. Each input is decoded in full once. Jobs with
+// additional outputs never match.
+func (s *AudioService) FindDuplicates(ctx context.Context, jobs []model.BatchJob) (*model.DuplicateReport, error) {
+	report := &model.DuplicateReport{}
+	hashes := make(map[string]string) // input and stream -> audio hash, "" when undecodable
+	tags := make(map[string]string)   // input and metadata policy -> copied metadata key
+	groups := make(map[string]int)    // audio hash and encoding key -> index into found
+	var found []model.DuplicateGroup
+
+	for _, bj := range jobs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		opts := bj.Options
+		if opts == nil {
+			opts = s.options(nil)
+		}
+		job := &pipeline.Job{
+			ID:         bj.ID,
+			InputPath:  bj.InputPath,
+			OutputPath: bj.OutputPath,
+			Options:    opts,
+			Log:        s.log,
+		}
+
+		encoding, err := pipeline.EncodingKey(job)
+		if err != nil {
+			// Invalid jobs fail on their own when the batch runs
+			report.Unchecked = append(report.Unchecked, bj.ID)
+			continue
+		}
+		input := inputStreamKey(bj.InputPath, opts)
+		hash, ok := hashes[input]
+		if !ok {
+			hash, err = s.pipeline.AudioHash(ctx, job)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				s.log.Warn("failed to hash input audio", zap.String("job_id", bj.ID), zap.Error(err))
+			}
+			hashes[input] = hash
+		}
+		if hash == "" {
+			report.Unchecked = append(report.Unchecked, bj.ID)
+			continue
+		}
+
+		tagsInput := pathutil.Key(bj.InputPath) + "\x00" + string(opts.MetadataPolicy)
+		copied, ok := tags[tagsInput]
+		if !ok {
+			copied, err = s.pipeline.InputMetadataKey(ctx, job)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				s.log.Warn("failed to read input metadata", zap.String("job_id", bj.ID), zap.Error(err))
+				report.Unchecked = append(report.Unchecked, bj.ID)
+				continue
+			}
+			tags[tagsInput] = copied
+		}
+
+		key := hash + "/" + encoding + "/" + copied
+		i, ok := groups[key]
+		if !ok {
+			i = len(found)
+			groups[key] = i
+			found = append(found, model.DuplicateGroup{AudioHash: hash})
+		}
+		found[i].JobIDs = append(found[i].JobIDs, bj.ID)
+		found[i].Inputs = append(found[i].Inputs, bj.InputPath)
+	}
+
+	for _, g := range found {
+		if len(g.JobIDs) > 1 {
+			report.Groups = append(report.Groups, g)
+		}
+	}
+	if n := report.Duplicates(); n > 0 {
+		s.log.Info("duplicate inputs found",
+			zap.Int("job_count", len(jobs)),
+			zap.Int("duplicates", n),
+			zap.Int("groups", len(report.Groups)),
+		)
+	}
+	return report, nil
+}
+
+// ProcessBatchDeduplicated runs FindDuplicates on jobs and then processes
+// them like ProcessBatch, handling the duplicates per action. Skipped and
+// linked duplicates get their result once the first job of their group
+// finishes, with DuplicateOf set; they fail when it fails.
+func (s *AudioService) ProcessBatchDeduplicated(ctx context.Context, jobs []model.BatchJob, action model.DuplicateAction) (<-chan model.BatchResult, *model.DuplicateReport, error) {
+	switch action {
+	case model.DuplicateFlag, model.DuplicateSkip, model.DuplicateLink:
+	default:
+		return nil, nil, pkgerrors.NewValidationError("action", action, "duplicate action must be flag, skip or link")
+	}
+
+	report, err := s.FindDuplicates(ctx, jobs)
+	if err != nil {
+		return nil, nil, err
+	}
+	if action == model.DuplicateFlag || len(report.Groups) == 0 {
+		results, err := s.ProcessBatch(ctx, jobs)
+		return results, report, err
+	}
+
+	byID := make(map[string]model.BatchJob, len(jobs))
+	for _, j := range jobs {
+		byID[j.ID] = j
+	}
+	duplicates := make(map[string][]model.BatchJob) // first job ID -> the jobs it stands in for
+	skip := make(map[string]bool)
+	for _, g := range report.Groups {
+		for _, id := range g.JobIDs[1:] {
+			duplicates[g.JobIDs[0]] = append(duplicates[g.JobIDs[0]], byID[id])
+			skip[id] = true
+		}
+	}
+	run := make([]model.BatchJob, 0, len(jobs)-len(skip))
+	for _, j := range jobs {
+		if !skip[j.ID] {
+			run = append(run, j)
+		}
+	}
+
+	inner, err := s.ProcessBatch(ctx, run)
+	if err != nil {
+		return nil, nil, err
+	}
+	results := make(chan model.BatchResult, len(jobs))
+	go func() {
+		defer close(results)
+		for r := range inner {
+			results <- r
+			for _, d := range duplicates[r.JobID] {
+				dr := s.duplicateResult(ctx, byID[r.JobID], d, r, action)
+				if d.OnComplete != nil {
+					d.OnComplete(dr)
+				}
+				results <- dr
+			}
+		}
+	}()
+	return results, report, nil
+}
+
+// duplicateResult settles job d, a duplicate of first, from first's result
+func (s *AudioService) duplicateResult(ctx context.Context, first, d model.BatchJob, r model.BatchResult, action model.DuplicateAction) model.BatchResult {
+	dr := model.BatchResult{JobID: d.ID, Profile: r.Profile, DuplicateOf: first.ID}
+	if r.Err != nil {
+		dr.Err = fmt.Errorf("duplicate of failed job %s: %w", first.ID, r.Err)
+		return dr
+	}
+	if action == model.DuplicateSkip {
+		dr.Skipped = true
+		return dr
+	}
+
+	opts := d.Options
+	if opts == nil {
+		opts = s.options(nil)
+	}
+	if err := s.pipeline.LinkOutput(ctx, opts, first.OutputPath, d.OutputPath); err != nil {
+		s.log.Error("failed to link duplicate output",
+			zap.String("job_id", d.ID),
+			zap.String("output", d.OutputPath),
+			zap.Error(err),
+		)
+		dr.Err = err
+		return dr
+	}
+	if r.Result != nil {
+		result := *r.Result
+		result.InputPath = d.InputPath
+		result.OutputPath = d.OutputPath
+		dr.Result = &result
+	}
+	return dr
+}
+
+// inputStreamKey identifies the audio a job decodes from its input
+func inputStreamKey(path string, opts *model.ProcessingOptions) string {
+	key := pathutil.Key(path) + "\x00" + opts.AudioLanguage
+	if opts.AudioStream != nil {
+		key += "\x00" + strconv.Itoa(*opts.AudioStream)
+	}
+	return key
+}
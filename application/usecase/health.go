@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/domain/ports"
+	"github.com/Skryldev/audio-lab/infrastructure/ffmpeg"
+	"github.com/Skryldev/audio-lab/infrastructure/storage"
+)
+
+const (
+	// healthCheckTimeout bounds each component check
+	healthCheckTimeout = 5 * time.Second
+
+	// minHealthyTempSpace is the free space the temp directory needs to
+	// hold intermediate files of a typical job
+	minHealthyTempSpace = 256 << 20
+)
+
+// HealthCheck verifies that ffmpeg and ffprobe run, every codec has an
+// encoder, the temp directory is writable with room to spare and the
+// worker pool gets an encode slot within the check timeout. The report is
+// unhealthy if any check fails.
+func (s *AudioService) HealthCheck(ctx context.Context) model.HealthReport {
+	report := model.HealthReport{Healthy: true, CheckedAt: time.Now()}
+	add := func(name string, check func(ctx context.Context) (skipped bool, msg string, err error)) {
+		checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+		defer cancel()
+
+		start := time.Now()
+		skipped, msg, err := check(checkCtx)
+		c := model.ComponentHealth{
+			Name:    name,
+			Healthy: err == nil,
+			Skipped: skipped,
+			Message: msg,
+			Latency: time.Since(start),
+		}
+		if err != nil {
+			c.Message = err.Error()
+			report.Healthy = false
+		}
+		report.Checks = append(report.Checks, c)
+	}
+
+	add("ffmpeg", s.checkFFmpeg)
+	add("ffprobe", s.checkFFprobe)
+	add("encoders", s.checkEncoders)
+	add("temp_dir", s.checkTempDir)
+	add("workers", func(ctx context.Context) (bool, string, error) {
+		if err := s.workerPool.Ping(ctx); err != nil {
+			return false, "", err
+		}
+		st := s.Stats()
+		return false, fmt.Sprintf("%d in flight, %d queued", st.InFlight, st.Queued), nil
+	})
+	return report
+}
+
+func (s *AudioService) checkFFmpeg(ctx context.Context) (bool, string, error) {
+	args := []string{"-hide_banner", "-version"}
+	if !ffmpeg.SupportsPipes(s.executor) {
+		return false, "", s.executor.Execute(ctx, args)
+	}
+	out, _, err := ffmpeg.Piped(s.executor).ExecuteWithOutput(ctx, args)
+	if err != nil {
+		return false, "", err
+	}
+	if v := ffmpeg.ParseVersion(out); v != "" {
+		return false, "version " + v, nil
+	}
+	return false, "", nil
+}
+
+// checkFFprobe runs ffprobe when the executor, or one it wraps, can
+func (s *AudioService) checkFFprobe(ctx context.Context) (bool, string, error) {
+	exec := s.executor
+	for exec != nil {
+		if pc, ok := exec.(ports.ProbeChecker); ok {
+			return false, "", pc.CheckProbe(ctx)
+		}
+		w, ok := exec.(interface{ Unwrap() ports.FFmpegExecutor })
+		if !ok {
+			break
+		}
+		exec = w.Unwrap()
+	}
+	return true, "executor cannot check ffprobe", nil
+}
+
+func (s *AudioService) checkEncoders(context.Context) (bool, string, error) {
+	if !s.pipeline.Capabilities().Detected() {
+		return true, "ffmpeg capabilities not detected", nil
+	}
+	if missing := s.pipeline.UnavailableCodecs(); len(missing) > 0 {
+		names := make([]string, len(missing))
+		for i, c := range missing {
+			names[i] = string(c)
+		}
+		return false, "", fmt.Errorf("no encoder for %s", strings.Join(names, ", "))
+	}
+	return false, "", nil
+}
+
+func (s *AudioService) checkTempDir(ctx context.Context) (bool, string, error) {
+	temps := s.pipeline.TempManager()
+	if err := temps.CheckWritable(); err != nil {
+		return false, "", err
+	}
+	free, err := storage.NewLocalStorage().FreeSpace(ctx, temps.Dir())
+	if err != nil {
+		// Platforms without free space reporting only get the write check
+		return false, temps.Dir(), nil
+	}
+	if free < minHealthyTempSpace {
+		return false, "", fmt.Errorf("%s has %d MiB free, want at least %d MiB", temps.Dir(), free>>20, minHealthyTempSpace>>20)
+	}
+	return false, fmt.Sprintf("%s, %d MiB free", temps.Dir(), free>>20), nil
+}
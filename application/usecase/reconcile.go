@@ -0,0 +1,106 @@
+package usecase
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Skryldev/audio-lab/application/pipeline"
+	"github.com/Skryldev/audio-lab/domain/model"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"github.com/Skryldev/audio-lab/pkg/pathutil"
+	"go.uber.org/zap"
+)
+
+// ReconcileBatch checks the expected outputs of jobs against storage,
+// reporting missing and zero-byte outputs plus unexpected files in the
+// output directories. It catches storage failures that per-job success
+// flags miss. Only files with an extension the batch writes to their
+// directory, or left-over temp files of such outputs, count as orphans;
+// other files sharing the directories are ignored.
+func (s *AudioService) ReconcileBatch(ctx context.Context, jobs []model.BatchJob) (*model.ReconciliationReport, error) {
+	expected := make(map[string]bool)
+	dirs := make(map[string]map[string]bool) // output directory -> extensions written there
+
+	add := func(path string) {
+		// Live sinks leave nothing in storage to check
+		if path == "" || pipeline.StreamsLive(path, &model.ProcessingOptions{}) {
+			return
+		}
+		clean := pathutil.Key(path)
+		expected[clean] = true
+		dir := filepath.Dir(clean)
+		if dirs[dir] == nil {
+			dirs[dir] = make(map[string]bool)
+		}
+		dirs[dir][strings.ToLower(filepath.Ext(clean))] = true
+	}
+	for _, job := range jobs {
+		add(job.OutputPath)
+		if job.Options != nil {
+			for _, out := range job.Options.AdditionalOutputs {
+				add(out.Path)
+			}
+		}
+	}
+
+	report := &model.ReconciliationReport{}
+
+	for path := range expected {
+		exists, err := s.storage.Exists(ctx, path)
+		if err != nil {
+			return nil, pkgerrors.NewProcessingError("reconcile", "failed to check output", err)
+		}
+		if !exists {
+			report.Missing = append(report.Missing, path)
+			continue
+		}
+		size, err := s.storage.Size(ctx, path)
+		if err != nil {
+			return nil, pkgerrors.NewProcessingError("reconcile", "failed to stat output", err)
+		}
+		if size == 0 {
+			report.Empty = append(report.Empty, path)
+			continue
+		}
+		report.OK = append(report.OK, path)
+	}
+
+	for dir, exts := range dirs {
+		files, err := s.storage.List(ctx, dir)
+		if err != nil {
+			// a missing directory already shows up as missing outputs
+			s.log.Warn("failed to list output directory", zap.String("dir", dir), zap.Error(err))
+			continue
+		}
+		for _, f := range files {
+			key := pathutil.Key(f)
+			if expected[key] {
+				continue
+			}
+			// Atomic writes encode into "<output>.tmp.<random>"
+			if i := strings.LastIndex(key, ".tmp."); i >= 0 {
+				key = key[:i]
+			}
+			if exts[strings.ToLower(filepath.Ext(key))] {
+				report.Orphans = append(report.Orphans, f)
+			}
+		}
+	}
+
+	sort.Strings(report.OK)
+	sort.Strings(report.Missing)
+	sort.Strings(report.Empty)
+	sort.Strings(report.Orphans)
+
+	if !report.Clean() {
+		s.log.Warn("batch reconciliation found problems",
+			zap.Int("missing", len(report.Missing)),
+			zap.Int("empty", len(report.Empty)),
+			zap.Int("orphans", len(report.Orphans)),
+		)
+	}
+
+	return report, nil
+}
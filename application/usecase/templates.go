@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"context"
+	"regexp"
+	"sync"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"github.com/Skryldev/audio-lab/pkg/pathutil"
+)
+
+var placeholderRe = regexp.MustCompile(`\{([A-Za-z0-9_]+)\}`)
+
+// templateRegistry holds named job templates
+type templateRegistry struct {
+	mu        sync.RWMutex
+	templates map[string]model.JobTemplate
+}
+
+func newTemplateRegistry() *templateRegistry {
+	return &templateRegistry{templates: make(map[string]model.JobTemplate)}
+}
+
+// RegisterTemplate adds or replaces a named job template
+func (s *AudioService) RegisterTemplate(t model.JobTemplate) error {
+	if t.Name == "" {
+		return pkgerrors.NewValidationError("name", "", "template name must not be empty")
+	}
+	if t.InputPath == "" || t.OutputPath == "" {
+		return pkgerrors.NewValidationError("path", t.Name, "template input and output paths must not be empty")
+	}
+
+	s.templates.mu.Lock()
+	defer s.templates.mu.Unlock()
+	s.templates.templates[t.Name] = t
+	return nil
+}
+
+// ExpandTemplate builds a BatchJob from a named template and parameters.
+// The reserved "id" parameter, if present, becomes the job ID.
+func (s *AudioService) ExpandTemplate(name string, params map[string]string) (model.BatchJob, error) {
+	s.templates.mu.RLock()
+	t, ok := s.templates.templates[name]
+	s.templates.mu.RUnlock()
+	if !ok {
+		return model.BatchJob{}, pkgerrors.NewValidationError("template", name, "unknown job template")
+	}
+
+	input, err := substitute(t.InputPath, params, t.Defaults, nil)
+	if err != nil {
+		return model.BatchJob{}, err
+	}
+	var clean func(string) string
+	if t.SanitizeNames {
+		clean = func(v string) string { return pathutil.SanitizeName(v, 0) }
+	}
+	output, err := substitute(t.OutputPath, params, t.Defaults, clean)
+	if err != nil {
+		return model.BatchJob{}, err
+	}
+
+	var opts *model.ProcessingOptions
+	if t.Options != nil {
+		copied := *t.Options
+		opts = &copied
+	}
+
+	id := params["id"]
+	if id == "" {
+		id = generateJobID(input)
+	}
+
+	return model.BatchJob{
+		ID:         id,
+		InputPath:  input,
+		OutputPath: output,
+		Options:    opts,
+	}, nil
+}
+
+// ProcessTemplate expands a template once per parameter set and runs the
+// resulting jobs as a batch
+func (s *AudioService) ProcessTemplate(ctx context.Context, name string, params ...map[string]string) (<-chan model.BatchResult, error) {
+	jobs := make([]model.BatchJob, 0, len(params))
+	for _, p := range params {
+		job, err := s.ExpandTemplate(name, p)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return s.ProcessBatch(ctx, jobs)
+}
+
+// substitute fills placeholders in pattern, passing each value through
+// clean when it is non-nil
+func substitute(pattern string, params, defaults map[string]string, clean func(string) string) (string, error) {
+	var missing string
+	out := placeholderRe.ReplaceAllStringFunc(pattern, func(m string) string {
+		key := m[1 : len(m)-1]
+		v, ok := params[key]
+		if !ok {
+			v, ok = defaults[key]
+		}
+		if ok {
+			if clean != nil {
+				return clean(v)
+			}
+			return v
+		}
+		if missing == "" {
+			missing = key
+		}
+		return m
+	})
+	if missing != "" {
+		return "", pkgerrors.NewValidationError("params", missing, "missing template parameter")
+	}
+	return out, nil
+}
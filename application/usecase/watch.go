@@ -0,0 +1,201 @@
+package usecase
+
+import (
+	"context"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/domain/ports"
+	"github.com/Skryldev/audio-lab/infrastructure/watcher"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"github.com/Skryldev/audio-lab/pkg/pathutil"
+	"go.uber.org/zap"
+)
+
+// watchResultBuffer lets a few results queue up while the caller is busy
+const watchResultBuffer = 16
+
+// Watch processes files as they appear under dir, once they have stopped
+// growing, until ctx is canceled. One result is sent per file; the channel
+// is closed once ctx is canceled and in-flight jobs have finished, and must
+// be drained.
+func (s *AudioService) Watch(ctx context.Context, dir string, rules model.WatchRules, opts ...ports.Option) (<-chan model.WatchResult, error) {
+	if dir == "" {
+		return nil, pkgerrors.NewValidationError("dir", "", "watch directory must not be empty")
+	}
+	if err := validateWatchRules(dir, rules); err != nil {
+		return nil, err
+	}
+
+	options := s.options(opts)
+	ext, include, err := dirSelection(rules.DirOptions, options)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := watcher.New(dir, watcher.Config{
+		Recursive: rules.Recursive,
+		StableFor: rules.StableFor,
+		Existing:  rules.ProcessExisting,
+		Ignore:    []string{rules.OutputDir, rules.DoneDir, rules.ErrorDir},
+	}, s.log)
+	if err != nil {
+		return nil, pkgerrors.NewIOError(dir, "failed to watch directory", err)
+	}
+
+	s.log.Info("watching directory",
+		zap.String("dir", dir),
+		zap.String("output_dir", rules.OutputDir),
+		zap.Bool("recursive", rules.Recursive),
+	)
+
+	files := make(chan string)
+	go func() {
+		defer close(files)
+		if err := w.Run(ctx, files); err != nil && ctx.Err() == nil {
+			s.log.Error("watch stopped", zap.String("dir", dir), zap.Error(err))
+		}
+	}()
+
+	results := make(chan model.WatchResult, watchResultBuffer)
+	go func() {
+		defer close(results)
+
+		var wg sync.WaitGroup
+		for in := range files {
+			rel := relSlash(dir, in)
+			if !matchAny(include, rel) || excluded(rules.Exclude, rel) {
+				continue
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results <- s.processWatched(ctx, dir, in, ext, options, rules)
+			}()
+		}
+		wg.Wait()
+	}()
+
+	return results, nil
+}
+
+// validateWatchRules rejects rules that would lose files or feed outputs
+// back into the watch
+func validateWatchRules(dir string, rules model.WatchRules) error {
+	if rules.OutputDir == "" {
+		return pkgerrors.NewValidationError("outputDir", "", "output directory must not be empty")
+	}
+
+	dirKey := pathutil.Key(filepath.Clean(dir))
+	for _, d := range []struct {
+		field, path string
+	}{
+		{"outputDir", rules.OutputDir},
+		{"doneDir", rules.DoneDir},
+		{"errorDir", rules.ErrorDir},
+	} {
+		if d.path != "" && pathutil.Key(filepath.Clean(d.path)) == dirKey {
+			return pkgerrors.NewValidationError(d.field, d.path, "must differ from the watched directory")
+		}
+	}
+
+	for _, a := range []struct {
+		field  string
+		action model.WatchAction
+		dir    string
+	}{
+		{"onDone", rules.OnDone, rules.DoneDir},
+		{"onError", rules.OnError, rules.ErrorDir},
+	} {
+		switch a.action {
+		case model.WatchKeep, model.WatchDelete:
+		case model.WatchMove:
+			if a.dir == "" {
+				return pkgerrors.NewValidationError(a.field, a.action, "move requires a destination directory")
+			}
+		default:
+			return pkgerrors.NewValidationError(a.field, a.action, "unknown watch action")
+		}
+	}
+	return nil
+}
+
+// processWatched runs one watched file through the encode lane and then
+// applies the done or error action to it
+func (s *AudioService) processWatched(ctx context.Context, dir, in, ext string, options *model.ProcessingOptions, rules model.WatchRules) model.WatchResult {
+	result := model.WatchResult{InputPath: in}
+
+	out, err := mirrorPath(dir, rules.OutputDir, in, ext)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.OutputPath = out
+
+	jobOpts := *options
+	batch, err := s.workerPool.Run(ctx, []model.BatchJob{{
+		ID:         generateJobID(in),
+		InputPath:  in,
+		OutputPath: out,
+		Options:    &jobOpts,
+	}}, s.reporter)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	r := <-batch
+	result.Result, result.Err = r.Result, r.Err
+
+	// Files interrupted by shutdown are left alone so the next run retries them
+	if ctx.Err() != nil {
+		return result
+	}
+
+	action, dest := rules.OnDone, rules.DoneDir
+	if result.Err != nil {
+		s.log.Error("watched file failed", zap.String("input", in), zap.Error(result.Err))
+		action, dest = rules.OnError, rules.ErrorDir
+	}
+	if err := s.disposeWatched(ctx, dir, in, action, dest); err != nil {
+		s.log.Warn("failed to dispose of watched file",
+			zap.String("input", in),
+			zap.String("action", string(action)),
+			zap.Error(err),
+		)
+	}
+	return result
+}
+
+// disposeWatched applies action to a processed source file
+func (s *AudioService) disposeWatched(ctx context.Context, dir, in string, action model.WatchAction, dest string) error {
+	switch action {
+	case model.WatchDelete:
+		return s.storage.Remove(ctx, in)
+	case model.WatchMove:
+		rel, err := filepath.Rel(dir, in)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+		if err := s.storage.MkdirAll(ctx, filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		return s.storage.Rename(ctx, in, target)
+	default:
+		return nil
+	}
+}
+
+// excluded reports whether rel or any of its parent directories matches an
+// exclude pattern, mirroring how directory ingestion prunes directories
+func excluded(exclude []string, rel string) bool {
+	for p := rel; p != "." && p != "/" && p != ""; p = path.Dir(p) {
+		if matchAny(exclude, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,187 @@
+// Package worker runs batch jobs pulled from a shared ports.JobQueue, so
+// several machines can work through one queue
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/domain/ports"
+	"github.com/Skryldev/audio-lab/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Config holds queue worker configuration
+type Config struct {
+	// Concurrency is the number of jobs processed at once (default: 1).
+	// Encodes still share the processor's worker lane.
+	Concurrency int
+
+	// VisibilityTimeout is the queue's reservation period; reservations are
+	// extended every third of it while a job runs. Defaults to the queue's
+	// own VisibilityTimeout() when it has one, else 5m.
+	VisibilityTimeout time.Duration
+
+	// MaxDeliveries drops jobs delivered more often than this, e.g. ones
+	// that crash the worker every time. 0 means unlimited.
+	MaxDeliveries int
+
+	// OnResult is called for every finished or dropped job (optional)
+	OnResult func(model.BatchResult)
+}
+
+// Worker pulls jobs from a queue and processes them. Jobs are acknowledged
+// once processed, successfully or not; jobs interrupted by shutdown or a
+// crash are redelivered after the visibility timeout.
+type Worker struct {
+	queue     ports.JobQueue
+	processor ports.AudioProcessor
+	cfg       Config
+	log       *logger.Logger
+}
+
+// New creates a worker that runs jobs from queue on processor
+func New(queue ports.JobQueue, processor ports.AudioProcessor, cfg Config, log *logger.Logger) (*Worker, error) {
+	if queue == nil {
+		return nil, fmt.Errorf("JobQueue is required")
+	}
+	if processor == nil {
+		return nil, fmt.Errorf("AudioProcessor is required")
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.VisibilityTimeout <= 0 {
+		cfg.VisibilityTimeout = 5 * time.Minute
+		if q, ok := queue.(interface{ VisibilityTimeout() time.Duration }); ok && q.VisibilityTimeout() > 0 {
+			cfg.VisibilityTimeout = q.VisibilityTimeout()
+		}
+	}
+	if log == nil {
+		var err error
+		log, err = logger.New(false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create logger: %w", err)
+		}
+	}
+
+	return &Worker{queue: queue, processor: processor, cfg: cfg, log: log}, nil
+}
+
+// Run processes jobs until ctx is canceled. Jobs still running are canceled
+// and left for redelivery.
+func (w *Worker) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for i := 0; i < w.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (w *Worker) loop(ctx context.Context) {
+	for {
+		job, err := w.queue.Dequeue(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil && job != nil {
+			// A job that can't be decoded will never succeed; drop it
+			log := w.log.With(zap.String("job_id", job.Job.ID), zap.Int("delivery", job.Deliveries))
+			log.Error("dropping undecodable job", zap.Error(err))
+			w.ack(ctx, job, log)
+			w.report(model.BatchResult{JobID: job.Job.ID, Err: err})
+			continue
+		}
+		if err != nil {
+			w.log.Error("failed to dequeue job", zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		w.handle(ctx, job)
+	}
+}
+
+func (w *Worker) handle(ctx context.Context, job *model.QueuedJob) {
+	log := w.log.With(zap.String("job_id", job.Job.ID), zap.Int("delivery", job.Deliveries))
+
+	if w.cfg.MaxDeliveries > 0 && job.Deliveries > w.cfg.MaxDeliveries {
+		log.Error("dropping job after too many deliveries")
+		w.ack(ctx, job, log)
+		w.report(model.BatchResult{
+			JobID: job.Job.ID,
+			Err:   fmt.Errorf("job dropped after %d deliveries", job.Deliveries-1),
+		})
+		return
+	}
+
+	// Keep the reservation alive for as long as the job runs
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	go w.extend(runCtx, job, log)
+
+	result := w.process(runCtx, job.Job)
+	if ctx.Err() != nil {
+		log.Info("job interrupted by shutdown, leaving it for redelivery")
+		return
+	}
+
+	w.ack(ctx, job, log)
+	w.report(result)
+}
+
+func (w *Worker) process(ctx context.Context, job model.BatchJob) model.BatchResult {
+	results, err := w.processor.ProcessBatch(ctx, []model.BatchJob{job})
+	if err != nil {
+		return model.BatchResult{JobID: job.ID, Err: err}
+	}
+	result, ok := <-results
+	if !ok {
+		return model.BatchResult{JobID: job.ID, Err: ctx.Err()}
+	}
+	return result
+}
+
+func (w *Worker) extend(ctx context.Context, job *model.QueuedJob, log *logger.Logger) {
+	ticker := time.NewTicker(w.cfg.VisibilityTimeout / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.queue.Extend(ctx, job); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				// The job may now run twice; at-least-once allows that
+				log.Warn("failed to extend job reservation", zap.Error(err))
+			}
+		}
+	}
+}
+
+func (w *Worker) ack(ctx context.Context, job *model.QueuedJob, log *logger.Logger) {
+	if err := w.queue.Ack(ctx, job); err != nil && !errors.Is(err, context.Canceled) {
+		log.Warn("failed to acknowledge job", zap.Error(err))
+	}
+}
+
+func (w *Worker) report(result model.BatchResult) {
+	if w.cfg.OnResult != nil {
+		w.cfg.OnResult(result)
+	}
+}
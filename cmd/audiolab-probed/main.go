@@ -0,0 +1,84 @@
+// Command audiolab-probed runs the read-only probe and analysis server on
+// its own, separately from encode workers. Clients may only name files
+// under -path-roots; -insecure accepts any server path, for local
+// development only.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	audiolab "github.com/Skryldev/audio-lab"
+	"github.com/Skryldev/audio-lab/application/jobspec"
+	"github.com/Skryldev/audio-lab/httpapi"
+)
+
+func main() {
+	addr := flag.String("addr", ":8081", "listen address")
+	concurrency := flag.Int("concurrency", 32, "maximum concurrent probe and analysis runs")
+	cacheTTL := flag.Duration("cache-ttl", 5*time.Minute, "probe and analysis cache TTL (negative disables)")
+	pathRoots := flag.String("path-roots", "", "comma-separated directories that request paths must lie under")
+	allowedHosts := flag.String("allowed-hosts", "", "comma-separated hosts URL inputs may name (a leading dot matches subdomains); empty allows any public host")
+	allowPrivate := flag.Bool("allow-private-urls", false, "accept URL inputs resolving to private network addresses")
+	insecure := flag.Bool("insecure", false, "accept any server path")
+	flag.Parse()
+
+	if *pathRoots == "" && !*insecure {
+		log.Fatal("-path-roots is required (or -insecure)")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// The processor's probe lane would otherwise cap the server at its
+	// default of 2 concurrent probes
+	processor, err := audiolab.New(audiolab.Config{ProbeWorkers: *concurrency})
+	if err != nil {
+		log.Fatalf("failed to create processor: %v", err)
+	}
+	defer processor.Close()
+
+	srv := &http.Server{
+		Addr: *addr,
+		Handler: httpapi.NewProbeServer(processor, httpapi.ProbeServerConfig{
+			MaxConcurrency: *concurrency,
+			CacheTTL:       *cacheTTL,
+			Paths: jobspec.PathPolicy{
+				AllowPaths:           true,
+				Roots:                splitList(*pathRoots),
+				AllowedHosts:         splitList(*allowedHosts),
+				AllowPrivateNetworks: *allowPrivate,
+			},
+		}),
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("probe server listening on %s", *addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("server failed: %v", err)
+	}
+}
+
+// splitList splits a comma-separated flag value, dropping empty elements
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
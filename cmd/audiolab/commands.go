@@ -0,0 +1,592 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	audiolab "github.com/Skryldev/audio-lab"
+	"github.com/Skryldev/audio-lab/application/jobspec"
+)
+
+func runProcess(ctx context.Context, args []string) error {
+	c := newCLI("process", "INPUT OUTPUT", true)
+	if err := c.parse(args); err != nil {
+		return err
+	}
+	if c.fs.NArg() != 2 {
+		return usageError("expected INPUT and OUTPUT")
+	}
+	opts, err := c.options()
+	if err != nil {
+		return err
+	}
+	p, err := c.processor()
+	if err != nil {
+		return err
+	}
+	defer c.close()
+	defer p.Close()
+
+	bar := newProgressBar(c.quiet || c.json)
+	p.AddReporter(bar)
+
+	result, err := p.ProcessAudio(ctx, c.fs.Arg(0), c.fs.Arg(1), opts...)
+	bar.finish()
+	c.printResult(bar, audiolab.BatchResult{JobID: c.fs.Arg(0), Result: result, Err: err})
+	if err != nil {
+		return errFailed
+	}
+	return nil
+}
+
+func runBatch(ctx context.Context, args []string) error {
+	c := newCLI("batch", "INPUT_DIR OUTPUT_DIR | -jobs FILE", true)
+	jobsFile := c.fs.String("jobs", "", "JSON file with an array of jobs ({\"input\", \"output\", \"options\"}); - reads stdin")
+	recursive := c.fs.Bool("recursive", false, "descend into subdirectories")
+	include := c.fs.String("include", "", "comma-separated file patterns to process (default: common audio extensions)")
+	exclude := c.fs.String("exclude", "", "comma-separated file and directory patterns to skip")
+	ext := c.fs.String("ext", "", "output extension, e.g. .ogg (default: derived from the codec)")
+	failFast := c.fs.Bool("fail-fast", false, "stop the batch after the first failed job")
+	maxFailures := c.fs.Float64("max-failures", 0, "stop the batch once more than this percentage of finished jobs failed")
+	minJobs := c.fs.Int("min-jobs", 10, "finished jobs required before -max-failures applies")
+	reportFile := c.fs.String("report", "", "write a batch summary to FILE, as CSV if it ends in .csv and JSON otherwise")
+	if err := c.parse(args); err != nil {
+		return err
+	}
+	if *maxFailures < 0 || *maxFailures > 100 {
+		return usageError("-max-failures must be between 0 and 100")
+	}
+	policy := audiolab.BatchPolicy{
+		FailFast:          *failFast,
+		MaxFailurePercent: *maxFailures,
+		MinJobs:           *minJobs,
+	}
+
+	var jobs []jobspec.Job
+	if *jobsFile != "" {
+		if c.fs.NArg() != 0 {
+			return usageError("-jobs takes no arguments")
+		}
+		var err error
+		if jobs, err = readJobs(*jobsFile); err != nil {
+			return err
+		}
+	} else if c.fs.NArg() != 2 {
+		return usageError("expected INPUT_DIR and OUTPUT_DIR, or -jobs")
+	}
+
+	opts, err := c.options()
+	if err != nil {
+		return err
+	}
+	p, err := c.processor()
+	if err != nil {
+		return err
+	}
+	defer c.close()
+	defer p.Close()
+
+	batch := make([]audiolab.BatchJob, 0, len(jobs))
+	for i := range jobs {
+		if jobs[i].Options == nil {
+			jobs[i].Options = &c.cfg.Options
+		}
+		if jobs[i].ID == "" {
+			jobs[i].ID = fmt.Sprintf("job-%d", i+1)
+		}
+		job, err := jobs[i].BatchJob(p.Defaults()...)
+		if err != nil {
+			return fmt.Errorf("%w: job %d: %v", errUsage, i+1, err)
+		}
+		batch = append(batch, job)
+	}
+
+	bar := newProgressBar(c.quiet || c.json)
+	p.AddReporter(bar)
+
+	if *jobsFile == "" {
+		batch, err = p.DirectoryJobs(ctx, c.fs.Arg(0), c.fs.Arg(1), audiolab.DirOptions{
+			Recursive: *recursive,
+			Include:   splitList(*include),
+			Exclude:   splitList(*exclude),
+			OutputExt: *ext,
+		}, opts...)
+		if err != nil {
+			return err
+		}
+	}
+	bar.setTotal(len(batch))
+
+	results, err := p.ProcessBatchWithPolicy(ctx, batch, policy)
+	if err != nil {
+		return err
+	}
+
+	summary := audiolab.NewBatchReport()
+	for r := range results {
+		bar.jobDone()
+		c.printResult(bar, r)
+		summary.Add(r)
+	}
+	bar.finish()
+	if *reportFile != "" {
+		if err := writeReport(*reportFile, summary); err != nil {
+			return err
+		}
+	}
+	if summary.Failed > 0 {
+		return errFailed
+	}
+	return nil
+}
+
+func runProbe(ctx context.Context, args []string) error {
+	c := newCLI("probe", "FILE...", false)
+	if err := c.parse(args); err != nil {
+		return err
+	}
+	if c.fs.NArg() == 0 {
+		return usageError("expected at least one FILE")
+	}
+	p, err := c.processor()
+	if err != nil {
+		return err
+	}
+	defer c.close()
+	defer p.Close()
+
+	var failed bool
+	for _, path := range c.fs.Args() {
+		meta, err := p.ProbeAudio(ctx, path)
+		if err != nil {
+			failed = true
+		}
+		c.printAnalysis(newAnalysis(path, meta, err))
+	}
+	if failed {
+		return errFailed
+	}
+	return nil
+}
+
+func runAnalyze(ctx context.Context, args []string) error {
+	c := newCLI("analyze", "FILE...", false)
+	if err := c.parse(args); err != nil {
+		return err
+	}
+	if c.fs.NArg() == 0 {
+		return usageError("expected at least one FILE")
+	}
+	p, err := c.processor()
+	if err != nil {
+		return err
+	}
+	defer c.close()
+	defer p.Close()
+
+	var failed bool
+	for _, path := range c.fs.Args() {
+		a := analyze(ctx, p, path)
+		if a.Error != nil {
+			failed = true
+		}
+		c.printAnalysis(a)
+	}
+	if failed {
+		return errFailed
+	}
+	return nil
+}
+
+func runWatch(ctx context.Context, args []string) error {
+	c := newCLI("watch", "-out OUTPUT_DIR DIR", true)
+	out := c.fs.String("out", "", "output directory (required)")
+	recursive := c.fs.Bool("recursive", false, "watch subdirectories")
+	include := c.fs.String("include", "", "comma-separated file patterns to process (default: common audio extensions)")
+	exclude := c.fs.String("exclude", "", "comma-separated file and directory patterns to skip")
+	ext := c.fs.String("ext", "", "output extension, e.g. .ogg (default: derived from the codec)")
+	stable := c.fs.Duration("stable", 2*time.Second, "how long a new file must stay unchanged before it is processed")
+	existing := c.fs.Bool("existing", false, "also process files present at start")
+	onDone := c.fs.String("on-done", "keep", "what to do with processed sources: keep, delete or move")
+	doneDir := c.fs.String("done-dir", "", "destination for -on-done move")
+	onError := c.fs.String("on-error", "keep", "what to do with failed sources: keep, delete or move")
+	errorDir := c.fs.String("error-dir", "", "destination for -on-error move")
+	if err := c.parse(args); err != nil {
+		return err
+	}
+	if c.fs.NArg() != 1 || *out == "" {
+		return usageError("expected -out OUTPUT_DIR and one DIR")
+	}
+	done, err := watchAction(*onDone)
+	if err != nil {
+		return err
+	}
+	failedAction, err := watchAction(*onError)
+	if err != nil {
+		return err
+	}
+	opts, err := c.options()
+	if err != nil {
+		return err
+	}
+	p, err := c.processor()
+	if err != nil {
+		return err
+	}
+	defer c.close()
+	defer p.Close()
+
+	bar := newProgressBar(c.quiet || c.json)
+	p.AddReporter(bar)
+
+	results, err := p.Watch(ctx, c.fs.Arg(0), audiolab.WatchRules{
+		DirOptions: audiolab.DirOptions{
+			Recursive: *recursive,
+			Include:   splitList(*include),
+			Exclude:   splitList(*exclude),
+			OutputExt: *ext,
+		},
+		OutputDir:       *out,
+		StableFor:       *stable,
+		ProcessExisting: *existing,
+		OnDone:          done,
+		OnError:         failedAction,
+		DoneDir:         *doneDir,
+		ErrorDir:        *errorDir,
+	}, opts...)
+	if err != nil {
+		return err
+	}
+	if !c.json {
+		bar.println(os.Stderr, "watching %s (Ctrl-C to stop)", c.fs.Arg(0))
+	}
+
+	for r := range results {
+		bar.jobDone()
+		c.printResult(bar, audiolab.BatchResult{JobID: r.InputPath, Result: r.Result, Err: r.Err})
+	}
+	bar.finish()
+	return nil
+}
+
+// printResult prints a job result
+func (c *cli) printResult(bar *progressBar, r audiolab.BatchResult) {
+	if c.json {
+		printJSON(jobspec.NewResult(r))
+		return
+	}
+
+	switch {
+	case r.Err != nil:
+		bar.println(os.Stderr, "FAIL %s: %v", r.JobID, r.Err)
+	case r.Skipped:
+		bar.println(os.Stdout, "skip %s (up to date)", r.Result.OutputPath)
+	case r.Result.Command != nil:
+		bar.println(os.Stdout, "%s", shellQuote(append([]string{"ffmpeg"}, r.Result.Command...)))
+	default:
+		line := fmt.Sprintf("ok   %s -> %s (%s)", r.Result.InputPath, r.Result.OutputPath, r.Result.Duration.Round(time.Millisecond))
+		if l := r.Result.Loudness; l != nil {
+			line += fmt.Sprintf(", %.1f -> %.1f LUFS", l.Input.Integrated, l.Output.Integrated)
+		}
+		bar.println(os.Stdout, "%s", line)
+	}
+}
+
+// shellQuote joins args into a command line that a POSIX shell splits back
+// into args
+func shellQuote(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		if a != "" && strings.IndexFunc(a, func(r rune) bool {
+			return !(r == '-' || r == '_' || r == '.' || r == '/' || r == ':' || r == '=' || r == ',' ||
+				r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z')
+		}) < 0 {
+			quoted[i] = a
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
+
+// readJobs reads a JSON array of jobs from path, or stdin for "-"
+func readJobs(path string) ([]jobspec.Job, error) {
+	f := os.Stdin
+	if path != "-" {
+		var err error
+		if f, err = os.Open(path); err != nil {
+			return nil, err
+		}
+		defer f.Close()
+	}
+	var jobs []jobspec.Job
+	if err := json.NewDecoder(f).Decode(&jobs); err != nil {
+		return nil, fmt.Errorf("%w: jobs %s: %v", errUsage, path, err)
+	}
+	return jobs, nil
+}
+
+// writeReport writes a batch summary to path, as CSV for .csv files and
+// JSON otherwise
+func writeReport(path string, summary *audiolab.BatchReport) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		err = summary.WriteCSV(f)
+	} else {
+		err = summary.WriteJSON(f)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func watchAction(s string) (audiolab.WatchAction, error) {
+	switch s {
+	case "", "keep":
+		return audiolab.WatchKeep, nil
+	case "delete":
+		return audiolab.WatchDelete, nil
+	case "move":
+		return audiolab.WatchMove, nil
+	}
+	return "", usageError("unknown action %q", s)
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func printJSON(v interface{}) {
+	_ = json.NewEncoder(os.Stdout).Encode(v)
+}
+
+// analysis is the probe and analyze output of a file
+type analysis struct {
+	Path            string         `json:"path"`
+	DurationSeconds float64        `json:"duration_seconds,omitempty"`
+	SampleRate      int            `json:"sample_rate,omitempty"`
+	Channels        int            `json:"channels,omitempty"`
+	Bitrate         int            `json:"bitrate,omitempty"`
+	Codec           string         `json:"codec,omitempty"`
+	Format          string         `json:"format,omitempty"`
+	Size            int64          `json:"size,omitempty"`
+	Streams         []stream       `json:"streams,omitempty"`
+	CoverArt        bool           `json:"cover_art,omitempty"`
+	Tags            *tags          `json:"tags,omitempty"`
+	Chapters        []chapter      `json:"chapters,omitempty"`
+	BWF             *jobspec.BWF   `json:"bwf,omitempty"`
+	PeakDBFS        []float64      `json:"peak_dbfs,omitempty"` // per channel
+	DCOffset        []float64      `json:"dc_offset,omitempty"` // per channel, -1..1
+	Clipping        *clipping      `json:"clipping,omitempty"`
+	Error           *jobspec.Error `json:"error,omitempty"`
+}
+
+// clipping is the sample-peak clipping in the analysis output
+type clipping struct {
+	Samples int64        `json:"samples"`
+	Regions []clipRegion `json:"regions,omitempty"`
+}
+
+// clipRegion is a stretch of clipped audio in the analysis output
+type clipRegion struct {
+	StartSeconds float64 `json:"start_seconds"`
+	EndSeconds   float64 `json:"end_seconds"`
+	Samples      int64   `json:"samples"`
+	PeakDBFS     float64 `json:"peak_dbfs"`
+}
+
+// maxPrintedClips bounds the clip regions listed in text output
+const maxPrintedClips = 10
+
+// tags are the common probed tags in the analysis output
+type tags struct {
+	Title  string `json:"title,omitempty"`
+	Artist string `json:"artist,omitempty"`
+	Album  string `json:"album,omitempty"`
+	Track  int    `json:"track,omitempty"`
+	Date   string `json:"date,omitempty"`
+	Genre  string `json:"genre,omitempty"`
+}
+
+// chapter is a probed chapter in the analysis output
+type chapter struct {
+	StartSeconds float64 `json:"start_seconds"`
+	EndSeconds   float64 `json:"end_seconds"`
+	Title        string  `json:"title,omitempty"`
+}
+
+// stream is a probed stream in the analysis output
+type stream struct {
+	Index    int    `json:"index"`
+	Kind     string `json:"kind"`
+	Codec    string `json:"codec,omitempty"`
+	Language string `json:"language,omitempty"`
+	Default  bool   `json:"default,omitempty"`
+}
+
+func newAnalysis(path string, meta *audiolab.AudioMetadata, err error) analysis {
+	a := analysis{Path: path}
+	if err != nil {
+		a.Error = jobspec.NewError(err)
+		return a
+	}
+	a.DurationSeconds = meta.Duration.Seconds()
+	a.SampleRate = meta.SampleRate
+	a.Channels = meta.Channels
+	a.Bitrate = meta.Bitrate
+	a.Codec = meta.Codec
+	a.Format = meta.Format
+	a.Size = meta.Size
+	a.CoverArt = meta.HasCoverArt
+	if t := meta.Tags; !t.IsZero() {
+		a.Tags = &tags{Title: t.Title, Artist: t.Artist, Album: t.Album, Track: t.Track, Date: t.Date, Genre: t.Genre}
+	}
+	for _, c := range meta.Chapters {
+		a.Chapters = append(a.Chapters, chapter{StartSeconds: c.Start.Seconds(), EndSeconds: c.End.Seconds(), Title: c.Title})
+	}
+	a.BWF = jobspec.NewBWF(meta.BWF)
+	for _, s := range meta.Streams {
+		a.Streams = append(a.Streams, stream{
+			Index:    s.Index,
+			Kind:     string(s.Kind),
+			Codec:    s.Codec,
+			Language: s.Language,
+			Default:  s.Disposition.Default,
+		})
+	}
+	return a
+}
+
+// analyze probes path and measures its per-channel sample peaks, DC offset
+// and clipping
+func analyze(ctx context.Context, p *audiolab.Processor, path string) analysis {
+	meta, err := p.ProbeAudio(ctx, path)
+	a := newAnalysis(path, meta, err)
+	if err != nil {
+		return a
+	}
+
+	m, err := p.AnalyzeAudio(ctx, path, audiolab.AnalysisOptions{})
+	if err != nil {
+		a.Error = jobspec.NewError(err)
+		return a
+	}
+	for _, peak := range m.Peak {
+		a.PeakDBFS = append(a.PeakDBFS, round2(peak))
+	}
+	for _, dc := range m.DCOffset {
+		a.DCOffset = append(a.DCOffset, math.Round(dc*1e6)/1e6)
+	}
+	a.Clipping = &clipping{Samples: m.Clipping.Samples}
+	for _, r := range m.Clipping.Regions {
+		a.Clipping.Regions = append(a.Clipping.Regions, clipRegion{
+			StartSeconds: r.Start.Seconds(),
+			EndSeconds:   r.End.Seconds(),
+			Samples:      r.Samples,
+			PeakDBFS:     round2(r.Peak),
+		})
+	}
+	return a
+}
+
+// round2 rounds a level to hundredths of a dB
+func round2(db float64) float64 {
+	return math.Round(db*100) / 100
+}
+
+func (c *cli) printAnalysis(a analysis) {
+	if c.json {
+		printJSON(a)
+		return
+	}
+	if a.Error != nil {
+		fmt.Fprintf(os.Stderr, "FAIL %s: %s\n", a.Path, a.Error.Message)
+		return
+	}
+	fmt.Printf("%s\n  format    %s (%s)\n  duration  %s\n  audio     %d Hz, %d ch, %d bps\n  size      %d bytes\n",
+		a.Path, a.Format, a.Codec,
+		time.Duration(a.DurationSeconds*float64(time.Second)).Round(time.Millisecond),
+		a.SampleRate, a.Channels, a.Bitrate, a.Size)
+	if t := a.Tags; t != nil {
+		var parts []string
+		for _, v := range []string{t.Artist, t.Title, t.Album} {
+			if v != "" {
+				parts = append(parts, v)
+			}
+		}
+		if len(parts) > 0 {
+			fmt.Printf("  tags      %s\n", strings.Join(parts, " - "))
+		}
+	}
+	if a.CoverArt {
+		fmt.Printf("  cover art yes\n")
+	}
+	if b := a.BWF; b != nil {
+		fmt.Printf("  bwf       %s %s %s\n", b.Originator, b.OriginationDate, b.OriginationTime)
+	}
+	for _, c := range a.Chapters {
+		start := time.Duration(c.StartSeconds * float64(time.Second)).Round(time.Second)
+		fmt.Printf("  chapter   %s %s\n", start, c.Title)
+	}
+	if len(a.Streams) > 1 {
+		for _, s := range a.Streams {
+			line := fmt.Sprintf("#%d %s %s", s.Index, s.Kind, s.Codec)
+			if s.Language != "" {
+				line += " [" + s.Language + "]"
+			}
+			if s.Default {
+				line += " (default)"
+			}
+			fmt.Printf("  stream    %s\n", line)
+		}
+	}
+	if len(a.PeakDBFS) > 0 {
+		levels := make([]string, len(a.PeakDBFS))
+		for i, db := range a.PeakDBFS {
+			levels[i] = fmt.Sprintf("%.2f", db)
+		}
+		fmt.Printf("  peak      %s dBFS\n", strings.Join(levels, " / "))
+	}
+	if len(a.DCOffset) > 0 {
+		offsets := make([]string, len(a.DCOffset))
+		for i, dc := range a.DCOffset {
+			offsets[i] = fmt.Sprintf("%.4f%%", dc*100)
+		}
+		fmt.Printf("  dc offset %s\n", strings.Join(offsets, " / "))
+	}
+	if c := a.Clipping; c != nil {
+		if c.Samples == 0 {
+			fmt.Printf("  clipping  none\n")
+		} else {
+			fmt.Printf("  clipping  %d samples in %d regions\n", c.Samples, len(c.Regions))
+		}
+		for i, r := range c.Regions {
+			if i == maxPrintedClips {
+				fmt.Printf("  clip      ... %d more\n", len(c.Regions)-i)
+				break
+			}
+			start := time.Duration(r.StartSeconds * float64(time.Second)).Round(time.Millisecond)
+			end := time.Duration(r.EndSeconds * float64(time.Second)).Round(time.Millisecond)
+			fmt.Printf("  clip      %s-%s %d samples, %.2f dBFS\n", start, end, r.Samples, r.PeakDBFS)
+		}
+	}
+}
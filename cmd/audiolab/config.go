@@ -0,0 +1,261 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	audiolab "github.com/Skryldev/audio-lab"
+	"github.com/Skryldev/audio-lab/application/config"
+	"github.com/Skryldev/audio-lab/domain/ports"
+	"github.com/Skryldev/audio-lab/infrastructure/storage"
+	"github.com/Skryldev/audio-lab/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// cli holds the flags shared by all commands
+type cli struct {
+	fs *flag.FlagSet
+
+	configPath string
+	json       bool
+	quiet      bool
+	verbose    bool
+	cfg        config.File // -config file; flags given take precedence
+
+	closers []io.Closer
+}
+
+// newCLI creates the flag set of a command with the shared flags. Option
+// flags are only added when withOptions is set.
+func newCLI(name, args string, withOptions bool) *cli {
+	c := &cli{fs: flag.NewFlagSet(name, flag.ContinueOnError)}
+	fs := c.fs
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: audiolab %s [flags] %s\n\nflags:\n", name, args)
+		fs.PrintDefaults()
+	}
+
+	fs.StringVar(&c.configPath, "config", "", "YAML or JSON config file with defaults for the flags below")
+	fs.BoolVar(&c.json, "json", false, "print results as JSON lines on stdout")
+	fs.BoolVar(&c.quiet, "quiet", false, "don't draw progress")
+	fs.BoolVar(&c.verbose, "v", false, "log pipeline activity to stderr")
+	fs.String("ffmpeg", "", "path to ffmpeg (auto-detected if empty)")
+	fs.String("ffprobe", "", "path to ffprobe (auto-detected if empty)")
+	fs.String("temp-dir", "", "directory for intermediate files")
+	fs.Int("workers", 4, "parallel encode workers")
+
+	if withOptions {
+		fs.String("preset", "", "named preset applied before the options below: "+strings.Join(presetNames(), ", "))
+		fs.String("codec", "", "output codec: opus, aac, mp3, pcm_s16le or pcm_s24le (default opus)")
+		fs.String("bitrate", "", "target bitrate in bps, or with a k suffix (e.g. 192k)")
+		fs.String("bitrate-mode", "", "vbr or cbr")
+		fs.Int("sample-rate", 0, "output sample rate in Hz")
+		fs.Bool("normalize", true, "apply EBU R128 loudness normalization")
+		fs.Float64("loudness", 0, "loudness target in LUFS")
+		fs.Bool("dc-removal", false, "remove DC offset with a 5 Hz highpass")
+		fs.Int("highpass", 0, "highpass filter frequency in Hz")
+		fs.Int("lowpass", 0, "lowpass filter frequency in Hz")
+		fs.Int("threads", 0, "ffmpeg decoder and encoder threads per job (0 lets ffmpeg decide)")
+		fs.Int("audio-stream", -1, "input stream index to encode, e.g. of a video (-1 picks the default)")
+		fs.String("audio-language", "", "encode the input's audio stream in this language, e.g. eng")
+		fs.String("cover-art", "", "image embedded as the front cover of MP3, M4A and FLAC outputs")
+		fs.Int("id3-version", 0, "ID3v2 version of MP3 outputs, 3 or 4 (0 keeps the default of 4)")
+		fs.Bool("gapless", false, "write encoder delay and padding to MP3 and M4A outputs")
+		fs.String("lyrics", "", "lyrics or transcript file (.txt or .lrc) embedded into MP3, M4A, FLAC and Ogg outputs")
+		fs.String("isrc", "", "ISRC of the recording, e.g. USRC17607839")
+		fs.String("barcode", "", "UPC or EAN of the release")
+		fs.String("catalog-number", "", "label catalog number of the release")
+		fs.String("profile", "", "profile name carried on progress and results")
+		fs.String("skip", "", "skip up-to-date outputs: newer or fingerprint")
+		fs.String("fingerprints", "", "fingerprint store file, required by -skip fingerprint")
+		fs.Bool("verify", false, "decode outputs after encoding and fail files that are truncated or damaged")
+		fs.Duration("timeout", 0, "maximum processing time per file")
+		fs.Int("retries", -1, "maximum retries per file (-1 keeps the default)")
+		fs.Bool("dry-run", false, "print the ffmpeg commands instead of running them")
+	}
+	return c
+}
+
+// parse parses args, loads the config file and applies the flags that were
+// set on top of it
+func (c *cli) parse(args []string) error {
+	if err := c.fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return err
+		}
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	if c.configPath != "" {
+		f, err := config.Load(c.configPath)
+		if err != nil {
+			return err
+		}
+		if err := f.RegisterPresets(); err != nil {
+			return fmt.Errorf("config %s: %w", c.configPath, err)
+		}
+		c.cfg = *f
+	}
+
+	var err error
+	c.fs.Visit(func(f *flag.Flag) {
+		if err == nil {
+			err = c.set(f)
+		}
+	})
+	return err
+}
+
+// set copies an explicitly given flag into the config
+func (c *cli) set(f *flag.Flag) error {
+	v := f.Value.String()
+	o := &c.cfg.Options
+	switch f.Name {
+	case "ffmpeg":
+		c.cfg.FFmpeg = v
+	case "ffprobe":
+		c.cfg.FFprobe = v
+	case "temp-dir":
+		c.cfg.TempDir = v
+	case "workers":
+		c.cfg.Workers, _ = strconv.Atoi(v)
+	case "fingerprints":
+		c.cfg.Fingerprints = v
+	case "preset":
+		o.Preset = v
+	case "codec":
+		o.Codec = v
+	case "bitrate":
+		bps, err := parseBitrate(v)
+		if err != nil {
+			return usageError("invalid -bitrate %q", v)
+		}
+		o.Bitrate = bps
+	case "bitrate-mode":
+		o.BitrateMode = v
+	case "sample-rate":
+		o.SampleRate, _ = strconv.Atoi(v)
+	case "normalize":
+		b, _ := strconv.ParseBool(v)
+		o.Normalize = &b
+	case "loudness":
+		lufs, _ := strconv.ParseFloat(v, 64)
+		o.LoudnessTarget = &lufs
+	case "dc-removal":
+		o.DCRemoval, _ = strconv.ParseBool(v)
+	case "highpass":
+		o.HighpassHz, _ = strconv.Atoi(v)
+	case "lowpass":
+		o.LowpassHz, _ = strconv.Atoi(v)
+	case "threads":
+		o.Threads, _ = strconv.Atoi(v)
+	case "audio-stream":
+		n, _ := strconv.Atoi(v)
+		if n >= 0 {
+			o.AudioStream = &n
+		}
+	case "audio-language":
+		o.AudioLanguage = v
+	case "cover-art":
+		o.CoverArt = v
+	case "id3-version":
+		o.ID3Version, _ = strconv.Atoi(v)
+	case "gapless":
+		o.Gapless, _ = strconv.ParseBool(v)
+	case "lyrics":
+		o.LyricsFile = v
+	case "isrc":
+		o.ISRC = v
+	case "barcode":
+		o.Barcode = v
+	case "catalog-number":
+		o.CatalogNumber = v
+	case "profile":
+		o.Profile = v
+	case "skip":
+		o.SkipPolicy = v
+	case "verify":
+		o.Verify, _ = strconv.ParseBool(v)
+	case "timeout":
+		d, _ := time.ParseDuration(v)
+		o.TimeoutSeconds = d.Seconds()
+	case "retries":
+		n, _ := strconv.Atoi(v)
+		if n >= 0 {
+			o.MaxRetries = &n
+		}
+	case "dry-run":
+		o.DryRun, _ = strconv.ParseBool(v)
+	}
+	return nil
+}
+
+// presetNames lists the registered presets for the -preset usage
+func presetNames() []string {
+	var names []string
+	for _, p := range audiolab.Presets() {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// parseBitrate accepts plain bps or a k suffix
+func parseBitrate(s string) (int, error) {
+	mult := 1
+	if strings.HasSuffix(strings.ToLower(s), "k") {
+		mult = 1000
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid bitrate %q", s)
+	}
+	return n * mult, nil
+}
+
+// options converts the configured processing options
+func (c *cli) options() ([]ports.Option, error) {
+	opts, err := c.cfg.Options.Apply()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errUsage, err)
+	}
+	return opts, nil
+}
+
+// processor creates a processor from the config
+func (c *cli) processor() (*audiolab.Processor, error) {
+	cfg := audiolab.Config{
+		FFmpegPath:  c.cfg.FFmpeg,
+		FFprobePath: c.cfg.FFprobe,
+		Workers:     c.cfg.Workers,
+		TempDir:     c.cfg.TempDir,
+		ZapLogger:   zap.NewNop(),
+	}
+	if c.verbose {
+		log, err := logger.New(true)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Logger = log
+	}
+	if c.cfg.Fingerprints != "" {
+		store, err := storage.OpenFingerprintFile(c.cfg.Fingerprints)
+		if err != nil {
+			return nil, err
+		}
+		c.closers = append(c.closers, store)
+		cfg.FingerprintStore = store
+	}
+	return audiolab.New(cfg)
+}
+
+// close releases resources opened for the command
+func (c *cli) close() {
+	for _, cl := range c.closers {
+		_ = cl.Close()
+	}
+}
@@ -0,0 +1,94 @@
+// Command audiolab runs the processing pipeline from the command line.
+//
+// Usage:
+//
+//	audiolab process [flags] INPUT OUTPUT
+//	audiolab batch   [flags] INPUT_DIR OUTPUT_DIR
+//	audiolab batch   [flags] -jobs FILE
+//	audiolab probe   [flags] FILE...
+//	audiolab analyze [flags] FILE...
+//	audiolab watch   [flags] -out OUTPUT_DIR DIR
+//
+// Processing options are read from a JSON config file (-config) and can be
+// overridden by flags. Progress is drawn on stderr when it is a terminal;
+// -json prints one JSON document per result on stdout instead of text.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// errUsage marks errors caused by bad arguments, which exit with status 2
+var errUsage = errors.New("usage error")
+
+// errFailed means at least one job failed; the failures were already printed
+var errFailed = errors.New("one or more jobs failed")
+
+var commands = map[string]func(ctx context.Context, args []string) error{
+	"process": runProcess,
+	"batch":   runBatch,
+	"probe":   runProbe,
+	"analyze": runAnalyze,
+	"watch":   runWatch,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	name := os.Args[1]
+	if name == "help" || name == "-h" || name == "-help" || name == "--help" {
+		usage()
+		return
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "audiolab: unknown command %q\n\n", name)
+		usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	err := cmd(ctx, os.Args[2:])
+	stop()
+
+	switch {
+	case err == nil:
+	case errors.Is(err, flag.ErrHelp):
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(os.Stderr, "audiolab %s: %v\n", name, err)
+		os.Exit(2)
+	case errors.Is(err, errFailed):
+		os.Exit(1)
+	default:
+		fmt.Fprintf(os.Stderr, "audiolab %s: %v\n", name, err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprint(os.Stderr, `usage: audiolab <command> [flags] [arguments]
+
+commands:
+  process   encode one file:                 process [flags] INPUT OUTPUT
+  batch     encode a directory or job list:  batch [flags] INPUT_DIR OUTPUT_DIR
+                                              batch [flags] -jobs FILE
+  probe     print file metadata:             probe [flags] FILE...
+  analyze   print metadata and levels:       analyze [flags] FILE...
+  watch     encode files dropped in a dir:   watch [flags] -out OUTPUT_DIR DIR
+
+Run "audiolab <command> -h" for the flags of a command.
+`)
+}
+
+// usageError wraps a message as an errUsage
+func usageError(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", errUsage, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Skryldev/audio-lab/pkg/progress"
+)
+
+const barWidth = 30
+
+// progressBar draws a single status line on a terminal: the progress of
+// the most recently updated job and, for batches, how many jobs finished
+type progressBar struct {
+	mu       sync.Mutex
+	out      io.Writer
+	enabled  bool
+	total    int
+	finished int
+	last     progress.Update
+	drawn    time.Time
+	visible  bool
+}
+
+// newProgressBar draws on stderr unless disabled or stderr isn't a terminal
+func newProgressBar(disabled bool) *progressBar {
+	b := &progressBar{out: os.Stderr}
+	if fi, err := os.Stderr.Stat(); err == nil && !disabled {
+		b.enabled = fi.Mode()&os.ModeCharDevice != 0
+	}
+	return b
+}
+
+// Report implements progress.Reporter
+func (b *progressBar) Report(u progress.Update) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.last = u
+	// Redraw at most 10 times a second
+	if time.Since(b.drawn) >= 100*time.Millisecond {
+		b.draw()
+	}
+}
+
+// setTotal sets the number of jobs of a batch
+func (b *progressBar) setTotal(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.total = n
+}
+
+// println clears the bar, prints a line and redraws the bar below it
+func (b *progressBar) println(w io.Writer, format string, args ...interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	visible := b.visible
+	b.clear()
+	fmt.Fprintf(w, format+"\n", args...)
+	if visible {
+		b.draw()
+	}
+}
+
+// jobDone counts a finished job of a batch
+func (b *progressBar) jobDone() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.finished++
+}
+
+// finish removes the bar
+func (b *progressBar) finish() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clear()
+	b.enabled = false
+}
+
+func (b *progressBar) draw() {
+	if !b.enabled || b.last.JobID == "" {
+		return
+	}
+	pct := b.last.Percent
+	if pct < 0 {
+		pct = 0
+	}
+	if pct > 100 {
+		pct = 100
+	}
+	filled := int(pct / 100 * barWidth)
+	line := fmt.Sprintf("[%s%s] %3.0f%% %-12s", strings.Repeat("=", filled), strings.Repeat(" ", barWidth-filled), pct, b.last.Stage)
+	if b.last.Speed > 0 {
+		line += fmt.Sprintf(" %.1fx", b.last.Speed)
+	}
+	if b.last.ETA > 0 {
+		line += fmt.Sprintf(" ETA %s", b.last.ETA.Round(time.Second))
+	}
+	switch {
+	case b.total > 0:
+		line += fmt.Sprintf(" %d/%d done", b.finished, b.total)
+	case b.finished > 0:
+		line += fmt.Sprintf(" %d done", b.finished)
+	}
+	fmt.Fprintf(b.out, "\r\033[K%s", line)
+	b.drawn = time.Now()
+	b.visible = true
+}
+
+func (b *progressBar) clear() {
+	if b.visible {
+		fmt.Fprint(b.out, "\r\033[K")
+		b.visible = false
+	}
+}
@@ -0,0 +1,236 @@
+// Command audiolabd serves the processing pipeline over gRPC (see
+// api/audiolab/v1/audiolab.proto) for non-Go services.
+//
+// Callers authenticate with a bearer token (-auth-tokens) or a TLS client
+// certificate (-tls-client-ca), and may only name files under -path-roots.
+// -insecure serves without TLS or authentication and accepts any path, for
+// local development only.
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	audiolab "github.com/Skryldev/audio-lab"
+	"github.com/Skryldev/audio-lab/application/jobspec"
+	"github.com/Skryldev/audio-lab/grpcapi"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func main() {
+	addr := flag.String("addr", ":9090", "listen address")
+	workers := flag.Int("workers", 4, "parallel encode workers")
+	ffmpegPath := flag.String("ffmpeg", "", "path to ffmpeg (auto-detected if empty)")
+	ffprobePath := flag.String("ffprobe", "", "path to ffprobe (auto-detected if empty)")
+	tempDir := flag.String("temp-dir", "", "directory for intermediate files")
+	metricsAddr := flag.String("metrics-addr", "", "serve Prometheus metrics on this address (disabled if empty)")
+	drainTimeout := flag.Duration("drain-timeout", 30*time.Second, "how long running jobs may finish on shutdown before they are canceled")
+	tlsCert := flag.String("tls-cert", "", "TLS certificate file")
+	tlsKey := flag.String("tls-key", "", "TLS private key file")
+	clientCA := flag.String("tls-client-ca", "", "CA file; clients presenting a certificate it signed are authenticated by the certificate's common name")
+	tokensFile := flag.String("auth-tokens", "", `file of "name token" lines; callers send "authorization: Bearer <token>"`)
+	pathRoots := flag.String("path-roots", "", "comma-separated directories that request paths must lie under")
+	allowedHosts := flag.String("allowed-hosts", "", "comma-separated hosts URL inputs may name (a leading dot matches subdomains); empty allows any public host")
+	allowPrivate := flag.Bool("allow-private-urls", false, "accept URL inputs resolving to private network addresses")
+	insecure := flag.Bool("insecure", false, "serve without TLS or authentication and accept any server path")
+	flag.Parse()
+
+	auth := grpcapi.AuthConfig{ClientCerts: *clientCA != ""}
+	if *tokensFile != "" {
+		tokens, err := readTokens(*tokensFile)
+		if err != nil {
+			log.Fatalf("invalid -auth-tokens: %v", err)
+		}
+		auth.Tokens = tokens
+	}
+	paths := jobspec.PathPolicy{
+		AllowPaths:           *pathRoots != "" || *insecure,
+		Roots:                splitList(*pathRoots),
+		AllowedHosts:         splitList(*allowedHosts),
+		AllowPrivateNetworks: *allowPrivate,
+	}
+	if !*insecure {
+		switch {
+		case *tlsCert == "":
+			log.Fatal("-tls-cert and -tls-key are required (or -insecure)")
+		case !auth.Enabled():
+			log.Fatal("-auth-tokens or -tls-client-ca is required (or -insecure)")
+		case *pathRoots == "":
+			log.Fatal("-path-roots is required (or -insecure)")
+		}
+	}
+	serverOpts := auth.ServerOptions()
+	if !auth.Enabled() {
+		serverOpts = nil
+	}
+	if *tlsCert != "" {
+		tlsCfg, err := loadTLS(*tlsCert, *tlsKey, *clientCA)
+		if err != nil {
+			log.Fatalf("invalid TLS configuration: %v", err)
+		}
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(tlsCfg)))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// AUDIOLAB_* variables configure the processor; flags given override them
+	cfg, err := audiolab.ConfigFromEnv()
+	if err != nil {
+		log.Fatalf("invalid environment: %v", err)
+	}
+	if cfg.Workers == 0 {
+		cfg.Workers = *workers
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "workers":
+			cfg.Workers = *workers
+		case "ffmpeg":
+			cfg.FFmpegPath = *ffmpegPath
+		case "ffprobe":
+			cfg.FFprobePath = *ffprobePath
+		case "temp-dir":
+			cfg.TempDir = *tempDir
+		}
+	})
+	if *metricsAddr != "" {
+		reg := prometheus.NewRegistry()
+		cfg.Metrics = reg
+		go func() {
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+			log.Printf("metrics listening on %s", *metricsAddr)
+			if err := http.ListenAndServe(*metricsAddr, mux); err != nil {
+				log.Printf("metrics server failed: %v", err)
+			}
+		}()
+	}
+
+	processor, err := audiolab.New(cfg)
+	if err != nil {
+		log.Fatalf("failed to create processor: %v", err)
+	}
+	defer processor.Close()
+
+	svc := grpcapi.NewServer(processor, grpcapi.ServerConfig{Paths: paths})
+	processor.AddReporter(svc)
+
+	srv := grpc.NewServer(serverOpts...)
+	svc.Register(srv)
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+
+	lis, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatalf("failed to listen: %v", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		// Let running jobs finish, but don't wait on them forever
+		drainCtx, cancel := context.WithTimeout(context.Background(), *drainTimeout)
+		defer cancel()
+		if err := processor.Shutdown(drainCtx); err != nil {
+			log.Printf("canceled jobs still running after %s", *drainTimeout)
+		}
+
+		done := make(chan struct{})
+		go func() {
+			srv.GracefulStop()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(30 * time.Second):
+			srv.Stop()
+		}
+	}()
+
+	log.Printf("gRPC server listening on %s", *addr)
+	if err := srv.Serve(lis); err != nil {
+		log.Fatalf("server failed: %v", err)
+	}
+}
+
+// readTokens parses a file of "name token" lines; blank lines and lines
+// starting with # are skipped
+func readTokens(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tokens := make(map[string]string)
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected \"name token\"", n)
+		}
+		tokens[fields[1]] = fields[0]
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("%s holds no tokens", path)
+	}
+	return tokens, nil
+}
+
+// loadTLS builds the server TLS config, verifying client certificates
+// against caFile when given
+func loadTLS(certFile, keyFile, caFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s holds no certificates", caFile)
+		}
+		cfg.ClientCAs = pool
+		// Token callers needn't present a certificate
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return cfg, nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty elements
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -1,6 +1,11 @@
 package model
 
-import "time"
+import (
+	"encoding/binary"
+	"io"
+	"os"
+	"time"
+)
 
 // Codec represents supported audio codecs
 type Codec string
@@ -9,8 +14,35 @@ const (
 	CodecOpus Codec = "opus"
 	CodecAAC  Codec = "aac"
 	CodecMP3  Codec = "mp3"
+
+	// Uncompressed little-endian PCM in WAV, e.g. for broadcast delivery.
+	// The bitrate follows from the sample rate and channels.
+	CodecPCM16 Codec = "pcm_s16le"
+	CodecPCM24 Codec = "pcm_s24le"
 )
 
+// IsPCM reports whether c is an uncompressed PCM codec
+func (c Codec) IsPCM() bool {
+	return c == CodecPCM16 || c == CodecPCM24
+}
+
+// Extension returns the conventional file extension of the codec, e.g.
+// ".opus", or "" for unknown codecs
+func (c Codec) Extension() string {
+	switch c {
+	case CodecOpus:
+		return ".opus"
+	case CodecAAC:
+		return ".m4a"
+	case CodecMP3:
+		return ".mp3"
+	case CodecPCM16, CodecPCM24:
+		return ".wav"
+	default:
+		return ""
+	}
+}
+
 // BitrateMode represents bitrate encoding mode
 type BitrateMode string
 
@@ -28,16 +60,254 @@ type AudioMetadata struct {
 	Codec      string
 	Format     string
 	Size       int64
+
+	// Streams lists every stream in the container with its classification.
+	// SampleRate, Channels, Bitrate and Codec above describe the
+	// DefaultAudioStream, or in a result's InputMeta the stream the job
+	// selected.
+	Streams []StreamInfo
+
+	// HasCoverArt reports whether the file embeds an attached picture
+	HasCoverArt bool
+
+	// Tags are the container's metadata tags, falling back to those of the
+	// default audio stream
+	Tags Tags
+
+	// Chapters are the file's chapter marks in order, e.g. of an
+	// audiobook or podcast episode
+	Chapters []Chapter
+
+	// BWF is the Broadcast Wave bext chunk of a WAV file; nil when it has
+	// none
+	BWF *BWF
+
+	// Chains lists the links of a chained Ogg file; empty unless the file
+	// holds more than one. Duration then covers all chains.
+	Chains []ChainInfo
+}
+
+// Tags are the common metadata tags of a file. Raw holds every tag with a
+// lower-cased key, including ones without a field here.
+type Tags struct {
+	Title       string
+	Artist      string
+	Album       string
+	AlbumArtist string
+	Track       int // 0 when untagged
+	TrackTotal  int
+	Disc        int
+	DiscTotal   int
+	Date        string // as tagged, e.g. "2024" or "2024-03-01"
+	Genre       string
+	Composer    string
+	Comment     string
+	Encoder     string
+
+	ISRC          string
+	Barcode       string // UPC or EAN
+	CatalogNumber string
+
+	Raw map[string]string
+}
+
+// IsZero reports whether no tag was found
+func (t Tags) IsZero() bool {
+	return len(t.Raw) == 0
+}
+
+// Chapter is a titled section of a file
+type Chapter struct {
+	Start time.Duration
+	End   time.Duration
+	Title string
+}
+
+// ChapterInput is one file of an audiobook, becoming one chapter
+type ChapterInput struct {
+	Path string
+
+	// Title names the chapter; default: the file's title tag, else its
+	// name without the extension
+	Title string
+}
+
+// ChainPlaceholder is replaced by the 1-based, zero-padded chain number in
+// chain-splitting output paths
+const ChainPlaceholder = "{chain}"
+
+// ChainInfo describes one link of a chained Ogg file
+type ChainInfo struct {
+	Index      int
+	Offset     int64 // byte offset of the chain's first page
+	Size       int64 // bytes
+	Codec      string
+	SampleRate int
+	Channels   int
+	Duration   time.Duration
+}
+
+// StreamKind classifies a container stream
+type StreamKind string
+
+const (
+	StreamKindAudio       StreamKind = "audio"
+	StreamKindVideo       StreamKind = "video"
+	StreamKindAttachedPic StreamKind = "attached_pic" // cover art, e.g. mjpeg in MP3
+	StreamKindSubtitle    StreamKind = "subtitle"
+	StreamKindData        StreamKind = "data"
+	StreamKindUnknown     StreamKind = "unknown"
+)
+
+// StreamInfo describes a single stream found by probing
+type StreamInfo struct {
+	Index int
+	Kind  StreamKind
+	Codec string
+
+	// Language is the stream's ISO 639-2 language tag, e.g. "eng"; empty
+	// when untagged
+	Language string
+	Title    string
+
+	Disposition StreamDisposition
+
+	// Audio streams only
+	SampleRate int
+	Channels   int
+	Bitrate    int
+
+	// Video and cover art streams only
+	Width  int
+	Height int
+}
+
+// StreamDisposition holds the container's flags for a stream
+type StreamDisposition struct {
+	Default         bool // the stream players pick by default
+	Original        bool
+	Dub             bool
+	Comment         bool // e.g. a director's commentary track
+	Forced          bool
+	HearingImpaired bool
+	VisualImpaired  bool // e.g. an audio description track
+	AttachedPic     bool
+}
+
+// AudioStreams returns the audio streams in container order
+func (m *AudioMetadata) AudioStreams() []StreamInfo {
+	var out []StreamInfo
+	for _, s := range m.Streams {
+		if s.Kind == StreamKindAudio {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// DefaultAudioStream returns the audio stream a job encodes: the first one
+// flagged default, else the first audio stream. ok is false when there is
+// no audio.
+func (m *AudioMetadata) DefaultAudioStream() (stream StreamInfo, ok bool) {
+	audio := m.AudioStreams()
+	if len(audio) == 0 {
+		return StreamInfo{}, false
+	}
+	for _, s := range audio {
+		if s.Disposition.Default {
+			return s, true
+		}
+	}
+	return audio[0], true
+}
+
+// CoverArt returns the first attached picture. ok is false when the file
+// has no cover art.
+func (m *AudioMetadata) CoverArt() (stream StreamInfo, ok bool) {
+	for _, s := range m.Streams {
+		if s.Kind == StreamKindAttachedPic {
+			return s, true
+		}
+	}
+	return StreamInfo{}, false
 }
 
 // ProcessingOptions holds all configuration for audio processing
 type ProcessingOptions struct {
+	// Profile names the preset these options came from; it is copied onto
+	// progress updates and results so multi-profile batches can be grouped
+	Profile string
+
+	// Preset names the preset applied with WithPreset, if any
+	Preset string
+
+	// Labels are free-form key/values for cost attribution, carried into
+	// logs, metrics and results. Keys must be valid Prometheus label names.
+	Labels map[string]string
+
+	// InputFormat forces the input demuxer (ffmpeg -f), needed for piped input
+	InputFormat string
+
+	// Threads caps the threads ffmpeg uses to decode and to encode each
+	// output (-threads); 0 lets ffmpeg decide, usually one per core
+	Threads int
+
+	// AudioStream selects the input audio stream to encode by its container
+	// index (StreamInfo.Index), AudioLanguage by its ISO 639-2 language tag.
+	// With neither set the probed DefaultAudioStream is encoded.
+	AudioStream   *int
+	AudioLanguage string
+
+	// OutputFormat forces the output muxer (ffmpeg -f); derived from the
+	// output path when empty
+	OutputFormat string
+
+	// Metadata are tags written to the outputs (ffmpeg -metadata), e.g.
+	// "title" or "artist". An empty value removes a tag copied from the
+	// input.
+	Metadata map[string]string
+
+	// MetadataPolicy decides which of the input's tags reach the outputs
+	MetadataPolicy MetadataPolicy
+
+	// CoverArt is embedded into MP3, M4A/MP4 and FLAC outputs when its
+	// Path is set
+	CoverArt CoverArt
+
+	// ID3 controls the tags of MP3 outputs; other outputs ignore it
+	ID3 ID3Options
+
+	// Chapters are written to MP3 and M4A/M4B/MP4 outputs in place of the
+	// input's. A zero End runs to the next chapter, or the end of the input.
+	Chapters []Chapter
+
+	// Lyrics are written to MP3, M4A/MP4, FLAC, Ogg and Opus outputs
+	Lyrics Lyrics
+
+	// Identifiers tag MP3, FLAC, Ogg, Opus and Matroska outputs with the
+	// recording's ISRC and the release's barcode and catalog number
+	Identifiers Identifiers
+
+	// BWF is written as the bext chunk of WAV outputs when set, which
+	// need a PCM codec. Fields left empty keep the input's when its tags
+	// are copied.
+	BWF *BWF
+
+	// Gapless records the encoder delay and padding of MP3 and AAC outputs
+	// so players can trim them and continuous albums play without gaps
+	Gapless bool
+
 	// Codec settings
 	Codec       Codec
 	Bitrate     int
 	BitrateMode BitrateMode
 	SampleRate  int
 
+	// EncoderPreference lists, per codec, ffmpeg encoders in order of
+	// preference; the first one the ffmpeg build has is used. Codecs not
+	// listed use the built-in lists (e.g. libfdk_aac, then aac).
+	EncoderPreference map[Codec][]string
+
 	// Normalization
 	NormalizationEnabled bool
 	LoudnessTarget       float64 // LUFS (EBU R128), default: -23
@@ -45,51 +315,266 @@ type ProcessingOptions struct {
 	LoudnessRange        float64 // LU, default: 7.0
 
 	// Filters
+	DCRemoval bool // 5 Hz highpass ahead of the other filters
+
 	HighpassEnabled bool
 	HighpassFreq    int // Hz, default: 80
 
 	LowpassEnabled bool
 	LowpassFreq    int // Hz, default: 18000
 
-	// Processing
+	// Podcast adds the podcast cleanup chain around loudness normalization
+	Podcast *PodcastOptions
+
+	// Padding
+	LeadingPadding  time.Duration // silence inserted before the audio
+	TrailingPadding time.Duration // silence appended after the audio
+
+	// CustomFilters are raw ffmpeg audio filters appended to the generated
+	// chain. FilterHooks then rewrite the chain's filter list in order; they
+	// are functions, so they don't survive JSON and jobs sent through a
+	// queue lose them. pipeline.WithFilterChainHook adapts a hook taking an
+	// ffmpeg.FilterChainBuilder, which this package can't import.
+	CustomFilters []string
+	FilterHooks   []func(filters []string) []string `json:"-"`
+
+	// AdditionalOutputs are encoded from the same decode and filter pass
+	AdditionalOutputs []AdditionalOutput
+
+	// Output directories and permissions
+	SkipCreateOutputDirs bool        // don't MkdirAll missing output directories
+	DirectOutputWrites   bool        // write outputs in place instead of temp file + rename
+	DirMode              os.FileMode // mode for created directories, default: 0755
+	FileMode             os.FileMode // applied to written outputs when non-zero
+	Owner                *FileOwner  // applied to outputs and created directories when set
+
+	// Live streaming (icecast:// and rtmp:// outputs). A reconnect resumes
+	// the input where the dropped connection left off, and these reconnects
+	// replace the job's retries.
+	StreamReconnectAttempts int           // reconnects after a dropped connection, default: 5
+	StreamReconnectDelay    time.Duration // wait between reconnects, default: 2s
+
+	// SkipPolicy skips jobs whose outputs are already up to date
+	SkipPolicy SkipPolicy
+
+	// VerifyOutput decodes every file output in full after encoding and
+	// fails the job, before outputs are moved into place or uploaded, on
+	// decode errors, a missing sample rate or bitrate, or a duration more
+	// than VerifyTolerance off the expected one (default: 500ms)
+	VerifyOutput    bool
+	VerifyTolerance time.Duration
+
+	// ExtraInputArgs are raw ffmpeg options placed before the input's -i,
+	// and ExtraOutputArgs raw options placed last before each output path,
+	// for ffmpeg features the typed options don't cover. They are passed
+	// through unchecked.
+	ExtraInputArgs  []string
+	ExtraOutputArgs []string
+
+	// DryRun validates the job and builds its ffmpeg command without
+	// running it; the command is returned in ProcessingResult.Command
+	DryRun bool
+
+	// Processing. Timeout doesn't apply to jobs with a live streaming
+	// output, which run for as long as their input plays.
 	Timeout time.Duration
 	Workers int
 
 	// Retry
-	MaxRetries  int
-	RetryDelay  time.Duration
+	MaxRetries      int           // attempts, including the first
+	RetryDelay      time.Duration // wait before the first retry
+	RetryMultiplier float64       // growth of the wait after each retry
+	RetryMaxDelay   time.Duration // cap on the wait
+	RetryJitter     float64       // random spread of each wait, as a fraction
+}
+
+// SkipPolicy decides when a job's existing outputs are reused
+type SkipPolicy string
+
+const (
+	SkipNever SkipPolicy = "" // always process
+
+	// SkipIfNewer skips when every output exists and is newer than the input
+	SkipIfNewer SkipPolicy = "newer"
+
+	// SkipIfFingerprint skips when every output exists and the recorded
+	// input content hash and command fingerprint still match
+	SkipIfFingerprint SkipPolicy = "fingerprint"
+)
+
+// CoverArt is an image embedded into outputs as their front cover
+type CoverArt struct {
+	Path string
+
+	// MaxSize scales the image down so neither side exceeds it, in pixels;
+	// 0 keeps its size
+	MaxSize int
+
+	// Format converts the image. Empty copies it as is, or writes JPEG
+	// when it is resized.
+	Format CoverArtFormat
+}
+
+// ID3Options controls the ID3 tags of MP3 outputs
+type ID3Options struct {
+	// Version is the ID3v2 version, 3 or 4; 0 keeps ffmpeg's default of 4.
+	// v2.3 stores text as UTF-16 (Latin-1 when it fits), v2.4 as UTF-8;
+	// many car head units and older players only read v2.3.
+	Version int
+
+	// WriteV1 appends an ID3v1 tag (Latin-1, fields cut to 30 bytes) for
+	// players that read nothing else
+	WriteV1 bool
+}
+
+// Lyrics are lyrics or a transcript embedded into outputs. LRC text
+// ("[01:02.50]line") is also written as synchronized lyrics to MP3.
+type Lyrics struct {
+	Text string // plain text or LRC
+	Path string // .txt or .lrc file read when the job runs, instead of Text
+
+	// Language is the ISO 639-2 code of the MP3 lyric frames, e.g. "eng";
+	// default "und"
+	Language string
 }
 
+// Identifiers are the codes distributors use to match a recording and its
+// release
+type Identifiers struct {
+	ISRC          string // recording code, e.g. "USRC17607839"; hyphens are dropped
+	Barcode       string // release UPC (12 digits) or EAN (13 digits)
+	CatalogNumber string // label catalog number, e.g. "WARP123"
+}
+
+// BWF is the bext chunk of a Broadcast Wave file (EBU Tech 3285), which
+// broadcast delivery specs require
+type BWF struct {
+	Description         string // up to 256 bytes
+	Originator          string // up to 32 bytes, e.g. the station
+	OriginatorReference string // up to 32 bytes
+	OriginationDate     string // yyyy-mm-dd
+	OriginationTime     string // hh:mm:ss
+
+	// TimeReference is the timecode of the first sample as samples since
+	// midnight, at the file's sample rate
+	TimeReference uint64
+
+	// CodingHistory lists the processing steps the audio went through, one
+	// "A=PCM,F=48000,W=24,M=stereo,T=..." line each
+	CodingHistory string
+}
+
+// CoverArtFormat is the image format cover art is converted to
+type CoverArtFormat string
+
+const (
+	CoverArtJPEG CoverArtFormat = "jpeg"
+	CoverArtPNG  CoverArtFormat = "png"
+)
+
+// MetadataPolicy decides what happens to the input's tags and chapters
+type MetadataPolicy string
+
+const (
+	// MetadataCopy copies the input's tags and chapters; tags set with
+	// Metadata override single ones. It is the default.
+	MetadataCopy MetadataPolicy = "copy"
+
+	// MetadataStrip drops every input tag and chapter and allows no
+	// Metadata, e.g. for regulated deliveries
+	MetadataStrip MetadataPolicy = "strip"
+
+	// MetadataReplace drops the input's tags so the outputs carry exactly
+	// the Metadata tags; chapters are kept
+	MetadataReplace MetadataPolicy = "replace"
+)
+
+// Bounds on job labels, which become metric label values
+const (
+	MaxJobLabels        = 8
+	MaxLabelValueLength = 128
+)
+
 // DefaultProcessingOptions returns sane defaults
 func DefaultProcessingOptions() *ProcessingOptions {
 	return &ProcessingOptions{
-		Codec:                CodecOpus,
-		Bitrate:              128000,
-		BitrateMode:          BitrateCBR,
-		SampleRate:           48000,
-		NormalizationEnabled: true,
-		LoudnessTarget:       -23.0,
-		TruePeakLimit:        -1.0,
-		LoudnessRange:        7.0,
-		HighpassEnabled:      false,
-		HighpassFreq:         80,
-		LowpassEnabled:       false,
-		LowpassFreq:          18000,
-		Timeout:              5 * time.Minute,
-		Workers:              4,
-		MaxRetries:           3,
-		RetryDelay:           time.Second,
+		Codec:                   CodecOpus,
+		Bitrate:                 128000,
+		BitrateMode:             BitrateCBR,
+		SampleRate:              48000,
+		NormalizationEnabled:    true,
+		LoudnessTarget:          -23.0,
+		TruePeakLimit:           -1.0,
+		LoudnessRange:           7.0,
+		HighpassEnabled:         false,
+		HighpassFreq:            80,
+		LowpassEnabled:          false,
+		LowpassFreq:             18000,
+		DirMode:                 0o755,
+		Timeout:                 5 * time.Minute,
+		Workers:                 4,
+		MaxRetries:              3,
+		RetryDelay:              time.Second,
+		RetryMultiplier:         2.0,
+		RetryMaxDelay:           30 * time.Second,
+		RetryJitter:             0.2,
+		StreamReconnectAttempts: 5,
+		StreamReconnectDelay:    2 * time.Second,
 	}
 }
 
 // ProcessingResult holds the result of an audio processing operation
 type ProcessingResult struct {
-	InputPath    string
-	OutputPath   string
-	InputMeta    *AudioMetadata
-	OutputMeta   *AudioMetadata
-	Duration     time.Duration
-	ProcessedAt  time.Time
+	InputPath   string
+	OutputPath  string
+	InputMeta   *AudioMetadata
+	OutputMeta  *AudioMetadata
+	Duration    time.Duration
+	ProcessedAt time.Time
+	Skipped     bool              // outputs were up to date; nothing was encoded
+	Profile     string            // ProcessingOptions.Profile of the job
+	Labels      map[string]string // ProcessingOptions.Labels of the job
+
+	// Loudness is set when normalization ran and holds before/after measurements
+	Loudness *LoudnessReport
+
+	// AdditionalOutputs holds one result per requested additional output
+	AdditionalOutputs []*ProcessingResult
+
+	// Command holds the ffmpeg arguments of a dry run (see
+	// ProcessingOptions.DryRun), without the binary itself
+	Command []string
+}
+
+// FileOwner is a numeric owner for output files; -1 leaves that id unchanged
+type FileOwner struct {
+	UID int
+	GID int
+}
+
+// AdditionalOutput is an extra output written by the same ffmpeg run.
+// Configure receives a copy of the job options and adjusts codec settings;
+// it can't be serialized, so queued jobs must leave it nil.
+type AdditionalOutput struct {
+	Path      string
+	Configure func(*ProcessingOptions) `json:"-"`
+}
+
+// LoudnessMeasurement holds EBU R128 measurements of a signal
+type LoudnessMeasurement struct {
+	Integrated float64 // LUFS
+	TruePeak   float64 // dBTP
+	LRA        float64 // LU
+	Threshold  float64 // LUFS
+}
+
+// LoudnessReport compares input and output loudness of a normalized file
+type LoudnessReport struct {
+	Target            float64 // LUFS
+	Input             LoudnessMeasurement
+	Output            LoudnessMeasurement
+	AppliedGain       float64 // dB, Output.Integrated - Input.Integrated
+	NormalizationType string  // "dynamic" or "linear" as reported by loudnorm
 }
 
 // BatchJob represents a batch processing job
@@ -98,11 +583,441 @@ type BatchJob struct {
 	InputPath  string
 	OutputPath string
 	Options    *ProcessingOptions
+
+	// Priority orders jobs waiting for a worker, across all batches of a
+	// processor: higher values start first, e.g. 10 for user-initiated
+	// jobs and -10 for backfills. Equal priorities run in submission order.
+	Priority int
+
+	// OnComplete, if set, is called by the worker with the job's result
+	// just before it is sent on the results channel. It runs on the worker
+	// goroutine and should return promptly. Queued jobs can't carry it.
+	OnComplete func(BatchResult) `json:"-"`
+}
+
+// DirOptions selects the files picked up by directory ingestion. Patterns
+// are case-insensitive globs matched against the file name, or against the
+// slash-separated path relative to the input directory when they contain a
+// "/".
+type DirOptions struct {
+	Recursive bool     // descend into subdirectories
+	Include   []string // default: DefaultAudioPatterns
+	Exclude   []string // applied after Include; also prunes directories
+	OutputExt string   // e.g. ".ogg"; default: derived from the codec
+}
+
+// DefaultAudioPatterns are the DirOptions.Include patterns used when none
+// are given
+var DefaultAudioPatterns = []string{
+	"*.wav", "*.flac", "*.aif", "*.aiff", "*.mp3", "*.m4a", "*.aac",
+	"*.ogg", "*.oga", "*.opus", "*.wma", "*.caf",
+}
+
+// WatchRules configures hot-folder processing. Files are selected and
+// mapped to outputs as in directory ingestion.
+type WatchRules struct {
+	DirOptions
+	OutputDir string
+
+	// StableFor is how long a new file's size and modification time must
+	// stay unchanged before it is processed (default: 2s)
+	StableFor time.Duration
+
+	// ProcessExisting also processes files already present at start
+	ProcessExisting bool
+
+	// OnDone and OnError decide what happens to a source file after it was
+	// processed successfully or failed
+	OnDone   WatchAction
+	OnError  WatchAction
+	DoneDir  string // destination for WatchMove after success
+	ErrorDir string // destination for WatchMove after failure
+}
+
+// WatchAction is applied to a watched source file once it is processed
+type WatchAction string
+
+const (
+	WatchKeep   WatchAction = ""       // leave the file in place
+	WatchDelete WatchAction = "delete" // remove the file
+	WatchMove   WatchAction = "move"   // move it under DoneDir or ErrorDir, keeping its relative path
+)
+
+// WatchResult is the outcome of one file picked up by a watch
+type WatchResult struct {
+	InputPath  string
+	OutputPath string
+	Result     *ProcessingResult
+	Err        error
+}
+
+// QueuedJob is a batch job reserved from a shared job queue
+type QueuedJob struct {
+	Job        BatchJob
+	Receipt    string // identifies this delivery for Ack and Extend
+	Deliveries int    // 1 on first delivery, higher when redelivered
 }
 
 // BatchResult holds results of a batch operation
 type BatchResult struct {
 	JobID   string
+	Profile string // set even when Err is non-nil
+	Skipped bool   // outputs were already up to date
 	Result  *ProcessingResult
 	Err     error
-}
\ No newline at end of file
+
+	// DuplicateOf is the ID of the job whose output stands in for this
+	// one's when the batch ran with duplicate detection
+	DuplicateOf string
+}
+
+// JobAttempt records one failed attempt at a job
+type JobAttempt struct {
+	Attempt   int           `json:"attempt"` // 1-based
+	StartedAt time.Time     `json:"started_at"`
+	Elapsed   time.Duration `json:"elapsed"`
+	Error     string        `json:"error"`
+}
+
+// DeadLetter is a batch job that failed after exhausting its retries, kept
+// so it can be inspected and retried once the underlying issue is fixed
+type DeadLetter struct {
+	Job       BatchJob     `json:"job"`
+	Error     string       `json:"error"`
+	ErrorCode string       `json:"error_code"`
+	Attempts  []JobAttempt `json:"attempts"`
+	FailedAt  time.Time    `json:"failed_at"`
+}
+
+// BatchPolicy stops a batch early when its jobs keep failing, e.g. because
+// ffmpeg lacks a codec, instead of running it to the end. Jobs not yet
+// finished when it trips are canceled and report ErrBatchAborted.
+type BatchPolicy struct {
+	// FailFast aborts the batch on the first failed job
+	FailFast bool
+
+	// MaxFailurePercent aborts the batch once more than this percentage of
+	// its finished jobs have failed; 0 disables the check
+	MaxFailurePercent float64
+
+	// MinJobs is the number of jobs that must finish before
+	// MaxFailurePercent is checked
+	MinJobs int
+}
+
+// Enabled reports whether the policy can abort a batch
+func (p BatchPolicy) Enabled() bool {
+	return p.FailFast || p.MaxFailurePercent > 0
+}
+
+// Tripped reports whether a batch with the given counts of finished and
+// failed jobs should be aborted
+func (p BatchPolicy) Tripped(finished, failed int) bool {
+	if failed == 0 {
+		return false
+	}
+	if p.FailFast {
+		return true
+	}
+	if p.MaxFailurePercent <= 0 || finished < p.MinJobs {
+		return false
+	}
+	return float64(failed)*100 > p.MaxFailurePercent*float64(finished)
+}
+
+// ToneKind represents the type of synthetic signal to generate
+type ToneKind string
+
+const (
+	ToneSine  ToneKind = "sine"
+	ToneNoise ToneKind = "noise"
+	ToneSweep ToneKind = "sweep"
+)
+
+// ToneSpec describes a deterministic synthetic audio signal
+type ToneSpec struct {
+	Kind       ToneKind
+	Duration   time.Duration
+	SampleRate int     // Hz, default: 48000
+	Channels   int     // default: 1
+	Amplitude  float64 // 0..1, default: 0.5
+
+	// Sine / sweep
+	Frequency    float64 // Hz, start frequency for sweeps, default: 440
+	EndFrequency float64 // Hz, sweep only, default: 20000
+
+	// Noise
+	NoiseColor string // white, pink, brown, blue, violet, velvet; default: white
+	Seed       int64  // fixed seed keeps noise output deterministic
+}
+
+// PreviewOptions configures a short faded preview clip
+type PreviewOptions struct {
+	Start   time.Duration // offset into the source
+	Length  time.Duration // clip length, default: 30s
+	FadeIn  time.Duration
+	FadeOut time.Duration
+}
+
+// PodcastOptions configures the podcast mastering chain. The zero value
+// masters a mono episode with every stage on.
+type PodcastOptions struct {
+	Stereo bool // keep two channels instead of mixing down to mono
+
+	// Stages left out of the chain, e.g. the denoiser for studio recordings
+	SkipDenoise    bool
+	SkipGate       bool
+	SkipCompressor bool
+	SkipDeEsser    bool
+	SkipLimiter    bool
+}
+
+// JobTemplate describes a recurring job whose paths contain {placeholders}
+// filled in at submission time, e.g. "shows/weekly/ep{episode}.wav"
+type JobTemplate struct {
+	Name       string
+	InputPath  string
+	OutputPath string
+	Options    *ProcessingOptions
+	Defaults   map[string]string // fallback values for placeholders
+
+	// SanitizeNames makes placeholder values in OutputPath safe as single
+	// file name elements (no separators, reserved characters or over-long names)
+	SanitizeNames bool
+}
+
+// WaveformOptions configures peak generation
+type WaveformOptions struct {
+	SamplesPerPixel int // input samples per output min/max pair, default: 256
+	Channels        int // 1 mixes down to mono, 2 keeps stereo; default: 1
+}
+
+// Waveform holds min/max peaks in the audiowaveform (version 2) layout,
+// directly consumable by wavesurfer.js and peaks.js
+type Waveform struct {
+	Version         int     `json:"version"`
+	Channels        int     `json:"channels"`
+	SampleRate      int     `json:"sample_rate"`
+	SamplesPerPixel int     `json:"samples_per_pixel"`
+	Bits            int     `json:"bits"`
+	Length          int     `json:"length"`
+	Data            []int16 `json:"data"` // per pixel: min,max for each channel
+}
+
+// WriteDat writes the waveform in audiowaveform's binary .dat format
+func (w *Waveform) WriteDat(out io.Writer) error {
+	header := []int32{
+		int32(w.Version),
+		0, // flags: 0 means 16-bit samples
+		int32(w.SampleRate),
+		int32(w.SamplesPerPixel),
+		int32(w.Length),
+	}
+	if w.Version >= 2 {
+		header = append(header, int32(w.Channels))
+	}
+	if err := binary.Write(out, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	return binary.Write(out, binary.LittleEndian, w.Data)
+}
+
+// AnalysisOptions configures sample-level analysis
+type AnalysisOptions struct {
+	ClipThreshold float64       // dBFS at or above which a sample counts as clipped, default: -0.01
+	ClipMinRun    int           // consecutive samples at the threshold that make a clip, default: 3
+	ClipMergeGap  time.Duration // clips closer than this form one region, default: 50ms
+}
+
+// AudioAnalysis holds sample-level measurements of decoded audio. Levels
+// are in dBFS; digital silence reads as -144.
+type AudioAnalysis struct {
+	SampleRate int
+	Channels   int
+	Duration   time.Duration // of the decoded audio
+	Peak       []float64     // sample peak per channel
+	DCOffset   []float64     // mean sample value per channel, -1..1
+	Clipping   ClippingReport
+}
+
+// ClippingReport holds the sample-peak clipping found in a file
+type ClippingReport struct {
+	Samples int64 // clipped samples across all channels
+	Regions []ClippingRegion
+}
+
+// Clipped reports whether any clipping was found
+func (r ClippingReport) Clipped() bool {
+	return r.Samples > 0
+}
+
+// ClippingRegion is a stretch of audio with clipped samples in any channel
+type ClippingRegion struct {
+	Start   time.Duration
+	End     time.Duration
+	Samples int64   // clipped samples in the region across channels
+	Peak    float64 // highest sample peak in the region
+}
+
+// HLSOptions configures HLS packaging
+type HLSOptions struct {
+	SegmentDuration time.Duration // default: 6s
+	Codec           Codec         // default: AAC
+	Bitrates        []int         // bps, one rendition per entry; default: 64k, 128k, 192k
+}
+
+// DASHOptions configures MPEG-DASH packaging
+type DASHOptions struct {
+	SegmentDuration time.Duration // default: 6s
+	Codec           Codec         // default: AAC
+	Bitrates        []int         // bps, one representation per entry; default: 64k, 128k, 192k
+}
+
+// Rendition describes one packaged bitrate variant
+type Rendition struct {
+	Codec        Codec
+	Bitrate      int
+	PlaylistPath string // variant playlist (HLS) or empty for DASH
+}
+
+// PackageResult holds the result of a streaming packaging operation
+type PackageResult struct {
+	InputPath    string
+	ManifestPath string // master.m3u8 or manifest.mpd
+	Renditions   []Rendition
+	InputMeta    *AudioMetadata
+	Duration     time.Duration
+	ProcessedAt  time.Time
+}
+
+// RenditionSpec describes one output of a multi-rendition encode. Zero
+// fields inherit from the job's ProcessingOptions.
+type RenditionSpec struct {
+	OutputPath  string
+	Codec       Codec
+	Bitrate     int
+	BitrateMode BitrateMode
+	SampleRate  int
+}
+
+// FileValidation reports the integrity of a file decoded in full. Valid
+// files decoded without errors and weren't truncated; warnings alone don't
+// make a file invalid.
+type FileValidation struct {
+	Path      string
+	Valid     bool
+	Duration  time.Duration // of the decoded audio
+	Expected  time.Duration // declared by the container, 0 if unknown
+	Truncated bool          // the decoded audio ends short of Expected
+
+	// Errors and Warnings hold the distinct messages ffmpeg logged, up to
+	// a limit; the counts include repeats and those past the limit
+	Errors       []string
+	Warnings     []string
+	ErrorCount   int
+	WarningCount int
+}
+
+// LosslessVerification compares the decoded audio of a source and its
+// transcode
+type LosslessVerification struct {
+	Identical bool   // same samples, sample rate and channel count
+	InputMD5  string // of the samples decoded to 64-bit float PCM
+	OutputMD5 string
+	Mismatch  string // why they differ, empty when Identical
+}
+
+// DuplicateAction is what a deduplicated batch does with jobs whose input
+// duplicates an earlier job's
+type DuplicateAction string
+
+const (
+	// DuplicateFlag reports duplicates and still encodes every job
+	DuplicateFlag DuplicateAction = "flag"
+	// DuplicateSkip encodes only the first job of each group
+	DuplicateSkip DuplicateAction = "skip"
+	// DuplicateLink encodes the first job of each group and links the
+	// other outputs to its output
+	DuplicateLink DuplicateAction = "link"
+)
+
+// DuplicateGroup is a set of batch jobs with the same decoded audio and
+// the same encoding settings, in batch order
+type DuplicateGroup struct {
+	AudioHash string   // SHA-256 of the decoded samples
+	JobIDs    []string // the first is the job that is encoded
+	Inputs    []string
+}
+
+// DuplicateReport lists the duplicate groups found in a batch
+type DuplicateReport struct {
+	Groups []DuplicateGroup
+
+	// Unchecked holds the IDs of jobs whose input couldn't be decoded;
+	// they are never treated as duplicates
+	Unchecked []string
+}
+
+// Duplicates returns the number of jobs that duplicate an earlier one
+func (r *DuplicateReport) Duplicates() int {
+	n := 0
+	for _, g := range r.Groups {
+		n += len(g.JobIDs) - 1
+	}
+	return n
+}
+
+// ReconciliationReport compares a batch's expected outputs against storage
+type ReconciliationReport struct {
+	OK      []string // present and non-empty
+	Missing []string // expected but not found
+	Empty   []string // present but zero bytes
+	Orphans []string // batch-like files found in output directories but not expected
+}
+
+// Clean reports whether every expected output exists and nothing is orphaned
+func (r *ReconciliationReport) Clean() bool {
+	return len(r.Missing) == 0 && len(r.Empty) == 0 && len(r.Orphans) == 0
+}
+
+// Stats is a snapshot of a processor's runtime statistics since it was
+// created
+type Stats struct {
+	InFlight  int   // jobs being processed
+	Queued    int   // batch jobs waiting for a worker
+	Completed int64 // jobs that succeeded, excluding skipped ones
+	Failed    int64
+	Skipped   int64 // jobs whose outputs were already up to date
+
+	// AverageSpeed is the realtime factor of completed jobs: seconds of
+	// input audio encoded per second of processing time
+	AverageSpeed float64
+
+	BytesIn  int64 // input bytes of completed jobs
+	BytesOut int64 // output bytes written by completed jobs
+
+	ByCodec map[Codec]int64 // completed jobs per output codec
+}
+
+// Capabilities describes what the ffmpeg build in use supports. Empty sets
+// mean detection didn't run or failed, not that nothing is supported.
+type Capabilities struct {
+	Version  string          // e.g. "6.1.1"
+	Encoders map[string]bool // encoder names, e.g. "libopus"
+	Filters  map[string]bool // filter names, e.g. "loudnorm"
+}
+
+// Detected reports whether encoders and filters were listed
+func (c *Capabilities) Detected() bool {
+	return c != nil && len(c.Encoders) > 0 && len(c.Filters) > 0
+}
+
+// HasEncoder reports whether the build includes the named encoder
+func (c *Capabilities) HasEncoder(name string) bool {
+	return c != nil && c.Encoders[name]
+}
+
+// HasFilter reports whether the build includes the named filter
+func (c *Capabilities) HasFilter(name string) bool {
+	return c != nil && c.Filters[name]
+}
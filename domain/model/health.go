@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// HealthReport is the outcome of a processor health check, shaped for
+// /healthz endpoints
+type HealthReport struct {
+	Healthy   bool              `json:"healthy"`
+	CheckedAt time.Time         `json:"checked_at"`
+	Checks    []ComponentHealth `json:"checks"`
+}
+
+// ComponentHealth is the outcome of checking one dependency
+type ComponentHealth struct {
+	Name    string        `json:"name"`
+	Healthy bool          `json:"healthy"`
+	Skipped bool          `json:"skipped,omitempty"` // not checkable with this setup
+	Message string        `json:"message,omitempty"`
+	Latency time.Duration `json:"latency_ns"`
+}
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+// JobInfo identifies the job a hook is called for
+type JobInfo struct {
+	ID         string
+	InputPath  string
+	OutputPath string
+	Profile    string
+	Labels     map[string]string
+}
+
+// Hooks are callbacks invoked at points of a job's lifecycle, e.g. for
+// auditing, billing or alerting. Any of them may be nil. They are called
+// synchronously from the goroutine running the job, concurrently for
+// concurrent jobs, and must not block.
+//
+// Stages are "validate", "probe", "encode", "finalize", "verify" and
+// "commit", plus "download" and "upload" when remote storage is staged.
+type Hooks struct {
+	// OnJobStart is called when a job starts, once per job
+	OnJobStart func(ctx context.Context, job JobInfo)
+
+	// OnStageStart and OnStageEnd bracket each pipeline stage of every
+	// attempt
+	OnStageStart func(ctx context.Context, job JobInfo, stage string)
+	OnStageEnd   func(ctx context.Context, job JobInfo, stage string, elapsed time.Duration, err error)
+
+	// OnJobComplete is called once a job has finished, successfully or
+	// not, after any retries
+	OnJobComplete func(ctx context.Context, job JobInfo, result *ProcessingResult, err error)
+
+	// OnRetry is called before a failed attempt is retried; attempt is the
+	// 1-based number of the attempt that failed
+	OnRetry func(ctx context.Context, job JobInfo, attempt int, err error)
+
+	// OnError is called for every error a job hits: failed attempts that
+	// are retried as well as the final failure
+	OnError func(ctx context.Context, job JobInfo, err error)
+}
@@ -0,0 +1,130 @@
+package model
+
+import "fmt"
+
+// CommandPlanVersion is the current CommandPlan schema version
+const CommandPlanVersion = 1
+
+// Plan paths connecting the stages of a piped multi-stage command: a
+// stage writing PlanPipeOutput feeds the next stage's PlanPipeInput
+const (
+	PlanPipeInput  = "pipe:0"
+	PlanPipeOutput = "pipe:1"
+)
+
+// CommandPlan is the complete, serializable description of an ffmpeg
+// invocation produced before execution. Plans can be reviewed, diffed,
+// stored and executed later.
+type CommandPlan struct {
+	Version     int          `json:"version"`
+	JobID       string       `json:"job_id,omitempty"`
+	GlobalArgs  []string     `json:"global_args,omitempty"`
+	Inputs      []PlanInput  `json:"inputs"`
+	Filtergraph string       `json:"filtergraph,omitempty"` // -filter_complex graph, if any
+	Outputs     []PlanOutput `json:"outputs"`
+}
+
+// PlanInput is a single ffmpeg input with its input options
+type PlanInput struct {
+	Path string   `json:"path"`
+	Args []string `json:"args,omitempty"` // options placed before -i
+}
+
+// PlanOutput is a single ffmpeg output with its output options
+type PlanOutput struct {
+	Path   string   `json:"path"`
+	Maps   []string `json:"maps,omitempty"`   // -map specifiers
+	Filter string   `json:"filter,omitempty"` // simple -af chain
+	Args   []string `json:"args,omitempty"`   // codec and muxer options
+}
+
+// Validate checks that the plan can be turned into a command
+func (p *CommandPlan) Validate() error {
+	if p.Version != CommandPlanVersion {
+		return fmt.Errorf("unsupported command plan version %d", p.Version)
+	}
+	if len(p.Inputs) == 0 {
+		return fmt.Errorf("command plan has no inputs")
+	}
+	if len(p.Outputs) == 0 {
+		return fmt.Errorf("command plan has no outputs")
+	}
+	for i, in := range p.Inputs {
+		if in.Path == "" {
+			return fmt.Errorf("command plan input %d has no path", i)
+		}
+	}
+	for i, out := range p.Outputs {
+		if out.Path == "" {
+			return fmt.Errorf("command plan output %d has no path", i)
+		}
+	}
+	return nil
+}
+
+// Args flattens the plan into ffmpeg argv (without the binary name)
+func (p *CommandPlan) Args() []string {
+	args := append([]string{}, p.GlobalArgs...)
+	for _, in := range p.Inputs {
+		args = append(args, in.Args...)
+		args = append(args, "-i", in.Path)
+	}
+	if p.Filtergraph != "" {
+		args = append(args, "-filter_complex", p.Filtergraph)
+	}
+	for _, out := range p.Outputs {
+		for _, m := range out.Maps {
+			args = append(args, "-map", m)
+		}
+		if out.Filter != "" {
+			args = append(args, "-af", out.Filter)
+		}
+		args = append(args, out.Args...)
+		args = append(args, out.Path)
+	}
+	return args
+}
+
+// CommandPlanJSONSchema is the JSON Schema (draft 2020-12) for CommandPlan
+const CommandPlanJSONSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "https://github.com/Skryldev/audio-lab/schemas/command-plan.json",
+  "title": "CommandPlan",
+  "type": "object",
+  "required": ["version", "inputs", "outputs"],
+  "additionalProperties": false,
+  "properties": {
+    "version": {"const": 1},
+    "job_id": {"type": "string"},
+    "global_args": {"type": "array", "items": {"type": "string"}},
+    "inputs": {
+      "type": "array",
+      "minItems": 1,
+      "items": {
+        "type": "object",
+        "required": ["path"],
+        "additionalProperties": false,
+        "properties": {
+          "path": {"type": "string", "minLength": 1},
+          "args": {"type": "array", "items": {"type": "string"}}
+        }
+      }
+    },
+    "filtergraph": {"type": "string"},
+    "outputs": {
+      "type": "array",
+      "minItems": 1,
+      "items": {
+        "type": "object",
+        "required": ["path"],
+        "additionalProperties": false,
+        "properties": {
+          "path": {"type": "string", "minLength": 1},
+          "maps": {"type": "array", "items": {"type": "string"}},
+          "filter": {"type": "string"},
+          "args": {"type": "array", "items": {"type": "string"}}
+        }
+      }
+    }
+  }
+}`
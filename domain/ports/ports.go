@@ -2,6 +2,9 @@ package ports
 
 import (
 	"context"
+	"io"
+	"os"
+	"time"
 
 	"github.com/Skryldev/audio-lab/domain/model"
 )
@@ -18,15 +21,38 @@ type AudioProcessor interface {
 	ProbeAudio(ctx context.Context, inputPath string) (*model.AudioMetadata, error)
 }
 
+// DefaultsProvider is implemented by processors that apply default options
+// to every job
+type DefaultsProvider interface {
+	// Defaults returns the options applied to every job ahead of its own
+	Defaults() []Option
+}
+
 // FFmpegExecutor is the abstraction for FFmpeg command execution
 type FFmpegExecutor interface {
 	// Execute runs an ffmpeg command with the given arguments
 	Execute(ctx context.Context, args []string) error
 
-	// Probe runs ffprobe and returns JSON output
+	// Probe runs ffprobe and returns its JSON output with the format,
+	// streams and chapters sections
 	Probe(ctx context.Context, inputPath string) ([]byte, error)
 }
 
+// PipedExecutor is implemented by executors that can capture ffmpeg's
+// output and stream its input. Executors without it still encode files,
+// without progress updates; features that read ffmpeg's output, such as
+// analysis, waveforms and verification, fail.
+type PipedExecutor interface {
+	FFmpegExecutor
+
+	// ExecuteWithOutput runs an ffmpeg command and returns its stdout and stderr
+	ExecuteWithOutput(ctx context.Context, args []string) (stdout, stderr []byte, err error)
+
+	// ExecutePiped runs an ffmpeg command streaming stdin from r and stdout into w
+	// and returns the captured stderr. Either r or w may be nil.
+	ExecutePiped(ctx context.Context, args []string, r io.Reader, w io.Writer) (stderr []byte, err error)
+}
+
 // StorageProvider abstracts filesystem or object storage operations
 type StorageProvider interface {
 	// Exists checks if a file exists
@@ -42,6 +68,135 @@ type StorageProvider interface {
 	TempFile(ctx context.Context, dir, pattern string) (string, error)
 }
 
+// FileStorage is implemented by providers that can also list, read, write
+// and move files. Providers without it use the local filesystem for these
+// operations.
+type FileStorage interface {
+	StorageProvider
+
+	// List returns the paths of the regular files directly inside dir
+	List(ctx context.Context, dir string) ([]string, error)
+
+	// ListDirs returns the paths of the subdirectories directly inside dir
+	ListDirs(ctx context.Context, dir string) ([]string, error)
+
+	// Open opens a file for reading
+	Open(ctx context.Context, path string) (io.ReadCloser, error)
+
+	// Create creates or truncates a file for writing
+	Create(ctx context.Context, path string) (io.WriteCloser, error)
+
+	// Rename moves oldPath to newPath, replacing newPath if it exists
+	Rename(ctx context.Context, oldPath, newPath string) error
+
+	// ModTime returns the last modification time of a file
+	ModTime(ctx context.Context, path string) (time.Time, error)
+
+	// MkdirAll creates dir and any missing parents with the given mode
+	MkdirAll(ctx context.Context, dir string, perm os.FileMode) error
+
+	// SetPermissions changes the mode (when non-zero) and owner (when non-nil)
+	// of path
+	SetPermissions(ctx context.Context, path string, perm os.FileMode, owner *model.FileOwner) error
+}
+
+// RemoteStorage is implemented by storage providers whose paths ffmpeg
+// cannot open directly, such as object stores. The pipeline stages inputs
+// to local temp files and uploads outputs when the provider implements it.
+type RemoteStorage interface {
+	StorageProvider
+
+	// Download copies the object at path into the local file localPath
+	Download(ctx context.Context, path, localPath string) error
+
+	// Upload copies the local file localPath to path
+	Upload(ctx context.Context, localPath, path string) error
+}
+
+// StorageRouter is implemented by providers that dispatch each path to
+// another provider, e.g. by URI scheme
+type StorageRouter interface {
+	StorageProvider
+
+	// Route returns the provider responsible for path and the path to pass it
+	Route(path string) (StorageProvider, string, error)
+}
+
+// ProbeChecker is implemented by executors that can verify ffprobe runs
+// without probing a file
+type ProbeChecker interface {
+	// CheckProbe runs ffprobe -version
+	CheckProbe(ctx context.Context) error
+}
+
+// ProcessReserver is implemented by executors that cap how many processes
+// run at once
+type ProcessReserver interface {
+	// ReserveProcesses takes n process slots at once for processes that
+	// must run together, such as piped stages. Calls made with the returned
+	// context run on the reserved slots until release is called.
+	ReserveProcesses(ctx context.Context, n int) (_ context.Context, release func(), err error)
+}
+
+// SpaceReporter is implemented by storage providers that can report the
+// free space available for writing to a path
+type SpaceReporter interface {
+	// FreeSpace returns the bytes available to unprivileged writers on the
+	// volume holding path
+	FreeSpace(ctx context.Context, path string) (uint64, error)
+}
+
+// FingerprintStore remembers the fingerprint each output was produced
+// with, for SkipIfFingerprint
+type FingerprintStore interface {
+	// Get returns the fingerprint recorded for output, if any
+	Get(ctx context.Context, output string) (fingerprint string, ok bool, err error)
+
+	// Put records the fingerprint output was produced with
+	Put(ctx context.Context, output, fingerprint string) error
+}
+
+// AdmissionGovernor is consulted before each job starts and can hold it
+// back, e.g. while the host is too hot or on battery power
+type AdmissionGovernor interface {
+	// Admit blocks until the job may start, or returns ctx's error
+	Admit(ctx context.Context, jobID string) error
+}
+
+// JobQueue is a job queue shared by several workers, with at-least-once
+// delivery: a dequeued job is reserved for a visibility timeout and handed
+// out again unless it is acknowledged in time
+type JobQueue interface {
+	// Enqueue adds a job to the queue
+	Enqueue(ctx context.Context, job model.BatchJob) error
+
+	// Dequeue blocks until a job can be reserved or ctx is done. A reserved
+	// job that can't be decoded is returned with the error, so that it can
+	// be acknowledged.
+	Dequeue(ctx context.Context) (*model.QueuedJob, error)
+
+	// Ack removes a finished job. It fails if the reservation has expired
+	// and the job was redelivered.
+	Ack(ctx context.Context, job *model.QueuedJob) error
+
+	// Extend renews the reservation of a job that is still being processed
+	Extend(ctx context.Context, job *model.QueuedJob) error
+}
+
+// DeadLetterStore keeps batch jobs that failed after exhausting their
+// retries. Letters are keyed by job ID; a later failure of the same ID
+// replaces the earlier letter.
+type DeadLetterStore interface {
+	// Add records a failed job
+	Add(ctx context.Context, letter model.DeadLetter) error
+
+	// List returns the recorded jobs, oldest failure first
+	List(ctx context.Context) ([]model.DeadLetter, error)
+
+	// Remove forgets the job with the given ID
+	Remove(ctx context.Context, jobID string) error
+}
+
 // ProgressReporter allows callers to receive progress updates
 type ProgressReporter interface {
 	// Report sends a progress update
@@ -72,6 +227,19 @@ func WithBitrateMode(mode model.BitrateMode) Option {
 	}
 }
 
+// WithEncoderPreference sets the ffmpeg encoders tried for codec, in
+// order, e.g. WithEncoderPreference(model.CodecAAC, "aac_at", "aac")
+func WithEncoderPreference(codec model.Codec, encoders ...string) Option {
+	return func(o *model.ProcessingOptions) {
+		prefs := make(map[model.Codec][]string, len(o.EncoderPreference)+1)
+		for c, e := range o.EncoderPreference {
+			prefs[c] = e
+		}
+		prefs[codec] = append([]string(nil), encoders...)
+		o.EncoderPreference = prefs
+	}
+}
+
 // WithSampleRate sets the output sample rate
 func WithSampleRate(hz int) Option {
 	return func(o *model.ProcessingOptions) {
@@ -93,6 +261,14 @@ func WithLoudnessTarget(lufs float64) Option {
 	}
 }
 
+// WithDCRemoval removes DC offset with a 5 Hz highpass ahead of the other
+// filters
+func WithDCRemoval() Option {
+	return func(o *model.ProcessingOptions) {
+		o.DCRemoval = true
+	}
+}
+
 // WithHighpass enables highpass filter at given frequency
 func WithHighpass(hz int) Option {
 	return func(o *model.ProcessingOptions) {
@@ -109,6 +285,329 @@ func WithLowpass(hz int) Option {
 	}
 }
 
+// WithPadding adds leading and trailing silence to the output
+func WithPadding(leading, trailing time.Duration) Option {
+	return func(o *model.ProcessingOptions) {
+		o.LeadingPadding = leading
+		o.TrailingPadding = trailing
+	}
+}
+
+// WithAdditionalOutput writes an extra output from the same decode and
+// filter pass, e.g. an MP3 alongside the main Opus file. opts are applied
+// on top of the job's options for that output only; options changing the
+// filters, such as normalization or padding, fail validation.
+func WithAdditionalOutput(path string, opts ...Option) Option {
+	return func(o *model.ProcessingOptions) {
+		o.AdditionalOutputs = append(o.AdditionalOutputs, model.AdditionalOutput{
+			Path: path,
+			Configure: func(out *model.ProcessingOptions) {
+				for _, opt := range opts {
+					opt(out)
+				}
+			},
+		})
+	}
+}
+
+// WithStreamReconnect configures reconnect handling for icecast:// and
+// rtmp:// outputs
+func WithStreamReconnect(attempts int, delay time.Duration) Option {
+	return func(o *model.ProcessingOptions) {
+		o.StreamReconnectAttempts = attempts
+		o.StreamReconnectDelay = delay
+	}
+}
+
+// WithInputFormat forces the input container format (e.g. "mp3", "wav"),
+// recommended for io.Reader input where ffmpeg cannot seek to detect it
+func WithInputFormat(format string) Option {
+	return func(o *model.ProcessingOptions) {
+		o.InputFormat = format
+	}
+}
+
+// WithThreads caps the decoder and encoder threads of the job's ffmpeg
+// process, e.g. 1 for bulk jobs sharing a host with latency-sensitive work
+func WithThreads(n int) Option {
+	return func(o *model.ProcessingOptions) {
+		o.Threads = n
+	}
+}
+
+// WithAudioStream encodes the input stream at container index i (as
+// probed in StreamInfo.Index), e.g. a second language track of a video
+func WithAudioStream(i int) Option {
+	return func(o *model.ProcessingOptions) {
+		o.AudioStream = &i
+	}
+}
+
+// WithAudioLanguage encodes the input's audio stream tagged with the ISO
+// 639-2 language lang, e.g. "eng"; a default-flagged one wins among several
+func WithAudioLanguage(lang string) Option {
+	return func(o *model.ProcessingOptions) {
+		o.AudioLanguage = lang
+	}
+}
+
+// WithOutputFormat forces the output container format (e.g. "ogg", "mp3")
+func WithOutputFormat(format string) Option {
+	return func(o *model.ProcessingOptions) {
+		o.OutputFormat = format
+	}
+}
+
+// WithOutputDirs controls whether missing output directories are created
+func WithOutputDirs(create bool) Option {
+	return func(o *model.ProcessingOptions) {
+		o.SkipCreateOutputDirs = !create
+	}
+}
+
+// WithOutputPermissions sets the mode for created directories and written
+// output files. A zero mode keeps the default.
+func WithOutputPermissions(dirMode, fileMode os.FileMode) Option {
+	return func(o *model.ProcessingOptions) {
+		if dirMode != 0 {
+			o.DirMode = dirMode
+		}
+		o.FileMode = fileMode
+	}
+}
+
+// WithOutputOwner chowns outputs and created directories; -1 leaves the
+// uid or gid unchanged
+func WithOutputOwner(uid, gid int) Option {
+	return func(o *model.ProcessingOptions) {
+		o.Owner = &model.FileOwner{UID: uid, GID: gid}
+	}
+}
+
+// WithAtomicWrites controls whether outputs are encoded into a temp file
+// and renamed into place after verification (the default) or written in
+// place
+func WithAtomicWrites(enabled bool) Option {
+	return func(o *model.ProcessingOptions) {
+		o.DirectOutputWrites = !enabled
+	}
+}
+
+// WithOutputVerification decodes outputs in full after encoding and fails
+// the job when they are truncated or damaged, before they replace existing
+// files. A zero tolerance keeps the default of 500ms between the input's
+// and the output's duration.
+func WithOutputVerification(tolerance time.Duration) Option {
+	return func(o *model.ProcessingOptions) {
+		o.VerifyOutput = true
+		o.VerifyTolerance = tolerance
+	}
+}
+
+// WithSkipPolicy skips jobs whose outputs are already up to date, e.g.
+// when re-running a large library after a few additions
+func WithSkipPolicy(policy model.SkipPolicy) Option {
+	return func(o *model.ProcessingOptions) {
+		o.SkipPolicy = policy
+	}
+}
+
+// WithCustomFilter appends a raw ffmpeg audio filter, e.g.
+// "acompressor=threshold=-20dB", after the generated filters
+func WithCustomFilter(expr string) Option {
+	return func(o *model.ProcessingOptions) {
+		o.CustomFilters = append(o.CustomFilters, expr)
+	}
+}
+
+// WithExtraInputArgs appends raw ffmpeg options placed before the input,
+// e.g. "-ss", "30" to seek. They are not validated.
+func WithExtraInputArgs(args ...string) Option {
+	return func(o *model.ProcessingOptions) {
+		o.ExtraInputArgs = append(o.ExtraInputArgs, args...)
+	}
+}
+
+// WithExtraOutputArgs appends raw ffmpeg options placed after the
+// generated codec and muxer options of each output, so they take
+// precedence, e.g. "-compression_level", "10". They are not validated.
+func WithExtraOutputArgs(args ...string) Option {
+	return func(o *model.ProcessingOptions) {
+		o.ExtraOutputArgs = append(o.ExtraOutputArgs, args...)
+	}
+}
+
+// WithDryRun makes jobs build and return their ffmpeg command (in
+// ProcessingResult.Command) without running it, e.g. to review generated
+// commands in CI
+func WithDryRun() Option {
+	return func(o *model.ProcessingOptions) {
+		o.DryRun = true
+	}
+}
+
+// WithProfile tags the job with a profile name that is carried on its
+// progress updates and results
+func WithProfile(name string) Option {
+	return func(o *model.ProcessingOptions) {
+		o.Profile = name
+	}
+}
+
+// WithMetadata writes tags such as "title", "artist" and "album" to the
+// outputs. Tags are merged with any set by earlier options; an empty value
+// removes the tag from the output.
+func WithMetadata(tags map[string]string) Option {
+	return func(o *model.ProcessingOptions) {
+		if o.Metadata == nil {
+			o.Metadata = make(map[string]string, len(tags))
+		}
+		for k, v := range tags {
+			o.Metadata[k] = v
+		}
+	}
+}
+
+// WithMetadataPolicy decides whether the input's tags are copied to the
+// outputs, stripped, or replaced by those set with WithMetadata
+func WithMetadataPolicy(policy model.MetadataPolicy) Option {
+	return func(o *model.ProcessingOptions) {
+		o.MetadataPolicy = policy
+	}
+}
+
+// WithCoverArt embeds the image at imagePath as the front cover of MP3
+// (APIC), M4A/MP4 and FLAC outputs. Other outputs fail validation, except
+// additional outputs and renditions, which are written without it.
+func WithCoverArt(imagePath string) Option {
+	return func(o *model.ProcessingOptions) {
+		o.CoverArt.Path = imagePath
+	}
+}
+
+// WithCoverArtResize scales embedded cover art down so neither side
+// exceeds maxSize pixels, e.g. 600 for players that reject large images
+func WithCoverArtResize(maxSize int) Option {
+	return func(o *model.ProcessingOptions) {
+		o.CoverArt.MaxSize = maxSize
+	}
+}
+
+// WithCoverArtFormat converts embedded cover art to format
+func WithCoverArtFormat(format model.CoverArtFormat) Option {
+	return func(o *model.ProcessingOptions) {
+		o.CoverArt.Format = format
+	}
+}
+
+// WithID3Version writes ID3v2.3 (3) or ID3v2.4 (4) tags to MP3 outputs;
+// v2.3 is the one legacy players and car head units read
+func WithID3Version(version int) Option {
+	return func(o *model.ProcessingOptions) {
+		o.ID3.Version = version
+	}
+}
+
+// WithID3v1 also writes an ID3v1 tag to MP3 outputs
+func WithID3v1(enabled bool) Option {
+	return func(o *model.ProcessingOptions) {
+		o.ID3.WriteV1 = enabled
+	}
+}
+
+// WithChapters writes chapters to MP3 (ID3 CHAP frames) and M4A/M4B/MP4
+// (QuickTime chapters) outputs, replacing the input's. Chapters must be in
+// order; one without an End runs until the next starts.
+func WithChapters(chapters []model.Chapter) Option {
+	return func(o *model.ProcessingOptions) {
+		o.Chapters = append([]model.Chapter(nil), chapters...)
+	}
+}
+
+// WithLyrics embeds lyrics or a transcript, plain or LRC, into outputs:
+// USLT (and SYLT for LRC) frames in MP3, ©lyr in M4A and LYRICS in FLAC
+// and Ogg
+func WithLyrics(text string) Option {
+	return func(o *model.ProcessingOptions) {
+		o.Lyrics.Text = text
+		o.Lyrics.Path = ""
+	}
+}
+
+// WithLyricsFile embeds the lyrics of a local .txt or .lrc file, read when
+// the job runs, as WithLyrics does
+func WithLyricsFile(path string) Option {
+	return func(o *model.ProcessingOptions) {
+		o.Lyrics.Path = path
+		o.Lyrics.Text = ""
+	}
+}
+
+// WithLyricsLanguage sets the ISO 639-2 language of MP3 lyric frames
+func WithLyricsLanguage(lang string) Option {
+	return func(o *model.ProcessingOptions) {
+		o.Lyrics.Language = lang
+	}
+}
+
+// WithISRC tags outputs with the recording's ISRC
+func WithISRC(isrc string) Option {
+	return func(o *model.ProcessingOptions) {
+		o.Identifiers.ISRC = isrc
+	}
+}
+
+// WithBarcode tags outputs with the release's UPC or EAN
+func WithBarcode(code string) Option {
+	return func(o *model.ProcessingOptions) {
+		o.Identifiers.Barcode = code
+	}
+}
+
+// WithCatalogNumber tags outputs with the label's catalog number
+func WithCatalogNumber(number string) Option {
+	return func(o *model.ProcessingOptions) {
+		o.Identifiers.CatalogNumber = number
+	}
+}
+
+// WithBWF writes bwf as the Broadcast Wave bext chunk of WAV outputs,
+// which need a PCM codec (model.CodecPCM16 or model.CodecPCM24)
+func WithBWF(bwf model.BWF) Option {
+	return func(o *model.ProcessingOptions) {
+		o.BWF = &bwf
+	}
+}
+
+// WithGapless writes the encoder delay and padding to MP3 (LAME header)
+// and M4A/MP4 (edit list and iTunes gapless flag) outputs, so tracks of
+// continuous mixes join without audible gaps
+func WithGapless(enabled bool) Option {
+	return func(o *model.ProcessingOptions) {
+		o.Gapless = enabled
+	}
+}
+
+// WithLabels attaches cost-attribution labels to the job. Labels are
+// merged with any set by earlier options.
+func WithLabels(labels map[string]string) Option {
+	return func(o *model.ProcessingOptions) {
+		if o.Labels == nil {
+			o.Labels = make(map[string]string, len(labels))
+		}
+		for k, v := range labels {
+			o.Labels[k] = v
+		}
+	}
+}
+
+// WithTimeout sets the maximum processing time of a job
+func WithTimeout(d time.Duration) Option {
+	return func(o *model.ProcessingOptions) {
+		o.Timeout = d
+	}
+}
+
 // WithWorkers sets the number of concurrent workers for batch processing
 func WithWorkers(n int) Option {
 	return func(o *model.ProcessingOptions) {
@@ -118,10 +617,25 @@ func WithWorkers(n int) Option {
 	}
 }
 
-// WithRetry sets retry configuration
-func WithRetry(maxRetries int, delay ...interface{}) Option {
+// WithRetry sets the number of attempts and the exponential backoff
+// between them: the first retry waits initialDelay, each following one
+// multiplier times longer up to maxDelay. Every wait is randomized by up to
+// ±jitter (a fraction, e.g. 0.2) so that failing jobs don't retry in
+// lockstep.
+func WithRetry(attempts int, initialDelay time.Duration, multiplier float64, maxDelay time.Duration, jitter float64) Option {
+	return func(o *model.ProcessingOptions) {
+		o.MaxRetries = attempts
+		o.RetryDelay = initialDelay
+		o.RetryMultiplier = multiplier
+		o.RetryMaxDelay = maxDelay
+		o.RetryJitter = jitter
+	}
+}
+
+// WithRetryAttempts sets the number of attempts, keeping the backoff
+func WithRetryAttempts(attempts int) Option {
 	return func(o *model.ProcessingOptions) {
-		o.MaxRetries = maxRetries
+		o.MaxRetries = attempts
 	}
 }
 
@@ -0,0 +1,120 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// healthService stays reachable without credentials for load balancer and
+// orchestrator probes
+const healthService = "/grpc.health.v1.Health/"
+
+// AuthConfig configures how callers authenticate. A caller's name scopes
+// the jobs whose progress it may watch.
+type AuthConfig struct {
+	// Tokens maps bearer tokens, sent as "authorization: Bearer <token>"
+	// metadata, to the names of the callers they authenticate
+	Tokens map[string]string
+
+	// ClientCerts accepts a verified TLS client certificate, naming the
+	// caller by its subject common name. The server's TLS config must
+	// verify client certificates.
+	ClientCerts bool
+}
+
+// Enabled reports whether any authentication method is configured
+func (a AuthConfig) Enabled() bool {
+	return len(a.Tokens) > 0 || a.ClientCerts
+}
+
+// ServerOptions returns interceptors that reject unauthenticated calls to
+// every service but health checking
+func (a AuthConfig) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if strings.HasPrefix(info.FullMethod, healthService) {
+				return handler(ctx, req)
+			}
+			ctx, err := a.authenticate(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if strings.HasPrefix(info.FullMethod, healthService) {
+				return handler(srv, ss)
+			}
+			ctx, err := a.authenticate(ss.Context())
+			if err != nil {
+				return err
+			}
+			return handler(srv, &authStream{ServerStream: ss, ctx: ctx})
+		}),
+	}
+}
+
+// authenticate returns ctx carrying the caller's name
+func (a AuthConfig) authenticate(ctx context.Context) (context.Context, error) {
+	if a.ClientCerts {
+		if p, ok := peer.FromContext(ctx); ok {
+			if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.VerifiedChains) > 0 {
+				if name := info.State.VerifiedChains[0][0].Subject.CommonName; name != "" {
+					return context.WithValue(ctx, callerKey{}, name), nil
+				}
+			}
+		}
+	}
+	if len(a.Tokens) > 0 {
+		md, _ := metadata.FromIncomingContext(ctx)
+		for _, v := range md.Get("authorization") {
+			scheme, token, ok := strings.Cut(v, " ")
+			if !ok || !strings.EqualFold(scheme, "bearer") {
+				continue
+			}
+			if name, ok := a.lookup(strings.TrimSpace(token)); ok {
+				return context.WithValue(ctx, callerKey{}, name), nil
+			}
+		}
+	}
+	return nil, status.Error(codes.Unauthenticated, "missing or invalid credentials")
+}
+
+// lookup finds token in constant time per configured token
+func (a AuthConfig) lookup(token string) (string, bool) {
+	var name string
+	found := false
+	for t, n := range a.Tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			name, found = n, true
+		}
+	}
+	return name, found
+}
+
+// authStream overrides the context of an authenticated stream
+type authStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authStream) Context() context.Context {
+	return s.ctx
+}
+
+type callerKey struct{}
+
+// Caller returns the name of the caller authenticated by AuthConfig, or ""
+// when authentication is off
+func Caller(ctx context.Context) string {
+	name, _ := ctx.Value(callerKey{}).(string)
+	return name
+}
@@ -0,0 +1,128 @@
+package grpcapi
+
+import (
+	"context"
+
+	audiolabv1 "github.com/Skryldev/audio-lab/api/audiolab/v1"
+	"github.com/Skryldev/audio-lab/application/jobspec"
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/domain/ports"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"github.com/Skryldev/audio-lab/pkg/progress"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// toBatchJob converts a job through its JSON form so both APIs validate
+// options and paths the same way
+func toBatchJob(ctx context.Context, j *audiolabv1.Job, paths jobspec.PathPolicy, defaults []ports.Option) (model.BatchJob, error) {
+	if j == nil {
+		return model.BatchJob{}, pkgerrors.NewValidationError("job", nil, "job is required")
+	}
+	spec := jobspec.Job{
+		ID:       j.GetId(),
+		Input:    j.GetInput(),
+		Output:   j.GetOutput(),
+		Options:  toOptions(j.GetOptions()),
+		Priority: int(j.GetPriority()),
+	}
+	if err := paths.CheckJob(ctx, &spec); err != nil {
+		return model.BatchJob{}, err
+	}
+	job, err := spec.BatchJob(defaults...)
+	if err != nil {
+		return model.BatchJob{}, err
+	}
+	if job.ID == "" {
+		job.ID = jobspec.NewJobID()
+	}
+	return job, nil
+}
+
+func toOptions(o *audiolabv1.Options) *jobspec.Options {
+	if o == nil {
+		return nil
+	}
+	opts := &jobspec.Options{
+		Profile:                o.GetProfile(),
+		Labels:                 o.GetLabels(),
+		Codec:                  o.GetCodec(),
+		Bitrate:                int(o.GetBitrate()),
+		BitrateMode:            o.GetBitrateMode(),
+		SampleRate:             int(o.GetSampleRate()),
+		InputFormat:            o.GetInputFormat(),
+		OutputFormat:           o.GetOutputFormat(),
+		Normalize:              o.Normalize,
+		LoudnessTarget:         o.LoudnessTarget,
+		HighpassHz:             int(o.GetHighpassHz()),
+		LowpassHz:              int(o.GetLowpassHz()),
+		LeadingPaddingSeconds:  o.GetLeadingPaddingSeconds(),
+		TrailingPaddingSeconds: o.GetTrailingPaddingSeconds(),
+		SkipPolicy:             o.GetSkipPolicy(),
+		TimeoutSeconds:         o.GetTimeoutSeconds(),
+	}
+	if o.MaxRetries != nil {
+		n := int(o.GetMaxRetries())
+		opts.MaxRetries = &n
+	}
+	for _, out := range o.GetAdditionalOutputs() {
+		opts.AdditionalOutputs = append(opts.AdditionalOutputs, jobspec.Output{
+			Path:        out.GetPath(),
+			Codec:       out.GetCodec(),
+			Bitrate:     int(out.GetBitrate()),
+			BitrateMode: out.GetBitrateMode(),
+			SampleRate:  int(out.GetSampleRate()),
+		})
+	}
+	return opts
+}
+
+func toJobResult(r model.BatchResult) *audiolabv1.JobResult {
+	out := &audiolabv1.JobResult{
+		Id:      r.JobID,
+		Profile: r.Profile,
+		Skipped: r.Skipped,
+	}
+	if res := r.Result; res != nil {
+		out.Labels = res.Labels
+		out.Input = res.InputPath
+		out.Output = res.OutputPath
+		out.DurationSeconds = res.Duration.Seconds()
+		out.InputMetadata = toMetadata(res.InputMeta)
+		out.OutputMetadata = toMetadata(res.OutputMeta)
+	}
+	if r.Err != nil {
+		e := jobspec.NewError(r.Err)
+		out.Error = &audiolabv1.Error{Code: e.Code, Message: e.Message}
+	}
+	return out
+}
+
+func toMetadata(m *model.AudioMetadata) *audiolabv1.AudioMetadata {
+	if m == nil {
+		return nil
+	}
+	return &audiolabv1.AudioMetadata{
+		DurationSeconds: m.Duration.Seconds(),
+		SampleRate:      int32(m.SampleRate),
+		Channels:        int32(m.Channels),
+		Bitrate:         int64(m.Bitrate),
+		Codec:           m.Codec,
+		Format:          m.Format,
+		Size:            m.Size,
+	}
+}
+
+func toProgress(u progress.Update) *audiolabv1.ProgressUpdate {
+	return &audiolabv1.ProgressUpdate{
+		JobId:     u.JobID,
+		Profile:   u.Profile,
+		Stage:     string(u.Stage),
+		Percent:   u.Percent,
+		Message:   u.Message,
+		Timestamp: timestamppb.New(u.Timestamp),
+
+		EtaSeconds:   u.ETA.Seconds(),
+		Speed:        u.Speed,
+		BytesWritten: u.BytesWritten,
+	}
+}
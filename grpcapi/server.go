@@ -0,0 +1,239 @@
+// Package grpcapi exposes the processor over gRPC using the service
+// defined in api/audiolab/v1
+package grpcapi
+
+import (
+	"context"
+	"sync"
+
+	audiolabv1 "github.com/Skryldev/audio-lab/api/audiolab/v1"
+	"github.com/Skryldev/audio-lab/application/jobspec"
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/domain/ports"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"github.com/Skryldev/audio-lab/pkg/progress"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// watchBuffer is the number of progress updates queued per WatchProgress
+// stream; updates beyond it are dropped for that stream
+const watchBuffer = 64
+
+// Processor is the subset of the processor served over gRPC
+type Processor interface {
+	ProcessBatch(ctx context.Context, jobs []model.BatchJob) (<-chan model.BatchResult, error)
+	ProbeAudio(ctx context.Context, inputPath string) (*model.AudioMetadata, error)
+	Defaults() []ports.Option
+}
+
+// ServerConfig holds configuration for the gRPC service
+type ServerConfig struct {
+	// Paths restricts the server files and URLs requests may name. The
+	// zero value accepts only public http(s) inputs, which leaves jobs
+	// without an output; set AllowPaths and Roots for file outputs.
+	Paths jobspec.PathPolicy
+}
+
+// Server implements audiolabv1.AudioLabServer. It is also a
+// progress.Reporter and must be subscribed to the processor's progress
+// updates for WatchProgress to receive any.
+type Server struct {
+	audiolabv1.UnimplementedAudioLabServer
+
+	processor Processor
+	cfg       ServerConfig
+
+	mu       sync.Mutex
+	watchers map[chan progress.Update]string // -> caller
+	owners   map[string]string               // running job ID -> caller
+}
+
+// NewServer creates a gRPC service backed by p. Serve it with
+// AuthConfig.ServerOptions so callers only watch their own jobs.
+func NewServer(p Processor, cfg ServerConfig) *Server {
+	return &Server{
+		processor: p,
+		cfg:       cfg,
+		watchers:  make(map[chan progress.Update]string),
+		owners:    make(map[string]string),
+	}
+}
+
+// Register adds the service to gs
+func (s *Server) Register(gs grpc.ServiceRegistrar) {
+	audiolabv1.RegisterAudioLabServer(gs, s)
+}
+
+// ProcessAudio runs a single job. Job failures are returned in the result;
+// only malformed requests fail the call.
+func (s *Server) ProcessAudio(ctx context.Context, req *audiolabv1.ProcessAudioRequest) (*audiolabv1.JobResult, error) {
+	job, err := toBatchJob(ctx, req.GetJob(), s.cfg.Paths, s.processor.Defaults())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if err := s.claim(ctx, []model.BatchJob{job}); err != nil {
+		return nil, err
+	}
+	defer s.release(job.ID)
+
+	results, err := s.processor.ProcessBatch(ctx, []model.BatchJob{job})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	result, ok := <-results
+	if !ok {
+		return nil, toStatus(ctx.Err())
+	}
+	return toJobResult(result), nil
+}
+
+// ProcessBatch runs jobs concurrently and streams each result as it
+// finishes
+func (s *Server) ProcessBatch(req *audiolabv1.ProcessBatchRequest, stream grpc.ServerStreamingServer[audiolabv1.JobResult]) error {
+	jobs := make([]model.BatchJob, 0, len(req.GetJobs()))
+	for _, j := range req.GetJobs() {
+		job, err := toBatchJob(stream.Context(), j, s.cfg.Paths, s.processor.Defaults())
+		if err != nil {
+			return toStatus(err)
+		}
+		jobs = append(jobs, job)
+	}
+	if err := s.claim(stream.Context(), jobs); err != nil {
+		return err
+	}
+	defer func() {
+		for _, j := range jobs {
+			s.release(j.ID)
+		}
+	}()
+
+	results, err := s.processor.ProcessBatch(stream.Context(), jobs)
+	if err != nil {
+		return toStatus(err)
+	}
+	for result := range results {
+		if err := stream.Send(toJobResult(result)); err != nil {
+			// Keep draining so the worker pool isn't blocked on the channel
+			for range results {
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// ProbeAudio returns metadata about a file
+func (s *Server) ProbeAudio(ctx context.Context, req *audiolabv1.ProbeAudioRequest) (*audiolabv1.AudioMetadata, error) {
+	if req.GetPath() == "" {
+		return nil, status.Error(codes.InvalidArgument, "path is required")
+	}
+	if err := s.cfg.Paths.CheckInput(ctx, "path", req.GetPath()); err != nil {
+		return nil, toStatus(err)
+	}
+	meta, err := s.processor.ProbeAudio(ctx, req.GetPath())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toMetadata(meta), nil
+}
+
+// WatchProgress streams progress updates of the caller's running jobs
+// until the client disconnects
+func (s *Server) WatchProgress(req *audiolabv1.WatchProgressRequest, stream grpc.ServerStreamingServer[audiolabv1.ProgressUpdate]) error {
+	var only map[string]bool
+	if ids := req.GetJobIds(); len(ids) > 0 {
+		only = make(map[string]bool, len(ids))
+		for _, id := range ids {
+			only[id] = true
+		}
+	}
+
+	ch := make(chan progress.Update, watchBuffer)
+	s.mu.Lock()
+	s.watchers[ch] = Caller(stream.Context())
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.watchers, ch)
+		s.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case u := <-ch:
+			if only != nil && !only[u.JobID] {
+				continue
+			}
+			if err := stream.Send(toProgress(u)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Report fans an update out to the WatchProgress streams of the job's
+// caller without blocking. Updates of jobs not submitted through this
+// server are dropped.
+func (s *Server) Report(u progress.Update) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	owner, ok := s.owners[u.JobID]
+	if !ok {
+		return
+	}
+	for ch, caller := range s.watchers {
+		if caller != owner {
+			continue
+		}
+		select {
+		case ch <- u:
+		default:
+		}
+	}
+}
+
+// claim records the caller as the owner of jobs, rejecting IDs of jobs
+// still running
+func (s *Server) claim(ctx context.Context, jobs []model.BatchJob) error {
+	caller := Caller(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, j := range jobs {
+		if _, ok := s.owners[j.ID]; ok {
+			for _, c := range jobs[:i] {
+				delete(s.owners, c.ID)
+			}
+			return status.Errorf(codes.AlreadyExists, "job %s is already running", j.ID)
+		}
+		s.owners[j.ID] = caller
+	}
+	return nil
+}
+
+// release forgets the owner of a finished job
+func (s *Server) release(id string) {
+	s.mu.Lock()
+	delete(s.owners, id)
+	s.mu.Unlock()
+}
+
+// toStatus maps structured errors onto gRPC status codes
+func toStatus(err error) error {
+	if err == nil {
+		return status.Error(codes.Canceled, "canceled")
+	}
+	code := codes.Internal
+	switch pkgerrors.CodeOf(err) {
+	case pkgerrors.ErrCodeValidation:
+		code = codes.InvalidArgument
+	case pkgerrors.ErrCodeTimeout:
+		code = codes.DeadlineExceeded
+	case pkgerrors.ErrCodeCanceled:
+		code = codes.Canceled
+	}
+	return status.Error(code, err.Error())
+}
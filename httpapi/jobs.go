@@ -0,0 +1,456 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Skryldev/audio-lab/application/jobspec"
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/domain/ports"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"github.com/Skryldev/audio-lab/pkg/progress"
+)
+
+// Job states
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// JobProcessor is the subset of the processor needed by the job server
+type JobProcessor interface {
+	ProcessBatch(ctx context.Context, jobs []model.BatchJob) (<-chan model.BatchResult, error)
+	Defaults() []ports.Option
+}
+
+// JobServerConfig holds configuration for the job server
+type JobServerConfig struct {
+	// WorkDir holds uploaded inputs and server-assigned outputs, one
+	// subdirectory per job (default: audiolab-http under os.TempDir())
+	WorkDir string
+
+	// MaxUploadBytes caps multipart uploads (default: 1 GiB)
+	MaxUploadBytes int64
+
+	// Retention is how long finished jobs and their files are kept
+	// (default: 1h)
+	Retention time.Duration
+
+	// Paths restricts the server files and URLs a submission may name. The
+	// zero value accepts only public http(s) inputs, and outputs are always
+	// written under WorkDir.
+	Paths jobspec.PathPolicy
+}
+
+// JobStatus is the JSON representation of a submitted job
+type JobStatus struct {
+	ID         string            `json:"id"`
+	State      string            `json:"state"`
+	Progress   *jobspec.Progress `json:"progress,omitempty"`
+	Result     *jobspec.Result   `json:"result,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+}
+
+// JobServer runs jobs submitted over HTTP: JSON or multipart submission,
+// status polling, result download and progress over server-sent events
+type JobServer struct {
+	processor JobProcessor
+	cfg       JobServerConfig
+	mux       *http.ServeMux
+
+	ctx    context.Context // canceled by Close; jobs outlive their requests
+	cancel context.CancelFunc
+
+	mu   sync.Mutex
+	jobs map[string]*httpJob
+}
+
+// httpJob is the server-side state of a submitted job
+type httpJob struct {
+	status     JobStatus
+	output     string
+	dir        string // per-job directory under WorkDir, removed on expiry
+	watchers   map[chan jobspec.Event]struct{}
+	finishedAt time.Time
+}
+
+// NewJobServer creates a job server. Subscribe it to the processor's
+// progress updates (Processor.AddReporter) for progress to be reported.
+func NewJobServer(p JobProcessor, cfg JobServerConfig) *JobServer {
+	if cfg.WorkDir == "" {
+		cfg.WorkDir = filepath.Join(os.TempDir(), "audiolab-http")
+	}
+	if cfg.MaxUploadBytes <= 0 {
+		cfg.MaxUploadBytes = 1 << 30
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = time.Hour
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &JobServer{
+		processor: p,
+		cfg:       cfg,
+		mux:       http.NewServeMux(),
+		ctx:       ctx,
+		cancel:    cancel,
+		jobs:      make(map[string]*httpJob),
+	}
+	s.Register(s.mux, "")
+	go s.expire()
+	return s
+}
+
+// Register mounts the job endpoints on mux under prefix (e.g. "/v1")
+func (s *JobServer) Register(mux *http.ServeMux, prefix string) {
+	mux.HandleFunc("POST "+prefix+"/jobs", s.handleSubmit)
+	mux.HandleFunc("GET "+prefix+"/jobs/{id}", s.handleStatus)
+	mux.HandleFunc("GET "+prefix+"/jobs/{id}/result", s.handleResult)
+	mux.HandleFunc("GET "+prefix+"/jobs/{id}/events", s.handleEvents)
+}
+
+// ServeHTTP makes JobServer usable as a standalone handler
+func (s *JobServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Close cancels running jobs and stops expiring finished ones
+func (s *JobServer) Close() {
+	s.cancel()
+}
+
+func (s *JobServer) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	id := jobspec.NewJobID()
+	dir := filepath.Join(s.cfg.WorkDir, id)
+
+	var (
+		spec jobspec.Job
+		err  error
+	)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		spec, err = s.readUpload(w, r, dir)
+	} else {
+		spec, err = s.readJSON(w, r)
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		writeError(w, statusForCode(pkgerrors.CodeOf(err)), err)
+		return
+	}
+
+	spec.ID = id
+	if spec.Output == "" {
+		ext := model.Codec(codecOf(spec.Options)).Extension()
+		spec.Output = filepath.Join(dir, "output"+ext)
+	}
+
+	job, err := spec.BatchJob(s.processor.Defaults()...)
+	if err != nil {
+		os.RemoveAll(dir)
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	hj := &httpJob{
+		status:   JobStatus{ID: id, State: JobQueued, CreatedAt: time.Now()},
+		output:   job.OutputPath,
+		dir:      dir,
+		watchers: make(map[chan jobspec.Event]struct{}),
+	}
+	s.mu.Lock()
+	s.jobs[id] = hj
+	s.mu.Unlock()
+
+	results, err := s.processor.ProcessBatch(s.ctx, []model.BatchJob{job})
+	if err != nil {
+		s.finish(model.BatchResult{JobID: id, Err: err})
+	} else {
+		go func() {
+			for result := range results {
+				s.finish(result)
+			}
+		}()
+	}
+
+	w.Header().Set("Location", r.URL.Path+"/"+id)
+	writeJSON(w, http.StatusAccepted, s.snapshot(hj))
+}
+
+// readJSON decodes a jobspec.Job, enforcing the path policy
+func (s *JobServer) readJSON(w http.ResponseWriter, r *http.Request) (jobspec.Job, error) {
+	var spec jobspec.Job
+	if err := readJSONBody(w, r, &spec); err != nil {
+		return spec, err
+	}
+	if spec.Input == "" {
+		return spec, pkgerrors.NewValidationError("input", "", "input must not be empty")
+	}
+	return spec, s.cfg.Paths.CheckJob(r.Context(), &spec)
+}
+
+// readUpload stores the "file" part under dir and decodes the optional
+// "options" part as jobspec.Options
+func (s *JobServer) readUpload(w http.ResponseWriter, r *http.Request, dir string) (jobspec.Job, error) {
+	var spec jobspec.Job
+	r.Body = http.MaxBytesReader(w, r.Body, s.cfg.MaxUploadBytes)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return spec, pkgerrors.NewValidationError("body", "", "invalid multipart body")
+	}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return spec, pkgerrors.NewValidationError("body", "", "invalid multipart body: "+err.Error())
+		}
+
+		switch part.FormName() {
+		case "file":
+			if spec.Input != "" {
+				return spec, pkgerrors.NewValidationError("file", part.FileName(), "only one file may be uploaded")
+			}
+			input, err := saveUpload(part, dir)
+			if err != nil {
+				return spec, err
+			}
+			spec.Input = input
+		case "options":
+			spec.Options = &jobspec.Options{}
+			if err := json.NewDecoder(part).Decode(spec.Options); err != nil {
+				return spec, pkgerrors.NewValidationError("options", "", "invalid options JSON")
+			}
+		}
+		part.Close()
+	}
+
+	if spec.Input == "" {
+		return spec, pkgerrors.NewValidationError("file", "", "file part is required")
+	}
+	// The input is the upload itself; only the options name other files
+	return spec, s.cfg.Paths.CheckOptions(r.Context(), spec.Options)
+}
+
+func saveUpload(part *multipart.Part, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", pkgerrors.NewIOError(dir, "failed to create job directory", err)
+	}
+	// Keep the extension so ffmpeg can detect the format from the name
+	path := filepath.Join(dir, "input"+strings.ToLower(filepath.Ext(part.FileName())))
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", pkgerrors.NewIOError(path, "failed to store upload", err)
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, part); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return "", pkgerrors.NewValidationError("file", tooLarge.Limit, "upload too large")
+		}
+		return "", pkgerrors.NewIOError(path, "failed to store upload", err)
+	}
+	return path, nil
+}
+
+func (s *JobServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	hj := s.lookup(w, r)
+	if hj == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.snapshot(hj))
+}
+
+func (s *JobServer) handleResult(w http.ResponseWriter, r *http.Request) {
+	hj := s.lookup(w, r)
+	if hj == nil {
+		return
+	}
+	st := s.snapshot(hj)
+	if st.State != JobSucceeded {
+		writeError(w, http.StatusConflict, pkgerrors.NewValidationError("state", st.State, "job has no result yet"))
+		return
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filepath.Base(hj.output)}))
+	http.ServeFile(w, r, hj.output)
+}
+
+// handleEvents streams jobspec.Events as server-sent events until the job
+// finishes or the client goes away
+func (s *JobServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	hj := s.lookup(w, r)
+	if hj == nil {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming unsupported"))
+		return
+	}
+
+	ch := make(chan jobspec.Event, 16)
+	s.mu.Lock()
+	st := hj.status
+	done := !hj.finishedAt.IsZero()
+	if !done {
+		hj.watchers[ch] = struct{}{}
+	}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(hj.watchers, ch)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	if st.Progress != nil {
+		writeEvent(w, jobspec.Event{Type: jobspec.EventProgress, JobID: st.ID, Progress: st.Progress, Timestamp: time.Now()})
+	}
+	if done {
+		writeEvent(w, jobspec.Event{Type: jobspec.EventResult, JobID: st.ID, Result: st.Result, Timestamp: time.Now()})
+		flusher.Flush()
+		return
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev := <-ch:
+			writeEvent(w, ev)
+			flusher.Flush()
+			if ev.Type == jobspec.EventResult {
+				return
+			}
+		}
+	}
+}
+
+func writeEvent(w io.Writer, ev jobspec.Event) {
+	data, _ := json.Marshal(ev)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data)
+}
+
+// Report records progress of submitted jobs and forwards it to their
+// event streams
+func (s *JobServer) Report(u progress.Update) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hj, ok := s.jobs[u.JobID]
+	if !ok || !hj.finishedAt.IsZero() {
+		return
+	}
+	p := jobspec.NewProgress(u)
+	hj.status.State = JobRunning
+	hj.status.Progress = &p
+	hj.broadcast(jobspec.Event{Type: jobspec.EventProgress, JobID: u.JobID, Progress: &p, Timestamp: u.Timestamp})
+}
+
+func (s *JobServer) finish(r model.BatchResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hj, ok := s.jobs[r.JobID]
+	if !ok {
+		return
+	}
+	res := jobspec.NewResult(r)
+	hj.finishedAt = time.Now()
+	hj.status.Result = &res
+	hj.status.FinishedAt = &hj.finishedAt
+	hj.status.State = JobSucceeded
+	if r.Err != nil {
+		hj.status.State = JobFailed
+	}
+	// The final event must not be dropped: watchers return after it
+	for ch := range hj.watchers {
+		select {
+		case ch <- jobspec.Event{Type: jobspec.EventResult, JobID: r.JobID, Result: &res, Timestamp: hj.finishedAt}:
+		default:
+			<-ch
+			ch <- jobspec.Event{Type: jobspec.EventResult, JobID: r.JobID, Result: &res, Timestamp: hj.finishedAt}
+		}
+	}
+}
+
+// broadcast sends ev to every watcher, dropping it for slow ones. Callers
+// hold s.mu.
+func (hj *httpJob) broadcast(ev jobspec.Event) {
+	for ch := range hj.watchers {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+func (s *JobServer) lookup(w http.ResponseWriter, r *http.Request) *httpJob {
+	id := r.PathValue("id")
+	s.mu.Lock()
+	hj, ok := s.jobs[id]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, pkgerrors.NewValidationError("id", id, "unknown job"))
+		return nil
+	}
+	return hj
+}
+
+func (s *JobServer) snapshot(hj *httpJob) JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return hj.status
+}
+
+// expire forgets finished jobs after Retention and removes their files
+func (s *JobServer) expire() {
+	ticker := time.NewTicker(s.cfg.Retention / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case now := <-ticker.C:
+			var dirs []string
+			s.mu.Lock()
+			for id, hj := range s.jobs {
+				if !hj.finishedAt.IsZero() && now.Sub(hj.finishedAt) > s.cfg.Retention {
+					delete(s.jobs, id)
+					dirs = append(dirs, hj.dir)
+				}
+			}
+			s.mu.Unlock()
+			for _, dir := range dirs {
+				os.RemoveAll(dir)
+			}
+		}
+	}
+}
+
+func codecOf(o *jobspec.Options) string {
+	if o == nil || o.Codec == "" {
+		return string(model.DefaultProcessingOptions().Codec)
+	}
+	return o.Codec
+}
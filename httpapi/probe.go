@@ -0,0 +1,512 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Skryldev/audio-lab/application/jobspec"
+	"github.com/Skryldev/audio-lab/domain/model"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+)
+
+// Prober is the subset of the processor needed by the probe server
+type Prober interface {
+	ProbeAudio(ctx context.Context, inputPath string) (*model.AudioMetadata, error)
+	AnalyzeAudio(ctx context.Context, inputPath string, opts model.AnalysisOptions) (*model.AudioAnalysis, error)
+}
+
+// ProbeServerConfig holds configuration for the read-only probe server
+type ProbeServerConfig struct {
+	// MaxConcurrency caps the number of probe and analysis runs in flight
+	// (default: 32). A processor's own probe lane caps probes too, so size
+	// its ProbeWorkers to match.
+	MaxConcurrency int
+
+	// CacheTTL is how long probe and analysis results are cached (default:
+	// 5m, negative disables). A file's results are dropped early when its
+	// size or modification time changes.
+	CacheTTL time.Duration
+
+	// Paths restricts the files and URLs clients may name. The zero value
+	// accepts public http(s) URLs only.
+	Paths jobspec.PathPolicy
+
+	// MaxBatchSize caps the number of paths accepted by the batch endpoint (default: 256)
+	MaxBatchSize int
+}
+
+// ProbeServer is a read-only HTTP server exposing only metadata endpoints.
+// It can be deployed separately from encode workers.
+type ProbeServer struct {
+	prober   Prober
+	sem      chan struct{}
+	cache    *probeCache[*model.AudioMetadata]
+	analyses *probeCache[*model.AudioAnalysis]
+	paths    jobspec.PathPolicy
+	maxBatch int
+	mux      *http.ServeMux
+}
+
+// NewProbeServer creates a new probe-only server
+func NewProbeServer(p Prober, cfg ProbeServerConfig) *ProbeServer {
+	concurrency := cfg.MaxConcurrency
+	if concurrency <= 0 {
+		concurrency = 32
+	}
+	ttl := cfg.CacheTTL
+	if ttl == 0 {
+		ttl = 5 * time.Minute
+	}
+	maxBatch := cfg.MaxBatchSize
+	if maxBatch <= 0 {
+		maxBatch = 256
+	}
+
+	s := &ProbeServer{
+		prober:   p,
+		sem:      make(chan struct{}, concurrency),
+		cache:    newProbeCache[*model.AudioMetadata](ttl),
+		analyses: newProbeCache[*model.AudioAnalysis](ttl),
+		paths:    cfg.Paths,
+		maxBatch: maxBatch,
+		mux:      http.NewServeMux(),
+	}
+	s.Register(s.mux, "")
+	return s
+}
+
+// Register mounts the probe endpoints on mux under prefix (e.g. "/v1")
+func (s *ProbeServer) Register(mux *http.ServeMux, prefix string) {
+	mux.HandleFunc("GET "+prefix+"/probe", s.handleProbe)
+	mux.HandleFunc("POST "+prefix+"/probe/batch", s.handleProbeBatch)
+	mux.HandleFunc("GET "+prefix+"/analyze", s.handleAnalyze)
+}
+
+// ServeHTTP makes ProbeServer usable as a standalone handler
+func (s *ProbeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// MetadataDTO is the JSON representation of model.AudioMetadata
+type MetadataDTO struct {
+	DurationSeconds float64 `json:"duration_seconds"`
+	SampleRate      int     `json:"sample_rate"`
+	Channels        int     `json:"channels"`
+	Bitrate         int     `json:"bitrate"`
+	Codec           string  `json:"codec"`
+	Format          string  `json:"format"`
+	Size            int64   `json:"size"`
+
+	Streams     []StreamDTO       `json:"streams,omitempty"`
+	HasCoverArt bool              `json:"has_cover_art"`
+	Tags        map[string]string `json:"tags,omitempty"` // lower-cased keys
+	Chapters    []ChapterDTO      `json:"chapters,omitempty"`
+	BWF         *BWFDTO           `json:"bwf,omitempty"`
+}
+
+// BWFDTO is the JSON representation of model.BWF
+type BWFDTO struct {
+	Description         string `json:"description,omitempty"`
+	Originator          string `json:"originator,omitempty"`
+	OriginatorReference string `json:"originator_reference,omitempty"`
+	OriginationDate     string `json:"origination_date,omitempty"`
+	OriginationTime     string `json:"origination_time,omitempty"`
+	TimeReference       uint64 `json:"time_reference"`
+	CodingHistory       string `json:"coding_history,omitempty"`
+}
+
+// ChapterDTO is the JSON representation of model.Chapter
+type ChapterDTO struct {
+	StartSeconds float64 `json:"start_seconds"`
+	EndSeconds   float64 `json:"end_seconds"`
+	Title        string  `json:"title,omitempty"`
+}
+
+// StreamDTO is the JSON representation of model.StreamInfo
+type StreamDTO struct {
+	Index       int      `json:"index"`
+	Kind        string   `json:"kind"`
+	Codec       string   `json:"codec,omitempty"`
+	Language    string   `json:"language,omitempty"`
+	Title       string   `json:"title,omitempty"`
+	Disposition []string `json:"disposition,omitempty"` // e.g. ["default"]
+	SampleRate  int      `json:"sample_rate,omitempty"`
+	Channels    int      `json:"channels,omitempty"`
+	Bitrate     int      `json:"bitrate,omitempty"`
+	Width       int      `json:"width,omitempty"`
+	Height      int      `json:"height,omitempty"`
+}
+
+// ProbeResponse is returned for every probed path
+type ProbeResponse struct {
+	Path     string       `json:"path"`
+	Metadata *MetadataDTO `json:"metadata,omitempty"`
+	Error    *ErrorDTO    `json:"error,omitempty"`
+	Cached   bool         `json:"cached"`
+}
+
+// ErrorDTO is the JSON representation of an error
+type ErrorDTO struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// AnalysisDTO is the JSON representation of model.AudioAnalysis
+type AnalysisDTO struct {
+	SampleRate      int         `json:"sample_rate"`
+	Channels        int         `json:"channels"`
+	DurationSeconds float64     `json:"duration_seconds"`
+	PeakDBFS        []float64   `json:"peak_dbfs"` // per channel
+	DCOffset        []float64   `json:"dc_offset"` // per channel, -1..1
+	Clipping        ClippingDTO `json:"clipping"`
+}
+
+// ClippingDTO is the JSON representation of model.ClippingReport
+type ClippingDTO struct {
+	Samples int64               `json:"samples"`
+	Regions []ClippingRegionDTO `json:"regions,omitempty"`
+}
+
+// ClippingRegionDTO is the JSON representation of model.ClippingRegion
+type ClippingRegionDTO struct {
+	StartSeconds float64 `json:"start_seconds"`
+	EndSeconds   float64 `json:"end_seconds"`
+	Samples      int64   `json:"samples"`
+	PeakDBFS     float64 `json:"peak_dbfs"`
+}
+
+// AnalyzeResponse is returned by the analyze endpoint
+type AnalyzeResponse struct {
+	Path     string       `json:"path"`
+	Analysis *AnalysisDTO `json:"analysis,omitempty"`
+	Error    *ErrorDTO    `json:"error,omitempty"`
+	Cached   bool         `json:"cached"`
+}
+
+type batchProbeRequest struct {
+	Paths []string `json:"paths"`
+}
+
+type batchProbeResponse struct {
+	Results []ProbeResponse `json:"results"`
+}
+
+func (s *ProbeServer) handleProbe(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		writeError(w, http.StatusBadRequest, pkgerrors.NewValidationError("path", "", "path query parameter is required"))
+		return
+	}
+
+	resp := s.probe(r.Context(), path)
+	status := http.StatusOK
+	if resp.Error != nil {
+		status = statusForCode(pkgerrors.ErrorCode(resp.Error.Code))
+	}
+	writeJSON(w, status, resp)
+}
+
+func (s *ProbeServer) handleProbeBatch(w http.ResponseWriter, r *http.Request) {
+	var req batchProbeRequest
+	if err := readJSONBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(req.Paths) == 0 {
+		writeError(w, http.StatusBadRequest, pkgerrors.NewValidationError("paths", 0, "at least one path is required"))
+		return
+	}
+	if len(req.Paths) > s.maxBatch {
+		writeError(w, http.StatusBadRequest, pkgerrors.NewValidationError("paths", len(req.Paths), "too many paths in batch"))
+		return
+	}
+
+	results := make([]ProbeResponse, len(req.Paths))
+	var wg sync.WaitGroup
+	for i, path := range req.Paths {
+		wg.Add(1)
+		go func(i int, path string) {
+			defer wg.Done()
+			results[i] = s.probe(r.Context(), path)
+		}(i, path)
+	}
+	wg.Wait()
+
+	writeJSON(w, http.StatusOK, batchProbeResponse{Results: results})
+}
+
+// handleAnalyze decodes the file at ?path= and reports its peaks, DC
+// offset and clipping. clip_threshold (dBFS), clip_min_run (samples) and
+// clip_merge_gap (a duration such as "50ms") tune the clip detection.
+func (s *ProbeServer) handleAnalyze(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	path := q.Get("path")
+	if path == "" {
+		writeError(w, http.StatusBadRequest, pkgerrors.NewValidationError("path", "", "path query parameter is required"))
+		return
+	}
+	opts, err := analysisOptions(q.Get("clip_threshold"), q.Get("clip_min_run"), q.Get("clip_merge_gap"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	resp := s.analyze(r.Context(), path, opts)
+	status := http.StatusOK
+	if resp.Error != nil {
+		status = statusForCode(pkgerrors.ErrorCode(resp.Error.Code))
+	}
+	writeJSON(w, status, resp)
+}
+
+// analysisOptions parses the analyze endpoint's query parameters; empty
+// ones keep their defaults
+func analysisOptions(threshold, minRun, mergeGap string) (model.AnalysisOptions, error) {
+	var opts model.AnalysisOptions
+	if threshold != "" {
+		v, err := strconv.ParseFloat(threshold, 64)
+		if err != nil || v > 0 {
+			return opts, pkgerrors.NewValidationError("clip_threshold", threshold, "threshold must be a level in dBFS at or below 0")
+		}
+		opts.ClipThreshold = v
+	}
+	if minRun != "" {
+		v, err := strconv.Atoi(minRun)
+		if err != nil || v < 1 {
+			return opts, pkgerrors.NewValidationError("clip_min_run", minRun, "run must be a positive number of samples")
+		}
+		opts.ClipMinRun = v
+	}
+	if mergeGap != "" {
+		v, err := time.ParseDuration(mergeGap)
+		if err != nil || v < 0 {
+			return opts, pkgerrors.NewValidationError("clip_merge_gap", mergeGap, "gap must be a non-negative duration")
+		}
+		opts.ClipMergeGap = v
+	}
+	return opts, nil
+}
+
+func (s *ProbeServer) probe(ctx context.Context, path string) ProbeResponse {
+	if err := s.paths.CheckInput(ctx, "path", path); err != nil {
+		return ProbeResponse{Path: path, Error: toErrorDTO(err)}
+	}
+	key, cacheable := cacheKey(path, "")
+	if cacheable {
+		if meta, ok := s.cache.get(key); ok {
+			return ProbeResponse{Path: path, Metadata: toMetadataDTO(meta), Cached: true}
+		}
+	}
+
+	if err := s.acquire(ctx); err != nil {
+		return ProbeResponse{Path: path, Error: toErrorDTO(err)}
+	}
+	defer s.release()
+
+	meta, err := s.prober.ProbeAudio(ctx, path)
+	if err != nil {
+		return ProbeResponse{Path: path, Error: toErrorDTO(err)}
+	}
+	if cacheable {
+		s.cache.put(key, meta)
+	}
+
+	return ProbeResponse{Path: path, Metadata: toMetadataDTO(meta)}
+}
+
+func (s *ProbeServer) analyze(ctx context.Context, path string, opts model.AnalysisOptions) AnalyzeResponse {
+	if err := s.paths.CheckInput(ctx, "path", path); err != nil {
+		return AnalyzeResponse{Path: path, Error: toErrorDTO(err)}
+	}
+	key, cacheable := cacheKey(path, fmt.Sprintf("%g/%d/%d", opts.ClipThreshold, opts.ClipMinRun, opts.ClipMergeGap))
+	if cacheable {
+		if a, ok := s.analyses.get(key); ok {
+			return AnalyzeResponse{Path: path, Analysis: toAnalysisDTO(a), Cached: true}
+		}
+	}
+
+	if err := s.acquire(ctx); err != nil {
+		return AnalyzeResponse{Path: path, Error: toErrorDTO(err)}
+	}
+	defer s.release()
+
+	a, err := s.prober.AnalyzeAudio(ctx, path, opts)
+	if err != nil {
+		return AnalyzeResponse{Path: path, Error: toErrorDTO(err)}
+	}
+	if cacheable {
+		s.analyses.put(key, a)
+	}
+
+	return AnalyzeResponse{Path: path, Analysis: toAnalysisDTO(a)}
+}
+
+// acquire takes one of the MaxConcurrency slots
+func (s *ProbeServer) acquire(ctx context.Context) error {
+	select {
+	case s.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *ProbeServer) release() {
+	<-s.sem
+}
+
+// cacheKey keys a result for path and the options that produced it. Local
+// files are keyed by their size and modification time as well, so a
+// changed file misses the cache; one that can't be stat'ed isn't cached,
+// leaving the prober to report why.
+func cacheKey(path, opts string) (string, bool) {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path + "\x00" + opts, true
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", false
+	}
+	return fmt.Sprintf("%s\x00%s\x00%d\x00%d", path, opts, fi.Size(), fi.ModTime().UnixNano()), true
+}
+
+func toAnalysisDTO(a *model.AudioAnalysis) *AnalysisDTO {
+	dto := &AnalysisDTO{
+		SampleRate:      a.SampleRate,
+		Channels:        a.Channels,
+		DurationSeconds: a.Duration.Seconds(),
+		PeakDBFS:        a.Peak,
+		DCOffset:        a.DCOffset,
+		Clipping:        ClippingDTO{Samples: a.Clipping.Samples},
+	}
+	for _, r := range a.Clipping.Regions {
+		dto.Clipping.Regions = append(dto.Clipping.Regions, ClippingRegionDTO{
+			StartSeconds: r.Start.Seconds(),
+			EndSeconds:   r.End.Seconds(),
+			Samples:      r.Samples,
+			PeakDBFS:     r.Peak,
+		})
+	}
+	return dto
+}
+
+func toMetadataDTO(m *model.AudioMetadata) *MetadataDTO {
+	if m == nil {
+		return nil
+	}
+	dto := &MetadataDTO{
+		DurationSeconds: m.Duration.Seconds(),
+		SampleRate:      m.SampleRate,
+		Channels:        m.Channels,
+		Bitrate:         m.Bitrate,
+		Codec:           m.Codec,
+		Format:          m.Format,
+		Size:            m.Size,
+		HasCoverArt:     m.HasCoverArt,
+		Tags:            m.Tags.Raw,
+	}
+	if b := m.BWF; b != nil {
+		bwf := BWFDTO(*b)
+		dto.BWF = &bwf
+	}
+	for _, c := range m.Chapters {
+		dto.Chapters = append(dto.Chapters, ChapterDTO{
+			StartSeconds: c.Start.Seconds(),
+			EndSeconds:   c.End.Seconds(),
+			Title:        c.Title,
+		})
+	}
+	for _, s := range m.Streams {
+		dto.Streams = append(dto.Streams, StreamDTO{
+			Index:       s.Index,
+			Kind:        string(s.Kind),
+			Codec:       s.Codec,
+			Language:    s.Language,
+			Title:       s.Title,
+			Disposition: dispositionFlags(s.Disposition),
+			SampleRate:  s.SampleRate,
+			Channels:    s.Channels,
+			Bitrate:     s.Bitrate,
+			Width:       s.Width,
+			Height:      s.Height,
+		})
+	}
+	return dto
+}
+
+// dispositionFlags lists the set flags by their ffprobe names
+func dispositionFlags(d model.StreamDisposition) []string {
+	var flags []string
+	for _, f := range []struct {
+		set  bool
+		name string
+	}{
+		{d.Default, "default"},
+		{d.Original, "original"},
+		{d.Dub, "dub"},
+		{d.Comment, "comment"},
+		{d.Forced, "forced"},
+		{d.HearingImpaired, "hearing_impaired"},
+		{d.VisualImpaired, "visual_impaired"},
+		{d.AttachedPic, "attached_pic"},
+	} {
+		if f.set {
+			flags = append(flags, f.name)
+		}
+	}
+	return flags
+}
+
+// probeCache is a small TTL cache of results keyed by cacheKey
+type probeCache[T any] struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	entries map[string]probeCacheEntry[T]
+}
+
+type probeCacheEntry[T any] struct {
+	value   T
+	expires time.Time
+}
+
+func newProbeCache[T any](ttl time.Duration) *probeCache[T] {
+	return &probeCache[T]{
+		ttl:     ttl,
+		entries: make(map[string]probeCacheEntry[T]),
+	}
+}
+
+func (c *probeCache[T]) get(key string) (T, bool) {
+	var zero T
+	if c.ttl < 0 {
+		return zero, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	e, ok := c.entries[key]
+	if !ok || time.Now().After(e.expires) {
+		return zero, false
+	}
+	return e.value, true
+}
+
+func (c *probeCache[T]) put(key string, value T) {
+	if c.ttl < 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for k, e := range c.entries {
+		if now.After(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = probeCacheEntry[T]{value: value, expires: now.Add(c.ttl)}
+}
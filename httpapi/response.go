@@ -0,0 +1,55 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+)
+
+// maxJSONBytes caps JSON request bodies
+const maxJSONBytes = 1 << 20
+
+// readJSONBody decodes r's body into v, reading at most maxJSONBytes
+func readJSONBody(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxJSONBytes)
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return pkgerrors.NewValidationError("body", tooLarge.Limit, "body too large")
+		}
+		return pkgerrors.NewValidationError("body", "", "invalid JSON body")
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, struct {
+		Error *ErrorDTO `json:"error"`
+	}{Error: toErrorDTO(err)})
+}
+
+// toErrorDTO maps an error onto its structured code where possible
+func toErrorDTO(err error) *ErrorDTO {
+	return &ErrorDTO{Code: string(pkgerrors.CodeOf(err)), Message: err.Error()}
+}
+
+func statusForCode(code pkgerrors.ErrorCode) int {
+	switch code {
+	case pkgerrors.ErrCodeValidation:
+		return http.StatusBadRequest
+	case pkgerrors.ErrCodeTimeout:
+		return http.StatusGatewayTimeout
+	case pkgerrors.ErrCodeCanceled:
+		return 499
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,113 @@
+// Package nats implements bus.Transport on NATS, using JetStream for
+// at-least-once delivery or core NATS queue groups for fire-and-forget
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Skryldev/audio-lab/application/bus"
+	natsgo "github.com/nats-io/nats.go"
+)
+
+// fetchWait bounds each JetStream pull request
+const fetchWait = 30 * time.Second
+
+// Config holds NATS transport configuration
+type Config struct {
+	// Subject that job messages are published on (required)
+	Subject string
+
+	// Queue is the queue group (core NATS) or durable consumer name
+	// (JetStream) shared by all workers (default: "audiolab")
+	Queue string
+
+	// JetStream consumes from a stream covering Subject, acknowledging each
+	// message once its job finished. Running jobs mark their message in
+	// progress every bus.Config.HeartbeatInterval, which must stay below
+	// the consumer's AckWait.
+	JetStream bool
+}
+
+// Transport is a bus.Transport on a NATS connection
+type Transport struct {
+	conn *natsgo.Conn
+	sub  *natsgo.Subscription
+	js   bool
+}
+
+// New subscribes to cfg.Subject on conn
+func New(conn *natsgo.Conn, cfg Config) (*Transport, error) {
+	if conn == nil {
+		return nil, fmt.Errorf("NATS connection is required")
+	}
+	if cfg.Subject == "" {
+		return nil, fmt.Errorf("subject is required")
+	}
+	if cfg.Queue == "" {
+		cfg.Queue = "audiolab"
+	}
+
+	t := &Transport{conn: conn, js: cfg.JetStream}
+	var err error
+	if cfg.JetStream {
+		var js natsgo.JetStreamContext
+		js, err = conn.JetStream()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open JetStream: %w", err)
+		}
+		t.sub, err = js.PullSubscribe(cfg.Subject, cfg.Queue, natsgo.ManualAck())
+	} else {
+		t.sub, err = conn.QueueSubscribeSync(cfg.Subject, cfg.Queue)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to %s: %w", cfg.Subject, err)
+	}
+	return t, nil
+}
+
+// Receive blocks until the next job message arrives or ctx is done
+func (t *Transport) Receive(ctx context.Context) (*bus.Message, error) {
+	if !t.js {
+		msg, err := t.sub.NextMsgWithContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &bus.Message{Data: msg.Data}, nil
+	}
+
+	for {
+		// Pull requests need a deadline; long-poll in bounded rounds
+		fetchCtx, cancel := context.WithTimeout(ctx, fetchWait)
+		msgs, err := t.sub.Fetch(1, natsgo.Context(fetchCtx))
+		cancel()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, natsgo.ErrTimeout) || (err == nil && len(msgs) == 0) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		msg := msgs[0]
+		return &bus.Message{
+			Data:       msg.Data,
+			Ack:        func() error { return msg.Ack() },
+			Nak:        func() error { return msg.Nak() },
+			InProgress: func() error { return msg.InProgress() },
+		}, nil
+	}
+}
+
+// Publish sends data on subject
+func (t *Transport) Publish(_ context.Context, subject string, data []byte) error {
+	return t.conn.Publish(subject, data)
+}
+
+// Close unsubscribes; the connection stays open
+func (t *Transport) Close() error {
+	return t.sub.Unsubscribe()
+}
@@ -0,0 +1,244 @@
+// Package cue parses CUE sheets, the track lists that accompany
+// single-file CD and vinyl rips
+package cue
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// framesPerSecond is the number of CD frames in a second of audio
+const framesPerSecond = 75
+
+// maxSheetSize bounds the sheets Parse reads; real ones are a few KiB
+const maxSheetSize = 1 << 20
+
+// Sheet is a parsed CUE sheet. Disc-level fields come from the commands
+// before the first TRACK.
+type Sheet struct {
+	Title      string
+	Performer  string
+	Songwriter string
+	Catalog    string // UPC/EAN of the release
+	Genre      string // REM GENRE
+	Date       string // REM DATE
+	Comment    string // REM COMMENT
+	Tracks     []Track
+}
+
+// Track is one TRACK of a sheet
+type Track struct {
+	Number     int
+	Type       string // e.g. "AUDIO" or "MODE1/2352"
+	File       string // FILE the track is in
+	Title      string
+	Performer  string
+	Songwriter string
+	ISRC       string
+
+	// Start is INDEX 01, where the track begins. INDEX 00, the start of
+	// the pregap, is ignored; the gap belongs to the previous track.
+	Start time.Duration
+}
+
+// IsAudio reports whether the track holds audio rather than data
+func (t Track) IsAudio() bool {
+	return strings.EqualFold(t.Type, "AUDIO")
+}
+
+// Files returns the distinct FILE names referenced by the tracks, in
+// order of appearance
+func (s *Sheet) Files() []string {
+	var files []string
+	seen := make(map[string]bool)
+	for _, t := range s.Tracks {
+		if !seen[t.File] {
+			seen[t.File] = true
+			files = append(files, t.File)
+		}
+	}
+	return files
+}
+
+// Parse reads a CUE sheet. Sheets that aren't valid UTF-8 are decoded as
+// Latin-1, the encoding most legacy rippers wrote. Unknown commands are
+// ignored.
+func Parse(r io.Reader) (*Sheet, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxSheetSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxSheetSize {
+		return nil, fmt.Errorf("cue sheet is larger than %d bytes", maxSheetSize)
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	text := string(data)
+	if !utf8.ValidString(text) {
+		text = latin1(data)
+	}
+
+	sheet := &Sheet{}
+	var (
+		file  string
+		track *Track
+	)
+	sc := bufio.NewScanner(strings.NewReader(text))
+	sc.Buffer(make([]byte, 0, 4096), maxSheetSize)
+	for line := 1; sc.Scan(); line++ {
+		fields := split(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		arg := func(i int) string {
+			if i < len(fields) {
+				return fields[i]
+			}
+			return ""
+		}
+
+		switch strings.ToUpper(fields[0]) {
+		case "FILE":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("cue line %d: FILE without a name", line)
+			}
+			file = fields[1]
+		case "TRACK":
+			n, err := strconv.Atoi(arg(1))
+			if err != nil || n < 1 || n > 99 {
+				return nil, fmt.Errorf("cue line %d: invalid track number %q", line, arg(1))
+			}
+			if file == "" {
+				return nil, fmt.Errorf("cue line %d: TRACK before FILE", line)
+			}
+			if track != nil && track.Start < 0 {
+				return nil, fmt.Errorf("cue track %d has no INDEX 01", track.Number)
+			}
+			sheet.Tracks = append(sheet.Tracks, Track{Number: n, Type: arg(2), File: file, Start: -1})
+			track = &sheet.Tracks[len(sheet.Tracks)-1]
+		case "INDEX":
+			if track == nil {
+				return nil, fmt.Errorf("cue line %d: INDEX outside a track", line)
+			}
+			at, err := parseTime(arg(2))
+			if err != nil {
+				return nil, fmt.Errorf("cue line %d: %w", line, err)
+			}
+			if n := arg(1); n == "01" || n == "1" {
+				track.Start = at
+			}
+		case "TITLE":
+			set(&sheet.Title, track, func(t *Track) *string { return &t.Title }, arg(1))
+		case "PERFORMER":
+			set(&sheet.Performer, track, func(t *Track) *string { return &t.Performer }, arg(1))
+		case "SONGWRITER":
+			set(&sheet.Songwriter, track, func(t *Track) *string { return &t.Songwriter }, arg(1))
+		case "ISRC":
+			if track != nil {
+				track.ISRC = arg(1)
+			}
+		case "CATALOG":
+			sheet.Catalog = arg(1)
+		case "REM":
+			if track != nil {
+				continue
+			}
+			value := strings.Join(fields[min(2, len(fields)):], " ")
+			switch strings.ToUpper(arg(1)) {
+			case "GENRE":
+				sheet.Genre = value
+			case "DATE":
+				sheet.Date = value
+			case "COMMENT":
+				sheet.Comment = value
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(sheet.Tracks) == 0 {
+		return nil, fmt.Errorf("cue sheet has no tracks")
+	}
+	if track.Start < 0 {
+		return nil, fmt.Errorf("cue track %d has no INDEX 01", track.Number)
+	}
+	return sheet, nil
+}
+
+// set assigns v to the current track's field, or to the disc-level one
+// before the first track
+func set(disc *string, track *Track, field func(*Track) *string, v string) {
+	if track != nil {
+		*field(track) = v
+		return
+	}
+	*disc = v
+}
+
+// parseTime parses an MM:SS:FF timestamp; FF counts CD frames
+func parseTime(s string) (time.Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid cue timestamp %q", s)
+	}
+	var v [3]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid cue timestamp %q", s)
+		}
+		v[i] = n
+	}
+	if v[1] >= 60 || v[2] >= framesPerSecond {
+		return 0, fmt.Errorf("invalid cue timestamp %q", s)
+	}
+	frames := int64((v[0]*60+v[1])*framesPerSecond + v[2])
+	return time.Duration(frames) * time.Second / framesPerSecond, nil
+}
+
+// split breaks a line into whitespace-separated fields; double-quoted
+// fields may contain spaces and lose their quotes
+func split(line string) []string {
+	var (
+		fields []string
+		cur    strings.Builder
+		quoted bool
+		inWord bool
+	)
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inWord = true
+		case !quoted && (r == ' ' || r == '\t' || r == '\r'):
+			if inWord {
+				fields = append(fields, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		fields = append(fields, cur.String())
+	}
+	return fields
+}
+
+// latin1 decodes ISO 8859-1 bytes
+func latin1(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
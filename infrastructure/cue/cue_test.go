@@ -0,0 +1,124 @@
+package cue
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		sheet      string
+		wantErr    bool
+		wantTitle  string
+		wantTracks []Track
+	}{
+		{
+			name: "quoted titles",
+			sheet: `PERFORMER "The Band"
+TITLE "Live at the Hall"
+FILE "live rip.flac" WAVE
+  TRACK 01 AUDIO
+    TITLE "Opening Night"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE Encore
+    INDEX 00 03:59:70
+    INDEX 01 04:00:00
+`,
+			wantTitle: "Live at the Hall",
+			wantTracks: []Track{
+				{Number: 1, Type: "AUDIO", File: "live rip.flac", Title: "Opening Night", Start: 0},
+				{Number: 2, Type: "AUDIO", File: "live rip.flac", Title: "Encore", Start: 4 * time.Minute},
+			},
+		},
+		{
+			name:      "byte order mark",
+			sheet:     "\xef\xbb\xbfTITLE \"Album\"\r\nFILE \"a.wav\" WAVE\r\n  TRACK 01 AUDIO\r\n    INDEX 01 00:00:00\r\n",
+			wantTitle: "Album",
+			wantTracks: []Track{
+				{Number: 1, Type: "AUDIO", File: "a.wav", Start: 0},
+			},
+		},
+		{
+			name:      "latin-1",
+			sheet:     "TITLE \"Caf\xe9\"\nFILE \"a.wav\" WAVE\n  TRACK 01 AUDIO\n    TITLE \"Se\xf1or\"\n    INDEX 01 00:00:00\n",
+			wantTitle: "Café",
+			wantTracks: []Track{
+				{Number: 1, Type: "AUDIO", File: "a.wav", Title: "Señor", Start: 0},
+			},
+		},
+		{
+			name: "missing index 01",
+			sheet: `FILE "a.wav" WAVE
+  TRACK 01 AUDIO
+    INDEX 00 00:00:00
+  TRACK 02 AUDIO
+    INDEX 01 01:00:00
+`,
+			wantErr: true,
+		},
+		{
+			name: "missing index 01 on the last track",
+			sheet: `FILE "a.wav" WAVE
+  TRACK 01 AUDIO
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+`,
+			wantErr: true,
+		},
+		{
+			name: "multiple files",
+			sheet: `FILE "01.wav" WAVE
+  TRACK 01 AUDIO
+    INDEX 01 00:00:00
+FILE "02.wav" WAVE
+  TRACK 02 AUDIO
+    INDEX 01 00:00:00
+  TRACK 03 AUDIO
+    INDEX 01 02:30:37
+`,
+			wantTracks: []Track{
+				{Number: 1, Type: "AUDIO", File: "01.wav", Start: 0},
+				{Number: 2, Type: "AUDIO", File: "02.wav", Start: 0},
+				{Number: 3, Type: "AUDIO", File: "02.wav", Start: 150*time.Second + 37*time.Second/framesPerSecond},
+			},
+		},
+		{
+			name:    "track before file",
+			sheet:   "TRACK 01 AUDIO\n  INDEX 01 00:00:00\n",
+			wantErr: true,
+		},
+		{
+			name:    "no tracks",
+			sheet:   "TITLE \"Empty\"\nFILE \"a.wav\" WAVE\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sheet, err := Parse(strings.NewReader(tt.sheet))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if sheet.Title != tt.wantTitle {
+				t.Errorf("Title = %q, want %q", sheet.Title, tt.wantTitle)
+			}
+			if !reflect.DeepEqual(sheet.Tracks, tt.wantTracks) {
+				t.Errorf("Tracks = %+v, want %+v", sheet.Tracks, tt.wantTracks)
+			}
+		})
+	}
+}
+
+func TestSheetFiles(t *testing.T) {
+	sheet := &Sheet{Tracks: []Track{{File: "a.wav"}, {File: "b.wav"}, {File: "b.wav"}, {File: "a.wav"}}}
+	if got, want := sheet.Files(), []string{"a.wav", "b.wav"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Files() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,79 @@
+package ffmpeg
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/domain/ports"
+)
+
+// DetectCapabilities runs ffmpeg -version, -encoders and -filters and
+// returns what the build supports. It fails with ErrPipesUnsupported for
+// executors that can't capture output.
+func DetectCapabilities(ctx context.Context, e ports.FFmpegExecutor) (*model.Capabilities, error) {
+	exec := Piped(e)
+	version, _, err := exec.ExecuteWithOutput(ctx, []string{"-hide_banner", "-version"})
+	if err != nil {
+		return nil, err
+	}
+	encoders, _, err := exec.ExecuteWithOutput(ctx, []string{"-hide_banner", "-encoders"})
+	if err != nil {
+		return nil, err
+	}
+	filters, _, err := exec.ExecuteWithOutput(ctx, []string{"-hide_banner", "-filters"})
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Capabilities{
+		Version:  ParseVersion(version),
+		Encoders: ParseEncoders(encoders),
+		Filters:  ParseFilters(filters),
+	}, nil
+}
+
+// ParseVersion extracts the version from ffmpeg -version output, e.g.
+// "6.1.1" from "ffmpeg version 6.1.1 Copyright ..."
+func ParseVersion(out []byte) string {
+	line, _, _ := strings.Cut(string(out), "\n")
+	fields := strings.Fields(line)
+	if len(fields) < 3 || fields[1] != "version" {
+		return ""
+	}
+	return fields[2]
+}
+
+// ParseEncoders extracts encoder names from ffmpeg -encoders output. Entry
+// lines follow a "------" separator as "<flags> <name> <description>".
+func ParseEncoders(out []byte) map[string]bool {
+	encoders := make(map[string]bool)
+	listing := false
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if !listing {
+			listing = strings.HasPrefix(fields[0], "---")
+			continue
+		}
+		if len(fields) >= 2 {
+			encoders[fields[1]] = true
+		}
+	}
+	return encoders
+}
+
+// ParseFilters extracts filter names from ffmpeg -filters output, whose
+// entry lines read "<flags> <name> <inputs>-><outputs> <description>"
+func ParseFilters(out []byte) map[string]bool {
+	filters := make(map[string]bool)
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && strings.Contains(fields[2], "->") {
+			filters[fields[1]] = true
+		}
+	}
+	return filters
+}
@@ -4,10 +4,14 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"math"
 	"os/exec"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/Skryldev/audio-lab/internal/argfmt"
 	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
 	"github.com/Skryldev/audio-lab/pkg/logger"
 	"go.uber.org/zap"
@@ -18,6 +22,7 @@ type Executor struct {
 	ffmpegPath  string
 	ffprobePath string
 	mu          sync.Mutex // guards concurrent ffmpeg invocations if needed
+	limits      ProcessLimits
 	log         *logger.Logger
 }
 
@@ -26,6 +31,9 @@ type ExecutorConfig struct {
 	FFmpegPath  string
 	FFprobePath string
 	Logger      *logger.Logger
+
+	// Limits lowers the priority of every ffmpeg and ffprobe process
+	Limits ProcessLimits
 }
 
 // NewExecutor creates a new FFmpeg executor
@@ -48,6 +56,10 @@ func NewExecutor(cfg ExecutorConfig) (*Executor, error) {
 		}
 	}
 
+	if err := setupLimits(cfg.Limits); err != nil {
+		return nil, err
+	}
+
 	log := cfg.Logger
 	if log == nil {
 		log, _ = logger.New(false)
@@ -56,27 +68,49 @@ func NewExecutor(cfg ExecutorConfig) (*Executor, error) {
 	return &Executor{
 		ffmpegPath:  ffmpegPath,
 		ffprobePath: ffprobePath,
+		limits:      cfg.Limits,
 		log:         log,
 	}, nil
 }
 
+// run starts cmd under the configured limits and waits for it
+func (e *Executor) run(cmd *exec.Cmd) error {
+	release, err := e.limits.start(cmd)
+	release()
+	if err != nil {
+		if cmd.Process != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+		}
+		return err
+	}
+	return cmd.Wait()
+}
+
 // Execute runs ffmpeg with the given arguments
 func (e *Executor) Execute(ctx context.Context, args []string) error {
+	_, _, err := e.ExecuteWithOutput(ctx, args)
+	return err
+}
+
+// ExecuteWithOutput runs ffmpeg and returns its captured stdout and stderr
+func (e *Executor) ExecuteWithOutput(ctx context.Context, args []string) ([]byte, []byte, error) {
 	cmd := exec.CommandContext(ctx, e.ffmpegPath, args...)
 
-	var stderr bytes.Buffer
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
 	e.log.Debug("executing ffmpeg",
 		zap.Strings("args", args),
 	)
 
-	if err := cmd.Run(); err != nil {
+	if err := e.run(cmd); err != nil {
 		exitCode := -1
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			exitCode = exitErr.ExitCode()
 		}
-		return pkgerrors.NewFFmpegError(
+		return stdout.Bytes(), stderr.Bytes(), pkgerrors.NewFFmpegError(
 			"ffmpeg execution failed",
 			args,
 			exitCode,
@@ -85,7 +119,37 @@ func (e *Executor) Execute(ctx context.Context, args []string) error {
 		)
 	}
 
-	return nil
+	return stdout.Bytes(), stderr.Bytes(), nil
+}
+
+// ExecutePiped runs ffmpeg with stdin read from r and stdout written to w
+func (e *Executor) ExecutePiped(ctx context.Context, args []string, r io.Reader, w io.Writer) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, e.ffmpegPath, args...)
+
+	var stderr bytes.Buffer
+	cmd.Stdin = r
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+
+	e.log.Debug("executing ffmpeg (piped)",
+		zap.Strings("args", args),
+	)
+
+	if err := e.run(cmd); err != nil {
+		exitCode := -1
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			exitCode = exitErr.ExitCode()
+		}
+		return stderr.Bytes(), pkgerrors.NewFFmpegError(
+			"ffmpeg execution failed",
+			args,
+			exitCode,
+			stderr.String(),
+			err,
+		)
+	}
+
+	return stderr.Bytes(), nil
 }
 
 // Probe runs ffprobe and returns JSON output
@@ -95,6 +159,7 @@ func (e *Executor) Probe(ctx context.Context, inputPath string) ([]byte, error)
 		"-print_format", "json",
 		"-show_format",
 		"-show_streams",
+		"-show_chapters",
 		inputPath,
 	}
 
@@ -104,7 +169,7 @@ func (e *Executor) Probe(ctx context.Context, inputPath string) ([]byte, error)
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	if err := cmd.Run(); err != nil {
+	if err := e.run(cmd); err != nil {
 		exitCode := -1
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			exitCode = exitErr.ExitCode()
@@ -121,6 +186,21 @@ func (e *Executor) Probe(ctx context.Context, inputPath string) ([]byte, error)
 	return stdout.Bytes(), nil
 }
 
+// CheckProbe runs ffprobe -version, implementing ports.ProbeChecker
+func (e *Executor) CheckProbe(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, e.ffprobePath, "-version")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := e.run(cmd); err != nil {
+		exitCode := -1
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			exitCode = exitErr.ExitCode()
+		}
+		return pkgerrors.NewFFmpegError("ffprobe execution failed", []string{"-version"}, exitCode, stderr.String(), err)
+	}
+	return nil
+}
+
 // BuildFilterChain constructs an ffmpeg audio filter string
 type FilterChainBuilder struct {
 	filters []string
@@ -130,27 +210,137 @@ func NewFilterChainBuilder() *FilterChainBuilder {
 	return &FilterChainBuilder{}
 }
 
+// AddDCRemoval adds a highpass below the audible range that removes DC
+// offset without touching the low end
+func (b *FilterChainBuilder) AddDCRemoval() *FilterChainBuilder {
+	b.filters = append(b.filters, "highpass=f=5")
+	return b
+}
+
 func (b *FilterChainBuilder) AddHighpass(freq int) *FilterChainBuilder {
-	b.filters = append(b.filters, fmt.Sprintf("highpass=f=%d", freq))
+	b.filters = append(b.filters, "highpass=f="+argfmt.Hz(freq))
 	return b
 }
 
 func (b *FilterChainBuilder) AddLowpass(freq int) *FilterChainBuilder {
-	b.filters = append(b.filters, fmt.Sprintf("lowpass=f=%d", freq))
+	b.filters = append(b.filters, "lowpass=f="+argfmt.Hz(freq))
 	return b
 }
 
 func (b *FilterChainBuilder) AddLoudnorm(targetLUFS, truePeak, LRA float64) *FilterChainBuilder {
-	filter := fmt.Sprintf("loudnorm=I=%.1f:TP=%.1f:LRA=%.1f", targetLUFS, truePeak, LRA)
+	filter := loudnormFilter(targetLUFS, truePeak, LRA)
 	b.filters = append(b.filters, filter)
 	return b
 }
 
+// AddLoudnormWithReport adds loudnorm and asks it to print its input/output
+// measurements as JSON on stderr
+func (b *FilterChainBuilder) AddLoudnormWithReport(targetLUFS, truePeak, LRA float64) *FilterChainBuilder {
+	filter := loudnormFilter(targetLUFS, truePeak, LRA) + ":print_format=json"
+	b.filters = append(b.filters, filter)
+	return b
+}
+
+// AddDelay inserts silence of the given length at the start of every channel
+func (b *FilterChainBuilder) AddDelay(d time.Duration) *FilterChainBuilder {
+	b.filters = append(b.filters, "adelay=delays="+argfmt.Millis(d)+"ms:all=1")
+	return b
+}
+
+// AddPad appends silence of the given length at the end of the stream
+func (b *FilterChainBuilder) AddPad(d time.Duration) *FilterChainBuilder {
+	b.filters = append(b.filters, "apad=pad_dur="+argfmt.Seconds(d))
+	return b
+}
+
+// AddFadeIn fades the audio in from silence over d, starting at the beginning
+func (b *FilterChainBuilder) AddFadeIn(d time.Duration) *FilterChainBuilder {
+	b.filters = append(b.filters, "afade=t=in:st=0:d="+argfmt.Seconds(d))
+	return b
+}
+
+// AddFadeInAt fades the audio in from silence over d, starting at start
+func (b *FilterChainBuilder) AddFadeInAt(start, d time.Duration) *FilterChainBuilder {
+	b.filters = append(b.filters, "afade=t=in:st="+argfmt.Seconds(start)+":d="+argfmt.Seconds(d))
+	return b
+}
+
+// AddFadeOut fades the audio out to silence over d, starting at start
+func (b *FilterChainBuilder) AddFadeOut(start, d time.Duration) *FilterChainBuilder {
+	b.filters = append(b.filters, "afade=t=out:st="+argfmt.Seconds(start)+":d="+argfmt.Seconds(d))
+	return b
+}
+
 func (b *FilterChainBuilder) AddResample(hz int) *FilterChainBuilder {
-	b.filters = append(b.filters, fmt.Sprintf("aresample=%d", hz))
+	b.filters = append(b.filters, "aresample="+argfmt.Hz(hz))
+	return b
+}
+
+// AddDenoise removes broadband noise such as hiss and hum with an FFT
+// denoiser; noiseFloor is the noise level in dB
+func (b *FilterChainBuilder) AddDenoise(noiseFloor float64) *FilterChainBuilder {
+	b.filters = append(b.filters, "afftdn=nf="+argfmt.Decibels(noiseFloor))
 	return b
 }
 
+// AddGate attenuates audio below threshold (dBFS) by ratio, muting room
+// tone and breaths between phrases
+func (b *FilterChainBuilder) AddGate(threshold, ratio float64) *FilterChainBuilder {
+	b.filters = append(b.filters, "agate=threshold="+argfmt.Float(dbToLinear(threshold), 6)+
+		":ratio="+argfmt.Float(ratio, 2)+":attack=10:release=250")
+	return b
+}
+
+// AddCompressor reduces audio above threshold (dBFS) by ratio and applies
+// makeup gain (dB)
+func (b *FilterChainBuilder) AddCompressor(threshold, ratio, makeup float64) *FilterChainBuilder {
+	b.filters = append(b.filters, "acompressor=threshold="+argfmt.Float(dbToLinear(threshold), 6)+
+		":ratio="+argfmt.Float(ratio, 2)+":attack=5:release=100:makeup="+argfmt.Float(dbToLinear(makeup), 6))
+	return b
+}
+
+// AddDeEsser tames sibilance; intensity ranges from 0 to 1
+func (b *FilterChainBuilder) AddDeEsser(intensity float64) *FilterChainBuilder {
+	b.filters = append(b.filters, "deesser=i="+argfmt.Float(intensity, 2))
+	return b
+}
+
+// AddLimiter keeps peaks below ceiling (dBFS) without raising the level
+func (b *FilterChainBuilder) AddLimiter(ceiling float64) *FilterChainBuilder {
+	b.filters = append(b.filters, "alimiter=limit="+argfmt.Float(dbToLinear(ceiling), 6)+":level=0")
+	return b
+}
+
+// Add appends a raw filter expression
+func (b *FilterChainBuilder) Add(filter string) *FilterChainBuilder {
+	if filter != "" {
+		b.filters = append(b.filters, filter)
+	}
+	return b
+}
+
+// Filters returns a copy of the chain's filters in order
+func (b *FilterChainBuilder) Filters() []string {
+	return append([]string(nil), b.filters...)
+}
+
+// SetFilters replaces the chain's filters, e.g. to reorder or drop some
+func (b *FilterChainBuilder) SetFilters(filters []string) *FilterChainBuilder {
+	b.filters = append(b.filters[:0:0], filters...)
+	return b
+}
+
+// dbToLinear converts a level in dB to an amplitude ratio
+func dbToLinear(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+func loudnormFilter(targetLUFS, truePeak, LRA float64) string {
+	return "loudnorm=I=" + argfmt.Decibels(targetLUFS) +
+		":TP=" + argfmt.Decibels(truePeak) +
+		":LRA=" + argfmt.Decibels(LRA)
+}
+
 func (b *FilterChainBuilder) Build() string {
 	return strings.Join(b.filters, ",")
 }
@@ -0,0 +1,49 @@
+package ffmpeg
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+// TestFilterChainGolden pins the exact filter strings generated for numeric
+// options, so formatting changes show up as a diff
+func TestFilterChainGolden(t *testing.T) {
+	chains := []struct {
+		name  string
+		chain *FilterChainBuilder
+	}{
+		{"cleanup", NewFilterChainBuilder().AddDCRemoval().AddHighpass(80).AddLowpass(16000).AddResample(48000)},
+		{"loudnorm", NewFilterChainBuilder().AddLoudnorm(-16, -1.5, 11)},
+		{"loudnorm report", NewFilterChainBuilder().AddLoudnormWithReport(-23.04, -0.96, 7)},
+		{"padding", NewFilterChainBuilder().AddDelay(1500 * time.Millisecond).AddPad(2*time.Second + 250*time.Millisecond)},
+		{"fades", NewFilterChainBuilder().AddFadeIn(500*time.Millisecond).AddFadeOut(29*time.Second+500*time.Millisecond, 500*time.Millisecond)},
+		{"voice", NewFilterChainBuilder().AddDenoise(-25).AddGate(-45, 2).AddCompressor(-18, 3, 4).AddDeEsser(0.5).AddLimiter(-1)},
+		{"negative zero", NewFilterChainBuilder().AddDenoise(-0.01).AddLimiter(0)},
+	}
+
+	var b strings.Builder
+	for _, c := range chains {
+		b.WriteString(c.name + ": " + c.chain.Build() + "\n")
+	}
+	got := b.String()
+
+	golden := filepath.Join("testdata", "filterchain.golden")
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("filter chains differ from %s (run with -update to accept):\ngot:\n%s\nwant:\n%s", golden, got, want)
+	}
+}
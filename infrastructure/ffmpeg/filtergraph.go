@@ -0,0 +1,170 @@
+package ffmpeg
+
+import (
+	"strings"
+
+	"github.com/Skryldev/audio-lab/internal/argfmt"
+)
+
+// Pad is a link in a filtergraph: either an input stream or a labeled
+// output of a chain
+type Pad struct {
+	label string
+	input bool
+}
+
+// InputPad returns the first audio stream of the input at index
+func InputPad(input int) Pad {
+	return InputStreamPad(input, 0)
+}
+
+// InputStreamPad returns the audio stream at index stream of the input at
+// index input
+func InputStreamPad(input, stream int) Pad {
+	return Pad{label: argfmt.Int(input) + ":a:" + argfmt.Int(stream), input: true}
+}
+
+// SpecifierPad returns the input stream matching an ffmpeg stream
+// specifier, e.g. "0:2" or "0:a:m:language:eng"
+func SpecifierPad(spec string) Pad {
+	return Pad{label: spec, input: true}
+}
+
+// Label returns the pad's label, e.g. "0:a:0" or "mix"
+func (p Pad) Label() string {
+	return p.label
+}
+
+// String returns the pad as written in a filtergraph, e.g. "[mix]"
+func (p Pad) String() string {
+	return "[" + p.label + "]"
+}
+
+// Map returns the pad as an ffmpeg -map specifier
+func (p Pad) Map() string {
+	if p.input {
+		return p.label
+	}
+	return p.String()
+}
+
+// MixOptions configures amix
+type MixOptions struct {
+	// Duration is "longest" (default), "shortest" or "first"
+	Duration string
+
+	// Weights scales each input; empty mixes them equally
+	Weights []float64
+
+	// NoNormalize keeps every input at full level instead of scaling them
+	// down by their count, as amix does by default
+	NoNormalize bool
+}
+
+// FiltergraphBuilder builds a -filter_complex graph of labeled chains, for
+// commands with several inputs or outputs that a flat -af chain can't
+// express
+type FiltergraphBuilder struct {
+	chains []string
+	labels int
+}
+
+// NewFiltergraphBuilder creates an empty filtergraph builder
+func NewFiltergraphBuilder() *FiltergraphBuilder {
+	return &FiltergraphBuilder{}
+}
+
+// Pad returns a pad with the given label, for naming a chain's outputs
+func (b *FiltergraphBuilder) Pad(label string) Pad {
+	return Pad{label: label}
+}
+
+// newPad returns a pad with a generated label unique within the graph
+func (b *FiltergraphBuilder) newPad() Pad {
+	p := Pad{label: "g" + argfmt.Int(b.labels)}
+	b.labels++
+	return p
+}
+
+// Chain adds a linear chain reading in and writing out. Filters are joined
+// with commas, so chain may be a FilterChainBuilder's Build output.
+func (b *FiltergraphBuilder) Chain(in []Pad, chain string, out ...Pad) *FiltergraphBuilder {
+	var sb strings.Builder
+	for _, p := range in {
+		sb.WriteString(p.String())
+	}
+	sb.WriteString(chain)
+	for _, p := range out {
+		sb.WriteString(p.String())
+	}
+	b.chains = append(b.chains, sb.String())
+	return b
+}
+
+// Filter runs in through chain and returns the filtered pad. An empty
+// chain returns in unchanged.
+func (b *FiltergraphBuilder) Filter(in Pad, chain *FilterChainBuilder) Pad {
+	if chain == nil || chain.IsEmpty() {
+		return in
+	}
+	out := b.newPad()
+	b.Chain([]Pad{in}, chain.Build(), out)
+	return out
+}
+
+// Split duplicates in into n pads with asplit
+func (b *FiltergraphBuilder) Split(in Pad, n int) []Pad {
+	out := make([]Pad, n)
+	for i := range out {
+		out[i] = b.newPad()
+	}
+	b.Chain([]Pad{in}, "asplit="+argfmt.Int(n), out...)
+	return out
+}
+
+// Mix sums the inputs into one stream with amix
+func (b *FiltergraphBuilder) Mix(opts MixOptions, in ...Pad) Pad {
+	filter := "amix=inputs=" + argfmt.Int(len(in))
+	if opts.Duration != "" {
+		filter += ":duration=" + opts.Duration
+	}
+	if len(opts.Weights) > 0 {
+		weights := make([]string, len(opts.Weights))
+		for i, w := range opts.Weights {
+			weights[i] = argfmt.Float(w, 3)
+		}
+		filter += ":weights='" + strings.Join(weights, " ") + "'"
+	}
+	if opts.NoNormalize {
+		filter += ":normalize=0"
+	}
+
+	out := b.newPad()
+	b.Chain(in, filter, out)
+	return out
+}
+
+// Concat plays the inputs back to back with concat
+func (b *FiltergraphBuilder) Concat(in ...Pad) Pad {
+	out := b.newPad()
+	b.Chain(in, "concat=n="+argfmt.Int(len(in))+":v=0:a=1", out)
+	return out
+}
+
+// Merge combines the inputs' channels into one multichannel stream with
+// amerge
+func (b *FiltergraphBuilder) Merge(in ...Pad) Pad {
+	out := b.newPad()
+	b.Chain(in, "amerge=inputs="+argfmt.Int(len(in)), out)
+	return out
+}
+
+// Build returns the graph, its chains separated by semicolons
+func (b *FiltergraphBuilder) Build() string {
+	return strings.Join(b.chains, ";")
+}
+
+// IsEmpty reports whether the graph has no chains
+func (b *FiltergraphBuilder) IsEmpty() bool {
+	return len(b.chains) == 0
+}
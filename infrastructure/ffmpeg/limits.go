@@ -0,0 +1,65 @@
+package ffmpeg
+
+import (
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+)
+
+// IOClass is an I/O scheduling class, as set by ionice
+type IOClass string
+
+const (
+	IOClassDefault    IOClass = ""
+	IOClassBestEffort IOClass = "best-effort"
+	IOClassIdle       IOClass = "idle" // only gets disk time when no one else wants it
+)
+
+// ProcessLimits lowers the priority of ffmpeg and ffprobe processes so bulk
+// transcoding doesn't starve other services on a shared host. The zero
+// value inherits everything from this process. Only Linux supports all
+// limits; elsewhere setting any of them fails NewExecutor. Nice and
+// IOClass run the processes through the nice and ionice commands, which
+// must be on the PATH.
+type ProcessLimits struct {
+	// Nice is the niceness added to this process's, 1 (slightly lower)
+	// to 19 (lowest)
+	Nice int
+
+	// IOClass and IOPriority set the I/O scheduling class and, for
+	// best-effort, its level from 0 (highest) to 7 (lowest)
+	IOClass    IOClass
+	IOPriority int
+
+	// CgroupPath is a cgroup v2 directory the processes are started in,
+	// e.g. /sys/fs/cgroup/audiolab. It must exist and be writable.
+	CgroupPath string
+
+	// CPUWeight is written to the cgroup's cpu.weight at startup, from 1
+	// to 10000 (100 is the kernel default). Zero leaves it unchanged.
+	CPUWeight int
+}
+
+// IsZero reports whether no limit is set
+func (l ProcessLimits) IsZero() bool {
+	return l == ProcessLimits{}
+}
+
+func (l ProcessLimits) validate() error {
+	if l.Nice < 0 || l.Nice > 19 {
+		return pkgerrors.NewValidationError("nice", l.Nice, "niceness must be between 0 and 19")
+	}
+	switch l.IOClass {
+	case IOClassDefault, IOClassBestEffort, IOClassIdle:
+	default:
+		return pkgerrors.NewValidationError("ioClass", l.IOClass, "I/O class must be best-effort or idle")
+	}
+	if l.IOPriority < 0 || l.IOPriority > 7 {
+		return pkgerrors.NewValidationError("ioPriority", l.IOPriority, "I/O priority must be between 0 and 7")
+	}
+	if l.CPUWeight < 0 || l.CPUWeight > 10000 {
+		return pkgerrors.NewValidationError("cpuWeight", l.CPUWeight, "CPU weight must be between 1 and 10000")
+	}
+	if l.CPUWeight > 0 && l.CgroupPath == "" {
+		return pkgerrors.NewValidationError("cpuWeight", l.CPUWeight, "CPU weight requires a cgroup path")
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+//go:build linux
+
+package ffmpeg
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// setupLimits validates l and configures its cgroup
+func setupLimits(l ProcessLimits) error {
+	if err := l.validate(); err != nil {
+		return err
+	}
+	if _, err := l.wrapper(); err != nil {
+		return err
+	}
+	if l.CgroupPath == "" {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(l.CgroupPath, "cgroup.procs")); err != nil {
+		return fmt.Errorf("cgroup %s: %w", l.CgroupPath, err)
+	}
+	if l.CPUWeight > 0 {
+		err := os.WriteFile(filepath.Join(l.CgroupPath, "cpu.weight"), []byte(strconv.Itoa(l.CPUWeight)), 0)
+		if err != nil {
+			return fmt.Errorf("cgroup %s: %w", l.CgroupPath, err)
+		}
+	}
+	return nil
+}
+
+// wrapper returns the nice and ionice command line that runs a process
+// under l's priorities, or nil when l leaves them unchanged
+func (l ProcessLimits) wrapper() ([]string, error) {
+	var args []string
+	if l.Nice > 0 {
+		path, err := exec.LookPath("nice")
+		if err != nil {
+			return nil, fmt.Errorf("niceness: %w", err)
+		}
+		args = append(args, path, "-n", strconv.Itoa(l.Nice))
+	}
+	if l.IOClass != IOClassDefault {
+		path, err := exec.LookPath("ionice")
+		if err != nil {
+			return nil, fmt.Errorf("I/O class: %w", err)
+		}
+		if l.IOClass == IOClassIdle {
+			args = append(args, path, "-c", "3")
+		} else {
+			args = append(args, path, "-c", "2", "-n", strconv.Itoa(l.IOPriority))
+		}
+	}
+	return args, nil
+}
+
+// start starts cmd under l: inside the cgroup and with its priorities from
+// the first instruction. The priorities are set through nice and ionice,
+// which exec the command in place, because setting them on a running
+// process misses the threads it has already spawned. The returned func
+// releases what start opened.
+func (l ProcessLimits) start(cmd *exec.Cmd) (func(), error) {
+	release := func() {}
+	wrapper, err := l.wrapper()
+	if err != nil {
+		return release, err
+	}
+	if len(wrapper) > 0 && cmd.Err == nil {
+		cmd.Args = append(append(wrapper, cmd.Path), cmd.Args[1:]...)
+		cmd.Path = wrapper[0]
+	}
+	if l.CgroupPath != "" {
+		dir, err := os.Open(l.CgroupPath)
+		if err != nil {
+			return release, err
+		}
+		release = func() { dir.Close() }
+		cmd.SysProcAttr = &syscall.SysProcAttr{UseCgroupFD: true, CgroupFD: int(dir.Fd())}
+	}
+	return release, cmd.Start()
+}
@@ -0,0 +1,23 @@
+//go:build !linux
+
+package ffmpeg
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// setupLimits validates l; process limits are only implemented on Linux
+func setupLimits(l ProcessLimits) error {
+	if err := l.validate(); err != nil {
+		return err
+	}
+	if !l.IsZero() {
+		return fmt.Errorf("process limits are not supported on this platform")
+	}
+	return nil
+}
+
+func (l ProcessLimits) start(cmd *exec.Cmd) (func(), error) {
+	return func() {}, cmd.Start()
+}
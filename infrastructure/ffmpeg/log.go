@@ -0,0 +1,60 @@
+package ffmpeg
+
+import "strings"
+
+// LevelLogArgs make ffmpeg log warnings and errors prefixed with their
+// level, e.g. "[mp3float @ 0x55d0] [error] Header missing", for ParseLog
+var LevelLogArgs = []string{"-v", "level+warning"}
+
+// LogLine is a message ffmpeg logged
+type LogLine struct {
+	Level     string // e.g. "error" or "warning"; empty if not prefixed
+	Component string // the logging demuxer or decoder, e.g. "mp3float"
+	Message   string
+}
+
+// IsError reports whether the line was logged at error level or above
+func (l LogLine) IsError() bool {
+	switch l.Level {
+	case "error", "fatal", "panic":
+		return true
+	}
+	return false
+}
+
+// logLevels are the level prefixes ffmpeg writes with the "level" flag
+var logLevels = map[string]bool{
+	"quiet": true, "panic": true, "fatal": true, "error": true, "warning": true,
+	"info": true, "verbose": true, "debug": true, "trace": true,
+}
+
+// ParseLog splits ffmpeg stderr written with LevelLogArgs into lines.
+// Context addresses are dropped so repeats of a message compare equal.
+func ParseLog(stderr []byte) []LogLine {
+	var lines []LogLine
+	for _, raw := range strings.Split(string(stderr), "\n") {
+		rest := strings.TrimSpace(raw)
+		if rest == "" {
+			continue
+		}
+		var l LogLine
+		for strings.HasPrefix(rest, "[") {
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				break
+			}
+			tag := rest[1:end]
+			if logLevels[tag] {
+				l.Level = tag
+			} else if name, _, ok := strings.Cut(tag, " @ "); ok && l.Component == "" {
+				l.Component = name
+			} else {
+				break
+			}
+			rest = strings.TrimSpace(rest[end+1:])
+		}
+		l.Message = rest
+		lines = append(lines, l)
+	}
+	return lines
+}
@@ -0,0 +1,75 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// LoudnormStats holds the measurements printed by loudnorm with print_format=json
+type LoudnormStats struct {
+	InputI            float64
+	InputTP           float64
+	InputLRA          float64
+	InputThresh       float64
+	OutputI           float64
+	OutputTP          float64
+	OutputLRA         float64
+	OutputThresh      float64
+	NormalizationType string
+	TargetOffset      float64
+}
+
+type loudnormJSON struct {
+	InputI            string `json:"input_i"`
+	InputTP           string `json:"input_tp"`
+	InputLRA          string `json:"input_lra"`
+	InputThresh       string `json:"input_thresh"`
+	OutputI           string `json:"output_i"`
+	OutputTP          string `json:"output_tp"`
+	OutputLRA         string `json:"output_lra"`
+	OutputThresh      string `json:"output_thresh"`
+	NormalizationType string `json:"normalization_type"`
+	TargetOffset      string `json:"target_offset"`
+}
+
+// ParseLoudnormOutput extracts the loudnorm JSON block from ffmpeg stderr
+func ParseLoudnormOutput(stderr []byte) (*LoudnormStats, error) {
+	end := bytes.LastIndexByte(stderr, '}')
+	if end < 0 {
+		return nil, fmt.Errorf("loudnorm report not found in ffmpeg output")
+	}
+	start := bytes.LastIndexByte(stderr[:end], '{')
+	if start < 0 {
+		return nil, fmt.Errorf("loudnorm report not found in ffmpeg output")
+	}
+
+	var raw loudnormJSON
+	if err := json.Unmarshal(stderr[start:end+1], &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse loudnorm report: %w", err)
+	}
+
+	return &LoudnormStats{
+		InputI:            parseFloat(raw.InputI),
+		InputTP:           parseFloat(raw.InputTP),
+		InputLRA:          parseFloat(raw.InputLRA),
+		InputThresh:       parseFloat(raw.InputThresh),
+		OutputI:           parseFloat(raw.OutputI),
+		OutputTP:          parseFloat(raw.OutputTP),
+		OutputLRA:         parseFloat(raw.OutputLRA),
+		OutputThresh:      parseFloat(raw.OutputThresh),
+		NormalizationType: raw.NormalizationType,
+		TargetOffset:      parseFloat(raw.TargetOffset),
+	}, nil
+}
+
+// parseFloat parses ffmpeg numeric output, mapping "-inf"/"inf" and garbage to 0
+func parseFloat(s string) float64 {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0
+	}
+	return f
+}
@@ -0,0 +1,100 @@
+package ffmpeg
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/Skryldev/audio-lab/domain/ports"
+)
+
+// NUTArgs select the NUT muxer or demuxer for a stream passed between
+// ffmpeg processes over a pipe. NUT carries raw PCM together with its
+// sample format, rate and channel layout, so nothing is written to disk
+// between stages.
+var NUTArgs = []string{"-f", "nut"}
+
+// RunPiped runs commands at once, each reading the previous one's stdout
+// on its stdin, e.g. a decode stage writing PCM to pipe:1 and an encode
+// stage reading pipe:0. r feeds the first command's stdin and w receives
+// the last one's stdout; either may be nil. The first failure stops the
+// other commands and is returned. stderrs holds each command's stderr.
+//
+// An executor that caps concurrent processes, or one it wraps, must
+// implement ports.ProcessReserver: the stages' slots are taken together
+// before any stage starts, as a stage waiting for a slot would stall the
+// stages that hold theirs.
+func RunPiped(ctx context.Context, exec ports.PipedExecutor, r io.Reader, w io.Writer, commands ...[]string) (stderrs [][]byte, err error) {
+	if res := processReserver(exec); res != nil {
+		var release func()
+		if ctx, release, err = res.ReserveProcesses(ctx, len(commands)); err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stderrs = make([][]byte, len(commands))
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+	)
+	in := r
+	var prev *io.PipeReader // stdout of the previous stage
+	for i, args := range commands {
+		var pr *io.PipeReader
+		var pw *io.PipeWriter
+		out := w
+		if i < len(commands)-1 {
+			pr, pw = io.Pipe()
+			out = pw
+		}
+		stageIn := prev
+
+		wg.Add(1)
+		go func(i int, args []string, in io.Reader, out io.Writer) {
+			defer wg.Done()
+			stderr, err := exec.ExecutePiped(ctx, args, in, out)
+			stderrs[i] = stderr
+			if pw != nil {
+				// EOF for the next stage, or the failure if there was one
+				pw.CloseWithError(err)
+			}
+			if stageIn != nil {
+				// Unblock the previous stage if this one stopped reading early
+				stageIn.CloseWithError(io.ErrClosedPipe)
+			}
+			if err != nil {
+				once.Do(func() {
+					first = err
+					cancel()
+				})
+			}
+		}(i, args, in, out)
+
+		if pr != nil {
+			in, prev = pr, pr
+		}
+	}
+	wg.Wait()
+	return stderrs, first
+}
+
+// processReserver returns exec, or the first executor it wraps, that
+// reserves process slots
+func processReserver(exec ports.FFmpegExecutor) ports.ProcessReserver {
+	for exec != nil {
+		if r, ok := exec.(ports.ProcessReserver); ok {
+			return r
+		}
+		w, ok := exec.(interface{ Unwrap() ports.FFmpegExecutor })
+		if !ok {
+			break
+		}
+		exec = w.Unwrap()
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/Skryldev/audio-lab/domain/ports"
+)
+
+// ErrPipesUnsupported is returned for output capture and piped I/O
+// requested from an executor that doesn't implement ports.PipedExecutor
+var ErrPipesUnsupported = errors.New("executor does not support capturing ffmpeg output or piped I/O")
+
+// Piped returns e as a ports.PipedExecutor. An executor implementing only
+// ports.FFmpegExecutor is wrapped so that its ExecuteWithOutput and
+// ExecutePiped fail with ErrPipesUnsupported, except a piped call with
+// neither input nor output, which runs Execute.
+func Piped(e ports.FFmpegExecutor) ports.PipedExecutor {
+	if p, ok := e.(ports.PipedExecutor); ok {
+		return p
+	}
+	return &plainExecutor{FFmpegExecutor: e}
+}
+
+// SupportsPipes reports whether e, or the executor it ultimately wraps,
+// implements ports.PipedExecutor
+func SupportsPipes(e ports.FFmpegExecutor) bool {
+	for e != nil {
+		if _, ok := e.(*plainExecutor); ok {
+			return false
+		}
+		w, ok := e.(interface{ Unwrap() ports.FFmpegExecutor })
+		if !ok {
+			break
+		}
+		e = w.Unwrap()
+	}
+	_, ok := e.(ports.PipedExecutor)
+	return ok
+}
+
+// plainExecutor adapts an executor without output capture
+type plainExecutor struct {
+	ports.FFmpegExecutor
+}
+
+func (e *plainExecutor) ExecuteWithOutput(ctx context.Context, args []string) ([]byte, []byte, error) {
+	return nil, nil, ErrPipesUnsupported
+}
+
+func (e *plainExecutor) ExecutePiped(ctx context.Context, args []string, r io.Reader, w io.Writer) ([]byte, error) {
+	if r != nil || w != nil {
+		return nil, ErrPipesUnsupported
+	}
+	return nil, e.Execute(ctx, args)
+}
+
+// Unwrap returns the wrapped executor
+func (e *plainExecutor) Unwrap() ports.FFmpegExecutor {
+	return e.FFmpegExecutor
+}
@@ -0,0 +1,74 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProgressArgs are global options that make ffmpeg write machine-readable
+// progress reports to stdout instead of the stats line on stderr
+var ProgressArgs = []string{"-progress", "pipe:1", "-nostats"}
+
+// ProgressReport is one block of ffmpeg's -progress output
+type ProgressReport struct {
+	OutTime   time.Duration // position in the output
+	Speed     float64       // realtime factor, e.g. 1.5 for "1.5x"; 0 if unknown
+	TotalSize int64         // bytes written so far
+	Done      bool          // the final report
+}
+
+// ProgressWriter parses -progress output written to it and calls fn once
+// per complete report
+type ProgressWriter struct {
+	fn      func(ProgressReport)
+	partial []byte
+	report  ProgressReport
+}
+
+// NewProgressWriter creates a writer that reports to fn
+func NewProgressWriter(fn func(ProgressReport)) *ProgressWriter {
+	return &ProgressWriter{fn: fn}
+}
+
+// Write implements io.Writer. It never fails, so ffmpeg is never blocked
+// on malformed output.
+func (w *ProgressWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.line(strings.TrimSpace(string(w.partial[:i])))
+		w.partial = w.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *ProgressWriter) line(line string) {
+	key, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return
+	}
+	switch key {
+	case "out_time_us", "out_time_ms":
+		// out_time_ms is in microseconds as well, despite its name
+		if us, err := strconv.ParseInt(value, 10, 64); err == nil && us >= 0 {
+			w.report.OutTime = time.Duration(us) * time.Microsecond
+		}
+	case "speed":
+		// "N/A" until ffmpeg can tell
+		x, _ := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "x"), 64)
+		w.report.Speed = x
+	case "total_size":
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			w.report.TotalSize = n
+		}
+	case "progress":
+		// "progress" closes each report
+		w.report.Done = value == "end"
+		w.fn(w.report)
+	}
+}
@@ -0,0 +1,36 @@
+package governor
+
+import (
+	"context"
+
+	"github.com/Skryldev/audio-lab/domain/ports"
+)
+
+// Chain returns a governor admitting a job once every one of governors
+// has, in order. Nil entries are skipped.
+func Chain(governors ...ports.AdmissionGovernor) ports.AdmissionGovernor {
+	var chain chain
+	for _, g := range governors {
+		if g != nil {
+			chain = append(chain, g)
+		}
+	}
+	switch len(chain) {
+	case 0:
+		return nil
+	case 1:
+		return chain[0]
+	}
+	return chain
+}
+
+type chain []ports.AdmissionGovernor
+
+func (c chain) Admit(ctx context.Context, jobID string) error {
+	for _, g := range c {
+		if err := g.Admit(ctx, jobID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
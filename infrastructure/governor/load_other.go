@@ -0,0 +1,10 @@
+//go:build !linux
+
+package governor
+
+import "fmt"
+
+// ReadLoadAverage is only implemented on Linux
+func ReadLoadAverage() (float64, error) {
+	return 0, fmt.Errorf("load average reading is not supported on this platform")
+}
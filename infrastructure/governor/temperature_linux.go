@@ -0,0 +1,44 @@
+//go:build linux
+
+package governor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ReadCPUTemperature returns the hottest reading across the kernel's
+// thermal zones, in degrees Celsius
+func ReadCPUTemperature() (float64, error) {
+	zones, err := filepath.Glob("/sys/class/thermal/thermal_zone*/temp")
+	if err != nil {
+		return 0, err
+	}
+	if len(zones) == 0 {
+		return 0, fmt.Errorf("no thermal zones found")
+	}
+
+	hottest := 0.0
+	found := false
+	for _, zone := range zones {
+		data, err := os.ReadFile(zone)
+		if err != nil {
+			continue
+		}
+		milli, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+		if err != nil {
+			continue
+		}
+		if c := milli / 1000; !found || c > hottest {
+			hottest = c
+			found = true
+		}
+	}
+	if !found {
+		return 0, fmt.Errorf("no readable thermal zones")
+	}
+	return hottest, nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package governor
+
+import "fmt"
+
+// ReadCPUTemperature is only implemented on Linux; elsewhere supply
+// ThermalConfig.Source
+func ReadCPUTemperature() (float64, error) {
+	return 0, fmt.Errorf("CPU temperature reading is not supported on this platform")
+}
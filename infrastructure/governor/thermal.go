@@ -0,0 +1,120 @@
+package governor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Skryldev/audio-lab/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// TemperatureSource reports the current temperature in degrees Celsius
+type TemperatureSource func() (float64, error)
+
+// ThermalConfig configures the thermal admission governor
+type ThermalConfig struct {
+	// MaxCelsius holds new jobs back once the temperature reaches it (default: 80)
+	MaxCelsius float64
+
+	// ResumeCelsius admits jobs again once the temperature drops below it
+	// (default: MaxCelsius - 5), avoiding rapid on/off toggling
+	ResumeCelsius float64
+
+	// PollInterval is how often the temperature is re-read while throttled (default: 5s)
+	PollInterval time.Duration
+
+	// Source overrides how temperature is read (default: Linux thermal zones)
+	Source TemperatureSource
+
+	Logger *logger.Logger
+}
+
+// Thermal is an AdmissionGovernor that throttles job starts while the host
+// is too hot
+type Thermal struct {
+	cfg ThermalConfig
+
+	mu        sync.Mutex
+	throttled bool
+}
+
+// NewThermal creates a thermal governor
+func NewThermal(cfg ThermalConfig) (*Thermal, error) {
+	if cfg.MaxCelsius <= 0 {
+		cfg.MaxCelsius = 80
+	}
+	if cfg.ResumeCelsius <= 0 || cfg.ResumeCelsius > cfg.MaxCelsius {
+		cfg.ResumeCelsius = cfg.MaxCelsius - 5
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 5 * time.Second
+	}
+	if cfg.Source == nil {
+		cfg.Source = ReadCPUTemperature
+	}
+	if cfg.Logger == nil {
+		var err error
+		cfg.Logger, err = logger.New(false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create logger: %w", err)
+		}
+	}
+	if _, err := cfg.Source(); err != nil {
+		return nil, fmt.Errorf("temperature source unavailable: %w", err)
+	}
+	return &Thermal{cfg: cfg}, nil
+}
+
+// Admit blocks while the temperature is above the configured threshold
+func (t *Thermal) Admit(ctx context.Context, jobID string) error {
+	for {
+		temp, err := t.cfg.Source()
+		if err != nil {
+			// fail open: a broken sensor must not stall all processing
+			t.cfg.Logger.Warn("failed to read temperature, admitting job",
+				zap.String("job_id", jobID),
+				zap.Error(err),
+			)
+			return nil
+		}
+
+		if t.admit(temp) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(t.cfg.PollInterval):
+		}
+	}
+}
+
+// admit applies the threshold with hysteresis and records the throttle state
+func (t *Thermal) admit(temp float64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	limit := t.cfg.MaxCelsius
+	if t.throttled {
+		limit = t.cfg.ResumeCelsius
+	}
+	if temp < limit {
+		if t.throttled {
+			t.cfg.Logger.Info("temperature recovered, resuming jobs", zap.Float64("celsius", temp))
+		}
+		t.throttled = false
+		return true
+	}
+
+	if !t.throttled {
+		t.cfg.Logger.Warn("temperature above threshold, throttling jobs",
+			zap.Float64("celsius", temp),
+			zap.Float64("max_celsius", t.cfg.MaxCelsius),
+		)
+	}
+	t.throttled = true
+	return false
+}
@@ -0,0 +1,205 @@
+// Package id3 adds frames ffmpeg can't write, such as lyrics, to the
+// ID3v2 tag at the start of an MP3 file
+package id3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf16"
+)
+
+const (
+	headerSize = 10
+
+	flagUnsync    = 0x80
+	flagExtHeader = 0x40
+	flagFooter    = 0x10
+
+	// maxTagSize is the largest size the 28-bit tag size field holds
+	maxTagSize = 1<<28 - 1
+)
+
+// Frame is an ID3v2 frame whose body is encoded for the version of the tag
+// it is written to
+type Frame struct {
+	ID     string
+	encode func(version byte) []byte
+}
+
+// SyncedText is a line of synchronized lyrics shown from Time on
+type SyncedText struct {
+	Time time.Duration
+	Text string
+}
+
+// USLT returns an unsynchronized lyrics frame. lang is an ISO 639-2 code.
+func USLT(lang, text string) Frame {
+	return Frame{ID: "USLT", encode: func(version byte) []byte {
+		var b bytes.Buffer
+		b.WriteByte(encoding(version))
+		b.WriteString(lang)
+		writeString(&b, version, "", true)
+		writeString(&b, version, text, false)
+		return b.Bytes()
+	}}
+}
+
+// SYLT returns a synchronized lyrics frame with millisecond timestamps
+func SYLT(lang string, lines []SyncedText) Frame {
+	return Frame{ID: "SYLT", encode: func(version byte) []byte {
+		var b bytes.Buffer
+		b.WriteByte(encoding(version))
+		b.WriteString(lang)
+		b.WriteByte(2) // timestamps in milliseconds
+		b.WriteByte(1) // content type: lyrics
+		writeString(&b, version, "", true)
+		for _, l := range lines {
+			writeString(&b, version, l.Text, true)
+			_ = binary.Write(&b, binary.BigEndian, uint32(l.Time.Milliseconds()))
+		}
+		return b.Bytes()
+	}}
+}
+
+// Rewrite copies the MP3 in src to dst with frames added to its ID3v2 tag,
+// replacing any frames with the same IDs. Files without a tag get an
+// ID3v2.4 one. Only v2.3 and v2.4 tags without unsynchronisation or an
+// extended header, as ffmpeg writes them, are supported.
+func Rewrite(dst io.Writer, src io.Reader, frames ...Frame) error {
+	header := make([]byte, headerSize)
+	n, err := io.ReadFull(src, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+	header = header[:n]
+
+	var (
+		version byte = 4
+		kept    []byte
+		padding int
+	)
+	if n == headerSize && bytes.HasPrefix(header, []byte("ID3")) {
+		version = header[3]
+		if version != 3 && version != 4 {
+			return fmt.Errorf("unsupported ID3v2.%d tag", version)
+		}
+		if header[5]&(flagUnsync|flagExtHeader|flagFooter) != 0 {
+			return fmt.Errorf("unsupported ID3v2 tag flags %#x", header[5])
+		}
+		body := make([]byte, syncsafe(header[6:10]))
+		if _, err := io.ReadFull(src, body); err != nil {
+			return fmt.Errorf("read ID3v2 tag: %w", err)
+		}
+		if kept, padding, err = keepFrames(body, version, frames); err != nil {
+			return err
+		}
+		header = nil
+	}
+
+	tag := bytes.NewBuffer(kept)
+	for _, f := range frames {
+		body := f.encode(version)
+		tag.WriteString(f.ID)
+		if version == 4 {
+			tag.Write(putSyncsafe(len(body)))
+		} else {
+			_ = binary.Write(tag, binary.BigEndian, uint32(len(body)))
+		}
+		tag.Write([]byte{0, 0})
+		tag.Write(body)
+	}
+	tag.Write(make([]byte, padding))
+	if tag.Len() > maxTagSize {
+		return fmt.Errorf("ID3v2 tag of %d bytes is too large", tag.Len())
+	}
+
+	out := append([]byte("ID3"), version, 0, 0)
+	out = append(out, putSyncsafe(tag.Len())...)
+	if _, err := dst.Write(out); err != nil {
+		return err
+	}
+	if _, err := tag.WriteTo(dst); err != nil {
+		return err
+	}
+	// Without a tag, the bytes read as a header are audio
+	if _, err := dst.Write(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// keepFrames returns the frames of a tag body that aren't replaced, and
+// the size of its padding
+func keepFrames(body []byte, version byte, replace []Frame) ([]byte, int, error) {
+	var kept []byte
+	pos := 0
+	for pos+headerSize <= len(body) && body[pos] != 0 {
+		id := string(body[pos : pos+4])
+		size := int(binary.BigEndian.Uint32(body[pos+4 : pos+8]))
+		if version == 4 {
+			size = syncsafe(body[pos+4 : pos+8])
+		}
+		end := pos + headerSize + size
+		if size < 0 || end > len(body) {
+			return nil, 0, fmt.Errorf("ID3v2 frame %q overruns the tag", id)
+		}
+		if !replaces(replace, id) {
+			kept = append(kept, body[pos:end]...)
+		}
+		pos = end
+	}
+	return kept, len(body) - pos, nil
+}
+
+// replaces reports whether one of frames has the given ID
+func replaces(frames []Frame, id string) bool {
+	for _, f := range frames {
+		if f.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// encoding returns the text encoding byte used for version: UTF-8 in
+// v2.4, UTF-16 with a byte order mark in v2.3, which has no UTF-8
+func encoding(version byte) byte {
+	if version == 4 {
+		return 3
+	}
+	return 1
+}
+
+// writeString writes s in the text encoding of version, followed by the
+// terminator when terminate is set
+func writeString(b *bytes.Buffer, version byte, s string, terminate bool) {
+	if version == 4 {
+		b.WriteString(s)
+		if terminate {
+			b.WriteByte(0)
+		}
+		return
+	}
+	b.Write([]byte{0xff, 0xfe})
+	for _, u := range utf16.Encode([]rune(s)) {
+		_ = binary.Write(b, binary.LittleEndian, u)
+	}
+	if terminate {
+		b.Write([]byte{0, 0})
+	}
+}
+
+// syncsafe decodes a 28-bit integer stored in the low 7 bits of 4 bytes
+func syncsafe(b []byte) int {
+	return int(b[0]&0x7f)<<21 | int(b[1]&0x7f)<<14 | int(b[2]&0x7f)<<7 | int(b[3]&0x7f)
+}
+
+// putSyncsafe encodes n as a 28-bit syncsafe integer
+func putSyncsafe(n int) []byte {
+	return []byte{byte(n>>21) & 0x7f, byte(n>>14) & 0x7f, byte(n>>7) & 0x7f, byte(n) & 0x7f}
+}
@@ -0,0 +1,121 @@
+// Package lrc parses LRC files, the line-timed lyrics format most players
+// and lyric sites use
+package lrc
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Line is a lyric line shown from Time on
+type Line struct {
+	Time time.Duration
+	Text string
+}
+
+// Lyrics are parsed LRC lyrics. Tags holds the header tags such as "ar"
+// (artist) and "ti" (title) with lower-cased keys.
+type Lyrics struct {
+	Lines []Line
+	Tags  map[string]string
+}
+
+// Parse reads LRC text, returning false when it has no timed lines and so
+// is plain lyrics. Lines with several timestamps are repeated at each, and
+// the [offset:ms] tag is applied. Word timings of enhanced LRC are dropped.
+func Parse(text string) (*Lyrics, bool) {
+	l := &Lyrics{Tags: make(map[string]string)}
+	for _, raw := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		rest := strings.TrimSpace(raw)
+		var times []time.Duration
+		for strings.HasPrefix(rest, "[") {
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				break
+			}
+			group := rest[1:end]
+			if t, ok := parseTime(group); ok {
+				times = append(times, t)
+			} else if key, value, ok := strings.Cut(group, ":"); ok && len(times) == 0 {
+				l.Tags[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+			} else {
+				break
+			}
+			rest = rest[end+1:]
+		}
+		text := strings.TrimSpace(stripWordTimes(rest))
+		for _, t := range times {
+			l.Lines = append(l.Lines, Line{Time: t, Text: text})
+		}
+	}
+	if len(l.Lines) == 0 {
+		return nil, false
+	}
+
+	// A positive offset shows the lyrics earlier
+	if ms, err := strconv.Atoi(strings.TrimPrefix(l.Tags["offset"], "+")); err == nil && ms != 0 {
+		for i := range l.Lines {
+			l.Lines[i].Time = max(l.Lines[i].Time-time.Duration(ms)*time.Millisecond, 0)
+		}
+	}
+	sort.SliceStable(l.Lines, func(i, j int) bool { return l.Lines[i].Time < l.Lines[j].Time })
+	return l, true
+}
+
+// Text returns the lyrics without timestamps, one line each
+func (l *Lyrics) Text() string {
+	lines := make([]string, len(l.Lines))
+	for i, line := range l.Lines {
+		lines[i] = line.Text
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseTime parses an "mm:ss", "mm:ss.xx" or "mm:ss:xx" timestamp
+func parseTime(s string) (time.Duration, bool) {
+	minutes, sec, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, false
+	}
+	m, err := strconv.Atoi(minutes)
+	if err != nil || m < 0 {
+		return 0, false
+	}
+	// Some writers separate the fraction with a colon
+	if whole, frac, ok := strings.Cut(sec, ":"); ok {
+		sec = whole + "." + frac
+	}
+	if len(sec) < 2 || sec[0] < '0' || sec[0] > '9' {
+		return 0, false
+	}
+	s64, err := strconv.ParseFloat(sec, 64)
+	if err != nil || s64 < 0 || s64 >= 60 {
+		return 0, false
+	}
+	return time.Duration(m)*time.Minute + time.Duration(s64*float64(time.Second)).Round(time.Millisecond), true
+}
+
+// stripWordTimes removes the <mm:ss.xx> word timings of enhanced LRC
+func stripWordTimes(s string) string {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(s, '<')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(s[start:], '>')
+		if end < 0 {
+			break
+		}
+		if _, ok := parseTime(s[start+1 : start+end]); !ok {
+			b.WriteString(s[:start+end+1])
+		} else {
+			b.WriteString(s[:start])
+		}
+		s = s[start+end+1:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
@@ -0,0 +1,354 @@
+// Package metrics exposes Prometheus metrics for jobs and ffmpeg runs
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/domain/ports"
+	"github.com/Skryldev/audio-lab/infrastructure/ffmpeg"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Options configures the collected metrics
+type Options struct {
+	// Namespace prefixes every metric name (default: "audiolab")
+	Namespace string
+
+	// JobLabels are job label keys (see ports.WithLabels) added as metric
+	// labels next to "profile". Keep them low-cardinality, e.g. "tenant".
+	JobLabels []string
+
+	// Profiles and JobLabelValues (by JobLabels key) list the values
+	// recorded as themselves; others are recorded as OtherValue. Without a
+	// list, the first MaxValues distinct values are kept. Empty values are
+	// always kept. Profiles and labels may come from network clients, so
+	// bound them to keep the number of series in check.
+	Profiles       []string
+	JobLabelValues map[string][]string
+
+	// MaxValues caps the distinct values of the profile and of each job
+	// label that has no list (default: 50)
+	MaxValues int
+}
+
+// OtherValue is recorded in place of profile and label values outside
+// Options.Profiles, Options.JobLabelValues or Options.MaxValues
+const OtherValue = "other"
+
+// Metrics records job and ffmpeg metrics. A nil *Metrics records nothing,
+// so callers don't need to check whether metrics are enabled.
+type Metrics struct {
+	jobLabels []string
+	values    []*valueSet // bounds the profile, then each job label
+
+	jobsStarted    *prometheus.CounterVec
+	jobsSucceeded  *prometheus.CounterVec
+	jobsFailed     *prometheus.CounterVec
+	jobsSkipped    *prometheus.CounterVec
+	encodeDuration *prometheus.HistogramVec
+	queueDepth     prometheus.Gauge
+	ffmpegExits    *prometheus.CounterVec
+	bytesIn        *prometheus.CounterVec
+	bytesOut       *prometheus.CounterVec
+}
+
+// New creates the metrics and registers them with reg. Collectors already
+// registered by another processor on the same registry are shared; New
+// fails if that processor's Namespace matches but its JobLabels differ.
+func New(reg prometheus.Registerer, opts Options) (*Metrics, error) {
+	ns := opts.Namespace
+	if ns == "" {
+		ns = "audiolab"
+	}
+	labels := append([]string{"profile"}, opts.JobLabels...)
+
+	maxValues := opts.MaxValues
+	if maxValues <= 0 {
+		maxValues = 50
+	}
+	values := []*valueSet{newValueSet(opts.Profiles, maxValues)}
+	for _, k := range opts.JobLabels {
+		values = append(values, newValueSet(opts.JobLabelValues[k], maxValues))
+	}
+
+	m := &Metrics{
+		jobLabels: opts.JobLabels,
+		values:    values,
+		jobsStarted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns, Name: "jobs_started_total",
+			Help: "Jobs that started processing.",
+		}, labels),
+		jobsSucceeded: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns, Name: "jobs_succeeded_total",
+			Help: "Jobs that finished successfully, excluding skipped ones.",
+		}, labels),
+		jobsFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns, Name: "jobs_failed_total",
+			Help: "Jobs that failed, by error code.",
+		}, append(append([]string(nil), labels...), "code")),
+		jobsSkipped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns, Name: "jobs_skipped_total",
+			Help: "Jobs skipped because their outputs were up to date.",
+		}, labels),
+		encodeDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns, Name: "encode_duration_seconds",
+			Help:    "Wall time of successful jobs.",
+			Buckets: prometheus.ExponentialBuckets(0.5, 2, 12), // 0.5s .. ~17m
+		}, labels),
+		queueDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: ns, Name: "queue_depth",
+			Help: "Batch jobs waiting for a worker.",
+		}),
+		ffmpegExits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns, Name: "ffmpeg_exits_total",
+			Help: "ffmpeg and ffprobe runs by exit code; -1 means the process did not exit normally.",
+		}, []string{"binary", "code"}),
+		bytesIn: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns, Name: "input_bytes_total",
+			Help: "Input bytes of successful jobs.",
+		}, labels),
+		bytesOut: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns, Name: "output_bytes_total",
+			Help: "Output bytes written by successful jobs, including additional outputs.",
+		}, labels),
+	}
+
+	var err error
+	register := func(c prometheus.Collector) prometheus.Collector {
+		if err != nil {
+			return c
+		}
+		var shared prometheus.Collector
+		shared, err = share(reg, c)
+		return shared
+	}
+	m.jobsStarted, _ = register(m.jobsStarted).(*prometheus.CounterVec)
+	m.jobsSucceeded, _ = register(m.jobsSucceeded).(*prometheus.CounterVec)
+	m.jobsFailed, _ = register(m.jobsFailed).(*prometheus.CounterVec)
+	m.jobsSkipped, _ = register(m.jobsSkipped).(*prometheus.CounterVec)
+	m.encodeDuration, _ = register(m.encodeDuration).(*prometheus.HistogramVec)
+	m.queueDepth, _ = register(m.queueDepth).(prometheus.Gauge)
+	m.ffmpegExits, _ = register(m.ffmpegExits).(*prometheus.CounterVec)
+	m.bytesIn, _ = register(m.bytesIn).(*prometheus.CounterVec)
+	m.bytesOut, _ = register(m.bytesOut).(*prometheus.CounterVec)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// share registers c with reg, returning the collector already registered
+// in its place if there is one. That collector must be of c's type and
+// have c's label names, which differ between processors with different
+// JobLabels.
+func share(reg prometheus.Registerer, c prometheus.Collector) (prometheus.Collector, error) {
+	err := reg.Register(c)
+	var are prometheus.AlreadyRegisteredError
+	if !errors.As(err, &are) {
+		return c, err
+	}
+	existing := are.ExistingCollector
+	if reflect.TypeOf(existing) != reflect.TypeOf(c) || !slices.Equal(descriptions(existing), descriptions(c)) {
+		return nil, fmt.Errorf("metrics: %s is already registered with different labels", descriptions(c)[0])
+	}
+	return existing, nil
+}
+
+// descriptions returns the descriptors of c as strings, covering metric
+// names, help and label names
+func descriptions(c prometheus.Collector) []string {
+	ch := make(chan *prometheus.Desc)
+	go func() {
+		c.Describe(ch)
+		close(ch)
+	}()
+	var out []string
+	for d := range ch {
+		out = append(out, d.String())
+	}
+	return out
+}
+
+// JobQueued counts a batch job waiting for a worker
+func (m *Metrics) JobQueued() {
+	if m == nil {
+		return
+	}
+	m.queueDepth.Inc()
+}
+
+// JobDequeued counts a batch job leaving the queue, started or not
+func (m *Metrics) JobDequeued() {
+	if m == nil {
+		return
+	}
+	m.queueDepth.Dec()
+}
+
+// JobStarted counts a job that starts processing
+func (m *Metrics) JobStarted(opts *model.ProcessingOptions) {
+	if m == nil {
+		return
+	}
+	m.jobsStarted.WithLabelValues(m.labelValues(opts)...).Inc()
+}
+
+// JobFinished records the outcome of a job started with JobStarted
+func (m *Metrics) JobFinished(opts *model.ProcessingOptions, result *model.ProcessingResult, err error, elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+	values := m.labelValues(opts)
+	switch {
+	case err != nil:
+		code := string(pkgerrors.CodeOf(err))
+		m.jobsFailed.WithLabelValues(append(values, code)...).Inc()
+	case result != nil && result.Skipped:
+		m.jobsSkipped.WithLabelValues(values...).Inc()
+	default:
+		m.jobsSucceeded.WithLabelValues(values...).Inc()
+		m.encodeDuration.WithLabelValues(values...).Observe(elapsed.Seconds())
+		if result != nil {
+			if result.InputMeta != nil {
+				m.bytesIn.WithLabelValues(values...).Add(float64(result.InputMeta.Size))
+			}
+			m.bytesOut.WithLabelValues(values...).Add(float64(outputBytes(result)))
+		}
+	}
+}
+
+// FFmpegExit records the exit code of an ffmpeg or ffprobe run from the
+// error it returned
+func (m *Metrics) FFmpegExit(binary string, err error) {
+	if m == nil {
+		return
+	}
+	code := 0
+	if err != nil {
+		code = -1
+		var ffErr *pkgerrors.FFmpegError
+		if errors.As(err, &ffErr) {
+			code = ffErr.ExitCode
+		}
+	}
+	m.ffmpegExits.WithLabelValues(binary, strconv.Itoa(code)).Inc()
+}
+
+// InstrumentExecutor wraps e to record the exit code of every run. A nil
+// m returns e unchanged.
+func (m *Metrics) InstrumentExecutor(e ports.FFmpegExecutor) ports.FFmpegExecutor {
+	if m == nil {
+		return e
+	}
+	return &executor{PipedExecutor: ffmpeg.Piped(e), m: m}
+}
+
+func (m *Metrics) labelValues(opts *model.ProcessingOptions) []string {
+	values := make([]string, 0, 1+len(m.jobLabels))
+	if opts == nil {
+		opts = &model.ProcessingOptions{}
+	}
+	values = append(values, m.values[0].bound(opts.Profile))
+	for i, k := range m.jobLabels {
+		values = append(values, m.values[i+1].bound(opts.Labels[k]))
+	}
+	return values
+}
+
+// valueSet bounds the values of one metric label to an allowlist or, if
+// none is given, to the first max values seen
+type valueSet struct {
+	allowed map[string]bool
+	fixed   bool
+
+	mu  sync.Mutex
+	max int
+}
+
+func newValueSet(allowed []string, max int) *valueSet {
+	s := &valueSet{allowed: make(map[string]bool), fixed: allowed != nil, max: max}
+	for _, v := range allowed {
+		s.allowed[v] = true
+	}
+	return s
+}
+
+// bound returns v if it is allowed, admitting it while there is room, or
+// OtherValue
+func (s *valueSet) bound(v string) string {
+	if v == "" {
+		return v
+	}
+	if s.fixed {
+		if s.allowed[v] {
+			return v
+		}
+		return OtherValue
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.allowed[v] {
+		if len(s.allowed) >= s.max {
+			return OtherValue
+		}
+		s.allowed[v] = true
+	}
+	return v
+}
+
+// outputBytes sums the output sizes of result and its additional outputs
+func outputBytes(result *model.ProcessingResult) int64 {
+	var n int64
+	if result.OutputMeta != nil {
+		n += result.OutputMeta.Size
+	}
+	for _, extra := range result.AdditionalOutputs {
+		n += outputBytes(extra)
+	}
+	return n
+}
+
+// executor records exit codes of the wrapped executor's runs
+type executor struct {
+	ports.PipedExecutor
+	m *Metrics
+}
+
+func (e *executor) Execute(ctx context.Context, args []string) error {
+	err := e.PipedExecutor.Execute(ctx, args)
+	e.m.FFmpegExit("ffmpeg", err)
+	return err
+}
+
+func (e *executor) ExecuteWithOutput(ctx context.Context, args []string) ([]byte, []byte, error) {
+	stdout, stderr, err := e.PipedExecutor.ExecuteWithOutput(ctx, args)
+	e.m.FFmpegExit("ffmpeg", err)
+	return stdout, stderr, err
+}
+
+func (e *executor) ExecutePiped(ctx context.Context, args []string, r io.Reader, w io.Writer) ([]byte, error) {
+	stderr, err := e.PipedExecutor.ExecutePiped(ctx, args, r, w)
+	e.m.FFmpegExit("ffmpeg", err)
+	return stderr, err
+}
+
+func (e *executor) Probe(ctx context.Context, inputPath string) ([]byte, error) {
+	out, err := e.PipedExecutor.Probe(ctx, inputPath)
+	e.m.FFmpegExit("ffprobe", err)
+	return out, err
+}
+
+// Unwrap returns the wrapped executor
+func (e *executor) Unwrap() ports.FFmpegExecutor {
+	return e.PipedExecutor
+}
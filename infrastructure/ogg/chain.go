@@ -0,0 +1,216 @@
+// Package ogg scans Ogg physical bitstreams for chained (concatenated)
+// logical streams, as found in internet radio rips
+package ogg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	pageHeaderSize = 27
+
+	flagBOS = 0x02 // first page of a logical stream
+)
+
+var capturePattern = []byte("OggS")
+
+// ErrNotOgg is returned by ScanChains for data that doesn't start with an
+// Ogg page
+var ErrNotOgg = errors.New("not an ogg stream")
+
+// Chain is one link of a chained Ogg file: a complete, independently
+// playable Ogg stream occupying [Offset, Offset+Size) of the file
+type Chain struct {
+	Index      int
+	Offset     int64
+	Size       int64
+	Codec      string // "opus", "vorbis", "flac", "speex" or "unknown"
+	SampleRate int
+	Channels   int
+	Duration   time.Duration
+}
+
+// logical tracks one logical stream within a chain
+type logical struct {
+	codec       string
+	rate        int // granule position units per second
+	channels    int
+	preSkip     int64 // Opus pre-skip in granule units
+	lastGranule int64
+}
+
+// ScanChains reads an Ogg file page by page and returns its chains. Page
+// bodies are skipped with Seek when r supports it.
+func ScanChains(r io.Reader) ([]Chain, error) {
+	var (
+		chains  []Chain
+		streams map[uint32]*logical
+		order   []uint32
+		offset  int64
+		inBOS   bool
+		header  [pageHeaderSize]byte
+	)
+
+	finish := func(end int64) {
+		if len(chains) == 0 {
+			return
+		}
+		c := &chains[len(chains)-1]
+		c.Size = end - c.Offset
+		for _, serial := range order {
+			s := streams[serial]
+			if c.Codec == "" || c.Codec == "unknown" {
+				c.Codec, c.SampleRate, c.Channels = s.codec, s.rate, s.channels
+			}
+			if s.rate > 0 && s.lastGranule > s.preSkip {
+				d := time.Duration(float64(s.lastGranule-s.preSkip) / float64(s.rate) * float64(time.Second))
+				if d > c.Duration {
+					c.Duration = d
+				}
+			}
+		}
+	}
+
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if offset == 0 {
+				return nil, ErrNotOgg
+			}
+			return nil, fmt.Errorf("ogg page at offset %d: %w", offset, err)
+		}
+		if !bytes.Equal(header[:4], capturePattern) {
+			if offset == 0 {
+				return nil, ErrNotOgg
+			}
+			return nil, fmt.Errorf("ogg page at offset %d: missing capture pattern", offset)
+		}
+
+		flags := header[5]
+		granule := int64(binary.LittleEndian.Uint64(header[6:14]))
+		serial := binary.LittleEndian.Uint32(header[14:18])
+
+		segments := make([]byte, header[26])
+		if _, err := io.ReadFull(r, segments); err != nil {
+			return nil, fmt.Errorf("ogg segment table at offset %d: %w", offset, err)
+		}
+		var bodySize int64
+		for _, s := range segments {
+			bodySize += int64(s)
+		}
+
+		if flags&flagBOS != 0 {
+			if !inBOS {
+				// A BOS page after data pages starts a new chain
+				finish(offset)
+				chains = append(chains, Chain{Index: len(chains), Offset: offset})
+				streams = make(map[uint32]*logical)
+				order = nil
+				inBOS = true
+			}
+			body := make([]byte, bodySize)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return nil, fmt.Errorf("ogg page body at offset %d: %w", offset, err)
+			}
+			streams[serial] = identify(body)
+			order = append(order, serial)
+		} else {
+			inBOS = false
+			if err := skip(r, bodySize); err != nil {
+				return nil, fmt.Errorf("ogg page body at offset %d: %w", offset, err)
+			}
+			// -1 means no packet completes on this page
+			if s, ok := streams[serial]; ok && granule != -1 {
+				s.lastGranule = granule
+			}
+		}
+
+		offset += pageHeaderSize + int64(len(segments)) + bodySize
+	}
+
+	finish(offset)
+	if len(chains) == 0 {
+		return nil, ErrNotOgg
+	}
+	return chains, nil
+}
+
+// Split copies each chain of r into the writer returned by next, in order.
+// chains must come from ScanChains over the same data.
+func Split(r io.Reader, chains []Chain, next func(Chain) (io.WriteCloser, error)) error {
+	var pos int64
+	for _, c := range chains {
+		if err := skip(r, c.Offset-pos); err != nil {
+			return err
+		}
+		w, err := next(c)
+		if err != nil {
+			return err
+		}
+		_, err = io.CopyN(w, r, c.Size)
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("copy ogg chain %d: %w", c.Index, err)
+		}
+		pos = c.Offset + c.Size
+	}
+	return nil
+}
+
+// skip advances r by n bytes, seeking when possible
+func skip(r io.Reader, n int64) error {
+	if n <= 0 {
+		return nil
+	}
+	if s, ok := r.(io.Seeker); ok {
+		_, err := s.Seek(n, io.SeekCurrent)
+		return err
+	}
+	_, err := io.CopyN(io.Discard, r, n)
+	return err
+}
+
+// identify reads codec parameters from a logical stream's first packet
+func identify(p []byte) *logical {
+	switch {
+	case len(p) >= 19 && bytes.HasPrefix(p, []byte("OpusHead")):
+		// Opus granule positions always count 48 kHz samples
+		return &logical{
+			codec:    "opus",
+			rate:     48000,
+			channels: int(p[9]),
+			preSkip:  int64(binary.LittleEndian.Uint16(p[10:12])),
+		}
+	case len(p) >= 16 && bytes.HasPrefix(p, []byte("\x01vorbis")):
+		return &logical{
+			codec:    "vorbis",
+			rate:     int(binary.LittleEndian.Uint32(p[12:16])),
+			channels: int(p[11]),
+		}
+	case len(p) >= 30 && bytes.HasPrefix(p, []byte("\x7fFLAC")):
+		// STREAMINFO follows the 13-byte mapping header and 4-byte block header
+		info := p[17:]
+		return &logical{
+			codec:    "flac",
+			rate:     int(info[10])<<12 | int(info[11])<<4 | int(info[12])>>4,
+			channels: int(info[12]>>1&0x07) + 1,
+		}
+	case len(p) >= 52 && bytes.HasPrefix(p, []byte("Speex   ")):
+		return &logical{
+			codec:    "speex",
+			rate:     int(binary.LittleEndian.Uint32(p[36:40])),
+			channels: int(binary.LittleEndian.Uint32(p[48:52])),
+		}
+	default:
+		return &logical{codec: "unknown"}
+	}
+}
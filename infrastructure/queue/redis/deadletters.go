@@ -0,0 +1,71 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// DeadLetters is a ports.DeadLetterStore kept in a Redis hash next to the
+// queue's keys, so failed jobs survive restarts and are visible to every
+// worker
+type DeadLetters struct {
+	client goredis.UniversalClient
+	key    string
+}
+
+// NewDeadLetters creates a dead-letter store on client, namespaced by
+// cfg.Prefix like the queue
+func NewDeadLetters(client goredis.UniversalClient, cfg Config) (*DeadLetters, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client is required")
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = "audiolab"
+	}
+	return &DeadLetters{client: client, key: "{" + cfg.Prefix + "}:queue:dead"}, nil
+}
+
+// Add records a failed job, replacing an earlier letter with the same ID
+func (d *DeadLetters) Add(ctx context.Context, letter model.DeadLetter) error {
+	payload, err := json.Marshal(letter)
+	if err != nil {
+		return fmt.Errorf("failed to encode dead letter %s: %w", letter.Job.ID, err)
+	}
+	if err := d.client.HSet(ctx, d.key, letter.Job.ID, payload).Err(); err != nil {
+		return fmt.Errorf("failed to store dead letter %s: %w", letter.Job.ID, err)
+	}
+	return nil
+}
+
+// List returns the recorded jobs, oldest failure first. Letters that can't
+// be decoded are skipped.
+func (d *DeadLetters) List(ctx context.Context) ([]model.DeadLetter, error) {
+	payloads, err := d.client.HGetAll(ctx, d.key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	out := make([]model.DeadLetter, 0, len(payloads))
+	for id, payload := range payloads {
+		var l model.DeadLetter
+		if json.Unmarshal([]byte(payload), &l) != nil {
+			continue
+		}
+		l.Job.ID = id
+		out = append(out, l)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].FailedAt.Before(out[j].FailedAt) })
+	return out, nil
+}
+
+// Remove forgets the job with the given ID
+func (d *DeadLetters) Remove(ctx context.Context, jobID string) error {
+	if err := d.client.HDel(ctx, d.key, jobID).Err(); err != nil {
+		return fmt.Errorf("failed to remove dead letter %s: %w", jobID, err)
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+// Package redis implements ports.JobQueue on Redis, so several machines can
+// pull jobs from one shared queue
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// ErrReservationLost is returned by Ack and Extend when the job's
+// visibility timeout expired and it was (or will be) handed out again
+var ErrReservationLost = errors.New("job reservation lost")
+
+// Config holds Redis queue configuration
+type Config struct {
+	// Prefix namespaces the queue's keys (default: "audiolab"). It is
+	// wrapped in a hash tag so all keys land in one Redis Cluster slot.
+	Prefix string
+
+	// VisibilityTimeout is how long a dequeued job stays reserved before it
+	// is redelivered (default: 5m)
+	VisibilityTimeout time.Duration
+
+	// PollInterval is the wait between reservation attempts while the queue
+	// is empty (default: 1s)
+	PollInterval time.Duration
+}
+
+// Queue is a ports.JobQueue backed by a Redis list of pending job IDs, a
+// hash of job payloads and a sorted set of reservation deadlines
+type Queue struct {
+	client goredis.UniversalClient
+	cfg    Config
+	keys   []string // pending, jobs, inflight, receipts, deliveries
+}
+
+// New creates a queue on client
+func New(client goredis.UniversalClient, cfg Config) (*Queue, error) {
+	if client == nil {
+		return nil, fmt.Errorf("redis client is required")
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = "audiolab"
+	}
+	if cfg.VisibilityTimeout <= 0 {
+		cfg.VisibilityTimeout = 5 * time.Minute
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+
+	p := "{" + cfg.Prefix + "}:queue:"
+	return &Queue{
+		client: client,
+		cfg:    cfg,
+		keys:   []string{p + "pending", p + "jobs", p + "inflight", p + "receipts", p + "deliveries"},
+	}, nil
+}
+
+// VisibilityTimeout returns the reservation period of dequeued jobs
+func (q *Queue) VisibilityTimeout() time.Duration {
+	return q.cfg.VisibilityTimeout
+}
+
+// Enqueue adds job to the tail of the queue. Jobs without an ID get a
+// random one. IDs should be unique: re-enqueueing an ID that is still
+// queued replaces its payload and may deliver it twice.
+func (q *Queue) Enqueue(ctx context.Context, job model.BatchJob) error {
+	if job.Options != nil {
+		for i, out := range job.Options.AdditionalOutputs {
+			if out.Configure != nil {
+				return pkgerrors.NewValidationError(fmt.Sprintf("additionalOutputs[%d].configure", i), out.Path, "queued jobs can't carry Configure funcs")
+			}
+		}
+	}
+	if job.ID == "" {
+		job.ID = newToken("job-")
+	}
+
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return pkgerrors.NewValidationError("job", job.ID, "failed to encode job: "+err.Error())
+	}
+
+	_, err = q.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.HSet(ctx, q.keys[1], job.ID, payload)
+		pipe.RPush(ctx, q.keys[0], job.ID)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to enqueue job %s: %w", job.ID, err)
+	}
+	return nil
+}
+
+// Dequeue reserves the next job, polling while the queue is empty. Jobs
+// whose reservation expired are requeued at the head first. A job whose
+// payload can't be decoded is returned with a ValidationError, so that it
+// can be acknowledged and dropped.
+func (q *Queue) Dequeue(ctx context.Context) (*model.QueuedJob, error) {
+	for {
+		job, err := q.reserve(ctx)
+		if err != nil || job != nil {
+			return job, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(q.cfg.PollInterval):
+		}
+	}
+}
+
+func (q *Queue) reserve(ctx context.Context) (*model.QueuedJob, error) {
+	receipt := newToken("")
+	res, err := reserveScript.Run(ctx, q.client, q.keys, q.cfg.VisibilityTimeout.Milliseconds(), receipt).Slice()
+	if errors.Is(err, goredis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve job: %w", err)
+	}
+	if len(res) != 3 {
+		return nil, fmt.Errorf("unexpected reserve reply: %v", res)
+	}
+
+	id, _ := res[0].(string)
+	payload, _ := res[1].(string)
+	deliveries, _ := res[2].(int64)
+
+	queued := &model.QueuedJob{Receipt: receipt, Deliveries: int(deliveries)}
+	if err := json.Unmarshal([]byte(payload), &queued.Job); err != nil {
+		queued.Job = model.BatchJob{ID: id}
+		return queued, pkgerrors.NewValidationError("job", id, "failed to decode job: "+err.Error())
+	}
+	queued.Job.ID = id
+	return queued, nil
+}
+
+// Ack removes a finished job from the queue
+func (q *Queue) Ack(ctx context.Context, job *model.QueuedJob) error {
+	return q.settle(ctx, ackScript, job)
+}
+
+// Extend pushes the job's reservation deadline one VisibilityTimeout out
+func (q *Queue) Extend(ctx context.Context, job *model.QueuedJob) error {
+	return q.settle(ctx, extendScript, job)
+}
+
+func (q *Queue) settle(ctx context.Context, script *goredis.Script, job *model.QueuedJob) error {
+	ok, err := script.Run(ctx, q.client, q.keys, job.Job.ID, job.Receipt, q.cfg.VisibilityTimeout.Milliseconds()).Int()
+	if err != nil {
+		return fmt.Errorf("failed to update job %s: %w", job.Job.ID, err)
+	}
+	if ok == 0 {
+		return ErrReservationLost
+	}
+	return nil
+}
+
+// Len returns the number of jobs waiting and the number reserved
+func (q *Queue) Len(ctx context.Context) (pending, reserved int64, err error) {
+	cmds, err := q.client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.LLen(ctx, q.keys[0])
+		pipe.ZCard(ctx, q.keys[2])
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return cmds[0].(*goredis.IntCmd).Val(), cmds[1].(*goredis.IntCmd).Val(), nil
+}
+
+func newToken(prefix string) string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return prefix + hex.EncodeToString(b)
+}
+
+// KEYS: pending, jobs, inflight, receipts, deliveries
+// ARGV: visibility timeout (ms), receipt
+var reserveScript = goredis.NewScript(`
+local t = redis.call('TIME')
+local now = t[1] * 1000 + math.floor(t[2] / 1000)
+
+local expired = redis.call('ZRANGEBYSCORE', KEYS[3], '-inf', now)
+for _, id in ipairs(expired) do
+	redis.call('ZREM', KEYS[3], id)
+	redis.call('HDEL', KEYS[4], id)
+	redis.call('LPUSH', KEYS[1], id)
+end
+
+while true do
+	local id = redis.call('LPOP', KEYS[1])
+	if not id then
+		return false
+	end
+	local payload = redis.call('HGET', KEYS[2], id)
+	if payload then
+		redis.call('ZADD', KEYS[3], now + tonumber(ARGV[1]), id)
+		redis.call('HSET', KEYS[4], id, ARGV[2])
+		local n = redis.call('HINCRBY', KEYS[5], id, 1)
+		return {id, payload, n}
+	end
+end
+`)
+
+// KEYS: pending, jobs, inflight, receipts, deliveries
+// ARGV: id, receipt
+var ackScript = goredis.NewScript(`
+if redis.call('HGET', KEYS[4], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+redis.call('ZREM', KEYS[3], ARGV[1])
+redis.call('HDEL', KEYS[4], ARGV[1])
+redis.call('HDEL', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[5], ARGV[1])
+return 1
+`)
+
+// KEYS: pending, jobs, inflight, receipts, deliveries
+// ARGV: id, receipt, visibility timeout (ms)
+var extendScript = goredis.NewScript(`
+if redis.call('HGET', KEYS[4], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+local t = redis.call('TIME')
+local now = t[1] * 1000 + math.floor(t[2] / 1000)
+redis.call('ZADD', KEYS[3], 'XX', now + tonumber(ARGV[3]), ARGV[1])
+return 1
+`)
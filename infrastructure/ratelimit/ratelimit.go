@@ -0,0 +1,218 @@
+// Package ratelimit bounds the resources the library consumes: how many
+// jobs start per minute and how many ffmpeg processes run at once. One
+// Limiter can be shared by several processors in the same process.
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/ports"
+	"github.com/Skryldev/audio-lab/infrastructure/ffmpeg"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+)
+
+// Config configures a Limiter. Zero fields are unlimited.
+type Config struct {
+	// JobsPerMinute caps how fast jobs start
+	JobsPerMinute float64
+
+	// Burst is how many jobs may start at once after an idle period
+	// before JobsPerMinute applies (default: 1)
+	Burst int
+
+	// MaxConcurrentFFmpeg caps the ffmpeg and ffprobe processes running
+	// at once
+	MaxConcurrentFFmpeg int
+}
+
+// Limiter enforces a Config. It is an AdmissionGovernor for the job rate
+// and wraps executors with LimitExecutor for the process cap.
+type Limiter struct {
+	interval time.Duration // between job starts; 0 is unlimited
+	burst    int
+	procs    chan struct{} // nil is unlimited
+	groupMu  sync.Mutex    // held while a group takes its slots
+
+	mu  sync.Mutex
+	tat time.Time // theoretical arrival time of the next job
+}
+
+// New creates a limiter
+func New(cfg Config) (*Limiter, error) {
+	if cfg.JobsPerMinute < 0 {
+		return nil, pkgerrors.NewValidationError("jobsPerMinute", cfg.JobsPerMinute, "rate must not be negative")
+	}
+	if cfg.MaxConcurrentFFmpeg < 0 {
+		return nil, pkgerrors.NewValidationError("maxConcurrentFFmpeg", cfg.MaxConcurrentFFmpeg, "limit must not be negative")
+	}
+	if cfg.Burst <= 0 {
+		cfg.Burst = 1
+	}
+
+	l := &Limiter{burst: cfg.Burst}
+	if cfg.JobsPerMinute > 0 {
+		l.interval = time.Duration(float64(time.Minute) / cfg.JobsPerMinute)
+	}
+	if cfg.MaxConcurrentFFmpeg > 0 {
+		l.procs = make(chan struct{}, cfg.MaxConcurrentFFmpeg)
+	}
+	return l, nil
+}
+
+// Admit blocks until the job rate allows another job to start,
+// implementing ports.AdmissionGovernor
+func (l *Limiter) Admit(ctx context.Context, jobID string) error {
+	if l.interval == 0 {
+		return nil
+	}
+
+	// Reserve the next start time, then wait for it
+	l.mu.Lock()
+	now := time.Now()
+	if l.tat.Before(now) {
+		l.tat = now
+	}
+	wait := l.tat.Add(-time.Duration(l.burst-1) * l.interval).Sub(now)
+	l.tat = l.tat.Add(l.interval)
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// Hand the reservation back
+		l.mu.Lock()
+		l.tat = l.tat.Add(-l.interval)
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// reservedKey marks a context whose processes run on slots reserved with
+// ReserveProcesses
+type reservedKey struct{ l *Limiter }
+
+// acquire takes a process slot, blocking while all are in use, and
+// returns the func that frees it
+func (l *Limiter) acquire(ctx context.Context) (func(), error) {
+	if l.procs == nil || ctx.Value(reservedKey{l}) != nil {
+		return func() {}, nil
+	}
+	select {
+	case l.procs <- struct{}{}:
+		return l.release, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// acquireN takes n slots, or every slot when n exceeds the cap. Groups take
+// their slots one at a time under groupMu, so two groups never each hold
+// part of what they need.
+func (l *Limiter) acquireN(ctx context.Context, n int) (int, error) {
+	if l.procs == nil {
+		return 0, nil
+	}
+	n = min(n, cap(l.procs))
+
+	l.groupMu.Lock()
+	defer l.groupMu.Unlock()
+	for i := 0; i < n; i++ {
+		select {
+		case l.procs <- struct{}{}:
+		case <-ctx.Done():
+			for ; i > 0; i-- {
+				l.release()
+			}
+			return 0, ctx.Err()
+		}
+	}
+	return n, nil
+}
+
+func (l *Limiter) release() {
+	if l.procs != nil {
+		<-l.procs
+	}
+}
+
+// Running returns the number of ffmpeg and ffprobe processes holding a slot
+func (l *Limiter) Running() int {
+	return len(l.procs)
+}
+
+// LimitExecutor wraps e so that its invocations count against the
+// process cap, waiting for a free slot first
+func (l *Limiter) LimitExecutor(e ports.FFmpegExecutor) ports.FFmpegExecutor {
+	return &executor{PipedExecutor: ffmpeg.Piped(e), limiter: l}
+}
+
+type executor struct {
+	ports.PipedExecutor
+	limiter *Limiter
+}
+
+func (e *executor) Execute(ctx context.Context, args []string) error {
+	release, err := e.limiter.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return e.PipedExecutor.Execute(ctx, args)
+}
+
+func (e *executor) ExecuteWithOutput(ctx context.Context, args []string) ([]byte, []byte, error) {
+	release, err := e.limiter.acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+	return e.PipedExecutor.ExecuteWithOutput(ctx, args)
+}
+
+func (e *executor) ExecutePiped(ctx context.Context, args []string, r io.Reader, w io.Writer) ([]byte, error) {
+	release, err := e.limiter.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return e.PipedExecutor.ExecutePiped(ctx, args, r, w)
+}
+
+func (e *executor) Probe(ctx context.Context, inputPath string) ([]byte, error) {
+	release, err := e.limiter.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return e.PipedExecutor.Probe(ctx, inputPath)
+}
+
+// ReserveProcesses takes n slots for processes that must run together,
+// such as piped stages that would deadlock if one waited for a slot held
+// by another. A group larger than the cap takes every slot.
+func (e *executor) ReserveProcesses(ctx context.Context, n int) (context.Context, func(), error) {
+	n, err := e.limiter.acquireN(ctx, n)
+	if err != nil {
+		return nil, nil, err
+	}
+	release := func() {
+		for i := 0; i < n; i++ {
+			e.limiter.release()
+		}
+	}
+	return context.WithValue(ctx, reservedKey{e.limiter}, true), release, nil
+}
+
+// Unwrap returns the wrapped executor
+func (e *executor) Unwrap() ports.FFmpegExecutor {
+	return e.PipedExecutor
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+)
+
+// MemoryDeadLetters implements ports.DeadLetterStore in memory; letters are
+// lost when the process exits
+type MemoryDeadLetters struct {
+	mu      sync.Mutex
+	letters map[string]model.DeadLetter
+}
+
+// NewMemoryDeadLetters creates an empty in-memory dead-letter store
+func NewMemoryDeadLetters() *MemoryDeadLetters {
+	return &MemoryDeadLetters{letters: make(map[string]model.DeadLetter)}
+}
+
+// Add records a failed job, replacing an earlier letter with the same ID
+func (s *MemoryDeadLetters) Add(_ context.Context, letter model.DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.letters[letter.Job.ID] = letter
+	return nil
+}
+
+// List returns the recorded jobs, oldest failure first
+func (s *MemoryDeadLetters) List(_ context.Context) ([]model.DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]model.DeadLetter, 0, len(s.letters))
+	for _, l := range s.letters {
+		out = append(out, l)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].FailedAt.Before(out[j].FailedAt) })
+	return out, nil
+}
+
+// Remove forgets the job with the given ID
+func (s *MemoryDeadLetters) Remove(_ context.Context, jobID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.letters, jobID)
+	return nil
+}
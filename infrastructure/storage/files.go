@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/Skryldev/audio-lab/domain/ports"
+)
+
+// Files returns p as a ports.FileStorage. A provider implementing only
+// ports.StorageProvider is wrapped so that the operations it lacks use
+// the local filesystem.
+func Files(p ports.StorageProvider) ports.FileStorage {
+	if f, ok := p.(ports.FileStorage); ok {
+		return f
+	}
+	return &localFiles{StorageProvider: p, FileStorage: NewLocalStorage()}
+}
+
+// localFiles completes a plain provider with LocalStorage. Only the
+// ports.FileStorage methods are taken from it, so optional interfaces such
+// as ports.SpaceReporter stay those of the provider, found with Unwrap.
+type localFiles struct {
+	ports.StorageProvider
+	ports.FileStorage
+}
+
+func (s *localFiles) Exists(ctx context.Context, path string) (bool, error) {
+	return s.StorageProvider.Exists(ctx, path)
+}
+
+func (s *localFiles) Size(ctx context.Context, path string) (int64, error) {
+	return s.StorageProvider.Size(ctx, path)
+}
+
+func (s *localFiles) Remove(ctx context.Context, path string) error {
+	return s.StorageProvider.Remove(ctx, path)
+}
+
+func (s *localFiles) TempFile(ctx context.Context, dir, pattern string) (string, error) {
+	return s.StorageProvider.TempFile(ctx, dir, pattern)
+}
+
+// Unwrap returns the wrapped provider
+func (s *localFiles) Unwrap() ports.StorageProvider {
+	return s.StorageProvider
+}
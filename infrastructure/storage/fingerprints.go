@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FingerprintFile implements ports.FingerprintStore as an append-only
+// JSON-lines file. The latest line for an output wins; the file is
+// compacted when it is opened.
+type FingerprintFile struct {
+	mu      sync.Mutex
+	f       *os.File
+	entries map[string]string
+}
+
+type fingerprintLine struct {
+	Output      string `json:"output"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// OpenFingerprintFile loads the store at path, creating it if needed
+func OpenFingerprintFile(path string) (*FingerprintFile, error) {
+	entries := make(map[string]string)
+	lines := 0
+	if f, err := os.Open(path); err == nil {
+		sc := bufio.NewScanner(f)
+		sc.Buffer(make([]byte, 64*1024), 1024*1024)
+		for sc.Scan() {
+			var l fingerprintLine
+			if json.Unmarshal(sc.Bytes(), &l) != nil {
+				continue // torn write from a crash
+			}
+			entries[l.Output] = l.Fingerprint
+			lines++
+		}
+		f.Close()
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if lines > len(entries) {
+		if err := compactFingerprints(path, entries); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FingerprintFile{f: f, entries: entries}, nil
+}
+
+// Get returns the fingerprint recorded for output, if any
+func (s *FingerprintFile) Get(_ context.Context, output string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fp, ok := s.entries[output]
+	return fp, ok, nil
+}
+
+// Put records the fingerprint output was produced with
+func (s *FingerprintFile) Put(_ context.Context, output, fingerprint string) error {
+	line, err := json.Marshal(fingerprintLine{Output: output, Fingerprint: fingerprint})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	s.entries[output] = fingerprint
+	return nil
+}
+
+// Close closes the underlying file
+func (s *FingerprintFile) Close() error {
+	return s.f.Close()
+}
+
+// compactFingerprints rewrites path with one line per output
+func compactFingerprints(path string, entries map[string]string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp.*")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for output, fp := range entries {
+		if err := enc.Encode(fingerprintLine{Output: output, Fingerprint: fp}); err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
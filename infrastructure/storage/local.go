@@ -2,11 +2,17 @@ package storage
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
 	"os"
 	"path/filepath"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/model"
 )
 
-// LocalStorage implements ports.StorageProvider for local filesystem
+// LocalStorage implements ports.FileStorage for local filesystem
 type LocalStorage struct{}
 
 // NewLocalStorage creates a new local storage provider
@@ -54,4 +60,101 @@ func (s *LocalStorage) TempFile(_ context.Context, dir, pattern string) (string,
 	}
 	defer f.Close()
 	return filepath.Abs(f.Name())
-}
\ No newline at end of file
+}
+
+// List returns the paths of the regular files directly inside dir
+func (s *LocalStorage) List(_ context.Context, dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.Type().IsRegular() {
+			paths = append(paths, filepath.Join(dir, e.Name()))
+		}
+	}
+	return paths, nil
+}
+
+// ListDirs returns the paths of the subdirectories directly inside dir
+func (s *LocalStorage) ListDirs(_ context.Context, dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() {
+			paths = append(paths, filepath.Join(dir, e.Name()))
+		}
+	}
+	return paths, nil
+}
+
+// Open opens a file for reading. The returned *os.File also implements
+// io.Seeker.
+func (s *LocalStorage) Open(_ context.Context, path string) (io.ReadCloser, error) {
+	return os.Open(path)
+}
+
+// Create creates or truncates a file for writing
+func (s *LocalStorage) Create(_ context.Context, path string) (io.WriteCloser, error) {
+	return os.Create(path)
+}
+
+// Rename moves oldPath to newPath, replacing newPath if it exists
+func (s *LocalStorage) Rename(_ context.Context, oldPath, newPath string) error {
+	return os.Rename(oldPath, newPath)
+}
+
+// Link makes newPath a hard link to oldPath, replacing newPath if it exists
+func (s *LocalStorage) Link(_ context.Context, oldPath, newPath string) error {
+	if err := os.Remove(newPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Link(oldPath, newPath)
+}
+
+// ModTime returns the last modification time of a file
+func (s *LocalStorage) ModTime(_ context.Context, path string) (time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// MkdirAll creates dir and any missing parents with the given mode
+func (s *LocalStorage) MkdirAll(_ context.Context, dir string, perm os.FileMode) error {
+	return os.MkdirAll(dir, perm)
+}
+
+// SetPermissions changes the mode (when non-zero) and owner (when non-nil)
+// of path
+func (s *LocalStorage) SetPermissions(_ context.Context, path string, perm os.FileMode, owner *model.FileOwner) error {
+	if perm != 0 {
+		if err := os.Chmod(path, perm); err != nil {
+			return err
+		}
+	}
+	if owner != nil {
+		return os.Chown(path, owner.UID, owner.GID)
+	}
+	return nil
+}
+
+// Checksum returns the hex-encoded SHA-256 of a file
+func (s *LocalStorage) Checksum(_ context.Context, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/model"
+	"github.com/Skryldev/audio-lab/domain/ports"
+)
+
+// Router implements ports.FileStorage by dispatching each path to the
+// provider registered for its URI scheme (s3://, gs://, http:// ...).
+// file:// URIs and plain paths go to the fallback provider with the scheme
+// stripped; other paths are passed through unchanged.
+type Router struct {
+	mu        sync.RWMutex
+	providers map[string]ports.StorageProvider
+	fallback  ports.StorageProvider
+}
+
+// NewRouter creates a router; fallback defaults to LocalStorage
+func NewRouter(fallback ports.StorageProvider) *Router {
+	if fallback == nil {
+		fallback = NewLocalStorage()
+	}
+	return &Router{
+		providers: make(map[string]ports.StorageProvider),
+		fallback:  fallback,
+	}
+}
+
+// Register routes paths with the given scheme (without "://") to provider
+func (r *Router) Register(scheme string, provider ports.StorageProvider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[strings.ToLower(scheme)] = provider
+}
+
+// Route returns the provider for path and the path to hand it
+func (r *Router) Route(path string) (ports.StorageProvider, string, error) {
+	scheme, ok := Scheme(path)
+	if !ok {
+		return r.fallback, path, nil
+	}
+
+	r.mu.RLock()
+	provider, found := r.providers[scheme]
+	r.mu.RUnlock()
+	if found {
+		return provider, path, nil
+	}
+	if scheme == "file" {
+		return r.fallback, strings.TrimPrefix(path[len(scheme):], "://"), nil
+	}
+	return nil, "", fmt.Errorf("no storage provider registered for scheme %q", scheme)
+}
+
+// Scheme returns the lower-cased URI scheme of path, if it has one
+func Scheme(path string) (string, bool) {
+	i := strings.Index(path, "://")
+	if i <= 0 {
+		return "", false
+	}
+	for _, c := range path[:i] {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '+' || c == '-' || c == '.') {
+			return "", false
+		}
+	}
+	return strings.ToLower(path[:i]), true
+}
+
+// Exists checks if a file exists
+func (r *Router) Exists(ctx context.Context, path string) (bool, error) {
+	p, path, err := r.Route(path)
+	if err != nil {
+		return false, err
+	}
+	return p.Exists(ctx, path)
+}
+
+// Size returns file size in bytes
+func (r *Router) Size(ctx context.Context, path string) (int64, error) {
+	p, path, err := r.Route(path)
+	if err != nil {
+		return 0, err
+	}
+	return p.Size(ctx, path)
+}
+
+// Remove deletes a file
+func (r *Router) Remove(ctx context.Context, path string) error {
+	p, path, err := r.Route(path)
+	if err != nil {
+		return err
+	}
+	return p.Remove(ctx, path)
+}
+
+// TempFile creates a temporary file in dir, or in the fallback provider's
+// temp location when dir is empty
+func (r *Router) TempFile(ctx context.Context, dir, pattern string) (string, error) {
+	if dir == "" {
+		return r.fallback.TempFile(ctx, dir, pattern)
+	}
+	p, dir, err := r.Route(dir)
+	if err != nil {
+		return "", err
+	}
+	return p.TempFile(ctx, dir, pattern)
+}
+
+// List returns the paths of the regular files directly inside dir
+func (r *Router) List(ctx context.Context, dir string) ([]string, error) {
+	p, dir, err := r.Route(dir)
+	if err != nil {
+		return nil, err
+	}
+	return Files(p).List(ctx, dir)
+}
+
+// ListDirs returns the paths of the subdirectories directly inside dir
+func (r *Router) ListDirs(ctx context.Context, dir string) ([]string, error) {
+	p, dir, err := r.Route(dir)
+	if err != nil {
+		return nil, err
+	}
+	return Files(p).ListDirs(ctx, dir)
+}
+
+// Open opens a file for reading
+func (r *Router) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	p, path, err := r.Route(path)
+	if err != nil {
+		return nil, err
+	}
+	return Files(p).Open(ctx, path)
+}
+
+// Create creates or truncates a file for writing
+func (r *Router) Create(ctx context.Context, path string) (io.WriteCloser, error) {
+	p, path, err := r.Route(path)
+	if err != nil {
+		return nil, err
+	}
+	return Files(p).Create(ctx, path)
+}
+
+// Rename moves oldPath to newPath; both must route to the same provider
+func (r *Router) Rename(ctx context.Context, oldPath, newPath string) error {
+	p, oldPath, err := r.Route(oldPath)
+	if err != nil {
+		return err
+	}
+	q, newPath, err := r.Route(newPath)
+	if err != nil {
+		return err
+	}
+	if p != q {
+		return fmt.Errorf("cannot rename across storage providers")
+	}
+	return Files(p).Rename(ctx, oldPath, newPath)
+}
+
+// ModTime returns the last modification time of a file
+func (r *Router) ModTime(ctx context.Context, path string) (time.Time, error) {
+	p, path, err := r.Route(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return Files(p).ModTime(ctx, path)
+}
+
+// MkdirAll creates dir and any missing parents with the given mode
+func (r *Router) MkdirAll(ctx context.Context, dir string, perm os.FileMode) error {
+	p, dir, err := r.Route(dir)
+	if err != nil {
+		return err
+	}
+	return Files(p).MkdirAll(ctx, dir, perm)
+}
+
+// SetPermissions changes the mode and owner of path
+func (r *Router) SetPermissions(ctx context.Context, path string, perm os.FileMode, owner *model.FileOwner) error {
+	p, path, err := r.Route(path)
+	if err != nil {
+		return err
+	}
+	return Files(p).SetPermissions(ctx, path, perm, owner)
+}
+
+// Checksum returns the hex-encoded SHA-256 of a file when the routed
+// provider supports checksums
+func (r *Router) Checksum(ctx context.Context, path string) (string, error) {
+	p, path, err := r.Route(path)
+	if err != nil {
+		return "", err
+	}
+	c, ok := p.(interface {
+		Checksum(ctx context.Context, path string) (string, error)
+	})
+	if !ok {
+		return "", fmt.Errorf("storage provider for %s does not support checksums", path)
+	}
+	return c.Checksum(ctx, path)
+}
+
+// FreeSpace reports the free space on the volume holding path when the
+// routed provider supports it
+func (r *Router) FreeSpace(ctx context.Context, path string) (uint64, error) {
+	p, path, err := r.Route(path)
+	if err != nil {
+		return 0, err
+	}
+	s, ok := p.(ports.SpaceReporter)
+	if !ok {
+		return 0, ErrFreeSpaceUnsupported
+	}
+	return s.FreeSpace(ctx, path)
+}
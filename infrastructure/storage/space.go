@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// ErrFreeSpaceUnsupported is returned by FreeSpace where the platform or
+// provider cannot report free space
+var ErrFreeSpaceUnsupported = errors.New("free space reporting not supported")
+
+// FreeSpace returns the bytes available to unprivileged writers on the
+// volume holding path. Path components that don't exist yet are skipped, so
+// it can be called before the output directory is created.
+func (s *LocalStorage) FreeSpace(_ context.Context, path string) (uint64, error) {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return freeSpace(dir)
+}
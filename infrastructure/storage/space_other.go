@@ -0,0 +1,7 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package storage
+
+func freeSpace(string) (uint64, error) {
+	return 0, ErrFreeSpaceUnsupported
+}
@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// tempPrefix starts every managed temp file name; the owning PID follows
+const tempPrefix = "audiolab-"
+
+// TempManager allocates intermediate files for multi-pass operations and
+// tracks them per job run so they can be removed together. Files are named
+// audiolab-<pid>-<job>-<random><ext>, which lets Sweep find files left by
+// a process that crashed.
+type TempManager struct {
+	dir     string
+	pid     int
+	pending atomic.Int64
+}
+
+// NewTempManager creates a manager that keeps its files in dir. The
+// default is a per-user directory under os.TempDir().
+func NewTempManager(dir string) *TempManager {
+	if dir == "" {
+		name := "audiolab"
+		if uid := os.Getuid(); uid >= 0 {
+			name += "-" + strconv.Itoa(uid)
+		}
+		dir = filepath.Join(os.TempDir(), name)
+	}
+	return &TempManager{dir: dir, pid: os.Getpid()}
+}
+
+// Dir returns the directory holding managed files
+func (m *TempManager) Dir() string {
+	return m.dir
+}
+
+// CheckWritable creates the directory if needed and writes and removes a
+// probe file in it
+func (m *TempManager) CheckWritable() error {
+	if err := os.MkdirAll(m.dir, 0o700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(m.dir, tempPrefix+"health-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	_, err = f.Write([]byte("ok"))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if rerr := os.Remove(name); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// Pending returns the number of allocated files not yet released
+func (m *TempManager) Pending() int {
+	return int(m.pending.Load())
+}
+
+// Scope starts tracking temp files for one run of a job
+func (m *TempManager) Scope(jobID string) *TempScope {
+	return &TempScope{m: m, jobID: sanitizeJobID(jobID)}
+}
+
+// TempScope holds the temp files of one job run
+type TempScope struct {
+	m     *TempManager
+	jobID string
+
+	mu    sync.Mutex
+	paths []string
+}
+
+// Allocate creates an empty temp file and returns its path. ext (e.g.
+// ".wav") is kept so ffmpeg can infer the format.
+func (s *TempScope) Allocate(ext string) (string, error) {
+	if err := os.MkdirAll(s.m.dir, 0o700); err != nil {
+		return "", err
+	}
+	pattern := fmt.Sprintf("%s%d-%s-*%s", tempPrefix, s.m.pid, s.jobID, ext)
+	f, err := os.CreateTemp(s.m.dir, pattern)
+	if err != nil {
+		return "", err
+	}
+	f.Close()
+
+	s.mu.Lock()
+	s.paths = append(s.paths, f.Name())
+	s.mu.Unlock()
+	s.m.pending.Add(1)
+	return f.Name(), nil
+}
+
+// Release removes every file allocated in the scope. It is safe to call
+// more than once and is meant to be deferred, so it also runs while a panic
+// unwinds the job's goroutine.
+func (s *TempScope) Release() error {
+	s.mu.Lock()
+	paths := s.paths
+	s.paths = nil
+	s.mu.Unlock()
+
+	var firstErr error
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) && firstErr == nil {
+			firstErr = err
+		}
+		s.m.pending.Add(-1)
+	}
+	return firstErr
+}
+
+// Sweep removes managed files left behind by earlier processes: files whose
+// owning process is gone, and any file older than maxAge (when > 0). Files
+// of the current process are never touched. It returns the number removed.
+func (m *TempManager) Sweep(maxAge time.Duration) (int, error) {
+	entries, err := os.ReadDir(m.dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, e := range entries {
+		name := e.Name()
+		if !e.Type().IsRegular() || !strings.HasPrefix(name, tempPrefix) {
+			continue
+		}
+		pid, ok := ownerPID(name)
+		if !ok || pid == m.pid {
+			continue
+		}
+
+		stale := !processAlive(pid)
+		if !stale && maxAge > 0 {
+			if info, err := e.Info(); err == nil && time.Since(info.ModTime()) > maxAge {
+				stale = true
+			}
+		}
+		if stale && os.Remove(filepath.Join(m.dir, name)) == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// ownerPID extracts the PID from a managed file name
+func ownerPID(name string) (int, bool) {
+	rest := strings.TrimPrefix(name, tempPrefix)
+	i := strings.IndexByte(rest, '-')
+	if i <= 0 {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(rest[:i])
+	return pid, err == nil
+}
+
+// processAlive reports whether a process with pid exists
+func processAlive(pid int) bool {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		// FindProcess opens a handle and fails if the process is gone
+		proc.Release()
+		return true
+	}
+	err = proc.Signal(syscall.Signal(0))
+	// EPERM means the process exists but belongs to another user
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// sanitizeJobID keeps job IDs from introducing separators or wildcards
+func sanitizeJobID(id string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == '*' || r == os.PathSeparator {
+			return '_'
+		}
+		return r
+	}, id)
+}
@@ -0,0 +1,101 @@
+// Package tracing creates OpenTelemetry spans for jobs, pipeline stages and
+// ffmpeg runs
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/Skryldev/audio-lab/domain/ports"
+	"github.com/Skryldev/audio-lab/infrastructure/ffmpeg"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName names the tracer used for all spans
+const InstrumentationName = "github.com/Skryldev/audio-lab"
+
+// Tracer returns the library's tracer from tp, or from the global provider
+// when tp is nil
+func Tracer(tp trace.TracerProvider) trace.Tracer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return tp.Tracer(InstrumentationName)
+}
+
+// End records err on span, if any, and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(attribute.String("audiolab.error_code", string(pkgerrors.CodeOf(err))))
+	}
+	span.End()
+}
+
+// InstrumentExecutor wraps e to run every ffmpeg and ffprobe invocation in
+// a child span of the caller's context
+func InstrumentExecutor(e ports.FFmpegExecutor, tracer trace.Tracer) ports.FFmpegExecutor {
+	return &executor{PipedExecutor: ffmpeg.Piped(e), tracer: tracer}
+}
+
+type executor struct {
+	ports.PipedExecutor
+	tracer trace.Tracer
+}
+
+func (e *executor) start(ctx context.Context, binary string, args []string) (context.Context, trace.Span) {
+	return e.tracer.Start(ctx, binary,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.StringSlice("ffmpeg.args", args)),
+	)
+}
+
+func (e *executor) end(span trace.Span, err error) {
+	var ffErr *pkgerrors.FFmpegError
+	switch {
+	case err == nil:
+		span.SetAttributes(attribute.Int("ffmpeg.exit_code", 0))
+	case errors.As(err, &ffErr):
+		span.SetAttributes(attribute.Int("ffmpeg.exit_code", ffErr.ExitCode))
+	}
+	End(span, err)
+}
+
+func (e *executor) Execute(ctx context.Context, args []string) error {
+	ctx, span := e.start(ctx, "ffmpeg", args)
+	err := e.PipedExecutor.Execute(ctx, args)
+	e.end(span, err)
+	return err
+}
+
+func (e *executor) ExecuteWithOutput(ctx context.Context, args []string) ([]byte, []byte, error) {
+	ctx, span := e.start(ctx, "ffmpeg", args)
+	stdout, stderr, err := e.PipedExecutor.ExecuteWithOutput(ctx, args)
+	e.end(span, err)
+	return stdout, stderr, err
+}
+
+func (e *executor) ExecutePiped(ctx context.Context, args []string, r io.Reader, w io.Writer) ([]byte, error) {
+	ctx, span := e.start(ctx, "ffmpeg", args)
+	stderr, err := e.PipedExecutor.ExecutePiped(ctx, args, r, w)
+	e.end(span, err)
+	return stderr, err
+}
+
+func (e *executor) Probe(ctx context.Context, inputPath string) ([]byte, error) {
+	ctx, span := e.start(ctx, "ffprobe", []string{inputPath})
+	out, err := e.PipedExecutor.Probe(ctx, inputPath)
+	e.end(span, err)
+	return out, err
+}
+
+// Unwrap returns the wrapped executor
+func (e *executor) Unwrap() ports.FFmpegExecutor {
+	return e.PipedExecutor
+}
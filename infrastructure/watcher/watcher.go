@@ -0,0 +1,229 @@
+// Package watcher reports files that appear in a directory once they have
+// stopped changing, for hot-folder ingest
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Skryldev/audio-lab/pkg/logger"
+	"github.com/Skryldev/audio-lab/pkg/pathutil"
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// DefaultStableFor is used when Config.StableFor is zero
+const DefaultStableFor = 2 * time.Second
+
+// Config holds watcher configuration
+type Config struct {
+	// Recursive also watches subdirectories, including ones created later
+	Recursive bool
+
+	// StableFor is how long a file's size and modification time must stay
+	// unchanged before it is reported (default: 2s)
+	StableFor time.Duration
+
+	// Existing also reports files already present when watching starts
+	Existing bool
+
+	// Ignore lists directories that are never watched or reported from,
+	// e.g. an output directory inside the watched one
+	Ignore []string
+}
+
+// pending tracks a file that has not settled yet
+type pending struct {
+	size    int64
+	modTime time.Time
+	since   time.Time // when size and modTime were last seen changing
+}
+
+// Watcher reports settled files under a directory
+type Watcher struct {
+	dir     string
+	cfg     Config
+	fs      *fsnotify.Watcher
+	ignore  map[string]bool
+	pending map[string]*pending
+	log     *logger.Logger
+}
+
+// New starts watching dir. Call Run to receive settled files.
+func New(dir string, cfg Config, log *logger.Logger) (*Watcher, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("watch directory is required")
+	}
+	if cfg.StableFor <= 0 {
+		cfg.StableFor = DefaultStableFor
+	}
+	if log == nil {
+		var err error
+		log, err = logger.New(false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create logger: %w", err)
+		}
+	}
+
+	fs, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create watcher: %w", err)
+	}
+
+	w := &Watcher{
+		dir:     dir,
+		cfg:     cfg,
+		fs:      fs,
+		ignore:  make(map[string]bool, len(cfg.Ignore)),
+		pending: make(map[string]*pending),
+		log:     log,
+	}
+	for _, d := range cfg.Ignore {
+		if d != "" {
+			w.ignore[key(d)] = true
+		}
+	}
+
+	if err := w.add(dir, cfg.Existing); err != nil {
+		fs.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Run sends the path of every settled file to files until ctx is canceled,
+// then closes the watcher. A file is reported again if it is rewritten.
+func (w *Watcher) Run(ctx context.Context, files chan<- string) error {
+	defer w.fs.Close()
+
+	ticker := time.NewTicker(w.cfg.StableFor / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case event, ok := <-w.fs.Events:
+			if !ok {
+				return nil
+			}
+			w.handle(event)
+
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return nil
+			}
+			w.log.Warn("watch error", zap.String("dir", w.dir), zap.Error(err))
+
+		case <-ticker.C:
+			for _, path := range w.settled(time.Now()) {
+				select {
+				case files <- path:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+	}
+}
+
+func (w *Watcher) handle(event fsnotify.Event) {
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		delete(w.pending, event.Name)
+		return
+	}
+	if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+		return
+	}
+
+	info, err := os.Stat(event.Name)
+	if err != nil {
+		return
+	}
+	if info.IsDir() {
+		if event.Has(fsnotify.Create) && w.cfg.Recursive {
+			// Files may land in the new directory before it is watched
+			if err := w.add(event.Name, true); err != nil {
+				w.log.Warn("failed to watch directory", zap.String("dir", event.Name), zap.Error(err))
+			}
+		}
+		return
+	}
+	if info.Mode().IsRegular() {
+		w.track(event.Name)
+	}
+}
+
+// add watches dir and, when Recursive, its subdirectories. With existing
+// set, the files already inside are tracked as well.
+func (w *Watcher) add(dir string, existing bool) error {
+	if w.ignore[key(dir)] {
+		return nil
+	}
+	if err := w.fs.Add(dir); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", dir, err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		switch {
+		case e.IsDir() && w.cfg.Recursive:
+			if err := w.add(path, existing); err != nil {
+				return err
+			}
+		case e.Type().IsRegular() && existing:
+			w.track(path)
+		}
+	}
+	return nil
+}
+
+// track (re)starts the settle timer for path
+func (w *Watcher) track(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	p, ok := w.pending[path]
+	if !ok || p.size != info.Size() || !p.modTime.Equal(info.ModTime()) {
+		w.pending[path] = &pending{size: info.Size(), modTime: info.ModTime(), since: time.Now()}
+	}
+}
+
+// settled returns and forgets the tracked files that haven't changed for
+// StableFor. Write events alone aren't trusted: some writers (network
+// shares, preallocating copiers) don't generate them reliably.
+func (w *Watcher) settled(now time.Time) []string {
+	var ready []string
+	for path, p := range w.pending {
+		info, err := os.Stat(path)
+		if err != nil {
+			delete(w.pending, path)
+			continue
+		}
+		if info.Size() != p.size || !info.ModTime().Equal(p.modTime) {
+			p.size, p.modTime, p.since = info.Size(), info.ModTime(), now
+			continue
+		}
+		if now.Sub(p.since) >= w.cfg.StableFor {
+			delete(w.pending, path)
+			ready = append(ready, path)
+		}
+	}
+	return ready
+}
+
+func key(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	return pathutil.Key(dir)
+}
@@ -0,0 +1,69 @@
+// Package argfmt formats numeric ffmpeg arguments deterministically.
+//
+// All helpers use strconv with fixed precision, so generated filter and
+// codec arguments are byte-identical across platforms and locales, never
+// use exponent notation and never emit "-0".
+package argfmt
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// Float formats v with exactly prec digits after the decimal point
+func Float(v float64, prec int) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		v = 0
+	}
+	s := strconv.FormatFloat(v, 'f', prec, 64)
+	// strconv preserves the sign of negative zero and of values that round to zero
+	if s[0] == '-' && isZero(s[1:]) {
+		s = s[1:]
+	}
+	return s
+}
+
+// Int formats an integer in base 10
+func Int(v int) string {
+	return strconv.Itoa(v)
+}
+
+// Int64 formats an int64 in base 10
+func Int64(v int64) string {
+	return strconv.FormatInt(v, 10)
+}
+
+// Seconds formats a duration as seconds with millisecond precision
+func Seconds(d time.Duration) string {
+	return Float(d.Seconds(), 3)
+}
+
+// Millis formats a duration as whole milliseconds
+func Millis(d time.Duration) string {
+	return Int64(d.Milliseconds())
+}
+
+// Kbps formats a bitrate in bps as ffmpeg's "<n>k" shorthand
+func Kbps(bps int) string {
+	return Int(bps/1000) + "k"
+}
+
+// Hz formats a frequency given in Hz
+func Hz(v int) string {
+	return Int(v)
+}
+
+// Decibels formats a level/gain in dB, LUFS or LU with one decimal place
+func Decibels(v float64) string {
+	return Float(v, 1)
+}
+
+func isZero(s string) bool {
+	for _, c := range s {
+		if c != '0' && c != '.' {
+			return false
+		}
+	}
+	return true
+}
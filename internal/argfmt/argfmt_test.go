@@ -0,0 +1,95 @@
+package argfmt
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestFloat(t *testing.T) {
+	tests := []struct {
+		name string
+		v    float64
+		prec int
+		want string
+	}{
+		{"integer", 3, 2, "3.00"},
+		{"rounds half to even", 0.125, 2, "0.12"},
+		{"rounds up", 0.126, 2, "0.13"},
+		{"zero precision", 2.5, 0, "2"},
+		{"negative", -23.45, 1, "-23.4"},
+		{"negative zero", math.Copysign(0, -1), 2, "0.00"},
+		{"negative rounds to zero", -0.0004, 3, "0.000"},
+		{"tiny not exponent", 1e-7, 6, "0.000000"},
+		{"large not exponent", 1e21, 0, "1000000000000000000000"},
+		{"nan", math.NaN(), 2, "0.00"},
+		{"positive infinity", math.Inf(1), 1, "0.0"},
+		{"negative infinity", math.Inf(-1), 1, "0.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Float(tt.v, tt.prec); got != tt.want {
+				t.Errorf("Float(%v, %d) = %q, want %q", tt.v, tt.prec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecibels(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want string
+	}{
+		{-23, "-23.0"},
+		{-16.04, "-16.0"},
+		{-1.5, "-1.5"},
+		{-0.04, "0.0"},
+		{11, "11.0"},
+	}
+	for _, tt := range tests {
+		if got := Decibels(tt.v); got != tt.want {
+			t.Errorf("Decibels(%v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestDurations(t *testing.T) {
+	tests := []struct {
+		d           time.Duration
+		wantSeconds string
+		wantMillis  string
+	}{
+		{0, "0.000", "0"},
+		{1500 * time.Millisecond, "1.500", "1500"},
+		{90 * time.Second, "90.000", "90000"},
+		{1234567 * time.Microsecond, "1.235", "1234"},
+		{-250 * time.Millisecond, "-0.250", "-250"},
+	}
+	for _, tt := range tests {
+		if got := Seconds(tt.d); got != tt.wantSeconds {
+			t.Errorf("Seconds(%v) = %q, want %q", tt.d, got, tt.wantSeconds)
+		}
+		if got := Millis(tt.d); got != tt.wantMillis {
+			t.Errorf("Millis(%v) = %q, want %q", tt.d, got, tt.wantMillis)
+		}
+	}
+}
+
+func TestIntegers(t *testing.T) {
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"Int", Int(-42), "-42"},
+		{"Int64", Int64(1 << 40), "1099511627776"},
+		{"Hz", Hz(44100), "44100"},
+		{"Kbps", Kbps(192000), "192k"},
+		{"Kbps truncates", Kbps(128999), "128k"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+}
@@ -3,11 +3,19 @@ package mocks
 import (
 	"context"
 	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Skryldev/audio-lab/domain/model"
 )
 
 // MockFFmpegExecutor is a test double for ports.FFmpegExecutor
 type MockFFmpegExecutor struct {
 	ExecuteFunc func(ctx context.Context, args []string) error
+	ExecuteWithOutputFunc func(ctx context.Context, args []string) ([]byte, []byte, error)
+	ExecutePipedFunc func(ctx context.Context, args []string, r io.Reader, w io.Writer) ([]byte, error)
 	ProbeFunc   func(ctx context.Context, inputPath string) ([]byte, error)
 	ExecutedArgs [][]string
 }
@@ -20,6 +28,28 @@ func (m *MockFFmpegExecutor) Execute(ctx context.Context, args []string) error {
 	return nil
 }
 
+func (m *MockFFmpegExecutor) ExecuteWithOutput(ctx context.Context, args []string) ([]byte, []byte, error) {
+	m.ExecutedArgs = append(m.ExecutedArgs, args)
+	if m.ExecuteWithOutputFunc != nil {
+		return m.ExecuteWithOutputFunc(ctx, args)
+	}
+	if m.ExecuteFunc != nil {
+		return nil, nil, m.ExecuteFunc(ctx, args)
+	}
+	return nil, nil, nil
+}
+
+func (m *MockFFmpegExecutor) ExecutePiped(ctx context.Context, args []string, r io.Reader, w io.Writer) ([]byte, error) {
+	m.ExecutedArgs = append(m.ExecutedArgs, args)
+	if m.ExecutePipedFunc != nil {
+		return m.ExecutePipedFunc(ctx, args, r, w)
+	}
+	if m.ExecuteFunc != nil {
+		return nil, m.ExecuteFunc(ctx, args)
+	}
+	return nil, nil
+}
+
 func (m *MockFFmpegExecutor) Probe(ctx context.Context, inputPath string) ([]byte, error) {
 	if m.ProbeFunc != nil {
 		return m.ProbeFunc(ctx, inputPath)
@@ -54,6 +84,14 @@ type MockStorageProvider struct {
 	SizeFunc     func(ctx context.Context, path string) (int64, error)
 	RemoveFunc   func(ctx context.Context, path string) error
 	TempFileFunc func(ctx context.Context, dir, pattern string) (string, error)
+	ListFunc     func(ctx context.Context, dir string) ([]string, error)
+	ListDirsFunc func(ctx context.Context, dir string) ([]string, error)
+	OpenFunc     func(ctx context.Context, path string) (io.ReadCloser, error)
+	CreateFunc   func(ctx context.Context, path string) (io.WriteCloser, error)
+	RenameFunc   func(ctx context.Context, oldPath, newPath string) error
+	ModTimeFunc  func(ctx context.Context, path string) (time.Time, error)
+	MkdirAllFunc func(ctx context.Context, dir string, perm os.FileMode) error
+	SetPermissionsFunc func(ctx context.Context, path string, perm os.FileMode, owner *model.FileOwner) error
 }
 
 func (m *MockStorageProvider) Exists(ctx context.Context, path string) (bool, error) {
@@ -82,4 +120,63 @@ func (m *MockStorageProvider) TempFile(ctx context.Context, dir, pattern string)
 		return m.TempFileFunc(ctx, dir, pattern)
 	}
 	return "/tmp/mock_temp_file", nil
-}
\ No newline at end of file
+}
+func (m *MockStorageProvider) List(ctx context.Context, dir string) ([]string, error) {
+	if m.ListFunc != nil {
+		return m.ListFunc(ctx, dir)
+	}
+	return nil, nil
+}
+
+func (m *MockStorageProvider) ListDirs(ctx context.Context, dir string) ([]string, error) {
+	if m.ListDirsFunc != nil {
+		return m.ListDirsFunc(ctx, dir)
+	}
+	return nil, nil
+}
+
+func (m *MockStorageProvider) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	if m.OpenFunc != nil {
+		return m.OpenFunc(ctx, path)
+	}
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+func (m *MockStorageProvider) Create(ctx context.Context, path string) (io.WriteCloser, error) {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(ctx, path)
+	}
+	return nopWriteCloser{io.Discard}, nil
+}
+
+func (m *MockStorageProvider) Rename(ctx context.Context, oldPath, newPath string) error {
+	if m.RenameFunc != nil {
+		return m.RenameFunc(ctx, oldPath, newPath)
+	}
+	return nil
+}
+
+func (m *MockStorageProvider) ModTime(ctx context.Context, path string) (time.Time, error) {
+	if m.ModTimeFunc != nil {
+		return m.ModTimeFunc(ctx, path)
+	}
+	return time.Time{}, nil
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func (m *MockStorageProvider) MkdirAll(ctx context.Context, dir string, perm os.FileMode) error {
+	if m.MkdirAllFunc != nil {
+		return m.MkdirAllFunc(ctx, dir, perm)
+	}
+	return nil
+}
+
+func (m *MockStorageProvider) SetPermissions(ctx context.Context, path string, perm os.FileMode, owner *model.FileOwner) error {
+	if m.SetPermissionsFunc != nil {
+		return m.SetPermissionsFunc(ctx, path, perm, owner)
+	}
+	return nil
+}
@@ -2,16 +2,36 @@ package audiolab
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
 
+	"github.com/Skryldev/audio-lab/application/bus"
+	"github.com/Skryldev/audio-lab/application/config"
+	"github.com/Skryldev/audio-lab/application/fixity"
+	"github.com/Skryldev/audio-lab/application/pipeline"
+	"github.com/Skryldev/audio-lab/application/presets"
+	"github.com/Skryldev/audio-lab/application/report"
 	"github.com/Skryldev/audio-lab/application/usecase"
+	"github.com/Skryldev/audio-lab/application/worker"
 	"github.com/Skryldev/audio-lab/domain/model"
 	"github.com/Skryldev/audio-lab/domain/ports"
 	"github.com/Skryldev/audio-lab/infrastructure/ffmpeg"
+	"github.com/Skryldev/audio-lab/infrastructure/governor"
+	"github.com/Skryldev/audio-lab/infrastructure/metrics"
+	"github.com/Skryldev/audio-lab/infrastructure/ratelimit"
 	"github.com/Skryldev/audio-lab/infrastructure/storage"
+	"github.com/Skryldev/audio-lab/infrastructure/tracing"
+	pkgerrors "github.com/Skryldev/audio-lab/pkg/errors"
 	"github.com/Skryldev/audio-lab/pkg/logger"
 	"github.com/Skryldev/audio-lab/pkg/progress"
 	"github.com/Skryldev/audio-lab/pkg/retry"
+	"github.com/Skryldev/audio-lab/pkg/schedule"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 // Re-export types for convenient use by callers
@@ -20,10 +40,87 @@ type (
 	BitrateMode    = model.BitrateMode
 	ProcessingResult = model.ProcessingResult
 	AudioMetadata  = model.AudioMetadata
+	StreamInfo     = model.StreamInfo
+	StreamDisposition = model.StreamDisposition
+	Tags           = model.Tags
+	Chapter        = model.Chapter
+	StreamKind     = model.StreamKind
 	BatchJob       = model.BatchJob
 	BatchResult    = model.BatchResult
+	BatchPolicy    = model.BatchPolicy
+	Stage          = pipeline.Stage
+	Preset         = presets.Preset
+	StageJob       = pipeline.Job
+	StageMiddleware = pipeline.StageMiddleware
+	DeadLetter     = model.DeadLetter
+	JobAttempt     = model.JobAttempt
+	BatchReport    = report.BatchReport
+	JobReport      = report.JobReport
+	LoudnessReport = model.LoudnessReport
+	LoudnessMeasurement = model.LoudnessMeasurement
+	Hooks          = model.Hooks
+	Stats          = model.Stats
+	Capabilities   = model.Capabilities
+	HealthReport   = model.HealthReport
+	ComponentHealth = model.ComponentHealth
+	JobInfo        = model.JobInfo
+	FixityStore    = fixity.Store
+	FixityConfig   = fixity.Config
+	FixityRecord   = fixity.Record
+	FixityReport   = fixity.Report
+	JobQueue       = ports.JobQueue
+	QueuedJob      = model.QueuedJob
+	WorkerConfig   = worker.Config
+	BusTransport   = bus.Transport
+	BusConfig      = bus.Config
+	PreviewOptions = model.PreviewOptions
+	JobTemplate    = model.JobTemplate
+	WaveformOptions = model.WaveformOptions
+	Waveform       = model.Waveform
+	AnalysisOptions = model.AnalysisOptions
+	AudioAnalysis  = model.AudioAnalysis
+	ClippingReport = model.ClippingReport
+	ClippingRegion = model.ClippingRegion
+	RenditionSpec  = model.RenditionSpec
+	HLSOptions     = model.HLSOptions
+	DASHOptions    = model.DASHOptions
+	Rendition      = model.Rendition
+	PackageResult  = model.PackageResult
+	ReconciliationReport = model.ReconciliationReport
+	LosslessVerification = model.LosslessVerification
+	FileValidation  = model.FileValidation
+	DuplicateAction = model.DuplicateAction
+	DuplicateGroup  = model.DuplicateGroup
+	DuplicateReport = model.DuplicateReport
+	CommandPlan    = model.CommandPlan
+	PlanInput      = model.PlanInput
+	PlanOutput     = model.PlanOutput
+	ToneSpec       = model.ToneSpec
+	ToneKind       = model.ToneKind
+	FileOwner      = model.FileOwner
+	ChainInfo      = model.ChainInfo
+	DirOptions     = model.DirOptions
+	WatchRules     = model.WatchRules
+	WatchResult    = model.WatchResult
+	WatchAction    = model.WatchAction
+	SkipPolicy     = model.SkipPolicy
+	MetadataPolicy = model.MetadataPolicy
+	CoverArt       = model.CoverArt
+	CoverArtFormat = model.CoverArtFormat
+	ID3Options     = model.ID3Options
+	BWF            = model.BWF
+	Identifiers    = model.Identifiers
+	Lyrics         = model.Lyrics
+	ChapterInput   = model.ChapterInput
+	PodcastOptions = model.PodcastOptions
+	ProcessLimits  = ffmpeg.ProcessLimits
+	AutoTuneConfig = pipeline.AutoTuneConfig
+	RateLimiter    = ratelimit.Limiter
+	RateLimitConfig = ratelimit.Config
+	IOClass        = ffmpeg.IOClass
 	ProgressUpdate = progress.Update
 	ProgressStage  = progress.Stage
+	ProcessingWindow = schedule.Window
 )
 
 // Re-export codec constants
@@ -31,6 +128,8 @@ const (
 	CodecOpus = model.CodecOpus
 	CodecAAC  = model.CodecAAC
 	CodecMP3  = model.CodecMP3
+	CodecPCM16 = model.CodecPCM16
+	CodecPCM24 = model.CodecPCM24
 
 	BitrateModeVBR = model.BitrateModeVBR
 	BitrateModeCBR = model.BitrateCBR
@@ -39,8 +138,70 @@ const (
 	StageNormalize = progress.StageNormalize
 	StageEncode    = progress.StageEncode
 	StageDone      = progress.StageDone
+	StageDownload  = progress.StageDownload
+	StageUpload    = progress.StageUpload
+
+	StageAnalyzePass1 = progress.StageAnalyzePass1
+	StageEncodePass2  = progress.StageEncodePass2
+
+	StreamKindAudio       = model.StreamKindAudio
+	StreamKindVideo       = model.StreamKindVideo
+	StreamKindAttachedPic = model.StreamKindAttachedPic
+	StreamKindSubtitle    = model.StreamKindSubtitle
+	StreamKindData        = model.StreamKindData
+	StreamKindUnknown     = model.StreamKindUnknown
+
+	ToneSine  = model.ToneSine
+	ToneNoise = model.ToneNoise
+	ToneSweep = model.ToneSweep
+
+	// ChainPlaceholder marks the chain number in ProcessChains output paths
+	ChainPlaceholder = model.ChainPlaceholder
+
+	PlanPipeInput  = model.PlanPipeInput
+	PlanPipeOutput = model.PlanPipeOutput
+
+	SkipNever         = model.SkipNever
+	SkipIfNewer       = model.SkipIfNewer
+	SkipIfFingerprint = model.SkipIfFingerprint
+
+	MetadataCopy    = model.MetadataCopy
+	MetadataStrip   = model.MetadataStrip
+	MetadataReplace = model.MetadataReplace
+
+	CoverArtJPEG = model.CoverArtJPEG
+	CoverArtPNG  = model.CoverArtPNG
+
+	DuplicateFlag = model.DuplicateFlag
+	DuplicateSkip = model.DuplicateSkip
+	DuplicateLink = model.DuplicateLink
+
+	WatchKeep   = model.WatchKeep
+	WatchDelete = model.WatchDelete
+	WatchMove   = model.WatchMove
+
+	IOClassBestEffort = ffmpeg.IOClassBestEffort
+	IOClassIdle       = ffmpeg.IOClassIdle
 )
 
+// ParseProcessingWindow parses "HH:MM-HH:MM" in loc (nil means local time)
+var ParseProcessingWindow = schedule.ParseWindow
+
+// StageName returns the name of the stage running with ctx, for use in
+// StageMiddleware
+var StageName = pipeline.StageName
+
+// NewBatchReport returns an empty report to Add batch results to, and
+// CollectBatchReport drains a result channel into one
+var (
+	NewBatchReport     = report.New
+	CollectBatchReport = report.Collect
+)
+
+// NewRateLimiter creates a limiter for Config.RateLimiter; share it
+// between processors to bound their combined resource use
+var NewRateLimiter = ratelimit.New
+
 // Re-export option functions
 var (
 	WithCodec          = ports.WithCodec
@@ -49,9 +210,56 @@ var (
 	WithSampleRate     = ports.WithSampleRate
 	WithNormalization  = ports.WithNormalization
 	WithLoudnessTarget = ports.WithLoudnessTarget
+	WithDCRemoval      = ports.WithDCRemoval
 	WithHighpass       = ports.WithHighpass
 	WithLowpass        = ports.WithLowpass
+	WithPadding        = ports.WithPadding
+	WithAdditionalOutput = ports.WithAdditionalOutput
+	WithStreamReconnect  = ports.WithStreamReconnect
+	WithInputFormat      = ports.WithInputFormat
+	WithThreads          = ports.WithThreads
+	WithAudioStream      = ports.WithAudioStream
+	WithAudioLanguage    = ports.WithAudioLanguage
+	WithOutputFormat     = ports.WithOutputFormat
+	WithMetadata         = ports.WithMetadata
+	WithMetadataPolicy   = ports.WithMetadataPolicy
+	WithCoverArt         = ports.WithCoverArt
+	WithCoverArtResize   = ports.WithCoverArtResize
+	WithCoverArtFormat   = ports.WithCoverArtFormat
+	WithID3Version       = ports.WithID3Version
+	WithID3v1            = ports.WithID3v1
+	WithGapless          = ports.WithGapless
+	WithBWF              = ports.WithBWF
+	WithISRC             = ports.WithISRC
+	WithLyrics           = ports.WithLyrics
+	WithLyricsFile       = ports.WithLyricsFile
+	WithLyricsLanguage   = ports.WithLyricsLanguage
+	WithBarcode          = ports.WithBarcode
+	WithCatalogNumber    = ports.WithCatalogNumber
+	WithChapters         = ports.WithChapters
+	WithProfile          = ports.WithProfile
+	WithPreset           = presets.WithPreset
+	RegisterPreset       = presets.Register
+	LookupPreset         = presets.Lookup
+	Presets              = presets.List
+	WithAtomicWrites     = ports.WithAtomicWrites
+	WithSkipPolicy       = ports.WithSkipPolicy
+	WithOutputVerification = ports.WithOutputVerification
+	WithDryRun           = ports.WithDryRun
+	WithExtraInputArgs   = ports.WithExtraInputArgs
+	WithCustomFilter     = ports.WithCustomFilter
+	WithEncoderPreference = ports.WithEncoderPreference
+	WithFilterChainHook  = pipeline.WithFilterChainHook
+	WithPodcastMastering = pipeline.WithPodcastMastering
+	WithExtraOutputArgs  = ports.WithExtraOutputArgs
+	WithTimeout          = ports.WithTimeout
+	WithLabels           = ports.WithLabels
+	WithOutputDirs        = ports.WithOutputDirs
+	WithOutputPermissions = ports.WithOutputPermissions
+	WithOutputOwner       = ports.WithOutputOwner
 	WithWorkers        = ports.WithWorkers
+	WithRetry          = ports.WithRetry
+	WithRetryAttempts  = ports.WithRetryAttempts
 )
 
 // Config holds top-level configuration for the processor
@@ -68,20 +276,225 @@ type Config struct {
 	// ZapLogger allows passing a *zap.Logger directly
 	ZapLogger *zap.Logger
 
+	// LogLevel is the level of the built-in logger: debug, info (default),
+	// warn or error. Ignored when Logger or ZapLogger is set.
+	LogLevel string
+
 	// ProgressCh is an optional channel for receiving progress updates
 	ProgressCh chan<- ProgressUpdate
 
 	// Workers sets the number of parallel batch workers (default: 4)
 	Workers int
 
+	// ProbeWorkers sets the size of the separate probe/analysis lane (default: 2)
+	ProbeWorkers int
+
+	// AutoTune sizes the worker pool from the CPU count and adjusts it to
+	// encode throughput and system load, instead of the fixed Workers
+	AutoTune *AutoTuneConfig
+
 	// RetryConfig overrides default retry behavior
 	RetryConfig *retry.Config
+
+	// Executor overrides the built-in ffmpeg executor (e.g. instrumented,
+	// sandboxed or mocked). FFmpegPath/FFprobePath are ignored when set.
+	// Executors not implementing ports.PipedExecutor encode without progress.
+	Executor ports.FFmpegExecutor
+
+	// ProcessLimits runs ffmpeg and ffprobe with lower CPU and I/O
+	// priority, or in a cgroup, on shared hosts. Ignored when Executor is
+	// set. Combine with WithThreads to also cap each job's threads.
+	ProcessLimits ProcessLimits
+
+	// Storage overrides the default local filesystem storage provider.
+	// Providers implementing ports.RemoteStorage have inputs downloaded and
+	// outputs uploaded around each encode. Providers not implementing
+	// ports.FileStorage use the local filesystem for listing and renames.
+	Storage ports.StorageProvider
+
+	// ProcessingWindows restricts batch processing to time-of-day windows
+	// (e.g. 22:00-06:00); queued jobs are held until a window opens.
+	// Empty means batches run at any time.
+	ProcessingWindows []ProcessingWindow
+
+	// Governor is consulted before each job starts and may hold it back
+	// (see infrastructure/governor for a Linux CPU-temperature governor)
+	Governor ports.AdmissionGovernor
+
+	// RateLimiter caps job starts per minute and concurrent ffmpeg
+	// processes (see NewRateLimiter). Processors given the same limiter
+	// share its limits.
+	RateLimiter *RateLimiter
+
+	// Templates registers named job templates for recurring workflows
+	Templates []JobTemplate
+
+	// TempDir holds intermediate files. Files left by crashed processes are
+	// removed at startup. Default: a per-user directory under os.TempDir().
+	TempDir string
+
+	// FingerprintStore records what produced each output and is required by
+	// SkipIfFingerprint (see storage.OpenFingerprintFile)
+	FingerprintStore ports.FingerprintStore
+
+	// Metrics registers Prometheus metrics for jobs, queue depth and ffmpeg
+	// runs (see infrastructure/metrics). Nil disables metrics.
+	Metrics prometheus.Registerer
+
+	// MetricsJobLabels are job label keys (see WithLabels) added as metric
+	// labels next to "profile"; keep them low-cardinality
+	MetricsJobLabels []string
+
+	// MetricsProfiles and MetricsJobLabelValues list the profile and label
+	// values recorded as themselves; others are recorded as "other" (see
+	// metrics.Options). MetricsProfiles defaults to the presets registered
+	// when the processor is created.
+	MetricsProfiles       []string
+	MetricsJobLabelValues map[string][]string
+
+	// TracerProvider provides OpenTelemetry spans for ProcessAudio, each
+	// pipeline stage and every ffmpeg run, as children of the caller's
+	// context. Nil uses the global provider (otel.SetTracerProvider).
+	TracerProvider trace.TracerProvider
+
+	// Hooks are called at job and stage lifecycle points by the pipeline
+	// and worker pool, e.g. for auditing, billing or alerting
+	Hooks Hooks
+
+	// SkipCapabilityDetection skips running ffmpeg -encoders and -filters
+	// in New. Capabilities then returns nil and jobs aren't checked against
+	// the build.
+	SkipCapabilityDetection bool
+
+	// DefaultOptions are applied to every job before its own options, e.g.
+	// WithPreset to give all jobs a house preset
+	DefaultOptions []ports.Option
+
+	// DeadLetters keeps batch jobs that failed after exhausting their
+	// retries, for DeadLetters and RetryFailed. Nil keeps them in memory;
+	// see redis.NewDeadLetters to persist them next to a shared queue.
+	DeadLetters ports.DeadLetterStore
+
+	// storages holds providers added with RegisterStorage, keyed by scheme
+	storages map[string]ports.StorageProvider
+}
+
+// RegisterStorage routes paths with the given URI scheme (e.g. "s3" for
+// s3://bucket/key) to provider. Paths without a scheme, and file:// URIs,
+// keep using Storage (or the local filesystem).
+func (c *Config) RegisterStorage(scheme string, provider ports.StorageProvider) {
+	if c.storages == nil {
+		c.storages = make(map[string]ports.StorageProvider)
+	}
+	c.storages[scheme] = provider
+}
+
+// LoadConfig reads a YAML or JSON config file (see application/config),
+// registers the presets it defines and returns the processor config and
+// default job options it describes. A fingerprints file is opened into
+// FingerprintStore, which the caller closes after the Processor.
+func LoadConfig(path string) (Config, []ports.Option, error) {
+	f, err := config.Load(path)
+	if err != nil {
+		return Config{}, nil, err
+	}
+	if err := f.RegisterPresets(); err != nil {
+		return Config{}, nil, err
+	}
+	opts, err := f.JobOptions()
+	if err != nil {
+		return Config{}, nil, err
+	}
+
+	cfg := Config{
+		FFmpegPath:  f.FFmpeg,
+		FFprobePath: f.FFprobe,
+		Workers:     f.Workers,
+		TempDir:     f.TempDir,
+	}
+	if f.Fingerprints != "" {
+		store, err := storage.OpenFingerprintFile(f.Fingerprints)
+		if err != nil {
+			return Config{}, nil, err
+		}
+		cfg.FingerprintStore = store
+	}
+	return cfg, opts, nil
+}
+
+// Environment variables read by ConfigFromEnv
+const (
+	EnvFFmpeg       = "AUDIOLAB_FFMPEG"
+	EnvFFprobe      = "AUDIOLAB_FFPROBE"
+	EnvWorkers      = "AUDIOLAB_WORKERS" // a count, or "auto" for AutoTune
+	EnvProbeWorkers = "AUDIOLAB_PROBE_WORKERS"
+	EnvTempDir      = "AUDIOLAB_TEMP_DIR"
+	EnvLogLevel     = "AUDIOLAB_LOG_LEVEL"
+	EnvPreset       = "AUDIOLAB_PRESET"
+	EnvThreads      = "AUDIOLAB_THREADS"
+	EnvNice         = "AUDIOLAB_NICE"
+)
+
+// ConfigFromEnv builds a Config from the AUDIOLAB_* environment variables
+// above. Unset variables keep their defaults; AUDIOLAB_PRESET and
+// AUDIOLAB_THREADS become WithPreset and WithThreads default options.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		FFmpegPath:  os.Getenv(EnvFFmpeg),
+		FFprobePath: os.Getenv(EnvFFprobe),
+		TempDir:     os.Getenv(EnvTempDir),
+		LogLevel:    os.Getenv(EnvLogLevel),
+	}
+
+	var err error
+	if os.Getenv(EnvWorkers) == "auto" {
+		cfg.AutoTune = &AutoTuneConfig{}
+	} else if cfg.Workers, err = envInt(EnvWorkers); err != nil {
+		return Config{}, err
+	}
+	if cfg.ProbeWorkers, err = envInt(EnvProbeWorkers); err != nil {
+		return Config{}, err
+	}
+	if cfg.ProcessLimits.Nice, err = envInt(EnvNice); err != nil {
+		return Config{}, err
+	}
+	if cfg.LogLevel != "" {
+		if _, err := zapcore.ParseLevel(cfg.LogLevel); err != nil {
+			return Config{}, pkgerrors.NewValidationError(EnvLogLevel, cfg.LogLevel, "unknown log level")
+		}
+	}
+	if name := os.Getenv(EnvPreset); name != "" {
+		cfg.DefaultOptions = append(cfg.DefaultOptions, presets.WithPreset(name))
+	}
+	threads, err := envInt(EnvThreads)
+	if err != nil {
+		return Config{}, err
+	}
+	if threads > 0 {
+		cfg.DefaultOptions = append(cfg.DefaultOptions, ports.WithThreads(threads))
+	}
+	return cfg, nil
+}
+
+// envInt reads a non-negative integer variable, 0 when unset
+func envInt(name string) (int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, pkgerrors.NewValidationError(name, v, "must be a non-negative integer")
+	}
+	return n, nil
 }
 
 // Processor is the main entry point
 type Processor struct {
-	service *usecase.AudioService
-	log     *logger.Logger
+	service  *usecase.AudioService
+	executor ports.FFmpegExecutor
+	storage  ports.StorageProvider
+	log      *logger.Logger
 }
 
 // New creates a new Processor with the given configuration
@@ -92,22 +505,64 @@ func New(cfg Config) (*Processor, error) {
 	}
 	if log == nil {
 		var err error
-		log, err = logger.New(false)
+		log, err = logger.NewWithLevel(false, cfg.LogLevel)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	exec, err := ffmpeg.NewExecutor(ffmpeg.ExecutorConfig{
-		FFmpegPath:  cfg.FFmpegPath,
-		FFprobePath: cfg.FFprobePath,
-		Logger:      log,
-	})
-	if err != nil {
-		return nil, err
+	exec := cfg.Executor
+	if exec == nil {
+		ffExec, err := ffmpeg.NewExecutor(ffmpeg.ExecutorConfig{
+			FFmpegPath:  cfg.FFmpegPath,
+			FFprobePath: cfg.FFprobePath,
+			Logger:      log,
+			Limits:      cfg.ProcessLimits,
+		})
+		if err != nil {
+			return nil, err
+		}
+		exec = ffExec
 	}
 
-	store := storage.NewLocalStorage()
+	var m *metrics.Metrics
+	if cfg.Metrics != nil {
+		var err error
+		profiles := cfg.MetricsProfiles
+		if profiles == nil {
+			for _, p := range presets.List() {
+				profiles = append(profiles, p.Name)
+			}
+		}
+		m, err = metrics.New(cfg.Metrics, metrics.Options{
+			JobLabels:      cfg.MetricsJobLabels,
+			Profiles:       profiles,
+			JobLabelValues: cfg.MetricsJobLabelValues,
+		})
+		if err != nil {
+			return nil, err
+		}
+		exec = m.InstrumentExecutor(exec)
+	}
+	gov := cfg.Governor
+	if cfg.RateLimiter != nil {
+		// Outside the metrics so that waiting for a slot isn't timed as a run
+		exec = cfg.RateLimiter.LimitExecutor(exec)
+		gov = governor.Chain(gov, cfg.RateLimiter)
+	}
+	exec = tracing.InstrumentExecutor(exec, tracing.Tracer(cfg.TracerProvider))
+
+	store := cfg.Storage
+	if store == nil {
+		store = storage.NewLocalStorage()
+	}
+	if len(cfg.storages) > 0 {
+		router := storage.NewRouter(store)
+		for scheme, provider := range cfg.storages {
+			router.Register(scheme, provider)
+		}
+		store = router
+	}
 
 	var reporter progress.Reporter = progress.NoopReporter{}
 	if cfg.ProgressCh != nil {
@@ -130,15 +585,30 @@ func New(cfg Config) (*Processor, error) {
 		Reporter:    reporter,
 		Logger:      log,
 		Workers:     workers,
+		ProbeWorkers: cfg.ProbeWorkers,
+		AutoTune:    cfg.AutoTune,
 		RetryConfig: retryCfg,
+		Templates:   cfg.Templates,
+		Windows:     cfg.ProcessingWindows,
+		Governor:    gov,
+		TempDir:     cfg.TempDir,
+		FingerprintStore: cfg.FingerprintStore,
+		Metrics:     m,
+		TracerProvider: cfg.TracerProvider,
+		Hooks:       cfg.Hooks,
+		DeadLetters: cfg.DeadLetters,
+		Defaults:    cfg.DefaultOptions,
+		SkipCapabilityDetection: cfg.SkipCapabilityDetection,
 	})
 	if err != nil {
 		return nil, err
 	}
 
 	return &Processor{
-		service: svc,
-		log:     log,
+		service:  svc,
+		executor: exec,
+		storage:  store,
+		log:      log,
 	}, nil
 }
 
@@ -152,12 +622,322 @@ func (p *Processor) ProcessBatch(ctx context.Context, jobs []BatchJob) (<-chan B
 	return p.service.ProcessBatch(ctx, jobs)
 }
 
+// Defaults returns Config.DefaultOptions, which jobs get ahead of their
+// own options. Pass them to jobspec.Job.BatchJob when converting jobs.
+func (p *Processor) Defaults() []ports.Option {
+	return p.service.Defaults()
+}
+
+// ProcessBatchWithPolicy processes jobs like ProcessBatch but stops early,
+// canceling the remaining jobs, when policy trips
+func (p *Processor) ProcessBatchWithPolicy(ctx context.Context, jobs []BatchJob, policy BatchPolicy) (<-chan BatchResult, error) {
+	return p.service.ProcessBatchWithPolicy(ctx, jobs, policy)
+}
+
+// ProcessDirectory discovers audio files under inputDir (by default the
+// common audio extensions) and processes them as a batch, mirroring the
+// directory structure under outputDir
+func (p *Processor) ProcessDirectory(ctx context.Context, inputDir, outputDir string, dir DirOptions, opts ...ports.Option) (<-chan BatchResult, error) {
+	return p.service.ProcessDirectory(ctx, inputDir, outputDir, dir, opts...)
+}
+
+// DirectoryJobs builds the batch ProcessDirectory would run without
+// running it, e.g. to pass it to ProcessBatchWithPolicy. Inputs that would
+// share an output are rejected.
+func (p *Processor) DirectoryJobs(ctx context.Context, inputDir, outputDir string, dir DirOptions, opts ...ports.Option) ([]BatchJob, error) {
+	return p.service.DirectoryJobs(ctx, inputDir, outputDir, dir, opts...)
+}
+
+// Watch turns dir into a hot folder: new files are processed once they stop
+// growing, with outputs mirrored under rules.OutputDir. Results arrive on
+// the returned channel, which must be drained, until ctx is canceled.
+func (p *Processor) Watch(ctx context.Context, dir string, rules WatchRules, opts ...ports.Option) (<-chan WatchResult, error) {
+	return p.service.Watch(ctx, dir, rules, opts...)
+}
+
+// ReconcileBatch compares the expected outputs of a finished batch against
+// storage, reporting missing, zero-byte and orphaned files
+func (p *Processor) ReconcileBatch(ctx context.Context, jobs []BatchJob) (*ReconciliationReport, error) {
+	return p.service.ReconcileBatch(ctx, jobs)
+}
+
+// FindDuplicates reports the jobs of a batch whose inputs hold the same
+// audio, compared by a hash of the decoded samples, and that would be
+// encoded with the same settings. Every input is decoded once.
+func (p *Processor) FindDuplicates(ctx context.Context, jobs []BatchJob) (*DuplicateReport, error) {
+	return p.service.FindDuplicates(ctx, jobs)
+}
+
+// ProcessBatchDeduplicated processes jobs like ProcessBatch after running
+// FindDuplicates. DuplicateSkip encodes one job per group and skips the
+// rest; DuplicateLink also hard-links (or copies) the encoded output to
+// the skipped jobs' output paths. DuplicateFlag only reports them.
+func (p *Processor) ProcessBatchDeduplicated(ctx context.Context, jobs []BatchJob, action DuplicateAction) (<-chan BatchResult, *DuplicateReport, error) {
+	return p.service.ProcessBatchDeduplicated(ctx, jobs, action)
+}
+
 // ProbeAudio returns metadata about an audio file without processing
 func (p *Processor) ProbeAudio(ctx context.Context, inputPath string) (*AudioMetadata, error) {
 	return p.service.ProbeAudio(ctx, inputPath)
 }
 
-// Close flushes the logger and releases resources
-func (p *Processor) Close() {
+// ProcessAudioReader processes audio streamed from r without writing it to
+// disk first. Use WithInputFormat to hint the container format.
+func (p *Processor) ProcessAudioReader(ctx context.Context, r io.Reader, output string, opts ...ports.Option) (*ProcessingResult, error) {
+	return p.service.ProcessAudioReader(ctx, r, output, opts...)
+}
+
+// ProcessAudioWriter processes input and streams the encoded result into w
+// (e.g. an HTTP response) without a temp file. Only streamable containers
+// are allowed; the container is derived from the codec unless
+// WithOutputFormat is given.
+func (p *Processor) ProcessAudioWriter(ctx context.Context, input string, w io.Writer, opts ...ports.Option) (*ProcessingResult, error) {
+	return p.service.ProcessAudioWriter(ctx, input, w, opts...)
+}
+
+// PlanAudio returns the ffmpeg command plan ProcessAudio would execute for
+// the given arguments. Plans serialize to JSON and can be executed later.
+func (p *Processor) PlanAudio(ctx context.Context, inputPath, outputPath string, opts ...ports.Option) (*CommandPlan, error) {
+	return p.service.PlanAudio(ctx, inputPath, outputPath, opts...)
+}
+
+// ExecutePlan runs a command plan previously produced by PlanAudio
+func (p *Processor) ExecutePlan(ctx context.Context, plan *CommandPlan) error {
+	return p.service.ExecutePlan(ctx, plan)
+}
+
+// ExecutePlans runs command plans as one piped multi-stage command: every
+// plan but the last writes its output to PlanPipeOutput (e.g. PCM with
+// "-f", "nut") and the next one reads it from PlanPipeInput, so no
+// intermediate files are written
+func (p *Processor) ExecutePlans(ctx context.Context, plans ...*CommandPlan) error {
+	return p.service.ExecutePlans(ctx, plans...)
+}
+
+// GenerateTone renders a synthetic sine, noise or sweep signal to output.
+// Useful for creating deterministic test fixtures without binary files.
+func (p *Processor) GenerateTone(ctx context.Context, spec ToneSpec, output string, opts ...ports.Option) (*ProcessingResult, error) {
+	return p.service.GenerateTone(ctx, spec, output, opts...)
+}
+
+// GeneratePreview produces a faded preview clip (30 seconds by default)
+// of input, e.g. for storefront previews
+func (p *Processor) GeneratePreview(ctx context.Context, input, output string, preview PreviewOptions, opts ...ports.Option) (*ProcessingResult, error) {
+	return p.service.GeneratePreview(ctx, input, output, preview, opts...)
+}
+
+// GenerateWaveform returns audiowaveform-compatible peaks for input. Use
+// json.Marshal on the result for JSON, or Waveform.WriteDat for binary.
+func (p *Processor) GenerateWaveform(ctx context.Context, input string, opts WaveformOptions) (*Waveform, error) {
+	return p.service.GenerateWaveform(ctx, input, opts)
+}
+
+// AnalyzeAudio decodes input and measures its per-channel sample peaks, DC
+// offset and clipping: the clipped sample count and the regions holding
+// them, with timestamps, so hot masters can be flagged before normalization
+// hides them. WithDCRemoval fixes an offset when encoding.
+func (p *Processor) AnalyzeAudio(ctx context.Context, input string, opts AnalysisOptions) (*AudioAnalysis, error) {
+	return p.service.AnalyzeAudio(ctx, input, opts)
+}
+
+// BuildAudiobook concatenates inputs, one chapter each, into an AAC
+// audiobook at output (.m4b) with chapter markers. Book tags such as
+// title, artist and album come from WithMetadata and artwork from
+// WithCoverArt; the inputs' own tags are not copied.
+func (p *Processor) BuildAudiobook(ctx context.Context, inputs []ChapterInput, output string, opts ...ports.Option) (*ProcessingResult, error) {
+	return p.service.BuildAudiobook(ctx, inputs, output, opts...)
+}
+
+// ProcessPodcastEpisode masters a spoken-word recording for podcast apps:
+// denoise, gate, compressor, de-esser, loudness normalization to -16 LUFS
+// and a limiter, encoded with the podcast preset to a mono MP3 unless
+// podcast.Stereo is set. Chapters from WithChapters are written as ID3
+// CHAP frames; opts override the preset, e.g. WithCodec for an M4A.
+func (p *Processor) ProcessPodcastEpisode(ctx context.Context, input, output string, podcast PodcastOptions, opts ...ports.Option) (*ProcessingResult, error) {
+	return p.service.ProcessPodcastEpisode(ctx, input, output, podcast, opts...)
+}
+
+// ExtractCoverArt writes the embedded cover art of input to output, a .jpg,
+// .png or .bmp file. Files without artwork fail validation; check
+// AudioMetadata.HasCoverArt to flag them beforehand.
+func (p *Processor) ExtractCoverArt(ctx context.Context, input, output string) (*ProcessingResult, error) {
+	return p.service.ExtractCoverArt(ctx, input, output)
+}
+
+// VerifyLossless proves that output, e.g. a FLAC or ALAC transcode of
+// input, holds bit-identical audio by comparing MD5s of the decoded PCM
+// (ffmpeg -f md5). Tags and container don't count. Keep the result's MD5s
+// as the per-file evidence archives ask for.
+func (p *Processor) VerifyLossless(ctx context.Context, input, output string) (*LosslessVerification, error) {
+	return p.service.VerifyLossless(ctx, input, output)
+}
+
+// ValidateFile decodes path in full, discarding the output, and reports
+// decode errors, truncation and container warnings, so damaged uploads can
+// be rejected before they are processed. Only operational failures, such
+// as a missing file, are returned as errors.
+func (p *Processor) ValidateFile(ctx context.Context, path string) (*FileValidation, error) {
+	return p.service.ValidateFile(ctx, path)
+}
+
+// ProcessLadder decodes input once and encodes every rendition (bitrate
+// and/or codec) in a single ffmpeg invocation, returning one result per spec
+func (p *Processor) ProcessLadder(ctx context.Context, input string, specs []RenditionSpec, opts ...ports.Option) ([]*ProcessingResult, error) {
+	return p.service.ProcessLadder(ctx, input, specs, opts...)
+}
+
+// ProcessChains splits a chained Ogg file (several logical streams back to
+// back, common in stream rips) and processes each chain into its own output.
+// outputPattern must contain ChainPlaceholder, e.g. "out/track-{chain}.opus".
+// Unchained and non-Ogg inputs are processed into a single output, numbered
+// 01. WithTimeout limits each chain. On error the results of the chains
+// completed so far are returned.
+func (p *Processor) ProcessChains(ctx context.Context, input, outputPattern string, opts ...ports.Option) ([]*ProcessingResult, error) {
+	return p.service.ProcessChains(ctx, input, outputPattern, opts...)
+}
+
+// SplitByCue cuts a single-file rip into the tracks listed in the CUE sheet
+// at cuePath, writing "NN - Title.ext" files to outDir with the extension
+// of the codec. Track, title, artist, album, genre and date tags come from
+// the sheet; WithMetadata overrides them. WithTimeout limits each track. On
+// error the results of the tracks completed so far are returned.
+func (p *Processor) SplitByCue(ctx context.Context, input, cuePath, outDir string, opts ...ports.Option) ([]*ProcessingResult, error) {
+	return p.service.SplitByCue(ctx, input, cuePath, outDir, opts...)
+}
+
+// PackageHLS encodes input into segments and an m3u8 master playlist under
+// dir, one variant per bitrate, in a single ffmpeg invocation
+func (p *Processor) PackageHLS(ctx context.Context, input, dir string, hls HLSOptions, opts ...ports.Option) (*PackageResult, error) {
+	return p.service.PackageHLS(ctx, input, dir, hls, opts...)
+}
+
+// PackageDASH encodes input into fMP4 segments and a manifest.mpd under dir,
+// sharing the bitrate ladder logic with PackageHLS
+func (p *Processor) PackageDASH(ctx context.Context, input, dir string, dash DASHOptions, opts ...ports.Option) (*PackageResult, error) {
+	return p.service.PackageDASH(ctx, input, dir, dash, opts...)
+}
+
+// RegisterTemplate adds or replaces a named job template
+func (p *Processor) RegisterTemplate(t JobTemplate) error {
+	return p.service.RegisterTemplate(t)
+}
+
+// ProcessTemplate runs one job per parameter set using the named template,
+// e.g. ProcessTemplate(ctx, "weekly-show", map[string]string{"episode": "42"})
+func (p *Processor) ProcessTemplate(ctx context.Context, name string, params ...map[string]string) (<-chan BatchResult, error) {
+	return p.service.ProcessTemplate(ctx, name, params...)
+}
+
+// NewFixityChecker creates a scheduler that periodically re-verifies stored
+// outputs recorded in store (checksum and optional full decode)
+func (p *Processor) NewFixityChecker(store FixityStore, cfg FixityConfig) (*fixity.Checker, error) {
+	hasher, ok := p.storage.(fixity.Checksummer)
+	if !ok {
+		return nil, fmt.Errorf("storage provider does not support checksums")
+	}
+	return fixity.NewChecker(store, p.storage, hasher, p.executor, cfg, p.log)
+}
+
+// NewQueueWorker creates a worker that pulls jobs from a shared queue (see
+// infrastructure/queue/redis) and processes them on this processor
+func (p *Processor) NewQueueWorker(queue JobQueue, cfg WorkerConfig) (*worker.Worker, error) {
+	return worker.New(queue, p.service, cfg, p.log)
+}
+
+// CancelJob cancels the queued or running batch job with the given ID,
+// killing its ffmpeg process, while the rest of its batch continues. Its
+// BatchResult fails with ErrCodeCanceled. It reports whether a job matched.
+func (p *Processor) CancelJob(jobID string) bool {
+	return p.service.CancelJob(jobID)
+}
+
+// DeadLetters returns the batch jobs that failed after exhausting their
+// retries, with their errors and attempt history, oldest first
+func (p *Processor) DeadLetters(ctx context.Context) ([]DeadLetter, error) {
+	return p.service.DeadLetters(ctx)
+}
+
+// RetryFailed re-runs the dead-lettered jobs as a new batch, e.g. after
+// installing a missing codec. Jobs that fail again are dead-lettered anew.
+func (p *Processor) RetryFailed(ctx context.Context) (<-chan BatchResult, error) {
+	return p.service.RetryFailed(ctx)
+}
+
+// HealthCheck verifies that ffmpeg and ffprobe run, each codec has an
+// encoder, the temp directory is writable with free space and the worker
+// pool accepts work. The report serializes to JSON for /healthz endpoints.
+func (p *Processor) HealthCheck(ctx context.Context) HealthReport {
+	return p.service.HealthCheck(ctx)
+}
+
+// Capabilities returns the ffmpeg version, encoders and filters detected
+// by New, or nil when detection was skipped or failed. Jobs needing an
+// encoder or filter the build lacks fail validation up front.
+func (p *Processor) Capabilities() *Capabilities {
+	return p.service.Capabilities()
+}
+
+// Stats returns a snapshot of runtime statistics: jobs in flight, queued,
+// completed and failed, encode speed, bytes processed and per-codec counts
+func (p *Processor) Stats() Stats {
+	return p.service.Stats()
+}
+
+// AddReporter subscribes r to the progress updates of all jobs, in
+// addition to ProgressCh
+func (p *Processor) AddReporter(r progress.Reporter) {
+	p.service.AddReporter(r)
+}
+
+// NewBusConsumer creates a consumer that runs JSON job messages from a
+// message bus (see infrastructure/bus/nats) on this processor and publishes
+// progress and result events back
+func (p *Processor) NewBusConsumer(transport BusTransport, cfg BusConfig) (*bus.Consumer, error) {
+	c, err := bus.NewConsumer(transport, p.service, cfg, p.log)
+	if err != nil {
+		return nil, err
+	}
+	p.service.AddReporter(c)
+	return c, nil
+}
+
+// Use inserts a custom stage into the pipeline, run after validation and
+// before the input is probed (e.g. a virus scan or an API call). Stages run
+// in registration order; a failing stage fails the job. Call Use before
+// processing starts.
+func (p *Processor) Use(name string, stage Stage) {
+	p.service.Use(name, stage)
+}
+
+// UseAfter inserts a custom stage run once outputs are encoded and
+// verified, before they are moved into place (e.g. watermarking). The
+// StageJob's output paths point at the files being written.
+func (p *Processor) UseAfter(name string, stage Stage) {
+	p.service.UseAfter(name, stage)
+}
+
+// UseMiddleware wraps every pipeline stage, built-in and custom, with mw,
+// e.g. for per-stage timing or logging. The first middleware is the
+// outermost; StageName tells stages apart. Call it before processing
+// starts.
+func (p *Processor) UseMiddleware(mw ...StageMiddleware) {
+	p.service.UseMiddleware(mw...)
+}
+
+// Shutdown stops accepting jobs, failing queued batch jobs with
+// ErrShuttingDown, and waits for running jobs to finish. Jobs still running
+// when ctx is done are canceled, and Shutdown returns ctx's error once
+// their ffmpeg processes have exited.
+func (p *Processor) Shutdown(ctx context.Context) error {
+	err := p.service.Shutdown(ctx)
 	_ = p.log.Sync()
+	return err
+}
+
+// Close cancels any running jobs, waits for them to exit and flushes the
+// logger. Use Shutdown to let running jobs finish first.
+func (p *Processor) Close() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_ = p.Shutdown(ctx)
 }
\ No newline at end of file
@@ -1,6 +1,7 @@
 package errors
 
 import (
+	"context"
 	"errors"
 	"fmt"
 )
@@ -36,6 +37,11 @@ func (e *MusicProcError) Unwrap() error {
 	return e.Cause
 }
 
+// ErrorCode returns e.Code; it is promoted to every structured error type
+func (e *MusicProcError) ErrorCode() ErrorCode {
+	return e.Code
+}
+
 // ProcessingError represents a general audio processing failure
 type ProcessingError struct {
 	MusicProcError
@@ -106,6 +112,62 @@ func (e *ValidationError) Error() string {
 	return fmt.Sprintf("[%s] field=%s value=%v: %s", e.Code, e.Field, e.Value, e.Message)
 }
 
+// IOError represents a storage or filesystem failure
+type IOError struct {
+	MusicProcError
+	Path string
+}
+
+func NewIOError(path, message string, cause error) *IOError {
+	return &IOError{
+		MusicProcError: MusicProcError{
+			Code:    ErrCodeIO,
+			Message: message,
+			Cause:   cause,
+		},
+		Path: path,
+	}
+}
+
+func (e *IOError) Error() string {
+	base := e.MusicProcError.Error()
+	return fmt.Sprintf("%s (path=%s)", base, e.Path)
+}
+
+// ErrJobCanceled is the cause of jobs stopped individually, e.g. by
+// Processor.CancelJob
+var ErrJobCanceled = &MusicProcError{Code: ErrCodeCanceled, Message: "job canceled"}
+
+// ErrBatchAborted is the cause of jobs canceled because their batch's
+// failure policy tripped
+var ErrBatchAborted = &MusicProcError{Code: ErrCodeCanceled, Message: "batch aborted by failure policy"}
+
+// ErrShuttingDown is returned for jobs submitted or still queued after
+// Processor.Shutdown was called, and is the cause of jobs it cancels
+var ErrShuttingDown = &MusicProcError{Code: ErrCodeCanceled, Message: "processor shutting down"}
+
+// ErrInsufficientSpace is the cause of IOErrors failing a job up front
+// because a volume lacks room for its output. Retrying won't free space,
+// so IsRetryable is false for it.
+var ErrInsufficientSpace = &MusicProcError{Code: ErrCodeIO, Message: "insufficient disk space"}
+
+// CodeOf returns the code of the first structured error in err's chain.
+// Context errors map onto ErrCodeTimeout and ErrCodeCanceled; anything
+// else is ErrCodeProcessing.
+func CodeOf(err error) ErrorCode {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrCodeTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrCodeCanceled
+	}
+	var coded interface{ ErrorCode() ErrorCode }
+	if errors.As(err, &coded) {
+		return coded.ErrorCode()
+	}
+	return ErrCodeProcessing
+}
+
 // Is enables errors.Is checks
 func Is(err, target error) bool {
 	return errors.Is(err, target)
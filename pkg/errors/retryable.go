@@ -0,0 +1,87 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// transientStderr are stderr fragments of failures that may go away when
+// the run is repeated, e.g. resource exhaustion or flaky network inputs
+var transientStderr = []string{
+	"resource temporarily unavailable",
+	"cannot allocate memory",
+	"input/output error",
+	"i/o error",
+	"broken pipe",
+	"connection reset",
+	"connection refused",
+	"connection timed out",
+	"operation timed out",
+	"network is unreachable",
+	"server returned 5",
+}
+
+// permanentStderr are stderr fragments of failures that repeat on every
+// run with the same input and arguments
+var permanentStderr = []string{
+	"unknown encoder",
+	"unknown decoder",
+	"encoder not found",
+	"decoder not found",
+	"invalid data found when processing input",
+	"is not supported",
+	"not supported by",
+	"unsupported sample rate",
+	"invalid sample format",
+	"invalid channel layout",
+	"unrecognized option",
+	"option not found",
+	"no such filter",
+	"error parsing filterchain",
+	"error initializing filter",
+	"does not contain any stream",
+	"could not find codec parameters",
+	"moov atom not found",
+	"invalid argument",
+	"no such file or directory",
+	"permission denied",
+	"no space left on device",
+	"server returned 4",
+}
+
+// Retryable reports whether the ffmpeg run may succeed when repeated. It
+// is false for failures its stderr shows to be deterministic, such as an
+// unknown encoder, invalid input data or an unsupported sample rate.
+// Unrecognized failures are assumed to be transient.
+func (e *FFmpegError) Retryable() bool {
+	stderr := strings.ToLower(e.Stderr)
+	for _, s := range transientStderr {
+		if strings.Contains(stderr, s) {
+			return true
+		}
+	}
+	for _, s := range permanentStderr {
+		if strings.Contains(stderr, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsRetryable reports whether the operation that failed with err is worth
+// retrying. Validation errors, cancellations, insufficient disk space and
+// deterministic ffmpeg failures are not.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrInsufficientSpace) {
+		return false
+	}
+	switch CodeOf(err) {
+	case ErrCodeValidation, ErrCodeCanceled, ErrCodeTimeout:
+		return false
+	}
+	if ffErr, ok := As[*FFmpegError](err); ok {
+		return ffErr.Retryable()
+	}
+	return true
+}
@@ -16,6 +16,12 @@ type Logger struct {
 
 // New creates a production-ready logger
 func New(development bool) (*Logger, error) {
+	return NewWithLevel(development, "")
+}
+
+// NewWithLevel is New logging at level ("debug", "info", "warn" or
+// "error"); empty keeps the default of the mode
+func NewWithLevel(development bool, level string) (*Logger, error) {
 	var cfg zap.Config
 	if development {
 		cfg = zap.NewDevelopmentConfig()
@@ -23,6 +29,13 @@ func New(development bool) (*Logger, error) {
 		cfg = zap.NewProductionConfig()
 		cfg.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 	}
+	if level != "" {
+		l, err := zapcore.ParseLevel(level)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Level = zap.NewAtomicLevelAt(l)
+	}
 
 	z, err := cfg.Build(zap.AddCallerSkip(1))
 	if err != nil {
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package pathutil
+
+// extendedPath is a no-op outside Windows
+func extendedPath(path string) string {
+	return path
+}
@@ -0,0 +1,27 @@
+//go:build windows
+
+package pathutil
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// maxPath is the Win32 MAX_PATH limit that \\?\ paths are exempt from
+const maxPath = 260
+
+// extendedPath adds the \\?\ prefix to paths at or above MAX_PATH
+func extendedPath(path string) string {
+	if len(path) < maxPath || strings.HasPrefix(path, `\\?\`) {
+		return path
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if strings.HasPrefix(abs, `\\`) {
+		// UNC share: \\server\share becomes \\?\UNC\server\share
+		return `\\?\UNC\` + abs[2:]
+	}
+	return `\\?\` + abs
+}
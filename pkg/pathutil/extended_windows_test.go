@@ -0,0 +1,29 @@
+//go:build windows
+
+package pathutil
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtendedPath(t *testing.T) {
+	long := strings.Repeat("a", maxPath)
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"short", `C:\music\in.wav`, `C:\music\in.wav`},
+		{"long drive path", `C:\` + long, `\\?\C:\` + long},
+		{"long unc path", `\\server\share\` + long, `\\?\UNC\server\share\` + long},
+		{"already extended", `\\?\C:\` + long, `\\?\C:\` + long},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extendedPath(tt.in); got != tt.want {
+				t.Errorf("extendedPath(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+// Package pathutil makes user-supplied file paths safe to hand to ffmpeg and
+// to compare across operating systems
+package pathutil
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxNameBytes is the file name length limit shared by ext4, APFS, NTFS
+// (in UTF-16 units, which is never less than this in bytes) and most others
+const MaxNameBytes = 255
+
+// NFC returns path in Unicode normalization form C. macOS reports names in
+// NFD, so the same file can otherwise appear under two different strings.
+func NFC(path string) string {
+	return norm.NFC.String(path)
+}
+
+// Key returns a cleaned, NFC-normalized form of path for use as a map key
+// or in comparisons
+func Key(path string) string {
+	return filepath.Clean(NFC(path))
+}
+
+// FFmpegArg converts a local path into a form ffmpeg can't misinterpret.
+// The "file:" protocol prefix stops names containing ':' from being read as
+// a protocol and names starting with '-' from being read as options; on
+// Windows, long paths also get the \\?\ prefix.
+func FFmpegArg(path string) string {
+	if path == "" || strings.HasPrefix(path, "file:") {
+		return path
+	}
+	return "file:" + extendedPath(path)
+}
+
+// SanitizeName makes a single path element safe on all common filesystems.
+// Characters reserved on Windows and control characters are replaced with
+// '_', trailing dots and spaces are trimmed, device names reserved on
+// Windows (CON, NUL, COM1, ...) get a '_' appended to their stem, and the
+// name is shortened to maxBytes (MaxNameBytes when <= 0) on a rune
+// boundary, keeping the extension.
+func SanitizeName(name string, maxBytes int) string {
+	if maxBytes <= 0 {
+		maxBytes = MaxNameBytes
+	}
+
+	var b strings.Builder
+	for _, r := range NFC(name) {
+		switch {
+		case r < 0x20 || r == 0x7f, strings.ContainsRune(`<>:"/\|?*`, r):
+			b.WriteRune('_')
+		case r == utf8.RuneError:
+			b.WriteRune('_')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	name = strings.TrimRight(b.String(), ". ")
+	if name == "" {
+		return "_"
+	}
+	if stem, _, _ := strings.Cut(name, "."); isReservedName(stem) {
+		name = stem + "_" + name[len(stem):]
+	}
+	if len(name) <= maxBytes {
+		return name
+	}
+
+	ext := filepath.Ext(name)
+	if len(ext) >= maxBytes {
+		ext = ""
+	}
+	return truncate(strings.TrimSuffix(name, ext), maxBytes-len(ext)) + ext
+}
+
+// isReservedName reports whether stem names a Windows device, which opens
+// the device instead of a file whatever the extension. Windows ignores
+// trailing spaces when matching them.
+func isReservedName(stem string) bool {
+	stem = strings.ToUpper(strings.TrimRight(stem, " "))
+	switch stem {
+	case "CON", "PRN", "AUX", "NUL":
+		return true
+	}
+	if len(stem) == 4 && (strings.HasPrefix(stem, "COM") || strings.HasPrefix(stem, "LPT")) {
+		return stem[3] >= '1' && stem[3] <= '9'
+	}
+	return false
+}
+
+// truncate shortens s to at most n bytes without splitting a rune
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
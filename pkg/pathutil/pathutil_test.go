@@ -0,0 +1,132 @@
+package pathutil
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestNFC(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"ascii", "track.wav", "track.wav"},
+		{"nfd to nfc", "Cafe\u0301.wav", "Caf\u00e9.wav"},
+		{"already nfc", "Caf\u00e9.wav", "Caf\u00e9.wav"},
+		{"hangul jamo", "\u1112\u1161\u11ab.mp3", "\ud55c.mp3"},
+		{"emoji", "\U0001f3b5 mix.flac", "\U0001f3b5 mix.flac"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NFC(tt.in); got != tt.want {
+				t.Errorf("NFC(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+	}{
+		{"nfd and nfc", "music/Cafe\u0301.wav", "music/Caf\u00e9.wav"},
+		{"unclean", "music/./albums/../Caf\u00e9.wav", "music/Caf\u00e9.wav"},
+		{"trailing slash", "music/", "music"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if Key(tt.a) != Key(tt.b) {
+				t.Errorf("Key(%q) = %q, Key(%q) = %q, want equal", tt.a, Key(tt.a), tt.b, Key(tt.b))
+			}
+		})
+	}
+}
+
+func TestFFmpegArg(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "", ""},
+		{"plain", "in.wav", "file:in.wav"},
+		{"colon", "take:1.wav", "file:take:1.wav"},
+		{"leading dash", "-y.wav", "file:-y.wav"},
+		{"already prefixed", "file:in.wav", "file:in.wav"},
+		{"unicode", "\u97f3\u697d/\U0001f3b5.wav", "file:\u97f3\u697d/\U0001f3b5.wav"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FFmpegArg(tt.in); got != tt.want {
+				t.Errorf("FFmpegArg(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		maxBytes int
+		want     string
+	}{
+		{"plain", "track 01.wav", 0, "track 01.wav"},
+		{"reserved chars", `a<b>c:d"e/f\g|h?i*.wav`, 0, "a_b_c_d_e_f_g_h_i_.wav"},
+		{"control chars", "a\tb\x7f.wav", 0, "a_b_.wav"},
+		{"invalid utf8", "a\xffb.wav", 0, "a_b.wav"},
+		{"trailing dots and spaces", "name. . ", 0, "name"},
+		{"only dots", "...", 0, "_"},
+		{"empty", "", 0, "_"},
+		{"nfd normalized", "Cafe\u0301.wav", 0, "Caf\u00e9.wav"},
+		{"emoji kept", "\U0001f3b5\U0001f3a7.mp3", 0, "\U0001f3b5\U0001f3a7.mp3"},
+		{"device name", "CON", 0, "CON_"},
+		{"device name with extension", "nul.wav", 0, "nul_.wav"},
+		{"device name with double extension", "Aux.tar.gz", 0, "Aux_.tar.gz"},
+		{"numbered device name", "com1.mp3", 0, "com1_.mp3"},
+		{"printer device name", "LPT9", 0, "LPT9_"},
+		{"device name with trailing space", "con .wav", 0, "con _.wav"},
+		{"device prefix only", "CONCERT.wav", 0, "CONCERT.wav"},
+		{"com0 is not a device", "COM0.wav", 0, "COM0.wav"},
+		{"truncated keeps extension", "abcdefghij.wav", 8, "abcd.wav"},
+		{"truncated on rune boundary", "\u00e9\u00e9\u00e9\u00e9.wav", 9, "\u00e9\u00e9.wav"},
+		{"truncated emoji", "\U0001f3b5\U0001f3b5\U0001f3b5.mp3", 10, "\U0001f3b5.mp3"},
+		{"extension longer than limit", "a.abcdefgh", 4, "a.ab"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeName(tt.in, tt.maxBytes); got != tt.want {
+				t.Errorf("SanitizeName(%q, %d) = %q, want %q", tt.in, tt.maxBytes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeNameLength(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"ascii", strings.Repeat("a", 300) + ".flac"},
+		{"two byte", strings.Repeat("\u00e9", 200) + ".flac"},
+		{"three byte", strings.Repeat("\u97f3", 200) + ".flac"},
+		{"emoji", strings.Repeat("\U0001f3b5", 100) + ".flac"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SanitizeName(tt.in, 0)
+			if len(got) > MaxNameBytes {
+				t.Errorf("len = %d, want <= %d", len(got), MaxNameBytes)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("result %q is not valid UTF-8", got)
+			}
+			if !strings.HasSuffix(got, ".flac") {
+				t.Errorf("result %q lost its extension", got)
+			}
+		})
+	}
+}
@@ -15,15 +15,108 @@ const (
 	StageFilter     Stage = "filter"
 	StageEncode     Stage = "encode"
 	StageDone       Stage = "done"
+
+	// Staging stages for remote storage
+	StageDownload Stage = "download"
+	StageUpload   Stage = "upload"
+
+	// Per-pass stages for multi-pass operations
+	StageAnalyzePass1 Stage = "analyze_pass1"
+	StageEncodePass2  Stage = "encode_pass2"
 )
 
 // Update holds a progress update
 type Update struct {
 	JobID     string
+	Profile   string // processing profile of the job, if any
 	Stage     Stage
-	Percent   float64
+	Percent   float64 // overall percent across all passes
 	Message   string
 	Timestamp time.Time
+
+	// Multi-pass operations set these; single-pass updates leave them zero
+	Pass        int     // 1-based index of the current pass
+	TotalPasses int     // number of passes in the operation
+	PassPercent float64 // percent complete within the current pass
+
+	// Encode updates parsed from ffmpeg's progress stream set these; other
+	// updates leave them zero
+	ETA          time.Duration // estimated time until the encode finishes
+	Speed        float64       // realtime factor, e.g. 2 encodes a minute of audio in 30s
+	BytesWritten int64         // output bytes written so far
+}
+
+// Pass describes one pass of a multi-pass operation and its share of the
+// total work
+type Pass struct {
+	Stage  Stage
+	Weight float64 // relative cost, e.g. 1 for analysis and 2 for encode
+}
+
+// MultiPass maps per-pass progress onto an overall percentage, weighting
+// each pass by its relative cost
+type MultiPass struct {
+	passes []Pass
+	total  float64
+}
+
+// NewMultiPass creates a tracker for the given passes. Non-positive weights
+// are treated as 1.
+func NewMultiPass(passes ...Pass) *MultiPass {
+	m := &MultiPass{passes: make([]Pass, len(passes))}
+	for i, p := range passes {
+		if p.Weight <= 0 {
+			p.Weight = 1
+		}
+		m.passes[i] = p
+		m.total += p.Weight
+	}
+	return m
+}
+
+// Overall returns the overall percent given the 0-based pass index and the
+// percent complete within that pass
+func (m *MultiPass) Overall(index int, passPercent float64) float64 {
+	if len(m.passes) == 0 || m.total == 0 {
+		return passPercent
+	}
+	if index < 0 {
+		return 0
+	}
+	if index >= len(m.passes) {
+		return 100
+	}
+	if passPercent < 0 {
+		passPercent = 0
+	}
+	if passPercent > 100 {
+		passPercent = 100
+	}
+
+	done := 0.0
+	for _, p := range m.passes[:index] {
+		done += p.Weight
+	}
+	done += m.passes[index].Weight * passPercent / 100
+	return done / m.total * 100
+}
+
+// Update builds a progress update for the given 0-based pass index
+func (m *MultiPass) Update(jobID string, index int, passPercent float64, msg string) Update {
+	stage := StageEncode
+	if index >= 0 && index < len(m.passes) {
+		stage = m.passes[index].Stage
+	}
+	return Update{
+		JobID:       jobID,
+		Stage:       stage,
+		Percent:     m.Overall(index, passPercent),
+		Message:     msg,
+		Timestamp:   time.Now(),
+		Pass:        index + 1,
+		TotalPasses: len(m.passes),
+		PassPercent: passPercent,
+	}
 }
 
 // Reporter is the interface for progress reporting
@@ -31,6 +124,11 @@ type Reporter interface {
 	Report(update Update)
 }
 
+// ReporterFunc adapts a function to the Reporter interface
+type ReporterFunc func(update Update)
+
+func (f ReporterFunc) Report(update Update) { f(update) }
+
 // ChannelReporter sends updates to a channel
 type ChannelReporter struct {
 	ch chan<- Update
@@ -2,6 +2,7 @@ package retry
 
 import (
 	"context"
+	"math/rand"
 	"time"
 )
 
@@ -11,6 +12,15 @@ type Config struct {
 	Delay       time.Duration
 	Multiplier  float64
 	MaxDelay    time.Duration
+
+	// Jitter randomizes each delay by up to this fraction in either
+	// direction (0.2 waits 80-120% of it), so that jobs failing together
+	// don't retry in lockstep. 0 disables it.
+	Jitter float64
+
+	// OnRetry is called before each retry with the 1-based number of the
+	// attempt that failed and its error
+	OnRetry func(attempt int, err error)
 }
 
 // DefaultConfig returns sensible retry defaults
@@ -20,6 +30,7 @@ func DefaultConfig() Config {
 		Delay:       time.Second,
 		Multiplier:  2.0,
 		MaxDelay:    30 * time.Second,
+		Jitter:      0.2,
 	}
 }
 
@@ -41,12 +52,15 @@ func Do(ctx context.Context, cfg Config, fn func() error) error {
 		if attempt == cfg.MaxAttempts-1 {
 			break
 		}
+		if cfg.OnRetry != nil {
+			cfg.OnRetry(attempt+1, lastErr)
+		}
 
 		// Apply exponential backoff
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case <-time.After(delay):
+		case <-time.After(jitter(delay, cfg.Jitter)):
 		}
 
 		delay = time.Duration(float64(delay) * cfg.Multiplier)
@@ -56,4 +70,15 @@ func Do(ctx context.Context, cfg Config, fn func() error) error {
 	}
 
 	return lastErr
-}
\ No newline at end of file
+}
+
+// jitter spreads d randomly by up to ±fraction
+func jitter(d time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 || d <= 0 {
+		return d
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	return time.Duration(float64(d) * (1 + fraction*(2*rand.Float64()-1)))
+}
@@ -0,0 +1,125 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Window is a daily time-of-day range during which work may run. Windows
+// where End is before Start wrap past midnight (e.g. 22:00-06:00).
+type Window struct {
+	Start    time.Duration // offset from local midnight
+	End      time.Duration // offset from local midnight
+	Location *time.Location
+}
+
+// ParseWindow parses "HH:MM-HH:MM" in the given location (nil means local)
+func ParseWindow(s string, loc *time.Location) (Window, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return Window{}, fmt.Errorf("invalid window %q: expected HH:MM-HH:MM", s)
+	}
+	start, err := parseClock(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid window %q: %w", s, err)
+	}
+	end, err := parseClock(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid window %q: %w", s, err)
+	}
+	return Window{Start: start, End: end, Location: loc}, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (w Window) location() *time.Location {
+	if w.Location == nil {
+		return time.Local
+	}
+	return w.Location
+}
+
+func (w Window) midnight(t time.Time) time.Time {
+	t = t.In(w.location())
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// Contains reports whether t falls inside the window
+func (w Window) Contains(t time.Time) bool {
+	if w.Start == w.End {
+		return true // a zero-length window means "always open"
+	}
+	offset := t.Sub(w.midnight(t))
+	if w.Start < w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+// NextOpen returns t if the window is open, otherwise the next time it opens
+func (w Window) NextOpen(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+	open := w.midnight(t).Add(w.Start)
+	if !open.After(t) {
+		open = w.midnight(t).AddDate(0, 0, 1).Add(w.Start)
+	}
+	return open
+}
+
+// Windows is a set of processing windows; work may run when any is open.
+// An empty set is always open.
+type Windows []Window
+
+// Open reports whether any window contains t
+func (ws Windows) Open(t time.Time) bool {
+	if len(ws) == 0 {
+		return true
+	}
+	for _, w := range ws {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpen returns the earliest time at or after t when a window is open
+func (ws Windows) NextOpen(t time.Time) time.Time {
+	if ws.Open(t) {
+		return t
+	}
+	next := ws[0].NextOpen(t)
+	for _, w := range ws[1:] {
+		if n := w.NextOpen(t); n.Before(next) {
+			next = n
+		}
+	}
+	return next
+}
+
+// Wait blocks until a window is open or ctx is done
+func (ws Windows) Wait(ctx context.Context) error {
+	for {
+		now := time.Now()
+		if ws.Open(now) {
+			return nil
+		}
+		timer := time.NewTimer(ws.NextOpen(now).Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}